@@ -18,7 +18,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
@@ -28,10 +28,12 @@ import (
 	"github.com/google/certificate-transparency-go/jsonclient"
 	"github.com/sigstore/rekor-monitor/internal/cmd"
 	"github.com/sigstore/rekor-monitor/pkg/ct"
+	"github.com/sigstore/rekor-monitor/pkg/events"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/notifications"
 	"github.com/sigstore/rekor-monitor/pkg/util"
 	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/sigstore-go/pkg/root"
 )
 
@@ -44,11 +46,12 @@ const (
 )
 
 type CTMonitorLogic struct {
-	ctlogClient     *ctclient.LogClient
-	flags           *cmd.MonitorFlags
-	config          *notifications.IdentityMonitorConfiguration
-	monitoredValues identity.MonitoredValues
-	trustedRoot     *root.TrustedRoot
+	ctlogClient *ctclient.LogClient
+	flags       *cmd.MonitorFlags
+	config      *notifications.IdentityMonitorConfiguration
+	matcher     *identity.Matcher
+	trustedRoot *root.TrustedRoot
+	events      *events.Bus
 }
 
 func (l CTMonitorLogic) Interval() time.Duration {
@@ -59,8 +62,8 @@ func (l CTMonitorLogic) Config() *notifications.IdentityMonitorConfiguration {
 	return l.config
 }
 
-func (l CTMonitorLogic) MonitoredValues() identity.MonitoredValues {
-	return l.monitoredValues
+func (l CTMonitorLogic) Matcher() *identity.Matcher {
+	return l.matcher
 }
 
 func (l CTMonitorLogic) Once() bool {
@@ -75,7 +78,7 @@ func (l CTMonitorLogic) NotificationContextNew() notifications.NotificationConte
 	return notifications.CreateNotificationContext(
 		"ct-monitor",
 		fmt.Sprintf("ct-monitor workflow results for %s", time.Now().Format(time.RFC822)),
-	)
+	).WithLogOrigin(l.flags.ServerURL)
 }
 
 func (l CTMonitorLogic) RunConsistencyCheck(_ context.Context) (cmd.Checkpoint, cmd.LogInfo, error) {
@@ -121,8 +124,16 @@ func (l CTMonitorLogic) GetEndIndex(cur cmd.LogInfo) *int64 {
 	return &checkpointEndIndex
 }
 
-func (l CTMonitorLogic) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
-	return ct.IdentitySearch(ctx, l.ctlogClient, config, monitoredValues)
+func (l CTMonitorLogic) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+	return ct.SearchIdentities(ctx, l.ctlogClient, config, l.flags.ServerURL, matcher)
+}
+
+func (l CTMonitorLogic) ErrorPolicy() cmd.ErrorPolicy {
+	return l.flags.ErrorPolicy()
+}
+
+func (l CTMonitorLogic) Events() *events.Bus {
+	return l.events
 }
 
 // This main function performs a periodic identity search.
@@ -131,8 +142,18 @@ func (l CTMonitorLogic) IdentitySearch(ctx context.Context, config *notification
 func mainWithReturn() int {
 	flags, config, err := cmd.ParseAndLoadConfig(publicCTServerURL, TUFRepository, outputIdentitiesFileName, "ct-monitor")
 	if err != nil {
-		log.Fatalf("error parsing flags and loading config: %v", err)
+		slog.Error("error parsing flags and loading config", logging.Err(err))
+		return 1
+	}
+
+	if flags.ReplayDeadLetters {
+		if err := cmd.ReplayDeadLetters(context.Background(), config); err != nil {
+			slog.Error("error replaying dead-lettered notifications", logging.Err(err))
+			return 1
+		}
+		return 0
 	}
+	slog.SetDefault(logging.WithOrigin(slog.Default(), logging.NewOrigin(flags.ServerURL)))
 	if flags.LogInfoFile == "" {
 		logInfoFileName := fmt.Sprintf("%s.txt", logInfoFileName)
 		flags.LogInfoFile = logInfoFileName
@@ -140,17 +161,20 @@ func mainWithReturn() int {
 
 	tufClient, err := cmd.GetTUFClient(flags)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error getting TUF client", logging.Err(err))
+		return 1
 	}
 
 	trustedRoot, err := root.GetTrustedRoot(tufClient)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error getting trusted root", logging.Err(err))
+		return 1
 	}
 
 	cleanupTrustedCAs, err := cmd.ConfigureTrustedCAs(config, trustedRoot)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error configuring trusted CAs", logging.Err(err))
+		return 1
 	}
 	defer cleanupTrustedCAs()
 
@@ -158,7 +182,7 @@ func mainWithReturn() int {
 	if flags.HTTPSCertChainFile != "" {
 		tlsConfig, err := util.TLSConfigForCA(flags.HTTPSCertChainFile)
 		if err != nil {
-			log.Printf("error getting TLS config: %v", err)
+			slog.Error("error getting TLS config", logging.Err(err))
 			return 1
 		}
 		httpClient = &http.Client{
@@ -171,30 +195,41 @@ func mainWithReturn() int {
 		UserAgent: flags.UserAgent,
 	})
 	if err != nil {
-		log.Printf("getting CT client: %v", err)
+		slog.Error("error getting CT client", logging.Err(err))
 		return 1
 	}
 
 	allOIDMatchers, err := config.MonitoredValues.OIDMatchers.RenderOIDMatchers()
 	if err != nil {
-		fmt.Printf("error parsing OID matchers: %v", err)
+		slog.Error("error parsing OID matchers", logging.Err(err))
+		return 1
 	}
 
 	monitoredValues := identity.MonitoredValues{
 		CertificateIdentities: config.MonitoredValues.CertificateIdentities,
 		OIDMatchers:           allOIDMatchers,
+		Rules:                 config.MonitoredValues.Rules,
+		Policies:              config.MonitoredValues.Policies,
 	}
 
 	cmd.PrintMonitoredValues(monitoredValues)
+	matcher, err := cmd.NewMatcher(monitoredValues)
+	if err != nil {
+		slog.Error("error compiling monitored values", logging.Err(err))
+		return 1
+	}
 
 	ctMonitorLogic := CTMonitorLogic{
-		ctlogClient:     ctlogClient,
-		flags:           flags,
-		config:          config,
-		monitoredValues: monitoredValues,
-		trustedRoot:     trustedRoot,
+		ctlogClient: ctlogClient,
+		flags:       flags,
+		config:      config,
+		matcher:     matcher,
+		trustedRoot: trustedRoot,
+		events:      cmd.NewEventBus(),
+	}
+	if err := cmd.MonitorLoop(ctMonitorLogic); err != nil {
+		return 1
 	}
-	cmd.MonitorLoop(ctMonitorLogic)
 	return 0
 }
 
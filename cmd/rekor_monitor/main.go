@@ -18,18 +18,21 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/sigstore/rekor-monitor/internal/cmd"
+	"github.com/sigstore/rekor-monitor/pkg/events"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/notifications"
 	rekor_v1 "github.com/sigstore/rekor-monitor/pkg/rekor/v1"
 	rekor_v2 "github.com/sigstore/rekor-monitor/pkg/rekor/v2"
 	rmutil "github.com/sigstore/rekor-monitor/pkg/util"
 	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/rekor/pkg/client"
 	rekor_client "github.com/sigstore/rekor/pkg/generated/client"
 	"github.com/sigstore/rekor/pkg/generated/models"
@@ -49,11 +52,12 @@ const (
 )
 
 type RekorV1MonitorLogic struct {
-	rekorClient     *rekor_client.Rekor
-	verifier        signature.Verifier
-	flags           *cmd.MonitorFlags
-	config          *notifications.IdentityMonitorConfiguration
-	monitoredValues identity.MonitoredValues
+	rekorClient *rekor_client.Rekor
+	verifier    signature.Verifier
+	flags       *cmd.MonitorFlags
+	config      *notifications.IdentityMonitorConfiguration
+	matcher     *identity.Matcher
+	events      *events.Bus
 }
 
 func (l RekorV1MonitorLogic) Interval() time.Duration {
@@ -64,8 +68,8 @@ func (l RekorV1MonitorLogic) Config() *notifications.IdentityMonitorConfiguratio
 	return l.config
 }
 
-func (l RekorV1MonitorLogic) MonitoredValues() identity.MonitoredValues {
-	return l.monitoredValues
+func (l RekorV1MonitorLogic) Matcher() *identity.Matcher {
+	return l.matcher
 }
 
 func (l RekorV1MonitorLogic) Once() bool {
@@ -80,7 +84,7 @@ func (l RekorV1MonitorLogic) NotificationContextNew() notifications.Notification
 	return notifications.CreateNotificationContext(
 		"rekor-monitor",
 		fmt.Sprintf("rekor-monitor workflow results for %s", time.Now().Format(time.RFC822)),
-	)
+	).WithLogOrigin(l.flags.ServerURL)
 }
 
 func (l RekorV1MonitorLogic) RunConsistencyCheck(_ context.Context) (cmd.Checkpoint, cmd.LogInfo, error) {
@@ -132,8 +136,16 @@ func (l RekorV1MonitorLogic) GetEndIndex(cur cmd.LogInfo) *int64 {
 	return &checkpointEndIndex
 }
 
-func (l RekorV1MonitorLogic) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
-	return rekor_v1.IdentitySearch(ctx, config, l.rekorClient, monitoredValues)
+func (l RekorV1MonitorLogic) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+	return rekor_v1.SearchIdentities(ctx, config, l.rekorClient, l.flags.ServerURL, matcher)
+}
+
+func (l RekorV1MonitorLogic) ErrorPolicy() cmd.ErrorPolicy {
+	return l.flags.ErrorPolicy()
+}
+
+func (l RekorV1MonitorLogic) Events() *events.Bus {
+	return l.events
 }
 
 type RekorV2MonitorLogic struct {
@@ -142,37 +154,39 @@ type RekorV2MonitorLogic struct {
 	config            *notifications.IdentityMonitorConfiguration
 	rekorShards       map[string]rekor_v2.ShardInfo
 	latestShardOrigin string
-	monitoredValues   identity.MonitoredValues
+	logOrigin         *logging.Origin
+	matcher           *identity.Matcher
+	events            *events.Bus
 }
 
-func (l RekorV2MonitorLogic) Interval() time.Duration {
+func (l *RekorV2MonitorLogic) Interval() time.Duration {
 	return l.flags.Interval
 }
 
-func (l RekorV2MonitorLogic) Config() *notifications.IdentityMonitorConfiguration {
+func (l *RekorV2MonitorLogic) Config() *notifications.IdentityMonitorConfiguration {
 	return l.config
 }
 
-func (l RekorV2MonitorLogic) MonitoredValues() identity.MonitoredValues {
-	return l.monitoredValues
+func (l *RekorV2MonitorLogic) Matcher() *identity.Matcher {
+	return l.matcher
 }
 
-func (l RekorV2MonitorLogic) Once() bool {
+func (l *RekorV2MonitorLogic) Once() bool {
 	return l.flags.Once
 }
 
-func (l RekorV2MonitorLogic) MonitorPort() int {
+func (l *RekorV2MonitorLogic) MonitorPort() int {
 	return l.flags.MonitorPort
 }
 
-func (l RekorV2MonitorLogic) NotificationContextNew() notifications.NotificationContext {
+func (l *RekorV2MonitorLogic) NotificationContextNew() notifications.NotificationContext {
 	return notifications.CreateNotificationContext(
 		"rekor-monitor-v2",
 		fmt.Sprintf("rekor-monitor v2 workflow results for %s", time.Now().Format(time.RFC822)),
-	)
+	).WithLogOrigin(l.latestShardOrigin)
 }
 
-func (l RekorV2MonitorLogic) RunConsistencyCheck(_ context.Context) (cmd.Checkpoint, cmd.LogInfo, error) {
+func (l *RekorV2MonitorLogic) RunConsistencyCheck(ctx context.Context) (cmd.Checkpoint, cmd.LogInfo, error) {
 	// On each iteration, we refresh the SigningConfig metadata and
 	// update the shards if we detect a change in the newest shard
 	signingConfig, err := rekor_v2.RefreshSigningConfig(l.tufClient)
@@ -192,6 +206,17 @@ func (l RekorV2MonitorLogic) RunConsistencyCheck(_ context.Context) (cmd.Checkpo
 		if err != nil {
 			return nil, nil, fmt.Errorf("error getting shards: %v", err)
 		}
+		l.logOrigin.Set(l.latestShardOrigin)
+		shardOrigins := make([]string, 0, len(l.rekorShards))
+		for origin := range l.rekorShards {
+			shardOrigins = append(shardOrigins, origin)
+		}
+		sort.Strings(shardOrigins)
+		l.events.Publish(ctx, events.ShardsUpdated{
+			Time:              time.Now(),
+			LatestShardOrigin: l.latestShardOrigin,
+			ShardOrigins:      shardOrigins,
+		})
 	}
 
 	prev, cur, err := rekor_v2.RunConsistencyCheck(context.Background(), l.rekorShards, l.latestShardOrigin, l.flags.LogInfoFile)
@@ -209,7 +234,7 @@ func (l RekorV2MonitorLogic) RunConsistencyCheck(_ context.Context) (cmd.Checkpo
 	return prevCheckpoint, curLogInfo, nil
 }
 
-func (l RekorV2MonitorLogic) WriteCheckpoint(prev cmd.Checkpoint, cur cmd.LogInfo) error {
+func (l *RekorV2MonitorLogic) WriteCheckpoint(prev cmd.Checkpoint, cur cmd.LogInfo) error {
 	prevCheckpoint, ok := prev.(*tlog.Checkpoint)
 	if !ok && prev != nil {
 		return fmt.Errorf("prev is not a Checkpoint")
@@ -224,7 +249,7 @@ func (l RekorV2MonitorLogic) WriteCheckpoint(prev cmd.Checkpoint, cur cmd.LogInf
 	return nil
 }
 
-func (l RekorV2MonitorLogic) GetStartIndex(prev cmd.Checkpoint, cur cmd.LogInfo) *int64 {
+func (l *RekorV2MonitorLogic) GetStartIndex(prev cmd.Checkpoint, cur cmd.LogInfo) *int64 {
 	prevCheckpoint, ok := prev.(*tlog.Checkpoint)
 	if !ok && cur != nil {
 		return nil
@@ -236,7 +261,7 @@ func (l RekorV2MonitorLogic) GetStartIndex(prev cmd.Checkpoint, cur cmd.LogInfo)
 	return &index
 }
 
-func (l RekorV2MonitorLogic) GetEndIndex(cur cmd.LogInfo) *int64 {
+func (l *RekorV2MonitorLogic) GetEndIndex(cur cmd.LogInfo) *int64 {
 	// TODO: interface is inconsistent between v1 and v2: cmd.LogInfo interface is used
 	// for LogInfo in v1 LogInfo but for Checkpoint in v2.
 	curCheckpoint, ok := cur.(*tlog.Checkpoint)
@@ -250,8 +275,16 @@ func (l RekorV2MonitorLogic) GetEndIndex(cur cmd.LogInfo) *int64 {
 	return &index
 }
 
-func (l RekorV2MonitorLogic) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
-	return rekor_v2.IdentitySearch(ctx, config, l.rekorShards, l.latestShardOrigin, monitoredValues)
+func (l *RekorV2MonitorLogic) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+	return rekor_v2.SearchIdentities(ctx, config, l.rekorShards, l.latestShardOrigin, matcher)
+}
+
+func (l *RekorV2MonitorLogic) ErrorPolicy() cmd.ErrorPolicy {
+	return l.flags.ErrorPolicy()
+}
+
+func (l *RekorV2MonitorLogic) Events() *events.Bus {
+	return l.events
 }
 
 func getRekorVersion(allRekorServices []root.Service, serverURL string) uint32 {
@@ -270,30 +303,39 @@ func getRekorVersion(allRekorServices []root.Service, serverURL string) uint32 {
 func mainWithReturn() int {
 	flags, config, err := cmd.ParseAndLoadConfig(publicRekorServerURL, TUFRepository, outputIdentitiesFileName, "rekor-monitor")
 	if err != nil {
-		log.Fatalf("error parsing flags and loading config: %v", err)
+		slog.Error("error parsing flags and loading config", logging.Err(err))
+		return 1
 	}
 
-	if err != nil {
-		log.Fatal(err)
+	if flags.ReplayDeadLetters {
+		if err := cmd.ReplayDeadLetters(context.Background(), config); err != nil {
+			slog.Error("error replaying dead-lettered notifications", logging.Err(err))
+			return 1
+		}
+		return 0
 	}
 
 	tufClient, err := cmd.GetTUFClient(flags)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error getting TUF client", logging.Err(err))
+		return 1
 	}
 
 	trustedRoot, err := root.GetTrustedRoot(tufClient)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error getting trusted root", logging.Err(err))
+		return 1
 	}
 	signingConfig, err := root.GetSigningConfig(tufClient)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error getting signing config", logging.Err(err))
+		return 1
 	}
 
 	cleanupTrustedCAs, err := cmd.ConfigureTrustedCAs(config, trustedRoot)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("error configuring trusted CAs", logging.Err(err))
+		return 1
 	}
 	defer cleanupTrustedCAs()
 
@@ -309,41 +351,42 @@ func mainWithReturn() int {
 	case 2:
 		rekorShards, latestShardOrigin, err := rekor_v2.GetRekorShards(context.Background(), trustedRoot, allRekorServices, flags.UserAgent, flags.HTTPSCertChainFile)
 		if err != nil {
-			log.Printf("error getting Rekor shards: %v\n", err)
+			slog.Error("error getting Rekor shards", logging.Err(err))
 			return 1
 		}
 		return mainLoopV2(tufClient, flags, config, rekorShards, latestShardOrigin)
 	default:
-		log.Printf("Unsupported server version %v, only '1' and '2' are supported\n", rekorVersion)
+		slog.Error("unsupported server version, only '1' and '2' are supported", "rekorVersion", rekorVersion)
 		return 1
 	}
 }
 
 func mainLoopV1(flags *cmd.MonitorFlags, config *notifications.IdentityMonitorConfiguration, trustedRoot *root.TrustedRoot) int {
+	slog.SetDefault(logging.WithOrigin(slog.Default(), logging.NewOrigin(flags.ServerURL)))
 	clientOpts := []client.Option{client.WithUserAgent(flags.UserAgent)}
 	if flags.HTTPSCertChainFile != "" {
 		tlsConfig, err := rmutil.TLSConfigForCA(flags.HTTPSCertChainFile)
 		if err != nil {
-			log.Printf("error getting TLS config: %v", err)
+			slog.Error("error getting TLS config", logging.Err(err))
 			return 1
 		}
 		clientOpts = append(clientOpts, client.WithTLSConfig(tlsConfig))
 	}
 	rekorClient, err := client.GetRekorClient(flags.ServerURL, clientOpts...)
 	if err != nil {
-		log.Printf("getting Rekor client: %v\n", err)
+		slog.Error("error getting Rekor client", logging.Err(err))
 		return 1
 	}
 
 	verifier, err := rekor_v1.GetLogVerifier(context.Background(), rekorClient, trustedRoot)
 	if err != nil {
-		log.Printf("error getting log verifier: %v\n", err)
+		slog.Error("error getting log verifier", logging.Err(err))
 		return 1
 	}
 
 	allOIDMatchers, err := config.MonitoredValues.OIDMatchers.RenderOIDMatchers()
 	if err != nil {
-		log.Printf("error parsing OID matchers: %v\n", err)
+		slog.Error("error parsing OID matchers", logging.Err(err))
 		return 1
 	}
 
@@ -352,24 +395,37 @@ func mainLoopV1(flags *cmd.MonitorFlags, config *notifications.IdentityMonitorCo
 		Subjects:              config.MonitoredValues.Subjects,
 		Fingerprints:          config.MonitoredValues.Fingerprints,
 		OIDMatchers:           allOIDMatchers,
+		Rules:                 config.MonitoredValues.Rules,
+		Policies:              config.MonitoredValues.Policies,
 	}
 
 	cmd.PrintMonitoredValues(monitoredValues)
+	matcher, err := cmd.NewMatcher(monitoredValues)
+	if err != nil {
+		slog.Error("error compiling monitored values", logging.Err(err))
+		return 1
+	}
 	rekorV1MonitorLogic := RekorV1MonitorLogic{
-		rekorClient:     rekorClient,
-		verifier:        verifier,
-		flags:           flags,
-		config:          config,
-		monitoredValues: monitoredValues,
+		rekorClient: rekorClient,
+		verifier:    verifier,
+		flags:       flags,
+		config:      config,
+		matcher:     matcher,
+		events:      cmd.NewEventBus(),
+	}
+	if err := cmd.MonitorLoop(rekorV1MonitorLogic); err != nil {
+		return 1
 	}
-	cmd.MonitorLoop(rekorV1MonitorLogic)
 	return 0
 }
 
 func mainLoopV2(tufClient *tuf.Client, flags *cmd.MonitorFlags, config *notifications.IdentityMonitorConfiguration, rekorShards map[string]rekor_v2.ShardInfo, latestShardOrigin string) int {
+	// the origin of the records follows the latest shard when the shards are updated
+	logOrigin := logging.NewOrigin(latestShardOrigin)
+	slog.SetDefault(logging.WithOrigin(slog.Default(), logOrigin))
 	allOIDMatchers, err := config.MonitoredValues.OIDMatchers.RenderOIDMatchers()
 	if err != nil {
-		fmt.Printf("error parsing OID matchers: %v", err)
+		slog.Error("error parsing OID matchers", logging.Err(err))
 		return 1
 	}
 
@@ -378,18 +434,29 @@ func mainLoopV2(tufClient *tuf.Client, flags *cmd.MonitorFlags, config *notifica
 		Subjects:              config.MonitoredValues.Subjects,
 		Fingerprints:          config.MonitoredValues.Fingerprints,
 		OIDMatchers:           allOIDMatchers,
+		Rules:                 config.MonitoredValues.Rules,
+		Policies:              config.MonitoredValues.Policies,
 	}
 
 	cmd.PrintMonitoredValues(monitoredValues)
-	rekorV2MonitorLogic := RekorV2MonitorLogic{
+	matcher, err := cmd.NewMatcher(monitoredValues)
+	if err != nil {
+		slog.Error("error compiling monitored values", logging.Err(err))
+		return 1
+	}
+	rekorV2MonitorLogic := &RekorV2MonitorLogic{
 		tufClient:         tufClient,
 		flags:             flags,
 		config:            config,
 		rekorShards:       rekorShards,
 		latestShardOrigin: latestShardOrigin,
-		monitoredValues:   monitoredValues,
+		logOrigin:         logOrigin,
+		matcher:           matcher,
+		events:            cmd.NewEventBus(),
+	}
+	if err := cmd.MonitorLoop(rekorV2MonitorLogic); err != nil {
+		return 1
 	}
-	cmd.MonitorLoop(rekorV2MonitorLogic)
 	return 0
 }
 
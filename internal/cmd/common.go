@@ -18,18 +18,26 @@ package cmd
 import (
 	"context"
 	"encoding/pem"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
 	"runtime"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/sigstore/rekor-monitor/pkg/events"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/notifications"
+	"github.com/sigstore/rekor-monitor/pkg/secrets"
 	"github.com/sigstore/rekor-monitor/pkg/server"
+	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/sigstore-go/pkg/root"
 	"github.com/sigstore/sigstore-go/pkg/tuf"
 	"gopkg.in/yaml.v2"
@@ -51,13 +59,33 @@ type MonitorFlags struct {
 	CARootsFile         string
 	CAIntermediatesFile string
 	HTTPSCertChainFile  string
+	LogLevel            string
+	LogFormat           string
+	// MaxConsecutiveFailures is the number of consecutive failed runs after which the monitor exits, 0 for unlimited
+	MaxConsecutiveFailures int
+	// MaxRetryBackoff caps the delay between retries of a failed run
+	MaxRetryBackoff time.Duration
+	// ReplayDeadLetters replays the dead-lettered notifications of the outbox instead of monitoring
+	ReplayDeadLetters bool
+	// ExitOnNotifierAuthFailure exits the monitor when a notification platform rejects its credentials
+	ExitOnNotifierAuthFailure bool
+}
+
+// ErrorPolicy returns the error policy of the monitor loop configured by the flags
+func (f *MonitorFlags) ErrorPolicy() ErrorPolicy {
+	return ErrorPolicy{
+		MaxConsecutiveFailures:    f.MaxConsecutiveFailures,
+		MaxBackoff:                f.MaxRetryBackoff,
+		ExitOnNotifierAuthFailure: f.ExitOnNotifierAuthFailure,
+	}
 }
 
 // MonitorLogic is the interface for the monitor loop logic
 type MonitorLogic interface {
 	Interval() time.Duration
 	Config() *notifications.IdentityMonitorConfiguration
-	MonitoredValues() identity.MonitoredValues
+	// Matcher returns the monitored values, compiled once when the configuration is loaded
+	Matcher() *identity.Matcher
 	Once() bool
 	MonitorPort() int
 	NotificationContextNew() notifications.NotificationContext
@@ -65,7 +93,15 @@ type MonitorLogic interface {
 	WriteCheckpoint(prev Checkpoint, cur LogInfo) error
 	GetStartIndex(prev Checkpoint, cur LogInfo) *int64
 	GetEndIndex(cur LogInfo) *int64
-	IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error)
+	// IdentitySearch returns the entries of the log index range (StartIndex, EndIndex]
+	// of the configuration matching the compiled monitored values, and the entries that
+	// could not be parsed. The monitor loop writes the matched entries to the identity outputs.
+	IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error)
+	// ErrorPolicy returns how the monitor loop reacts to failed runs
+	ErrorPolicy() ErrorPolicy
+	// Events returns the bus on which the monitor loop publishes its events.
+	// It may return nil if no one is subscribed to the events.
+	Events() *events.Bus
 }
 
 type Checkpoint interface{}
@@ -86,8 +122,18 @@ func ParseMonitorFlags(defaultServerURL, defaultTUFRepository string, baseUserAg
 	caRootsFilePath := flag.String("ca-roots", "", "path to a bundle file of CA certificates in PEM format")
 	caIntermediatesFilePath := flag.String("ca-intermediates", "", "path to a bundle file of CA intermediate certificates in PEM format. The flag must be used together with --ca-roots")
 	httpsChainPath := flag.String("https-cert-chain", "", "path to a list of CA certificates in PEM format for the HTTPS connection to the log server")
+	logLevel := flag.String("log-level", "info", "minimum level of log messages to output. Can be 'debug', 'info', 'warn' or 'error'")
+	logFormat := flag.String("log-format", logging.FormatText, "format of log messages. Can be 'text' or 'json'")
+	maxConsecutiveFailures := flag.Int("max-consecutive-failures", 0, "number of consecutive failed monitor runs after which the monitor exits. 0 means the monitor never gives up")
+	maxRetryBackoff := flag.Duration("max-retry-backoff", time.Hour, "maximum delay between retries of a failed monitor run")
+	replayDeadLetters := flag.Bool("replay-dead-letters", false, "queue the dead-lettered notifications of the notification outbox again, deliver them and exit")
+	exitOnNotifierAuthFailure := flag.Bool("exit-on-notifier-auth-failure", false, "exit the monitor when a notification platform rejects its credentials, instead of retrying the notifications on the next runs")
 	flag.Parse()
 
+	if *maxConsecutiveFailures < 0 {
+		return nil, fmt.Errorf("max-consecutive-failures must not be negative")
+	}
+
 	if *caIntermediatesFilePath != "" && *caRootsFilePath == "" {
 		return nil, fmt.Errorf("ca-intermediates must be used together with --ca-roots")
 	}
@@ -101,22 +147,38 @@ func ParseMonitorFlags(defaultServerURL, defaultTUFRepository string, baseUserAg
 	))
 
 	return &MonitorFlags{
-		ConfigFile:          *configFilePath,
-		ConfigYaml:          *configYamlInput,
-		Once:                *once,
-		LogInfoFile:         *logInfoFile,
-		MonitorPort:         *monitorPort,
-		ServerURL:           *serverURL,
-		Interval:            *interval,
-		UserAgent:           finalUserAgent,
-		TUFRepository:       *tufRepository,
-		TUFRootPath:         *tufRootPath,
-		CARootsFile:         *caRootsFilePath,
-		CAIntermediatesFile: *caIntermediatesFilePath,
-		HTTPSCertChainFile:  *httpsChainPath,
+		ConfigFile:                *configFilePath,
+		ConfigYaml:                *configYamlInput,
+		Once:                      *once,
+		LogInfoFile:               *logInfoFile,
+		MonitorPort:               *monitorPort,
+		ServerURL:                 *serverURL,
+		Interval:                  *interval,
+		UserAgent:                 finalUserAgent,
+		TUFRepository:             *tufRepository,
+		TUFRootPath:               *tufRootPath,
+		CARootsFile:               *caRootsFilePath,
+		CAIntermediatesFile:       *caIntermediatesFilePath,
+		HTTPSCertChainFile:        *httpsChainPath,
+		LogLevel:                  *logLevel,
+		LogFormat:                 *logFormat,
+		MaxConsecutiveFailures:    *maxConsecutiveFailures,
+		MaxRetryBackoff:           *maxRetryBackoff,
+		ReplayDeadLetters:         *replayDeadLetters,
+		ExitOnNotifierAuthFailure: *exitOnNotifierAuthFailure,
 	}, nil
 }
 
+// ConfigureLogging sets the default structured logger based on the log level and format flags
+func ConfigureLogging(flags *MonitorFlags) error {
+	logger, err := logging.NewLogger(os.Stderr, flags.LogFormat, flags.LogLevel)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
 // LoadMonitorConfig loads the monitor configuration from flags
 func LoadMonitorConfig(flags *MonitorFlags, defaultOutputFile string) (*notifications.IdentityMonitorConfiguration, error) {
 	var config notifications.IdentityMonitorConfiguration
@@ -154,6 +216,12 @@ func LoadMonitorConfig(flags *MonitorFlags, defaultOutputFile string) (*notifica
 			config.OutputIdentitiesFile += ".txt"
 		case "json":
 			config.OutputIdentitiesFile += ".json"
+		case "ndjson":
+			config.OutputIdentitiesFile += ".jsonl"
+		case "csv":
+			config.OutputIdentitiesFile += ".csv"
+		case "otlp":
+			config.OutputIdentitiesFile += ".otlp.jsonl"
 		}
 	}
 
@@ -165,10 +233,14 @@ func LoadMonitorConfig(flags *MonitorFlags, defaultOutputFile string) (*notifica
 		config.CAIntermediatesFile = flags.CAIntermediatesFile
 	}
 
-	if err := config.Validate(); err != nil {
+	if err := config.ResolveSecrets(context.Background()); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %v", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, secrets.RedactError(fmt.Errorf("invalid configuration: %v", err))
+	}
+
 	return &config, nil
 }
 
@@ -178,6 +250,9 @@ func ParseAndLoadConfig(defaultServerURL, defaultTUFRepository, defaultOutputFil
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := ConfigureLogging(flags); err != nil {
+		return nil, nil, err
+	}
 	config, err := LoadMonitorConfig(flags, defaultOutputFile)
 	if err != nil {
 		return nil, nil, err
@@ -200,7 +275,7 @@ func GetTUFClient(flags *MonitorFlags) (*tuf.Client, error) {
 		options := tuf.DefaultOptions().WithRoot(tuf.StagingRoot()).WithRepositoryBaseURL(tuf.StagingMirror)
 		return tuf.New(options)
 	default:
-		fmt.Printf("Using custom TUF repository: %s\n", flags.TUFRepository)
+		slog.Info("using custom TUF repository", "tufRepository", flags.TUFRepository)
 		if flags.TUFRootPath == "" {
 			return nil, fmt.Errorf("tuf-root-path is required when using a custom TUF repository")
 		}
@@ -278,24 +353,57 @@ func ConfigureTrustedCAs(config *notifications.IdentityMonitorConfiguration, tru
 	return cleanupFiles, nil
 }
 
-// PrintMonitoredValues prints the monitored values to the console
+// PrintMonitoredValues logs the monitored values
 func PrintMonitoredValues(monitoredValues identity.MonitoredValues) {
 	for _, certID := range monitoredValues.CertificateIdentities {
 		if len(certID.Issuers) == 0 {
-			fmt.Printf("Monitoring certificate subject %s\n", certID.CertSubject)
+			slog.Info("monitoring certificate subject", "certSubject", certID.CertSubject)
 		} else {
-			fmt.Printf("Monitoring certificate subject %s for issuer(s) %s\n", certID.CertSubject, strings.Join(certID.Issuers, ","))
+			slog.Info("monitoring certificate subject", "certSubject", certID.CertSubject, "issuers", strings.Join(certID.Issuers, ","))
 		}
 	}
 	for _, fp := range monitoredValues.Fingerprints {
-		fmt.Printf("Monitoring fingerprint %s\n", fp)
+		slog.Info("monitoring fingerprint", "fingerprint", fp)
 	}
 	for _, sub := range monitoredValues.Subjects {
-		fmt.Printf("Monitoring subject %s\n", sub)
+		slog.Info("monitoring subject", "subject", sub)
+	}
+	for _, oidMatcher := range monitoredValues.OIDMatchers {
+		for _, extValue := range oidMatcher.ExtensionValues {
+			slog.Info("monitoring extension", "objectIdentifier", oidMatcher.ObjectIdentifier.String(), "extensionValue", extValue.Value, "matchMode", extValue.MatchMode, "negate", extValue.Negate)
+		}
+	}
+	for _, rule := range monitoredValues.Rules {
+		slog.Info("monitoring rule", "rule", rule.Name)
+	}
+	for _, policy := range monitoredValues.Policies {
+		slog.Info("monitoring policy", "policy", policy.Name, "expression", policy.Expression)
 	}
 }
 
-func MonitorLoop(loopLogic MonitorLogic) {
+// NewMatcher checks the monitored values and compiles them, so that monitor runs
+// match log entries without compiling the monitored values again
+func NewMatcher(monitoredValues identity.MonitoredValues) (*identity.Matcher, error) {
+	// the identity search is skipped if there are no monitored values
+	if identity.MonitoredValuesExist(monitoredValues) {
+		if err := identity.VerifyMonitoredValues(monitoredValues); err != nil {
+			return nil, err
+		}
+	}
+	return identity.NewMatcher(monitoredValues)
+}
+
+// NewEventBus creates the bus on which the monitor loop publishes its events,
+// with the subscribers logging the events and counting them in the metrics
+func NewEventBus() *events.Bus {
+	return events.NewBus(events.LogSubscriber(), server.EventsSubscriber())
+}
+
+// MonitorLoop runs the consistency check and identity search on every interval
+// until the monitor is done or shut down. A failed run is retried on the same log
+// index range according to the error policy. MonitorLoop returns an error if the
+// monitor exits because of a failure.
+func MonitorLoop(loopLogic MonitorLogic) error {
 	ticker := time.NewTicker(loopLogic.Interval())
 	defer ticker.Stop()
 
@@ -304,107 +412,462 @@ func MonitorLoop(loopLogic MonitorLogic) {
 
 	if !loopLogic.Once() {
 		if err := server.StartMetricsServer(ctx, loopLogic.MonitorPort()); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to start Prometheus metrics server: %v\n", err)
+			slog.Error("failed to start Prometheus metrics server", logging.Err(err))
 		}
 	}
 
-	config := loopLogic.Config()
+	policy := loopLogic.ErrorPolicy()
+	consecutiveFailures := 0
+	state := &monitorState{}
 
 	// To get an immediate first tick, for-select is at the end of the loop
 	for {
-		fmt.Fprint(os.Stderr, "New monitor run at ", time.Now().Format(time.RFC3339), "\n")
+		slog.Info("new monitor run")
 		server.IncLogIndexVerificationTotal()
-		inputEndIndex := config.EndIndex
 
-		prevCheckpoint, curCheckpoint, err := loopLogic.RunConsistencyCheck(ctx)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error running consistency check: %v\n", err)
-			if loopLogic.Once() {
-				return
+		done, err := runMonitorIteration(ctx, loopLogic, state)
+		err = classifyError(err, policy)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			slog.Info("shutting down gracefully")
+			return nil
+		case err != nil:
+			consecutiveFailures++
+			server.SetConsecutiveRunFailures(consecutiveFailures)
+			if loopLogic.Once() || IsUnrecoverable(err) {
+				return err
+			}
+			if policy.MaxConsecutiveFailures > 0 && consecutiveFailures >= policy.MaxConsecutiveFailures {
+				slog.Error("giving up after consecutive failed monitor runs", "consecutiveFailures", consecutiveFailures)
+				return fmt.Errorf("giving up after %d consecutive failed monitor runs: %w", consecutiveFailures, err)
+			}
+			delay := policy.Backoff(loopLogic.Interval(), consecutiveFailures)
+			slog.Info("retrying failed monitor run", "consecutiveFailures", consecutiveFailures, "retryIn", delay)
+			ticker.Reset(delay)
+		default:
+			if consecutiveFailures > 0 {
+				ticker.Reset(loopLogic.Interval())
+			}
+			consecutiveFailures = 0
+			server.SetConsecutiveRunFailures(0)
+			if done {
+				return nil
 			}
-			server.IncLogIndexVerificationFailure()
-			goto waitForTick
 		}
 
-		if identity.MonitoredValuesExist(loopLogic.MonitoredValues()) {
-			if config.StartIndex == nil {
-				if prevCheckpoint != nil {
-					config.StartIndex = loopLogic.GetStartIndex(prevCheckpoint, curCheckpoint)
-				} else {
-					fmt.Fprintf(os.Stderr, "no start index set and no log checkpoint, just saving checkpoint\n")
-				}
+		select {
+		case <-ticker.C:
+			continue
+		case <-ctx.Done():
+			slog.Info("shutting down gracefully")
+			return nil
+		}
+	}
+}
+
+// monitorState holds the state of the monitor loop across runs
+type monitorState struct {
+	// consistencyFailed is set when a consistency check failure was notified
+	// and the incident has not been resolved yet
+	consistencyFailed bool
+	// pending are the notifications of searched ranges that could not be sent,
+	// with only the notifiers that failed
+	pending []notifications.RoutedNotification
+	// pendingErr is the error of the last failed attempt to send the pending notifications
+	pendingErr error
+}
+
+// runMonitorIteration runs a single consistency check and identity search, and
+// reports whether the monitor is done. If the search fails, the identity search
+// cursor is kept on the failed range and the checkpoint is not persisted, so
+// that the next run covers the same entries. Once a range is searched, it is
+// not searched again: notifications that could not be sent are retried in the
+// next runs for the failed notifiers only, before any new range is searched.
+func runMonitorIteration(ctx context.Context, loopLogic MonitorLogic, state *monitorState) (bool, error) {
+	config := loopLogic.Config()
+	bus := loopLogic.Events()
+	inputEndIndex := config.EndIndex
+
+	if err := config.RefreshSecrets(ctx); err != nil {
+		slog.Warn("error refreshing secrets, using their previous values", logging.Err(err))
+	}
+
+	prevCheckpoint, curCheckpoint, err := loopLogic.RunConsistencyCheck(ctx)
+	if err != nil {
+		slog.Error("error running consistency check", logging.Err(err))
+		bus.Publish(ctx, events.ConsistencyFailed{Time: time.Now(), Err: err})
+		server.IncLogIndexVerificationFailure()
+		notifyConsistencyFailure(ctx, loopLogic, config, err)
+		state.consistencyFailed = true
+		return false, err
+	}
+	bus.Publish(ctx, events.CheckpointVerified{Time: time.Now(), Previous: prevCheckpoint, Current: curCheckpoint})
+	if state.consistencyFailed {
+		resolvers := notifications.CreateResolverPool(*config)
+		if err := notifications.ResolveIncidents(ctx, resolvers, loopLogic.NotificationContextNew()); err != nil {
+			slog.Error("failed to resolve consistency failure incidents", logging.Err(err))
+			server.IncNotificationFailure()
+		} else {
+			state.consistencyFailed = false
+		}
+	}
+
+	if len(state.pending) > 0 {
+		if err := retryPendingNotifications(ctx, loopLogic, config, state); err != nil {
+			return false, err
+		}
+	}
+
+	var outputErr error
+	if identity.MonitoredValuesExist(loopLogic.Matcher().MonitoredValues()) {
+		if config.StartIndex == nil {
+			if prevCheckpoint != nil {
+				config.StartIndex = loopLogic.GetStartIndex(prevCheckpoint, curCheckpoint)
+			} else {
+				slog.Info("no start index set and no log checkpoint, just saving checkpoint")
 			}
+		}
+
+		if config.EndIndex == nil {
+			config.EndIndex = loopLogic.GetEndIndex(curCheckpoint)
+		}
 
-			if config.EndIndex == nil {
-				config.EndIndex = loopLogic.GetEndIndex(curCheckpoint)
+		if config.StartIndex != nil && config.EndIndex != nil {
+			if *config.StartIndex > *config.EndIndex {
+				slog.Error("start index must be less or equal than end index", logging.IndexRange(*config.StartIndex, *config.EndIndex)...)
+				return false, Unrecoverable(fmt.Errorf("start index %d must be less or equal than end index %d", *config.StartIndex, *config.EndIndex))
 			}
 
-			if config.StartIndex != nil && config.EndIndex != nil {
-				if *config.StartIndex > *config.EndIndex {
-					fmt.Fprintf(os.Stderr, "start index %d must be less or equal than end index %d", *config.StartIndex, *config.EndIndex)
-					return
-				}
-
-				foundEntries, failedEntries, err := loopLogic.IdentitySearch(ctx, config, loopLogic.MonitoredValues())
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "failed to successfully complete identity search: %v\n", err)
-					return
-				}
-
-				if len(foundEntries) > 0 || len(failedEntries) > 0 {
-					notificationPool := notifications.CreateNotificationPool(*config)
-
-					if len(foundEntries) > 0 {
-						notificationData := notifications.NotificationData{
-							Context: loopLogic.NotificationContextNew(),
-							Payload: identity.MonitoredIdentityList(foundEntries),
-						}
-
-						err = notifications.TriggerNotifications(notificationPool, notificationData)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "failed to trigger notifications for found entries: %v", err)
-							return
-						}
-					}
-					if len(failedEntries) > 0 {
-						fmt.Fprintf(os.Stderr, "failed to parse some log entries: %v", failedEntries)
-
-						notificationData := notifications.NotificationData{
-							Context: loopLogic.NotificationContextNew(),
-							Payload: identity.FailedLogEntryList(failedEntries),
-						}
-
-						err = notifications.TriggerNotifications(notificationPool, notificationData)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "failed to trigger notifications for failed entries: %v", err)
-							return
-						}
-					}
-				}
+			matchedEntries, err := searchAndNotify(ctx, loopLogic, config, state)
+			if err != nil {
+				config.EndIndex = inputEndIndex
+				return false, err
 			}
 
-			config.StartIndex = config.EndIndex
-			config.EndIndex = nil
+			// The range is committed once searched, so that its matched
+			// entries are written to the identity outputs exactly once
+			if err := file.WriteMatchedIdentityEntries(config.IdentityOutputs(), matchedEntries, config.IdentityMetadataFile, *config.EndIndex); err != nil {
+				slog.Error("failed to write identity outputs", append(logging.IndexRange(*config.StartIndex, *config.EndIndex), logging.Err(err))...)
+				outputErr = err
+			}
 		}
 
-		// Write checkpoint after identity search to ensure identities are
-		// always searched even if something fails in the middle
-		if err := loopLogic.WriteCheckpoint(prevCheckpoint, curCheckpoint); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to write checkpoint: %v", err)
-			return
+		// With a configured end index, the committed range is searched
+		// again as an empty range if the run is retried
+		config.StartIndex = config.EndIndex
+		config.EndIndex = inputEndIndex
+	}
+
+	sendHeldNotifications(ctx, loopLogic, config)
+	deliverQueuedNotifications(ctx, loopLogic, config)
+
+	// Write checkpoint after identity search to ensure identities are
+	// always searched even if something fails in the middle
+	if err := loopLogic.WriteCheckpoint(prevCheckpoint, curCheckpoint); err != nil {
+		slog.Error("failed to write checkpoint", logging.Err(err))
+		server.IncCheckpointWriteFailure()
+		return false, err
+	}
+
+	if outputErr != nil {
+		return false, outputErr
+	}
+	if len(state.pending) > 0 {
+		return false, fmt.Errorf("%d notifications could not be sent and are retried in the next run: %w", len(state.pending), state.pendingErr)
+	}
+	return loopLogic.Once() || inputEndIndex != nil, nil
+}
+
+// retryPendingNotifications sends the pending notifications of previously
+// searched ranges to the notifiers that failed, keeping the ones that fail again
+func retryPendingNotifications(ctx context.Context, loopLogic MonitorLogic, config *notifications.IdentityMonitorConfiguration, state *monitorState) error {
+	slog.Info("retrying notifications that could not be sent", "count", len(state.pending))
+	pending, err := sendRoutedNotifications(ctx, loopLogic, notifications.CreateNamedNotificationPool(*config), state.pending)
+	state.pending, state.pendingErr = pending, err
+	return err
+}
+
+// sendRoutedNotifications sends routed notifications to each of their notifiers,
+// and returns the notifications with the notifiers they could not be sent to
+func sendRoutedNotifications(ctx context.Context, loopLogic MonitorLogic, namedPool map[string]notifications.NotificationPlatform, routed []notifications.RoutedNotification) ([]notifications.RoutedNotification, error) {
+	failed := []notifications.RoutedNotification{}
+	var errs []error
+	for _, notification := range routed {
+		failedNotifiers := []string{}
+		for _, name := range notification.Notifiers {
+			platform, ok := namedPool[name]
+			if !ok {
+				slog.Warn("dropping notification for a notifier that is no longer configured", "notifier", name)
+				continue
+			}
+			if err := notifications.TriggerNotifications([]notifications.NotificationPlatform{platform}, notification.Data); err != nil {
+				slog.Error("failed to trigger notifications", "payload", fmt.Sprintf("%T", notification.Data.Payload), "notifier", name, logging.Err(err))
+				loopLogic.Events().Publish(ctx, events.NotificationFailed{Time: time.Now(), Err: err})
+				server.IncNotificationFailure()
+				errs = append(errs, notifierError{notifier: name, err: err})
+				failedNotifiers = append(failedNotifiers, name)
+			}
 		}
+		if len(failedNotifiers) > 0 {
+			failed = append(failed, notifications.RoutedNotification{Data: notification.Data, Notifiers: failedNotifiers})
+		}
+	}
+	return failed, errors.Join(errs...)
+}
+
+// notifyConsistencyFailure notifies the incident platforms about a failed
+// consistency check. Incident platforms deduplicate repeated failures of the
+// same log, and the incident is resolved once the consistency check succeeds.
+func notifyConsistencyFailure(ctx context.Context, loopLogic MonitorLogic, config *notifications.IdentityMonitorConfiguration, consistencyErr error) {
+	resolvers := notifications.CreateResolverPool(*config)
+	if len(resolvers) == 0 {
+		return
+	}
+	notificationContext := loopLogic.NotificationContextNew()
+	data := notifications.NotificationData{
+		Context: notificationContext,
+		Payload: notifications.ConsistencyFailure{
+			LogOrigin:  notificationContext.LogOrigin,
+			Error:      consistencyErr.Error(),
+			DetectedAt: time.Now().UTC(),
+		},
+	}
+	if err := notifications.TriggerNotifications(resolvers, data); err != nil {
+		slog.Error("failed to trigger notifications for consistency failure", logging.Err(err))
+		loopLogic.Events().Publish(ctx, events.NotificationFailed{Time: time.Now(), Err: err})
+		server.IncNotificationFailure()
+	}
+}
 
-		if loopLogic.Once() || inputEndIndex != nil {
+// sendHeldNotifications sends the notifications held by the notification
+// policies that are due, if configured. With an outbox, they are queued for
+// delivery instead. Failures keep the notifications held and don't fail the
+// monitor run.
+func sendHeldNotifications(ctx context.Context, loopLogic MonitorLogic, config *notifications.IdentityMonitorConfiguration) {
+	if config.NotificationPolicies == nil {
+		return
+	}
+	fail := func(err error) {
+		slog.Error("failed to send held notifications", logging.Err(err))
+		loopLogic.Events().Publish(ctx, events.NotificationFailed{Time: time.Now(), Err: err})
+		server.IncNotificationFailure()
+	}
+	policies, err := notifications.LoadNotificationPolicies(*config.NotificationPolicies)
+	if err != nil {
+		fail(err)
+		return
+	}
+	var outbox *notifications.Outbox
+	if config.NotificationOutbox != nil {
+		if outbox, err = notifications.NewOutbox(*config.NotificationOutbox); err != nil {
+			fail(err)
 			return
 		}
+	}
 
-	waitForTick:
-		select {
-		case <-ticker.C:
+	namedPool := notifications.CreateNamedNotificationPool(*config)
+	due := policies.Due(time.Now())
+	for _, name := range slices.Sorted(maps.Keys(due)) {
+		if err := sendDue(name, due[name], namedPool, outbox, policies); err != nil {
+			fail(fmt.Errorf("notifier %s: %w", name, err))
 			continue
-		case <-ctx.Done():
-			fmt.Fprintf(os.Stderr, "Shutting down gracefully...")
-			return
 		}
+		if err := policies.MarkSent(name, time.Now()); err != nil {
+			fail(err)
+		}
+	}
+}
+
+// sendDue sends the due notifications of a notifier, or queues them in the
+// outbox. Each notification is released once sent, so that a failure doesn't
+// send the notifications before it again.
+func sendDue(name string, due []notifications.NotificationData, namedPool map[string]notifications.NotificationPlatform, outbox *notifications.Outbox, policies *notifications.NotificationPolicies) error {
+	platform, ok := namedPool[name]
+	if !ok {
+		slog.Warn("dropping notifications held for a notifier that is no longer configured", "notifier", name)
+		return nil
+	}
+	for _, data := range due {
+		var err error
+		if outbox != nil {
+			err = outbox.Enqueue(data, []string{name})
+		} else {
+			err = notifications.TriggerNotifications([]notifications.NotificationPlatform{platform}, data)
+		}
+		if err != nil {
+			return err
+		}
+		if err := policies.MarkDelivered(name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliverQueuedNotifications delivers the notifications queued in the outbox, if
+// configured. Failed deliveries stay queued and don't fail the monitor run.
+func deliverQueuedNotifications(ctx context.Context, loopLogic MonitorLogic, config *notifications.IdentityMonitorConfiguration) {
+	if config.NotificationOutbox == nil {
+		return
+	}
+	outbox, err := notifications.NewOutbox(*config.NotificationOutbox)
+	if err == nil {
+		err = outbox.Deliver(ctx, notifications.CreateNamedNotificationPool(*config))
+	}
+	if err != nil {
+		slog.Error("failed to deliver queued notifications", logging.Err(err))
+		loopLogic.Events().Publish(ctx, events.NotificationFailed{Time: time.Now(), Err: err})
+		server.IncNotificationFailure()
+	}
+}
+
+// ReplayDeadLetters queues the dead-lettered notifications of the outbox
+// again and delivers them
+func ReplayDeadLetters(ctx context.Context, config *notifications.IdentityMonitorConfiguration) error {
+	if config.NotificationOutbox == nil {
+		return fmt.Errorf("replaying dead-lettered notifications requires a notificationOutbox configuration")
+	}
+	outbox, err := notifications.NewOutbox(*config.NotificationOutbox)
+	if err != nil {
+		return err
+	}
+	replayed, err := outbox.ReplayDeadLetters()
+	if err != nil {
+		return err
+	}
+	slog.Info("replaying dead-lettered notifications", "count", replayed)
+	return outbox.Deliver(ctx, notifications.CreateNamedNotificationPool(*config))
+}
+
+// splitHeldNotifiers adds a routed notification to the notifications to hold
+// for the notifiers with a notification policy, and returns it for the
+// remaining notifiers
+func splitHeldNotifiers(policies *notifications.NotificationPolicies, routed notifications.RoutedNotification, held map[string][]notifications.NotificationData) notifications.RoutedNotification {
+	if policies == nil {
+		return routed
+	}
+	notifiers := []string{}
+	for _, name := range routed.Notifiers {
+		if policies.Applies(name) {
+			held[name] = append(held[name], routed.Data)
+		} else {
+			notifiers = append(notifiers, name)
+		}
+	}
+	routed.Notifiers = notifiers
+	return routed
+}
+
+// searchAndNotify searches the log index range (StartIndex, EndIndex] of the
+// configuration for the monitored values, sends notifications for the found
+// and the unparsable entries and returns the matched entries. Notifications
+// that could not be sent are added to the pending notifications of the state
+// without failing the search.
+func searchAndNotify(ctx context.Context, loopLogic MonitorLogic, config *notifications.IdentityMonitorConfiguration, state *monitorState) ([]identity.LogEntry, error) {
+	bus := loopLogic.Events()
+
+	matcher := loopLogic.Matcher()
+	matchedEntries, failedEntries, err := loopLogic.IdentitySearch(ctx, config, matcher)
+	if err != nil {
+		slog.Error("failed to successfully complete identity search", append(logging.IndexRange(*config.StartIndex, *config.EndIndex), logging.Err(err))...)
+		server.IncIdentitySearchFailure()
+		return nil, err
+	}
+	foundEntries := identity.CreateMonitoredIdentities(matchedEntries, identity.CreateIdentitiesList(matcher.MonitoredValues()))
+
+	if len(foundEntries) > 0 {
+		bus.Publish(ctx, events.IdentityMatched{
+			Time:       time.Now(),
+			StartIndex: *config.StartIndex,
+			EndIndex:   *config.EndIndex,
+			Identities: foundEntries,
+		})
+	}
+	if len(failedEntries) > 0 {
+		bus.Publish(ctx, events.EntryParseFailed{
+			Time:       time.Now(),
+			StartIndex: *config.StartIndex,
+			EndIndex:   *config.EndIndex,
+			Entries:    failedEntries,
+		})
+	}
+
+	notificationContext := loopLogic.NotificationContextNew().WithCheckpoint(*config.StartIndex, *config.EndIndex)
+	notificationData := []notifications.NotificationData{}
+	if len(foundEntries) > 0 {
+		notificationData = append(notificationData, notifications.NotificationData{
+			Context: notificationContext,
+			Payload: identity.MonitoredIdentityList(foundEntries),
+		})
+	}
+	if len(failedEntries) > 0 {
+		for _, failedEntry := range failedEntries {
+			slog.Warn("failed to parse log entry",
+				slog.Int64(logging.KeyIndex, failedEntry.Index),
+				slog.String(logging.KeyUUID, failedEntry.UUID),
+				slog.String(logging.KeyError, failedEntry.Error))
+		}
+		notificationData = append(notificationData, notifications.NotificationData{
+			Context: notificationContext,
+			Payload: identity.FailedLogEntryList(failedEntries),
+		})
+	}
+	if len(notificationData) == 0 {
+		return matchedEntries, nil
+	}
+
+	var policies *notifications.NotificationPolicies
+	if config.NotificationPolicies != nil {
+		if policies, err = notifications.LoadNotificationPolicies(*config.NotificationPolicies); err != nil {
+			return nil, err
+		}
+	}
+	// With an outbox, notifications are only queued here and delivered
+	// at the end of the run, so that a failed delivery is retried
+	// without searching the same log entries again
+	var outbox *notifications.Outbox
+	if config.NotificationOutbox != nil {
+		if outbox, err = notifications.NewOutbox(*config.NotificationOutbox); err != nil {
+			return nil, err
+		}
+	}
+
+	// All notifications are routed before any of them is held or queued, and
+	// they are held at once, so that a failure doesn't hold some of them twice
+	// when the range is searched again
+	routed := []notifications.RoutedNotification{}
+	held := map[string][]notifications.NotificationData{}
+	for _, data := range notificationData {
+		for _, routedNotification := range notifications.RouteNotification(*config, data) {
+			routed = append(routed, splitHeldNotifiers(policies, routedNotification, held))
+		}
+	}
+	if len(held) > 0 {
+		if err := policies.HoldAll(held, time.Now()); err != nil {
+			slog.Error("failed to hold notifications", logging.Err(err))
+			server.IncNotificationFailure()
+			return nil, err
+		}
+	}
+
+	// Once notifications are held, the range is committed even if queueing
+	// fails, and the notifications that could not be queued are retried as
+	// pending notifications
+	if outbox != nil {
+		for i, routedNotification := range routed {
+			if err := outbox.Enqueue(routedNotification.Data, routedNotification.Notifiers); err != nil {
+				slog.Error("failed to queue notification", logging.Err(err))
+				server.IncNotificationFailure()
+				state.pending, state.pendingErr = append(state.pending, routed[i:]...), err
+				break
+			}
+		}
+		return matchedEntries, nil
+	}
+
+	pending, err := sendRoutedNotifications(ctx, loopLogic, notifications.CreateNamedNotificationPool(*config), routed)
+	if err != nil {
+		state.pending, state.pendingErr = append(state.pending, pending...), err
 	}
+	return matchedEntries, nil
 }
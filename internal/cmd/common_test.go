@@ -17,12 +17,17 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/sigstore/rekor-monitor/pkg/events"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/notifications"
 )
@@ -414,6 +419,26 @@ func TestLoadMonitorConfig_ConfigFilePermissions(t *testing.T) {
 	}
 }
 
+func TestLoadMonitorConfig_SecretReferences(t *testing.T) {
+	t.Setenv("REKOR_MONITOR_TEST_GITHUB_TOKEN", "ghp_loadconfig")
+
+	flags := &MonitorFlags{
+		ConfigYaml: "githubIssue:\n  repositoryOwner: sigstore\n  repositoryName: rekor-monitor\n  authenticationToken: env:REKOR_MONITOR_TEST_GITHUB_TOKEN\n",
+	}
+	config, err := LoadMonitorConfig(flags, "default")
+	if err != nil {
+		t.Fatalf("LoadMonitorConfig() unexpected error: %v", err)
+	}
+	if config.GitHubIssue.AuthenticationToken != "ghp_loadconfig" {
+		t.Errorf("LoadMonitorConfig() authenticationToken = %s, want ghp_loadconfig", config.GitHubIssue.AuthenticationToken)
+	}
+
+	flags.ConfigYaml = "githubIssue:\n  repositoryOwner: sigstore\n  repositoryName: rekor-monitor\n  authenticationToken: env:REKOR_MONITOR_TEST_UNSET_TOKEN\n"
+	if _, err := LoadMonitorConfig(flags, "default"); err == nil || !strings.Contains(err.Error(), "env:REKOR_MONITOR_TEST_UNSET_TOKEN") {
+		t.Errorf("LoadMonitorConfig() expected unresolved secret error, got %v", err)
+	}
+}
+
 func TestLoadMonitorConfig_EmptyConfigFile(t *testing.T) {
 	// Test with an empty config file
 	tmpDir := t.TempDir()
@@ -483,13 +508,17 @@ type TestMonitorLoop struct {
 	// RunConsistencyCheckFn for custom RunConsistencyCheck logic (or nil if not set)
 	runConsistencyCheckFn func(ctx context.Context) (Checkpoint, LogInfo, error)
 	// IdentitySearchFn for custom IdentitySearch logic (or nil if not set)
-	identitySearchFn func(ctx context.Context, config *notifications.IdentityMonitorConfiguration, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error)
+	identitySearchFn func(ctx context.Context, config *notifications.IdentityMonitorConfiguration, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error)
 	// Monitored values to return (or default set if nil)
 	monitoredValues *identity.MonitoredValues
 	// config to return (or default set if nil)
 	config *notifications.IdentityMonitorConfiguration
 	// once flag
 	once *bool
+	// event bus to return (or nil if not set)
+	events *events.Bus
+	// error policy to return
+	errorPolicy ErrorPolicy
 	// Output tracking
 	identitySearchCalled         int
 	notificationContextNewCalled int
@@ -515,17 +544,22 @@ func (b *TestMonitorLoop) Config() *notifications.IdentityMonitorConfiguration {
 	return b.config
 }
 
-func (b *TestMonitorLoop) MonitoredValues() identity.MonitoredValues {
-	if b.monitoredValues == nil {
-		return identity.MonitoredValues{
-			CertificateIdentities: []identity.CertificateIdentity{
-				{CertSubject: "test-subject", Issuers: []string{"test-issuer"}},
-			},
-			Fingerprints: []string{"sha256:abcdef1234567890"},
-			Subjects:     []string{"test@example.com"},
-		}
+func (b *TestMonitorLoop) Matcher() *identity.Matcher {
+	monitoredValues := identity.MonitoredValues{
+		CertificateIdentities: []identity.CertificateIdentity{
+			{CertSubject: "test-subject", Issuers: []string{"test-issuer"}},
+		},
+		Fingerprints: []string{"sha256:abcdef1234567890"},
+		Subjects:     []string{"test@example.com"},
+	}
+	if b.monitoredValues != nil {
+		monitoredValues = *b.monitoredValues
+	}
+	matcher, err := NewMatcher(monitoredValues)
+	if err != nil {
+		panic(err)
 	}
-	return *b.monitoredValues
+	return matcher
 }
 
 func (b *TestMonitorLoop) Once() bool {
@@ -573,14 +607,15 @@ func (b *TestMonitorLoop) GetEndIndex(_ LogInfo) *int64 {
 	return intPtr(10)
 }
 
-func (b *TestMonitorLoop) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+func (b *TestMonitorLoop) IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 	b.identitySearchCalled++
 
 	if b.identitySearchFn != nil {
-		return b.identitySearchFn(context.WithValue(ctx, TestContextKey("loopLogic"), b), config, monitoredValues)
+		return b.identitySearchFn(context.WithValue(ctx, TestContextKey("loopLogic"), b), config, matcher)
 	}
 
 	// Verify that the monitored values are passed correctly
+	monitoredValues := matcher.MonitoredValues()
 	if len(monitoredValues.CertificateIdentities) != 1 {
 		return nil, nil, fmt.Errorf("Expected 1 certificate identity, got %d", len(monitoredValues.CertificateIdentities))
 	}
@@ -591,17 +626,46 @@ func (b *TestMonitorLoop) IdentitySearch(ctx context.Context, config *notificati
 		return nil, nil, fmt.Errorf("Expected 1 subject, got %d", len(monitoredValues.Subjects))
 	}
 
-	// Return some found identities to trigger notifications
-	return []identity.MonitoredIdentity{
+	// Return a matched entry to trigger notifications
+	return []identity.LogEntry{
 		{
-			Identity: "test-identity",
-			FoundIdentityEntries: []identity.LogEntry{
-				{CertSubject: "test-subject", Index: 5, UUID: "test-uuid"},
-			},
+			MatchedIdentity:     "test-subject",
+			MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
+			CertSubject:         "test-subject",
+			Index:               5,
+			UUID:                "test-uuid",
 		},
 	}, nil, nil
 }
 
+func (b *TestMonitorLoop) ErrorPolicy() ErrorPolicy {
+	return b.errorPolicy
+}
+
+func (b *TestMonitorLoop) Events() *events.Bus {
+	return b.events
+}
+
+func TestNewMatcher(t *testing.T) {
+	matcher, err := NewMatcher(identity.MonitoredValues{Subjects: []string{"user@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subjects := matcher.MonitoredValues().Subjects; len(subjects) != 1 || subjects[0] != "user@example.com" {
+		t.Errorf("unexpected monitored subjects %v", subjects)
+	}
+	// the identity search is skipped without monitored values
+	if _, err := NewMatcher(identity.MonitoredValues{}); err != nil {
+		t.Errorf("expected no error without monitored values, got %v", err)
+	}
+	if _, err := NewMatcher(identity.MonitoredValues{Fingerprints: []string{""}}); err == nil || !strings.Contains(err.Error(), "fingerprint empty") {
+		t.Errorf("expected empty fingerprint error, got %v", err)
+	}
+	if _, err := NewMatcher(identity.MonitoredValues{Subjects: []string{"("}}); err == nil {
+		t.Errorf("expected error for invalid subject regex")
+	}
+}
+
 func TestMonitorLoop_BasicExecution(t *testing.T) {
 	// Test basic execution with callbacks being called correctly
 	loopLogic := &TestMonitorLoop{}
@@ -736,8 +800,9 @@ func TestMonitorLoop_NoPreviousCheckpoint(t *testing.T) {
 	// Test that MonitorLoop handles no previous checkpoint + once=false correctly
 	once := false
 	loopLogic := &TestMonitorLoop{
-		once:   &once,
-		config: &notifications.IdentityMonitorConfiguration{},
+		once:        &once,
+		config:      &notifications.IdentityMonitorConfiguration{},
+		errorPolicy: ErrorPolicy{MaxConsecutiveFailures: 1},
 		runConsistencyCheckFn: func(ctx context.Context) (Checkpoint, LogInfo, error) {
 			switch ctx.Value(TestContextKey("loopLogic")).(*TestMonitorLoop).runConsistencyCheckCalled {
 			case 1:
@@ -746,12 +811,12 @@ func TestMonitorLoop_NoPreviousCheckpoint(t *testing.T) {
 				return "prev-checkpoint", "current-checkpoint", nil
 			}
 		},
-		identitySearchFn: func(ctx context.Context, _ *notifications.IdentityMonitorConfiguration, _ identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+		identitySearchFn: func(ctx context.Context, _ *notifications.IdentityMonitorConfiguration, _ *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 			switch ctx.Value(TestContextKey("loopLogic")).(*TestMonitorLoop).identitySearchCalled {
 			case 3:
-				return []identity.MonitoredIdentity{}, []identity.FailedLogEntry{}, fmt.Errorf("stop the loop")
+				return []identity.LogEntry{}, []identity.FailedLogEntry{}, fmt.Errorf("stop the loop")
 			default:
-				return []identity.MonitoredIdentity{}, []identity.FailedLogEntry{}, nil
+				return []identity.LogEntry{}, []identity.FailedLogEntry{}, nil
 			}
 		},
 	}
@@ -767,3 +832,342 @@ func TestMonitorLoop_NoPreviousCheckpoint(t *testing.T) {
 		t.Error("WriteCheckpointFn should be called when no previous checkpoint exists and later")
 	}
 }
+
+func TestMonitorLoop_PublishesEvents(t *testing.T) {
+	// Test that MonitorLoop publishes the events of a run to subscribers
+	var received []events.Event
+	loopLogic := &TestMonitorLoop{
+		events: events.NewBus(events.SubscriberFunc(func(_ context.Context, event events.Event) {
+			received = append(received, event)
+		})),
+		identitySearchFn: func(_ context.Context, _ *notifications.IdentityMonitorConfiguration, _ *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+			return []identity.LogEntry{{MatchedIdentity: "test@example.com", MatchedIdentityType: identity.MatchedIdentityTypeSubject, Subject: "test@example.com"}},
+				[]identity.FailedLogEntry{{Index: 3, Error: "parse error"}},
+				nil
+		},
+	}
+	MonitorLoop(loopLogic)
+
+	expected := []events.Type{
+		events.TypeCheckpointVerified,
+		events.TypeIdentityMatched,
+		events.TypeEntryParseFailed,
+	}
+	if len(received) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(received))
+	}
+	for i, eventType := range expected {
+		if received[i].EventType() != eventType {
+			t.Errorf("Expected event %s at position %d, got %s", eventType, i, received[i].EventType())
+		}
+	}
+	matched := received[1].(events.IdentityMatched)
+	if matched.StartIndex != 1 || matched.EndIndex != 10 {
+		t.Errorf("Expected index range (1, 10], got (%d, %d]", matched.StartIndex, matched.EndIndex)
+	}
+	if len(matched.Identities) != 1 || matched.Identities[0].Identity != "test@example.com" {
+		t.Errorf("Unexpected matched identities %v", matched.Identities)
+	}
+}
+
+func TestMonitorLoop_PublishesConsistencyFailed(t *testing.T) {
+	// Test that MonitorLoop publishes consistency check failures
+	var received []events.Event
+	loopLogic := &TestMonitorLoop{
+		runConsistencyError: true,
+		events: events.NewBus(events.SubscriberFunc(func(_ context.Context, event events.Event) {
+			received = append(received, event)
+		})),
+	}
+	MonitorLoop(loopLogic)
+
+	if len(received) != 1 || received[0].EventType() != events.TypeConsistencyFailed {
+		t.Fatalf("Expected a single ConsistencyFailed event, got %v", received)
+	}
+	if received[0].(events.ConsistencyFailed).Err == nil {
+		t.Error("Expected ConsistencyFailed event to carry the error")
+	}
+}
+
+func TestMonitorLoop_RetriesFailedRange(t *testing.T) {
+	// Test that a failed identity search is retried on the same range
+	// without persisting the checkpoint, and that the loop exits after
+	// the configured number of consecutive failures
+	once := false
+	var ranges [][2]int64
+	loopLogic := &TestMonitorLoop{
+		once:        &once,
+		config:      &notifications.IdentityMonitorConfiguration{},
+		errorPolicy: ErrorPolicy{MaxConsecutiveFailures: 2},
+		identitySearchFn: func(ctx context.Context, config *notifications.IdentityMonitorConfiguration, _ *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+			ranges = append(ranges, [2]int64{*config.StartIndex, *config.EndIndex})
+			if ctx.Value(TestContextKey("loopLogic")).(*TestMonitorLoop).identitySearchCalled == 2 {
+				return nil, nil, nil
+			}
+			return nil, nil, fmt.Errorf("transient error")
+		},
+	}
+	err := MonitorLoop(loopLogic)
+	if err == nil {
+		t.Fatal("Expected an error after consecutive failures")
+	}
+	if IsUnrecoverable(err) {
+		t.Errorf("Expected a recoverable error, got %v", err)
+	}
+
+	expected := [][2]int64{{1, 10}, {1, 10}, {10, 10}, {10, 10}}
+	if fmt.Sprint(ranges) != fmt.Sprint(expected) {
+		t.Errorf("Expected searched ranges %v, got %v", expected, ranges)
+	}
+	if loopLogic.writeCheckpointCalled != 1 {
+		t.Errorf("Expected 1 checkpoint write, got %d", loopLogic.writeCheckpointCalled)
+	}
+}
+
+func TestMonitorLoop_OnceReturnsError(t *testing.T) {
+	// Test that a failed run is not retried when running once
+	loopLogic := &TestMonitorLoop{
+		identitySearchFn: func(_ context.Context, _ *notifications.IdentityMonitorConfiguration, _ *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+			return nil, nil, fmt.Errorf("transient error")
+		},
+	}
+	if err := MonitorLoop(loopLogic); err == nil {
+		t.Error("Expected an error")
+	}
+	if loopLogic.identitySearchCalled != 1 {
+		t.Errorf("Expected 1 identity search call, got %d", loopLogic.identitySearchCalled)
+	}
+	if loopLogic.writeCheckpointCalled != 0 {
+		t.Errorf("Expected no checkpoint write, got %d", loopLogic.writeCheckpointCalled)
+	}
+}
+
+func TestMonitorLoop_UnrecoverableError(t *testing.T) {
+	// Test that the loop exits on an invalid index range even if not running once
+	once := false
+	loopLogic := &TestMonitorLoop{
+		once: &once,
+		config: &notifications.IdentityMonitorConfiguration{
+			StartIndex: intPtr(20),
+			EndIndex:   intPtr(10),
+		},
+	}
+	err := MonitorLoop(loopLogic)
+	if !IsUnrecoverable(err) {
+		t.Errorf("Expected an unrecoverable error, got %v", err)
+	}
+	if loopLogic.runConsistencyCheckCalled != 1 {
+		t.Errorf("Expected 1 iteration, got %d", loopLogic.runConsistencyCheckCalled)
+	}
+}
+
+func TestMonitorLoop_ResolvesConsistencyFailure(t *testing.T) {
+	// Test that a consistency failure is notified to incident platforms
+	// and resolved once the consistency check succeeds again
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event struct {
+			EventAction string `json:"event_action"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		actions = append(actions, event.EventAction)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	once := false
+	loopLogic := &TestMonitorLoop{
+		once:            &once,
+		monitoredValues: &identity.MonitoredValues{},
+		config: &notifications.IdentityMonitorConfiguration{
+			EndIndex:  intPtr(10),
+			PagerDuty: &notifications.PagerDutyNotificationInput{RoutingKey: "key", EventsURL: server.URL},
+		},
+		runConsistencyCheckFn: func(ctx context.Context) (Checkpoint, LogInfo, error) {
+			if ctx.Value(TestContextKey("loopLogic")).(*TestMonitorLoop).runConsistencyCheckCalled == 1 {
+				return nil, nil, fmt.Errorf("inconsistent log")
+			}
+			return "prev-checkpoint", "current-checkpoint", nil
+		},
+	}
+	if err := MonitorLoop(loopLogic); err != nil {
+		t.Fatalf("MonitorLoop() error = %v", err)
+	}
+
+	if fmt.Sprint(actions) != "[trigger resolve]" {
+		t.Errorf("expected trigger and resolve events, got %v", actions)
+	}
+}
+
+func TestMonitorLoop_HoldsNotificationsByPolicy(t *testing.T) {
+	// Test that notifications are held by a digest policy across runs, and
+	// sent in a later run once the policy allows it
+	var received []notifications.WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload notifications.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	policies := &notifications.NotificationPoliciesInput{
+		StateFile: filepath.Join(t.TempDir(), "policies.json"),
+		Policies:  map[string]notifications.NotificationPolicy{"webhook": {DigestInterval: time.Hour}},
+	}
+	config := &notifications.IdentityMonitorConfiguration{
+		StartIndex:           intPtr(1),
+		EndIndex:             intPtr(10),
+		Webhook:              &notifications.WebhookNotificationInput{URL: server.URL},
+		NotificationPolicies: policies,
+	}
+	if err := MonitorLoop(&TestMonitorLoop{config: config}); err != nil {
+		t.Fatalf("MonitorLoop() error = %v", err)
+	}
+	if len(received) != 0 {
+		t.Fatalf("Expected the notification to be held, got %d notifications", len(received))
+	}
+
+	policies.Policies["webhook"] = notifications.NotificationPolicy{}
+	config.StartIndex, config.EndIndex = intPtr(10), intPtr(20)
+	loopLogic := &TestMonitorLoop{
+		config: config,
+		identitySearchFn: func(_ context.Context, _ *notifications.IdentityMonitorConfiguration, _ *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+			return nil, nil, nil
+		},
+	}
+	if err := MonitorLoop(loopLogic); err != nil {
+		t.Fatalf("MonitorLoop() error = %v", err)
+	}
+	if len(received) != 1 || len(received[0].MatchedEntries) != 1 || received[0].MatchedEntries[0].Index != 5 {
+		t.Fatalf("Expected the held notification to be sent, got %+v", received)
+	}
+}
+
+func TestMonitorLoop_RetriesOnlyFailedNotifiers(t *testing.T) {
+	// Test that a notification that could not be sent is retried only for
+	// the failed notifier, without searching the range again or writing
+	// its matched entries to the identity outputs twice
+	var sent, attempts int
+	okServer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		sent++
+	}))
+	defer okServer.Close()
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer failingServer.Close()
+
+	outputFile := filepath.Join(t.TempDir(), "identities.txt")
+	once := false
+	var ranges [][2]int64
+	loopLogic := &TestMonitorLoop{
+		once: &once,
+		config: &notifications.IdentityMonitorConfiguration{
+			StartIndex:             intPtr(1),
+			EndIndex:               intPtr(10),
+			OutputIdentitiesFile:   outputFile,
+			OutputIdentitiesFormat: "text",
+			Webhook:                &notifications.WebhookNotificationInput{URL: okServer.URL},
+			Notifiers: []notifications.NotifierConfig{
+				{Name: "failing", Type: "webhook", Platform: notifications.WebhookNotificationInput{URL: failingServer.URL}},
+			},
+		},
+		identitySearchFn: func(_ context.Context, config *notifications.IdentityMonitorConfiguration, _ *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+			ranges = append(ranges, [2]int64{*config.StartIndex, *config.EndIndex})
+			if *config.StartIndex == *config.EndIndex {
+				return nil, nil, nil
+			}
+			return []identity.LogEntry{{MatchedIdentity: "test@example.com", MatchedIdentityType: identity.MatchedIdentityTypeSubject, Subject: "test@example.com", Index: 5}}, nil, nil
+		},
+	}
+	if err := MonitorLoop(loopLogic); err != nil {
+		t.Fatalf("MonitorLoop() error = %v", err)
+	}
+
+	if sent != 1 || attempts != 2 {
+		t.Errorf("Expected 1 notification to the working notifier and 2 attempts to the failing one, got %d and %d", sent, attempts)
+	}
+	expected := [][2]int64{{1, 10}, {10, 10}}
+	if fmt.Sprint(ranges) != fmt.Sprint(expected) {
+		t.Errorf("Expected searched ranges %v, got %v", expected, ranges)
+	}
+	output, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(output), "\n"); lines != 1 {
+		t.Errorf("Expected the matched entry to be written once, got %d times:\n%s", lines, output)
+	}
+}
+
+func TestMonitorLoop_NotifierAuthFailureIsRetried(t *testing.T) {
+	// Test that the loop keeps running when a notification platform rejects its
+	// credentials, and sends the notification once they are accepted again
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	once := false
+	loopLogic := &TestMonitorLoop{
+		once: &once,
+		config: &notifications.IdentityMonitorConfiguration{
+			StartIndex: intPtr(1),
+			EndIndex:   intPtr(10),
+			Webhook:    &notifications.WebhookNotificationInput{URL: server.URL},
+		},
+		identitySearchFn: func(_ context.Context, config *notifications.IdentityMonitorConfiguration, _ *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+			if *config.StartIndex == *config.EndIndex {
+				return nil, nil, nil
+			}
+			return []identity.LogEntry{{MatchedIdentity: "test@example.com", MatchedIdentityType: identity.MatchedIdentityTypeSubject, Subject: "test@example.com", Index: 5}}, nil, nil
+		},
+	}
+	if err := MonitorLoop(loopLogic); err != nil {
+		t.Fatalf("MonitorLoop() error = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected the notification to be sent again after the auth failure, got %d attempts", attempts)
+	}
+	if loopLogic.runConsistencyCheckCalled != 2 {
+		t.Errorf("Expected 2 iterations, got %d", loopLogic.runConsistencyCheckCalled)
+	}
+}
+
+func TestMonitorLoop_NotifierAuthFailureExitsIfConfigured(t *testing.T) {
+	// Test that the loop exits when a notification platform rejects its
+	// credentials and the error policy says so
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	once := false
+	loopLogic := &TestMonitorLoop{
+		once:        &once,
+		errorPolicy: ErrorPolicy{ExitOnNotifierAuthFailure: true},
+		config: &notifications.IdentityMonitorConfiguration{
+			StartIndex: intPtr(1),
+			EndIndex:   intPtr(10),
+			Webhook:    &notifications.WebhookNotificationInput{URL: server.URL},
+		},
+	}
+	err := MonitorLoop(loopLogic)
+	if !IsUnrecoverable(err) {
+		t.Errorf("Expected an unrecoverable error, got %v", err)
+	}
+	if loopLogic.runConsistencyCheckCalled != 1 {
+		t.Errorf("Expected 1 iteration, got %d", loopLogic.runConsistencyCheckCalled)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/certificate-transparency-go/jsonclient"
+	"github.com/sigstore/rekor-monitor/pkg/notifications"
+)
+
+// ErrorPolicy controls how the monitor loop reacts to failed runs.
+// Failed runs are retried on the same log index range, waiting the
+// monitor interval doubled for every consecutive failure.
+type ErrorPolicy struct {
+	// MaxConsecutiveFailures is the number of consecutive failed runs after
+	// which the monitor loop exits. 0 means the loop never gives up.
+	MaxConsecutiveFailures int
+	// MaxBackoff caps the delay between retries of a failed run. It has
+	// no effect if it is shorter than the monitor interval.
+	MaxBackoff time.Duration
+	// ExitOnNotifierAuthFailure makes the monitor loop exit when a
+	// notification platform rejects its credentials. By default, the
+	// notifications are kept pending and retried on the next runs.
+	ExitOnNotifierAuthFailure bool
+}
+
+// Backoff returns the delay before the next run after the given number of
+// consecutive failures
+func (p ErrorPolicy) Backoff(interval time.Duration, failures int) time.Duration {
+	maxBackoff := max(p.MaxBackoff, interval)
+	delay := interval
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// unrecoverableError marks an error after which retrying the monitor run
+// cannot succeed, e.g. because of an invalid configuration
+type unrecoverableError struct {
+	err error
+}
+
+func (e unrecoverableError) Error() string {
+	return e.err.Error()
+}
+
+func (e unrecoverableError) Unwrap() error {
+	return e.err
+}
+
+// Unrecoverable wraps an error so that the monitor loop exits instead of
+// retrying the failed run
+func Unrecoverable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return unrecoverableError{err: err}
+}
+
+// IsUnrecoverable reports whether the monitor loop should exit on the error
+func IsUnrecoverable(err error) bool {
+	var unrecoverable unrecoverableError
+	return errors.As(err, &unrecoverable)
+}
+
+// notifierError is the error of a notification platform a notification
+// could not be sent to
+type notifierError struct {
+	notifier string
+	err      error
+}
+
+func (e notifierError) Error() string {
+	return fmt.Sprintf("notifier %s: %v", e.notifier, e.err)
+}
+
+func (e notifierError) Unwrap() error {
+	return e.err
+}
+
+// classifyError marks the error of a failed monitor run as unrecoverable if
+// retrying the run cannot succeed: an invalid configuration, or credentials
+// rejected by the log. Credentials rejected by a notification platform are
+// only unrecoverable if the error policy says so.
+func classifyError(err error, policy ErrorPolicy) error {
+	if err == nil || IsUnrecoverable(err) {
+		return err
+	}
+	if errors.Is(err, notifications.ErrInvalidConfiguration) {
+		return Unrecoverable(err)
+	}
+	var notifierErr notifierError
+	if errors.As(err, &notifierErr) {
+		if policy.ExitOnNotifierAuthFailure && notifications.IsAuthFailure(err) {
+			return Unrecoverable(err)
+		}
+		return err
+	}
+	if isLogAuthFailure(err) {
+		return Unrecoverable(err)
+	}
+	return err
+}
+
+func isAuthStatus(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
+
+// isLogAuthFailure reports whether an error is caused by the log rejecting
+// the credentials of the monitor
+func isLogAuthFailure(err error) bool {
+	// errors of the generated Rekor client
+	var codeErr interface{ Code() int }
+	if errors.As(err, &codeErr) && isAuthStatus(codeErr.Code()) {
+		return true
+	}
+	// errors of the CT log client
+	var rspErr jsonclient.RspError
+	return errors.As(err, &rspErr) && isAuthStatus(rspErr.StatusCode)
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/google/certificate-transparency-go/jsonclient"
+	"github.com/google/go-github/v65/github"
+	"github.com/sigstore/rekor-monitor/pkg/notifications"
+	"github.com/sigstore/rekor/pkg/generated/client/tlog"
+)
+
+func TestErrorPolicyBackoff(t *testing.T) {
+	policy := ErrorPolicy{MaxBackoff: time.Hour}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 5 * time.Minute},
+		{failures: 2, want: 10 * time.Minute},
+		{failures: 3, want: 20 * time.Minute},
+		{failures: 4, want: 40 * time.Minute},
+		{failures: 5, want: time.Hour},
+		{failures: 1000, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := policy.Backoff(5*time.Minute, tt.failures); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	// a maximum backoff shorter than the interval is ignored
+	policy = ErrorPolicy{MaxBackoff: time.Second}
+	if got := policy.Backoff(time.Minute, 3); got != time.Minute {
+		t.Errorf("Backoff() = %v, want %v", got, time.Minute)
+	}
+}
+
+func TestIsUnrecoverable(t *testing.T) {
+	err := errors.New("invalid range")
+	if IsUnrecoverable(err) {
+		t.Errorf("expected plain error to be recoverable")
+	}
+	if !IsUnrecoverable(Unrecoverable(err)) {
+		t.Errorf("expected wrapped error to be unrecoverable")
+	}
+	wrapped := fmt.Errorf("monitor run: %w", Unrecoverable(err))
+	if !IsUnrecoverable(wrapped) || !errors.Is(wrapped, err) {
+		t.Errorf("expected unrecoverable error to be detected through wrapping")
+	}
+	if Unrecoverable(nil) != nil {
+		t.Errorf("expected nil error to stay nil")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	notifierAuth := notifierError{notifier: "email", err: fmt.Errorf("error sending email: %w", &textproto.Error{Code: 535, Msg: "authentication failed"})}
+	testCases := map[string]struct {
+		err           error
+		policy        ErrorPolicy
+		unrecoverable bool
+	}{
+		"transient":                   {err: errors.New("connection reset")},
+		"invalid configuration":       {err: fmt.Errorf("%w: stateFile must be set", notifications.ErrInvalidConfiguration), unrecoverable: true},
+		"rekor unauthorized":          {err: fmt.Errorf("error getting log info: %w", tlog.NewGetLogInfoDefault(http.StatusUnauthorized)), unrecoverable: true},
+		"rekor server error":          {err: tlog.NewGetLogInfoDefault(http.StatusBadGateway)},
+		"ct forbidden":                {err: fmt.Errorf("error getting STH: %w", jsonclient.RspError{Err: errors.New("forbidden"), StatusCode: http.StatusForbidden}), unrecoverable: true},
+		"smtp authentication":         {err: notifierAuth},
+		"smtp authentication exiting": {err: errors.Join(notifierAuth), policy: ErrorPolicy{ExitOnNotifierAuthFailure: true}, unrecoverable: true},
+		"smtp unavailable exiting":    {err: notifierError{notifier: "email", err: &textproto.Error{Code: 421, Msg: "service not available"}}, policy: ErrorPolicy{ExitOnNotifierAuthFailure: true}},
+		"github forbidden":            {err: notifierError{notifier: "github", err: &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusForbidden}}}},
+		"github forbidden exiting":    {err: notifierError{notifier: "github", err: &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusForbidden}}}, policy: ErrorPolicy{ExitOnNotifierAuthFailure: true}, unrecoverable: true},
+		"github server error exiting": {err: notifierError{notifier: "github", err: &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}}, policy: ErrorPolicy{ExitOnNotifierAuthFailure: true}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := IsUnrecoverable(classifyError(tc.err, tc.policy)); got != tc.unrecoverable {
+				t.Errorf("expected unrecoverable %v, got %v", tc.unrecoverable, got)
+			}
+		})
+	}
+}
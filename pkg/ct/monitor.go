@@ -17,8 +17,10 @@ package ct
 import (
 	"context"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
-	"os"
+	"log/slog"
+	"time"
 
 	ct "github.com/google/certificate-transparency-go"
 	ctclient "github.com/google/certificate-transparency-go/client"
@@ -27,72 +29,159 @@ import (
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/notifications"
 	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 )
 
 func GetCTLogEntries(ctx context.Context, logClient *ctclient.LogClient, startIndex int64, endIndex int64) ([]ct.LogEntry, error) {
 	entries, err := logClient.GetEntries(ctx, startIndex, endIndex)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving certificate transparency log entries: %v", err)
+		return nil, fmt.Errorf("error retrieving certificate transparency log entries: %w", err)
 	}
 	return entries, nil
 }
 
+// ScanEntryCertSubject returns the matches of the monitored certificate identities
+// in a CT log entry.
+//
+// Deprecated: ScanEntryCertSubject compiles the monitored values on every call. Use
+// MatchedIndices with a matcher compiled once by identity.NewMatcher.
 func ScanEntryCertSubject(logEntry ct.LogEntry, monitoredCertIDs []identity.CertificateIdentity) ([]identity.LogEntry, error) {
-	cert := logEntry.X509Cert
-	if cert == nil && logEntry.Precert != nil {
-		cert = logEntry.Precert.TBSCertificate
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{CertificateIdentities: monitoredCertIDs})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching  at index %d: %w", logEntry.Index, err)
 	}
+	return scanEntryCertSubject(logEntry, matcher)
+}
 
-	if cert == nil {
-		return nil, fmt.Errorf("unsupported CT log entry at index %d", logEntry.Index)
+// ScanEntryOIDExtensions returns the matches of the monitored OID extensions in a
+// CT log entry.
+//
+// Deprecated: ScanEntryOIDExtensions compiles the monitored values on every call.
+// Use MatchedIndices with a matcher compiled once by identity.NewMatcher.
+func ScanEntryOIDExtensions(logEntry ct.LogEntry, monitoredOIDMatchers []extensions.OIDExtension) ([]identity.LogEntry, error) {
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{OIDMatchers: monitoredOIDMatchers})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", logEntry.Index, err)
 	}
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredCertID := range monitoredCertIDs {
-		match, sub, iss, err := identity.CertMatchesPolicy(cert, monitoredCertID.CertSubject, monitoredCertID.Issuers)
-		if err != nil {
-			return nil, fmt.Errorf("error with policy matching  at index %d: %w", logEntry.Index, err)
-		} else if match {
-			matchedEntries = append(matchedEntries, identity.LogEntry{
-				MatchedIdentity:     monitoredCertID.CertSubject,
-				MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
-				CertSubject:         sub,
-				Issuer:              iss,
-				Index:               logEntry.Index,
-			})
-		}
+	return scanEntryOIDExtensions(logEntry, matcher)
+}
+
+// scanEntryCertSubject returns the matches of the certificate identities of a
+// compiled matcher in a CT log entry
+func scanEntryCertSubject(logEntry ct.LogEntry, matcher *identity.Matcher) ([]identity.LogEntry, error) {
+	cert, err := entryCertificate(logEntry)
+	if err != nil {
+		return nil, err
+	}
+	matchedEntries, err := identity.MatchCertificateIdentities(matcher, []*google_x509.Certificate{cert})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching  at index %d: %w", logEntry.Index, err)
 	}
-	return matchedEntries, nil
+	return withEntryCertificate(matchedEntries, logEntry), nil
 }
 
-func ScanEntryOIDExtensions(logEntry ct.LogEntry, monitoredOIDMatchers []extensions.OIDExtension) ([]identity.LogEntry, error) {
+// scanEntryOIDExtensions returns the matches of the OID extensions of a compiled
+// matcher in a CT log entry
+func scanEntryOIDExtensions(logEntry ct.LogEntry, matcher *identity.Matcher) ([]identity.LogEntry, error) {
+	cert, err := entryCertificate(logEntry)
+	if err != nil {
+		return nil, err
+	}
+	matchedEntries, err := identity.MatchOIDExtensions(matcher, []*google_x509.Certificate{cert})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", logEntry.Index, err)
+	}
+	return withEntryCertificate(matchedEntries, logEntry), nil
+}
+
+// ScanEntryRules returns the matches of the compiled rules in the certificate or
+// precertificate of a CT log entry. CT log entries have no subjects or key
+// fingerprints, so only the certificate conditions of a rule can match.
+func ScanEntryRules(logEntry ct.LogEntry, rules identity.CompiledRules) ([]identity.LogEntry, error) {
+	cert, err := entryCertificate(logEntry)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return []identity.LogEntry{}, nil
+	}
+	matchedEntries, err := rules.Match(identity.NewRuleEntry([]*google_x509.Certificate{cert}, nil, nil))
+	if err != nil {
+		return nil, fmt.Errorf("error with rule matching at index %d: %w", logEntry.Index, err)
+	}
+	return withEntryCertificate(matchedEntries, logEntry), nil
+}
+
+// ScanEntryPolicies returns the matches of the compiled policies in the
+// certificate or precertificate of a CT log entry, evaluated with the metadata
+// of the entry
+func ScanEntryPolicies(logEntry ct.LogEntry, policies identity.CompiledPolicies) ([]identity.LogEntry, error) {
+	cert, err := entryCertificate(logEntry)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return []identity.LogEntry{}, nil
+	}
+	policyEntry, err := identity.NewPolicyEntry([]*google_x509.Certificate{cert}, nil, nil, entryMetadata(logEntry))
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", logEntry.Index, err)
+	}
+	matchedEntries, err := policies.Match(policyEntry)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", logEntry.Index, err)
+	}
+	return withEntryCertificate(matchedEntries, logEntry), nil
+}
+
+// entryCertificate returns the certificate of a CT log entry, or the TBS
+// certificate of a precertificate entry
+func entryCertificate(logEntry ct.LogEntry) (*google_x509.Certificate, error) {
 	cert := logEntry.X509Cert
 	if cert == nil && logEntry.Precert != nil {
 		cert = logEntry.Precert.TBSCertificate
 	}
-
 	if cert == nil {
 		return nil, fmt.Errorf("unsupported CT log entry at index %d", logEntry.Index)
 	}
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredOID := range monitoredOIDMatchers {
-		match, _, extValue, err := identity.OIDMatchesPolicy(cert, monitoredOID.ObjectIdentifier, monitoredOID.ExtensionValues)
-		if err != nil {
-			return nil, fmt.Errorf("error with policy matching at index %d: %w", logEntry.Index, err)
-		}
-		if match {
-			matchedEntries = append(matchedEntries, identity.LogEntry{
-				MatchedIdentity:     extValue,
-				MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
-				Index:               logEntry.Index,
-				OIDExtension:        monitoredOID.ObjectIdentifier,
-				ExtensionValue:      extValue,
-			})
-		}
+	return cert, nil
+}
+
+// withEntryCertificate sets the index and certificate of a CT log entry on its matches
+func withEntryCertificate(matchedEntries []identity.LogEntry, logEntry ct.LogEntry) []identity.LogEntry {
+	for i := range matchedEntries {
+		matchedEntries[i].Index = logEntry.Index
+		matchedEntries[i].Certificate = entryCertificatePEM(logEntry)
+	}
+	return matchedEntries
+}
+
+// entryCertificatePEM returns the PEM encoding of the certificate of a CT log
+// entry, or of the precertificate as it was submitted
+func entryCertificatePEM(logEntry ct.LogEntry) string {
+	if logEntry.Precert != nil && len(logEntry.Precert.Submitted.Data) > 0 {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: logEntry.Precert.Submitted.Data}))
+	}
+	return identity.CertificatePEM(logEntry.X509Cert)
+}
+
+// entryMetadata returns the metadata of a CT log entry shared by all matches of the entry
+func entryMetadata(logEntry ct.LogEntry) identity.EntryMetadata {
+	metadata := identity.EntryMetadata{LogKind: identity.LogKindCT}
+	if logEntry.X509Cert != nil {
+		metadata.EntryKind = "x509"
+	} else if logEntry.Precert != nil {
+		metadata.EntryKind = "precert"
+	}
+	if timestampedEntry := logEntry.Leaf.TimestampedEntry; timestampedEntry != nil && timestampedEntry.Timestamp != 0 {
+		metadata.IntegratedTime = time.UnixMilli(int64(timestampedEntry.Timestamp)).UTC() //nolint: gosec // G115
 	}
-	return matchedEntries, nil
+	return metadata
 }
 
-func MatchedIndices(logEntries []ct.LogEntry, mvs identity.MonitoredValues, caRoots string, caIntermediates string) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
+// MatchedIndices returns the log entries that contain the requested identities, with
+// a single finding per log entry listing every rule that matched the entry.
+func MatchedIndices(logEntries []ct.LogEntry, matcher *identity.Matcher, caRoots string, caIntermediates string) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 	matchedEntries := []identity.LogEntry{}
 	failedEntries := []identity.FailedLogEntry{}
 	for _, entry := range logEntries {
@@ -100,7 +189,8 @@ func MatchedIndices(logEntries []ct.LogEntry, mvs identity.MonitoredValues, caRo
 			cert, err := x509.ParseCertificate(entry.X509Cert.Raw)
 			if err == nil {
 				if err = identity.ValidateCertificateChain([]*x509.Certificate{cert}, caRoots, caIntermediates); err != nil {
-					fmt.Fprintf(os.Stderr, "error validating certificate chain for log entry at index %d: %v\n", entry.Index, err)
+					slog.Warn("certificate chain for log entry could not be verified against trusted CAs, skipping the entry",
+						slog.Int64(logging.KeyIndex, entry.Index), logging.Err(err))
 					continue
 				}
 			}
@@ -108,13 +198,16 @@ func MatchedIndices(logEntries []ct.LogEntry, mvs identity.MonitoredValues, caRo
 			cert, err := google_x509.ParseCertificate(entry.Precert.Submitted.Data)
 			if err == nil {
 				if err = identity.ValidatePreCertificateChain([]*google_x509.Certificate{cert}, caRoots, caIntermediates); err != nil {
-					fmt.Fprintf(os.Stderr, "error validating pre-certificate chain for log entry at index %d: %v\n", entry.Index, err)
+					slog.Warn("pre-certificate chain for log entry could not be verified against trusted CAs, skipping the entry",
+						slog.Int64(logging.KeyIndex, entry.Index), logging.Err(err))
 					continue
 				}
 			}
 		}
 
-		matchedCertSubjectEntries, err := ScanEntryCertSubject(entry, mvs.CertificateIdentities)
+		metadata := entryMetadata(entry)
+
+		matchedCertSubjectEntries, err := scanEntryCertSubject(entry, matcher)
 		if err != nil {
 			failedEntries = append(failedEntries, identity.FailedLogEntry{
 				Index: entry.Index,
@@ -122,9 +215,9 @@ func MatchedIndices(logEntries []ct.LogEntry, mvs identity.MonitoredValues, caRo
 			})
 			continue
 		}
-		matchedEntries = append(matchedEntries, matchedCertSubjectEntries...)
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedCertSubjectEntries, metadata)...)
 
-		matchedOIDEntries, err := ScanEntryOIDExtensions(entry, mvs.OIDMatchers)
+		matchedOIDEntries, err := scanEntryOIDExtensions(entry, matcher)
 		if err != nil {
 			failedEntries = append(failedEntries, identity.FailedLogEntry{
 				Index: entry.Index,
@@ -132,28 +225,63 @@ func MatchedIndices(logEntries []ct.LogEntry, mvs identity.MonitoredValues, caRo
 			})
 			continue
 		}
-		matchedEntries = append(matchedEntries, matchedOIDEntries...)
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedOIDEntries, metadata)...)
+
+		matchedRuleEntries, err := ScanEntryRules(entry, matcher.Rules)
+		if err != nil {
+			failedEntries = append(failedEntries, identity.FailedLogEntry{
+				Index: entry.Index,
+				Error: fmt.Sprintf("error matching rules: %v", err),
+			})
+			continue
+		}
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedRuleEntries, metadata)...)
+
+		matchedPolicyEntries, err := ScanEntryPolicies(entry, matcher.Policies)
+		if err != nil {
+			failedEntries = append(failedEntries, identity.FailedLogEntry{
+				Index: entry.Index,
+				Error: fmt.Sprintf("error matching policies: %v", err),
+			})
+			continue
+		}
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedPolicyEntries, metadata)...)
 	}
 
-	return matchedEntries, failedEntries, nil
+	return identity.ConsolidateMatches(matchedEntries), failedEntries, nil
 }
 
-func IdentitySearch(ctx context.Context, client *ctclient.LogClient, config *notifications.IdentityMonitorConfiguration, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+// SearchIdentities returns the entries of the log index range (StartIndex, EndIndex]
+// of the configuration matching the compiled monitored values, and the entries that
+// could not be parsed. Unlike IdentitySearch, it doesn't write the identity outputs.
+func SearchIdentities(ctx context.Context, client *ctclient.LogClient, config *notifications.IdentityMonitorConfiguration, logOrigin string, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 	entries, err := GetCTLogEntries(ctx, client, *config.StartIndex, *config.EndIndex)
 	if err != nil {
 		return nil, nil, err
 	}
-	matchedEntries, failedEntries, err := MatchedIndices(entries, monitoredValues, config.CARootsFile, config.CAIntermediatesFile)
+	matchedEntries, failedEntries, err := MatchedIndices(entries, matcher, config.CARootsFile, config.CAIntermediatesFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	identity.SetDetected(matchedEntries, logOrigin, time.Now())
+	return matchedEntries, failedEntries, nil
+}
+
+// IdentitySearch searches the log index range (StartIndex, EndIndex] of the
+// configuration, writes the matched entries to the identity outputs and
+// returns them grouped by monitored identity
+func IdentitySearch(ctx context.Context, client *ctclient.LogClient, config *notifications.IdentityMonitorConfiguration, logOrigin string, matcher *identity.Matcher) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+	matchedEntries, failedEntries, err := SearchIdentities(ctx, client, config, logOrigin, matcher)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	err = file.WriteMatchedIdentityEntries(config.OutputIdentitiesFile, config.OutputIdentitiesFormat, matchedEntries, config.IdentityMetadataFile, *config.EndIndex)
+	err = file.WriteMatchedIdentityEntries(config.IdentityOutputs(), matchedEntries, config.IdentityMetadataFile, *config.EndIndex)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	identities := identity.CreateIdentitiesList(monitoredValues)
+	identities := identity.CreateIdentitiesList(matcher.MonitoredValues())
 	monitoredIdentities := identity.CreateMonitoredIdentities(matchedEntries, identities)
 	return monitoredIdentities, failedEntries, nil
 }
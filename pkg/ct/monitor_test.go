@@ -34,6 +34,16 @@ const (
 	organizationName = "test-org"
 )
 
+// testMatcher compiles the monitored values of a test
+func testMatcher(t *testing.T, mvs identity.MonitoredValues) *identity.Matcher {
+	t.Helper()
+	matcher, err := identity.NewMatcher(mvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matcher
+}
+
 func TestScanEntryCertSubject(t *testing.T) {
 	testCases := map[string]struct {
 		inputEntry    ct.LogEntry
@@ -103,6 +113,7 @@ func TestScanEntryCertSubject(t *testing.T) {
 			inputEntry: ct.LogEntry{
 				Index: 1,
 				Precert: &ct.Precertificate{
+					Submitted: ct.ASN1Cert{Data: []byte{0x30, 0x00}},
 					TBSCertificate: &x509.Certificate{
 						DNSNames:       []string{subjectName},
 						EmailAddresses: []string{organizationName},
@@ -135,13 +146,51 @@ func TestScanEntryCertSubject(t *testing.T) {
 					Index:               1,
 					CertSubject:         subjectName,
 					Issuer:              issuerName,
+					Certificate:         "-----BEGIN CERTIFICATE-----\nMAA=\n-----END CERTIFICATE-----\n",
+				},
+				{
+					MatchedIdentity:     organizationName,
+					MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
+					Index:               1,
+					CertSubject:         organizationName,
+					Issuer:              issuerName,
+					Certificate:         "-----BEGIN CERTIFICATE-----\nMAA=\n-----END CERTIFICATE-----\n",
+				},
+			},
+			expectedErr: false,
+		},
+		"matching subject with expected provenance": {
+			inputEntry: ct.LogEntry{
+				Index: 1,
+				X509Cert: &x509.Certificate{
+					DNSNames:       []string{subjectName},
+					EmailAddresses: []string{organizationName},
+					Extensions: []pkix.Extension{
+						{
+							Id:    google_asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1},
+							Value: []byte(issuerName),
+						},
+					},
+				},
+			},
+			inputSubjects: []identity.CertificateIdentity{
+				{
+					CertSubject:        subjectName,
+					ExpectedProvenance: &identity.ExpectedProvenance{Issuers: []string{issuerName}},
 				},
+				{
+					CertSubject:        organizationName,
+					ExpectedProvenance: &identity.ExpectedProvenance{Issuers: []string{"https://token.actions.githubusercontent.com"}},
+				},
+			},
+			expectedVal: []identity.LogEntry{
 				{
 					MatchedIdentity:     organizationName,
 					MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
 					Index:               1,
 					CertSubject:         organizationName,
 					Issuer:              issuerName,
+					Violations:          []identity.Violation{{Kind: identity.ViolationUnexpectedIssuer, Value: issuerName}},
 				},
 			},
 			expectedErr: false,
@@ -196,7 +245,7 @@ func TestScanEntryOIDExtensions(t *testing.T) {
 			inputOIDExtensions: []extensions.OIDExtension{
 				{
 					ObjectIdentifier: unmatchedAsn1OID,
-					ExtensionValues:  []string{extValueString},
+					ExtensionValues:  extensions.ExactValues(extValueString),
 				},
 			},
 			expectedVal: []identity.LogEntry{},
@@ -210,7 +259,7 @@ func TestScanEntryOIDExtensions(t *testing.T) {
 			inputOIDExtensions: []extensions.OIDExtension{
 				{
 					ObjectIdentifier: matchedAsn1OID,
-					ExtensionValues:  []string{extValueString},
+					ExtensionValues:  extensions.ExactValues(extValueString),
 				},
 			},
 			expectedVal: []identity.LogEntry{
@@ -224,6 +273,42 @@ func TestScanEntryOIDExtensions(t *testing.T) {
 			},
 			expectedErr: false,
 		},
+		"matching glob": {
+			inputEntry: ct.LogEntry{
+				Index:    1,
+				X509Cert: cert,
+			},
+			inputOIDExtensions: []extensions.OIDExtension{
+				{
+					ObjectIdentifier: matchedAsn1OID,
+					ExtensionValues:  []extensions.ExtensionValue{{Value: "other", MatchMode: extensions.MatchModePrefix}, {Value: "test * value", MatchMode: extensions.MatchModeGlob}},
+				},
+			},
+			expectedVal: []identity.LogEntry{
+				{
+					MatchedIdentity:     "test * value",
+					MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
+					Index:               1,
+					OIDExtension:        matchedAsn1OID,
+					ExtensionValue:      extValueString,
+				},
+			},
+			expectedErr: false,
+		},
+		"negated regex not matching": {
+			inputEntry: ct.LogEntry{
+				Index:    1,
+				X509Cert: cert,
+			},
+			inputOIDExtensions: []extensions.OIDExtension{
+				{
+					ObjectIdentifier: matchedAsn1OID,
+					ExtensionValues:  []extensions.ExtensionValue{{Value: "^test", MatchMode: extensions.MatchModeRegex, Negate: true}},
+				},
+			},
+			expectedVal: []identity.LogEntry{},
+			expectedErr: false,
+		},
 		"matching subject precertificate": {
 			inputEntry: ct.LogEntry{
 				Index: 1,
@@ -234,7 +319,7 @@ func TestScanEntryOIDExtensions(t *testing.T) {
 			inputOIDExtensions: []extensions.OIDExtension{
 				{
 					ObjectIdentifier: matchedAsn1OID,
-					ExtensionValues:  []string{extValueString},
+					ExtensionValues:  extensions.ExactValues(extValueString),
 				},
 			},
 			expectedVal: []identity.LogEntry{
@@ -255,7 +340,7 @@ func TestScanEntryOIDExtensions(t *testing.T) {
 			inputOIDExtensions: []extensions.OIDExtension{
 				{
 					ObjectIdentifier: unmatchedAsn1OID,
-					ExtensionValues:  []string{extValueString},
+					ExtensionValues:  extensions.ExactValues(extValueString),
 				},
 			},
 			expectedVal: nil,
@@ -281,6 +366,82 @@ func TestScanEntryOIDExtensions(t *testing.T) {
 	}
 }
 
+func TestScanEntryRules(t *testing.T) {
+	cert, err := mockCertificateWithExtension(google_asn1.ObjectIdentifier(extensions.OIDSourceRepositoryOwnerURI), "https://github.com/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.EmailAddresses = []string{"dev@corp.com"}
+	rules, err := identity.CompileRules([]identity.Rule{{
+		Name: "outside-contributor",
+		RuleCondition: identity.RuleCondition{All: []identity.RuleCondition{
+			{CertSubject: ".*@corp.com"},
+			{Not: &identity.RuleCondition{Extension: &identity.ExtensionCondition{Name: "SourceRepositoryOwnerURI", Value: "^https://github.com/corp$"}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []ct.LogEntry{{Index: 1, X509Cert: cert}, {Index: 1, Precert: &ct.Precertificate{TBSCertificate: cert}}} {
+		logEntries, err := ScanEntryRules(entry, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []identity.LogEntry{{
+			MatchedIdentity:     "outside-contributor",
+			MatchedIdentityType: identity.MatchedIdentityTypeRule,
+			CertSubject:         "dev@corp.com",
+			Index:               1,
+		}}
+		if !reflect.DeepEqual(logEntries, expected) {
+			t.Errorf("expected %v, received %v", expected, logEntries)
+		}
+	}
+
+	if _, err := ScanEntryRules(ct.LogEntry{Index: 1}, rules); err == nil {
+		t.Errorf("expected error for entry without certificate")
+	}
+}
+
+func TestScanEntryPolicies(t *testing.T) {
+	cert, err := mockCertificateWithExtension(google_asn1.ObjectIdentifier(extensions.OIDSourceRepositoryOwnerURI), "https://github.com/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.EmailAddresses = []string{"dev@corp.com"}
+	policies, err := identity.CompilePolicies([]identity.Policy{{
+		Name:       "outside-contributor",
+		Expression: `entry.kind == "precert" && entry.certificates.exists(c, c.sans.exists(s, s.endsWith("@corp.com")) && !("https://github.com/corp" in c.extensions.SourceRepositoryOwnerURI))`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logEntries, err := ScanEntryPolicies(ct.LogEntry{Index: 1, Precert: &ct.Precertificate{TBSCertificate: cert}}, policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []identity.LogEntry{{
+		MatchedIdentity:     "outside-contributor",
+		MatchedIdentityType: identity.MatchedIdentityTypePolicy,
+		CertSubject:         "dev@corp.com",
+		Index:               1,
+	}}
+	if !reflect.DeepEqual(logEntries, expected) {
+		t.Errorf("expected %v, received %v", expected, logEntries)
+	}
+
+	// the entry kind of certificates is x509
+	logEntries, err = ScanEntryPolicies(ct.LogEntry{Index: 1, X509Cert: cert}, policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logEntries) != 0 {
+		t.Errorf("expected no matches, received %v", logEntries)
+	}
+}
+
 func TestMatchedIndices(t *testing.T) {
 	extCert, err := mockCertificateWithExtension(google_asn1.ObjectIdentifier{2, 5, 29, 17}, "test cert value")
 	if err != nil {
@@ -326,7 +487,7 @@ func TestMatchedIndices(t *testing.T) {
 				OIDMatchers: []extensions.OIDExtension{
 					{
 						ObjectIdentifier: unmatchedAsn1OID,
-						ExtensionValues:  []string{"unmatched extension value"},
+						ExtensionValues:  extensions.ExactValues("unmatched extension value"),
 					},
 				},
 			},
@@ -346,7 +507,10 @@ func TestMatchedIndices(t *testing.T) {
 				{
 					MatchedIdentity:     subjectName,
 					MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
+					MatchedRules:        []identity.MatchedRule{{MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, MatchedIdentity: subjectName, Value: subjectName}},
 					Index:               1,
+					LogKind:             identity.LogKindCT,
+					EntryKind:           "x509",
 					CertSubject:         subjectName,
 					Issuer:              issuerName,
 				},
@@ -358,7 +522,7 @@ func TestMatchedIndices(t *testing.T) {
 				OIDMatchers: []extensions.OIDExtension{
 					{
 						ObjectIdentifier: matchedAsn1OID,
-						ExtensionValues:  []string{extValueString},
+						ExtensionValues:  extensions.ExactValues(extValueString),
 					},
 				},
 			},
@@ -366,7 +530,10 @@ func TestMatchedIndices(t *testing.T) {
 				{
 					MatchedIdentity:     extValueString,
 					MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
+					MatchedRules:        []identity.MatchedRule{{MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue, MatchedIdentity: extValueString, Value: extValueString, OIDExtension: matchedAsn1OID.String()}},
 					Index:               1,
+					LogKind:             identity.LogKindCT,
+					EntryKind:           "x509",
 					OIDExtension:        matchedAsn1OID,
 					ExtensionValue:      extValueString,
 				},
@@ -384,31 +551,33 @@ func TestMatchedIndices(t *testing.T) {
 				OIDMatchers: []extensions.OIDExtension{
 					{
 						ObjectIdentifier: matchedAsn1OID,
-						ExtensionValues:  []string{extValueString},
+						ExtensionValues:  extensions.ExactValues(extValueString),
 					},
 				},
 			},
+			// matches of the same entry are consolidated into a single finding
 			expected: []identity.LogEntry{
 				{
 					MatchedIdentity:     subjectName,
 					MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
-					Index:               1,
-					CertSubject:         subjectName,
-					Issuer:              issuerName,
-				},
-				{
-					MatchedIdentity:     extValueString,
-					MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
-					Index:               1,
-					OIDExtension:        matchedAsn1OID,
-					ExtensionValue:      extValueString,
+					MatchedRules: []identity.MatchedRule{
+						{MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, MatchedIdentity: subjectName, Value: subjectName},
+						{MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue, MatchedIdentity: extValueString, Value: extValueString, OIDExtension: matchedAsn1OID.String()},
+					},
+					Index:          1,
+					LogKind:        identity.LogKindCT,
+					EntryKind:      "x509",
+					CertSubject:    subjectName,
+					Issuer:         issuerName,
+					OIDExtension:   matchedAsn1OID,
+					ExtensionValue: extValueString,
 				},
 			},
 		},
 	}
 
 	for _, tc := range testCases {
-		matchedEntries, failedEntries, err := MatchedIndices(tc.inputEntries, tc.inputMonitoredValues, "", "")
+		matchedEntries, failedEntries, err := MatchedIndices(tc.inputEntries, testMatcher(t, tc.inputMonitoredValues), "", "")
 		if err != nil {
 			t.Errorf("error matching indices: %v", err)
 		}
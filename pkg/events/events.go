@@ -0,0 +1,182 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events provides a typed event stream for the monitor loop, so that
+// integrations such as metrics, audit logs, webhooks or tests can observe what
+// happened during a monitor run without parsing the log output.
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
+)
+
+// Type identifies the kind of an event
+type Type string
+
+const (
+	TypeCheckpointVerified Type = "CheckpointVerified"
+	TypeConsistencyFailed  Type = "ConsistencyFailed"
+	TypeIdentityMatched    Type = "IdentityMatched"
+	TypeEntryParseFailed   Type = "EntryParseFailed"
+	TypeNotificationFailed Type = "NotificationFailed"
+	TypeShardsUpdated      Type = "ShardsUpdated"
+)
+
+// Event is implemented by every event published on a Bus
+type Event interface {
+	EventType() Type
+}
+
+// CheckpointVerified is published when the consistency of the log has been
+// verified between the previously persisted checkpoint and the current one.
+// Previous is nil on the first run, when no checkpoint was persisted yet.
+type CheckpointVerified struct {
+	Time     time.Time
+	Previous any
+	Current  any
+}
+
+// ConsistencyFailed is published when the consistency check of the log failed
+type ConsistencyFailed struct {
+	Time time.Time
+	Err  error
+}
+
+// IdentityMatched is published when an identity search over the log
+// indices (StartIndex, EndIndex] found entries matching the monitored values
+type IdentityMatched struct {
+	Time       time.Time
+	StartIndex int64
+	EndIndex   int64
+	Identities []identity.MonitoredIdentity
+}
+
+// EntryParseFailed is published when an identity search over the log
+// indices (StartIndex, EndIndex] could not parse some of the entries
+type EntryParseFailed struct {
+	Time       time.Time
+	StartIndex int64
+	EndIndex   int64
+	Entries    []identity.FailedLogEntry
+}
+
+// NotificationFailed is published when a notification could not be sent
+type NotificationFailed struct {
+	Time time.Time
+	Err  error
+}
+
+// ShardsUpdated is published when the set of monitored log shards changed,
+// for example after a new shard was added to the signing configuration
+type ShardsUpdated struct {
+	Time              time.Time
+	LatestShardOrigin string
+	ShardOrigins      []string
+}
+
+// EventType implements the Event interface
+func (CheckpointVerified) EventType() Type { return TypeCheckpointVerified }
+
+// EventType implements the Event interface
+func (ConsistencyFailed) EventType() Type { return TypeConsistencyFailed }
+
+// EventType implements the Event interface
+func (IdentityMatched) EventType() Type { return TypeIdentityMatched }
+
+// EventType implements the Event interface
+func (EntryParseFailed) EventType() Type { return TypeEntryParseFailed }
+
+// EventType implements the Event interface
+func (NotificationFailed) EventType() Type { return TypeNotificationFailed }
+
+// EventType implements the Event interface
+func (ShardsUpdated) EventType() Type { return TypeShardsUpdated }
+
+// Subscriber receives the events published on a Bus
+type Subscriber interface {
+	HandleEvent(ctx context.Context, event Event)
+}
+
+// SubscriberFunc adapts a function to the Subscriber interface
+type SubscriberFunc func(ctx context.Context, event Event)
+
+// HandleEvent implements the Subscriber interface
+func (f SubscriberFunc) HandleEvent(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// Bus delivers published events to all registered subscribers.
+// Events are delivered synchronously and in registration order, so
+// subscribers should return quickly and hand off any slow work.
+// A nil *Bus is valid and discards all events.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber
+}
+
+// NewBus creates a new event bus with the given subscribers
+func NewBus(subscribers ...Subscriber) *Bus {
+	return &Bus{subscribers: subscribers}
+}
+
+// Subscribe registers a subscriber for all future events
+func (b *Bus) Subscribe(subscriber Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Publish delivers an event to all registered subscribers
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subscribers := make([]Subscriber, len(b.subscribers))
+	copy(subscribers, b.subscribers)
+	b.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		subscriber.HandleEvent(ctx, event)
+	}
+}
+
+// LogSubscriber returns a subscriber logging every event at debug level with
+// the default logger, so that the event stream can be followed in the logs
+func LogSubscriber() Subscriber {
+	return SubscriberFunc(func(ctx context.Context, event Event) {
+		attrs := []any{slog.String("event", string(event.EventType()))}
+		switch e := event.(type) {
+		case ConsistencyFailed:
+			attrs = append(attrs, logging.Err(e.Err))
+		case IdentityMatched:
+			attrs = append(attrs, logging.IndexRange(e.StartIndex, e.EndIndex)...)
+			attrs = append(attrs, slog.Int("identities", len(e.Identities)))
+		case EntryParseFailed:
+			attrs = append(attrs, logging.IndexRange(e.StartIndex, e.EndIndex)...)
+			attrs = append(attrs, slog.Int("entries", len(e.Entries)))
+		case NotificationFailed:
+			attrs = append(attrs, logging.Err(e.Err))
+		case ShardsUpdated:
+			attrs = append(attrs, slog.String("latestShardOrigin", e.LatestShardOrigin), slog.Any("shardOrigins", e.ShardOrigins))
+		}
+		slog.DebugContext(ctx, "monitor event", attrs...)
+	})
+}
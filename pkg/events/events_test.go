@@ -0,0 +1,52 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBusPublish(t *testing.T) {
+	var first, second []Type
+	bus := NewBus(SubscriberFunc(func(_ context.Context, event Event) {
+		first = append(first, event.EventType())
+	}))
+	bus.Subscribe(SubscriberFunc(func(_ context.Context, event Event) {
+		second = append(second, event.EventType())
+	}))
+
+	bus.Publish(context.Background(), CheckpointVerified{})
+	bus.Publish(context.Background(), ConsistencyFailed{Err: errors.New("inconsistent")})
+
+	expected := []Type{TypeCheckpointVerified, TypeConsistencyFailed}
+	for _, received := range [][]Type{first, second} {
+		if len(received) != len(expected) {
+			t.Fatalf("expected %d events, received %d", len(expected), len(received))
+		}
+		for i := range expected {
+			if received[i] != expected[i] {
+				t.Errorf("expected event %s at position %d, received %s", expected[i], i, received[i])
+			}
+		}
+	}
+}
+
+func TestNilBusPublish(_ *testing.T) {
+	var bus *Bus
+	// publishing on a nil bus must not panic
+	bus.Publish(context.Background(), ShardsUpdated{})
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensions
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+)
+
+// ExtensionEncoding is how the value of a certificate extension is decoded
+// into the values compared with monitored extension values
+type ExtensionEncoding string
+
+const (
+	// EncodingString decodes an ASN.1 string, such as a UTF8String,
+	// IA5String or PrintableString
+	EncodingString ExtensionEncoding = "string"
+	// EncodingOctetString decodes an ASN.1 OCTET STRING holding text
+	EncodingOctetString ExtensionEncoding = "octetString"
+	// EncodingInteger decodes an ASN.1 INTEGER into its decimal representation
+	EncodingInteger ExtensionEncoding = "integer"
+	// EncodingBoolean decodes an ASN.1 BOOLEAN into true or false
+	EncodingBoolean ExtensionEncoding = "boolean"
+	// EncodingRaw decodes the DER-encoded extension value into lowercase hex
+	EncodingRaw ExtensionEncoding = "raw"
+	// EncodingRawString decodes the extension value as a string without
+	// ASN.1 decoding, as the deprecated Fulcio extensions are encoded
+	EncodingRawString ExtensionEncoding = "rawString"
+	// EncodingExtKeyUsage decodes the extended key usage extension into
+	// the names of its key purposes, such as codeSigning, or their OIDs in
+	// dot notation for unnamed key purposes
+	EncodingExtKeyUsage ExtensionEncoding = "extKeyUsage"
+	// EncodingKeyUsage decodes the key usage extension into the names of its
+	// key usages, such as digitalSignature
+	EncodingKeyUsage ExtensionEncoding = "keyUsage"
+	// EncodingCertificatePolicies decodes the certificate policies extension
+	// into the policy OIDs in dot notation
+	EncodingCertificatePolicies ExtensionEncoding = "certificatePolicies"
+)
+
+var extensionEncodings = []ExtensionEncoding{EncodingString, EncodingOctetString, EncodingInteger, EncodingBoolean,
+	EncodingRaw, EncodingRawString, EncodingExtKeyUsage, EncodingKeyUsage, EncodingCertificatePolicies}
+
+var (
+	OIDKeyUsage            = asn1.ObjectIdentifier{2, 5, 29, 15}
+	OIDCertificatePolicies = asn1.ObjectIdentifier{2, 5, 29, 32}
+	OIDExtKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 37}
+)
+
+// extKeyUsageNames are the names of the key purposes of RFC 5280, keyed by
+// their OID in dot notation
+var extKeyUsageNames = map[string]string{
+	"2.5.29.37.0":       "any",
+	"1.3.6.1.5.5.7.3.1": "serverAuth",
+	"1.3.6.1.5.5.7.3.2": "clientAuth",
+	"1.3.6.1.5.5.7.3.3": "codeSigning",
+	"1.3.6.1.5.5.7.3.4": "emailProtection",
+	"1.3.6.1.5.5.7.3.8": "timeStamping",
+	"1.3.6.1.5.5.7.3.9": "OCSPSigning",
+}
+
+// keyUsageNames are the names of the key usages of RFC 5280, in the order of
+// their bits
+var keyUsageNames = []string{"digitalSignature", "contentCommitment", "keyEncipherment", "dataEncipherment",
+	"keyAgreement", "keyCertSign", "cRLSign", "encipherOnly", "decipherOnly"}
+
+// ResolveExtensionEncoding returns the encoding of a monitored extension,
+// which is the default encoding of the extension if the encoding is empty
+func ResolveExtensionEncoding(encoding ExtensionEncoding, oid asn1.ObjectIdentifier) (ExtensionEncoding, error) {
+	if encoding == "" {
+		return DefaultExtensionEncoding(oid), nil
+	}
+	if !slices.Contains(extensionEncodings, encoding) {
+		return "", fmt.Errorf("unknown extension encoding %s", encoding)
+	}
+	return encoding, nil
+}
+
+// DefaultExtensionEncoding returns the encoding of an extension when none is
+// configured: the standard encoding of the extended key usage, key usage and
+// certificate policies extensions, raw strings for the deprecated Fulcio
+// extensions, and ASN.1 strings otherwise
+func DefaultExtensionEncoding(oid asn1.ObjectIdentifier) ExtensionEncoding {
+	switch {
+	case oid.Equal(OIDExtKeyUsage):
+		return EncodingExtKeyUsage
+	case oid.Equal(OIDKeyUsage):
+		return EncodingKeyUsage
+	case oid.Equal(OIDCertificatePolicies):
+		return EncodingCertificatePolicies
+	case IsDeprecatedExtension(oid):
+		return EncodingRawString
+	}
+	return EncodingString
+}
+
+// policyInformation is a certificate policy of the certificate policies extension
+type policyInformation struct {
+	Policy     asn1.ObjectIdentifier
+	Qualifiers asn1.RawValue `asn1:"optional"`
+}
+
+// DecodeExtension decodes the value of a certificate extension into the values
+// compared with monitored extension values. Extensions with a list of values,
+// such as the extended key usage extension, decode into several values.
+func DecodeExtension(value []byte, encoding ExtensionEncoding) ([]string, error) {
+	unmarshal := func(out any) error {
+		rest, err := asn1.Unmarshal(value, out)
+		if err != nil {
+			return err
+		}
+		if len(rest) != 0 {
+			return fmt.Errorf("unmarshalling %s extension had rest", encoding)
+		}
+		return nil
+	}
+
+	switch encoding {
+	case EncodingString, "":
+		var decoded string
+		if err := unmarshal(&decoded); err != nil {
+			return nil, err
+		}
+		return []string{decoded}, nil
+	case EncodingOctetString:
+		var decoded []byte
+		if err := unmarshal(&decoded); err != nil {
+			return nil, err
+		}
+		return []string{string(decoded)}, nil
+	case EncodingInteger:
+		decoded := new(big.Int)
+		if err := unmarshal(&decoded); err != nil {
+			return nil, err
+		}
+		return []string{decoded.String()}, nil
+	case EncodingBoolean:
+		var decoded bool
+		if err := unmarshal(&decoded); err != nil {
+			return nil, err
+		}
+		return []string{strconv.FormatBool(decoded)}, nil
+	case EncodingRaw:
+		return []string{hex.EncodeToString(value)}, nil
+	case EncodingRawString:
+		return []string{string(value)}, nil
+	case EncodingExtKeyUsage:
+		var decoded []asn1.ObjectIdentifier
+		if err := unmarshal(&decoded); err != nil {
+			return nil, err
+		}
+		usages := make([]string, 0, len(decoded))
+		for _, oid := range decoded {
+			if name, ok := extKeyUsageNames[oid.String()]; ok {
+				usages = append(usages, name)
+			} else {
+				usages = append(usages, oid.String())
+			}
+		}
+		return usages, nil
+	case EncodingKeyUsage:
+		var decoded asn1.BitString
+		if err := unmarshal(&decoded); err != nil {
+			return nil, err
+		}
+		usages := []string{}
+		for i, name := range keyUsageNames {
+			if decoded.At(i) != 0 {
+				usages = append(usages, name)
+			}
+		}
+		return usages, nil
+	case EncodingCertificatePolicies:
+		var decoded []policyInformation
+		if err := unmarshal(&decoded); err != nil {
+			return nil, err
+		}
+		policies := make([]string, 0, len(decoded))
+		for _, policy := range decoded {
+			policies = append(policies, policy.Policy.String())
+		}
+		return policies, nil
+	}
+	return nil, fmt.Errorf("unknown extension encoding %s", encoding)
+}
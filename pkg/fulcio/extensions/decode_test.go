@@ -0,0 +1,193 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensions
+
+import (
+	"encoding/asn1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustMarshal(t *testing.T, value any, params string) []byte {
+	t.Helper()
+	der, err := asn1.MarshalWithParams(value, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestDecodeExtension(t *testing.T) {
+	testCases := map[string]struct {
+		value    []byte
+		encoding ExtensionEncoding
+		expected []string
+		err      string
+	}{
+		"UTF8String": {
+			value:    mustMarshal(t, "https://github.com/owner", "utf8"),
+			encoding: EncodingString,
+			expected: []string{"https://github.com/owner"},
+		},
+		"IA5String": {
+			value:    mustMarshal(t, "https://github.com/owner", "ia5"),
+			encoding: EncodingString,
+			expected: []string{"https://github.com/owner"},
+		},
+		"PrintableString": {
+			value:    mustMarshal(t, "owner", "printable"),
+			encoding: EncodingString,
+			expected: []string{"owner"},
+		},
+		"OCTET STRING as string": {
+			value:    mustMarshal(t, []byte("owner"), ""),
+			encoding: EncodingString,
+			err:      "tags don't match",
+		},
+		"OCTET STRING": {
+			value:    mustMarshal(t, []byte("owner"), ""),
+			encoding: EncodingOctetString,
+			expected: []string{"owner"},
+		},
+		"integer": {
+			value:    mustMarshal(t, 1234567, ""),
+			encoding: EncodingInteger,
+			expected: []string{"1234567"},
+		},
+		"boolean": {
+			value:    mustMarshal(t, true, ""),
+			encoding: EncodingBoolean,
+			expected: []string{"true"},
+		},
+		"raw": {
+			value:    mustMarshal(t, true, ""),
+			encoding: EncodingRaw,
+			expected: []string{"0101ff"},
+		},
+		"raw string": {
+			value:    []byte("https://token.actions.githubusercontent.com"),
+			encoding: EncodingRawString,
+			expected: []string{"https://token.actions.githubusercontent.com"},
+		},
+		"extended key usage": {
+			value:    mustMarshal(t, []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 3}, {1, 2, 3, 4}}, ""),
+			encoding: EncodingExtKeyUsage,
+			expected: []string{"codeSigning", "1.2.3.4"},
+		},
+		"key usage": {
+			// digitalSignature and keyCertSign
+			value:    mustMarshal(t, asn1.BitString{Bytes: []byte{0x84}, BitLength: 6}, ""),
+			encoding: EncodingKeyUsage,
+			expected: []string{"digitalSignature", "keyCertSign"},
+		},
+		"certificate policies": {
+			value: mustMarshal(t, []policyInformation{
+				{Policy: asn1.ObjectIdentifier{2, 23, 140, 1, 2, 1}},
+				{Policy: asn1.ObjectIdentifier{1, 2, 3}, Qualifiers: asn1.RawValue{FullBytes: mustMarshal(t, []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 2, 1}}, "")}},
+			}, ""),
+			encoding: EncodingCertificatePolicies,
+			expected: []string{"2.23.140.1.2.1", "1.2.3"},
+		},
+		"trailing data": {
+			value:    append(mustMarshal(t, "owner", "utf8"), 0x00),
+			encoding: EncodingString,
+			err:      "unmarshalling string extension had rest",
+		},
+		"unknown encoding": {
+			value:    []byte{},
+			encoding: "float",
+			err:      "unknown extension encoding float",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values, err := DecodeExtension(tc.value, tc.encoding)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, values)
+			}
+		})
+	}
+}
+
+func TestResolveExtensionEncoding(t *testing.T) {
+	testCases := map[string]struct {
+		encoding ExtensionEncoding
+		oid      asn1.ObjectIdentifier
+		expected ExtensionEncoding
+	}{
+		"default":                   {oid: OIDSourceRepositoryURI, expected: EncodingString},
+		"deprecated":                {oid: OIDIssuer, expected: EncodingRawString},
+		"extended key usage":        {oid: OIDExtKeyUsage, expected: EncodingExtKeyUsage},
+		"key usage":                 {oid: OIDKeyUsage, expected: EncodingKeyUsage},
+		"certificate policies":      {oid: OIDCertificatePolicies, expected: EncodingCertificatePolicies},
+		"configured":                {encoding: EncodingInteger, oid: asn1.ObjectIdentifier{1, 2, 3}, expected: EncodingInteger},
+		"configured for deprecated": {encoding: EncodingString, oid: OIDIssuer, expected: EncodingString},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			encoding, err := ResolveExtensionEncoding(tc.encoding, tc.oid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoding != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, encoding)
+			}
+		})
+	}
+	if _, err := ResolveExtensionEncoding("utf16", OIDIssuer); err == nil {
+		t.Errorf("expected error for unknown encoding")
+	}
+}
+
+func TestRenderOIDMatchersEncodings(t *testing.T) {
+	oidMatchers := OIDMatchers{
+		CustomExtensions: []CustomExtension{
+			{ObjectIdentifier: "1.2.3", ExtensionValues: ExactValues("42"), Encoding: EncodingInteger},
+			{ObjectIdentifier: "2.5.29.37", ExtensionValues: ExactValues("codeSigning")},
+		},
+	}
+	rendered, err := oidMatchers.RenderOIDMatchers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodings := map[string]ExtensionEncoding{}
+	for _, oidMatcher := range rendered {
+		encodings[oidMatcher.ObjectIdentifier.String()] = oidMatcher.Encoding
+	}
+	expected := map[string]ExtensionEncoding{"1.2.3": EncodingInteger, "2.5.29.37": ""}
+	if !reflect.DeepEqual(encodings, expected) {
+		t.Errorf("expected %v, got %v", expected, encodings)
+	}
+
+	oidMatchers.OIDExtensions = []OIDExtension{{ObjectIdentifier: asn1.ObjectIdentifier{1, 2, 3}, ExtensionValues: ExactValues("true"), Encoding: EncodingBoolean}}
+	if _, err := oidMatchers.RenderOIDMatchers(); err == nil || !strings.Contains(err.Error(), "conflicting encodings boolean and integer for OID extension 1.2.3") {
+		t.Errorf("expected conflicting encodings error, got %v", err)
+	}
+
+	invalid := OIDMatchers{CustomExtensions: []CustomExtension{{ObjectIdentifier: "1.2.3", ExtensionValues: ExactValues("42"), Encoding: "float"}}}
+	if err := invalid.Validate(); err == nil || !strings.Contains(err.Error(), "invalid encoding of 1.2.3: unknown extension encoding float") {
+		t.Errorf("expected invalid encoding error, got %v", err)
+	}
+}
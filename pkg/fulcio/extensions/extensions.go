@@ -25,6 +25,7 @@ import (
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"reflect"
 	"slices"
 	"strconv"
 	"strings"
@@ -97,92 +98,99 @@ func init() {
 	OIDSourceRepositoryVisibilityAtSigning = createFulcioOID([]int{1, 22})
 }
 
-// FulcioExtensions contains all custom X.509 extensions defined by Fulcio.
+// FulcioExtensions contains all custom X.509 extensions defined by Fulcio,
+// with the monitored values of each extension.
 type FulcioExtensions struct {
 	// The OIDC issuer. Should match `iss` claim of ID token or, in the case of
 	// a federated login like Dex it should match the issuer URL of the
 	// upstream issuer. The issuer is not set the extensions are invalid and
 	// will fail to render.
-	Issuer []string // OID 1.3.6.1.4.1.57264.1.8 and 1.3.6.1.4.1.57264.1.1 (Deprecated)
+	Issuer []ExtensionValue // OID 1.3.6.1.4.1.57264.1.8 and 1.3.6.1.4.1.57264.1.1 (Deprecated)
 
 	// Deprecated
 	// Triggering event of the Github Workflow. Matches the `event_name` claim of ID
 	// tokens from Github Actions
-	GithubWorkflowTrigger []string `json:"GithubWorkflowTrigger,omitempty" yaml:"github-workflow-trigger,omitempty"` // OID 1.3.6.1.4.1.57264.1.2
+	GithubWorkflowTrigger []ExtensionValue `json:"GithubWorkflowTrigger,omitempty" yaml:"github-workflow-trigger,omitempty"` // OID 1.3.6.1.4.1.57264.1.2
 
 	// Deprecated
 	// SHA of git commit being built in Github Actions. Matches the `sha` claim of ID
 	// tokens from Github Actions
-	GithubWorkflowSHA []string `json:"GithubWorkflowSHA,omitempty" yaml:"github-workflow-sha,omitempty"` // OID 1.3.6.1.4.1.57264.1.3
+	GithubWorkflowSHA []ExtensionValue `json:"GithubWorkflowSHA,omitempty" yaml:"github-workflow-sha,omitempty"` // OID 1.3.6.1.4.1.57264.1.3
 
 	// Deprecated
 	// Name of Github Actions Workflow. Matches the `workflow` claim of the ID
 	// tokens from Github Actions
-	GithubWorkflowName []string `json:"GithubWorkflowName,omitempty" yaml:"github-workflow-name,omitempty"` // OID 1.3.6.1.4.1.57264.1.4
+	GithubWorkflowName []ExtensionValue `json:"GithubWorkflowName,omitempty" yaml:"github-workflow-name,omitempty"` // OID 1.3.6.1.4.1.57264.1.4
 
 	// Deprecated
 	// Repository of the Github Actions Workflow. Matches the `repository` claim of the ID
 	// tokens from Github Actions
-	GithubWorkflowRepository []string `json:"GithubWorkflowRepository,omitempty" yaml:"github-workflow-repository,omitempty"` // OID 1.3.6.1.4.1.57264.1.5
+	GithubWorkflowRepository []ExtensionValue `json:"GithubWorkflowRepository,omitempty" yaml:"github-workflow-repository,omitempty"` // OID 1.3.6.1.4.1.57264.1.5
 
 	// Deprecated
 	// Git Ref of the Github Actions Workflow. Matches the `ref` claim of the ID tokens
 	// from Github Actions
-	GithubWorkflowRef []string `json:"GithubWorkflowRef,omitempty" yaml:"github-workflow-ref,omitempty"` // 1.3.6.1.4.1.57264.1.6
+	GithubWorkflowRef []ExtensionValue `json:"GithubWorkflowRef,omitempty" yaml:"github-workflow-ref,omitempty"` // 1.3.6.1.4.1.57264.1.6
 
 	// Reference to specific build instructions that are responsible for signing.
-	BuildSignerURI []string `json:"BuildSignerURI,omitempty" yaml:"build-signer-uri,omitempty"` // 1.3.6.1.4.1.57264.1.9
+	BuildSignerURI []ExtensionValue `json:"BuildSignerURI,omitempty" yaml:"build-signer-uri,omitempty"` // 1.3.6.1.4.1.57264.1.9
 
 	// Immutable reference to the specific version of the build instructions that is responsible for signing.
-	BuildSignerDigest []string `json:"BuildSignerDigest,omitempty" yaml:"build-signer-digest,omitempty"` // 1.3.6.1.4.1.57264.1.10
+	BuildSignerDigest []ExtensionValue `json:"BuildSignerDigest,omitempty" yaml:"build-signer-digest,omitempty"` // 1.3.6.1.4.1.57264.1.10
 
 	// Specifies whether the build took place in platform-hosted cloud infrastructure or customer/self-hosted infrastructure.
-	RunnerEnvironment []string `json:"RunnerEnvironment,omitempty" yaml:"runner-environment,omitempty"` // 1.3.6.1.4.1.57264.1.11
+	RunnerEnvironment []ExtensionValue `json:"RunnerEnvironment,omitempty" yaml:"runner-environment,omitempty"` // 1.3.6.1.4.1.57264.1.11
 
 	// Source repository URL that the build was based on.
-	SourceRepositoryURI []string `json:"SourceRepositoryURI,omitempty" yaml:"source-repository-uri,omitempty"` // 1.3.6.1.4.1.57264.1.12
+	SourceRepositoryURI []ExtensionValue `json:"SourceRepositoryURI,omitempty" yaml:"source-repository-uri,omitempty"` // 1.3.6.1.4.1.57264.1.12
 
 	// Immutable reference to a specific version of the source code that the build was based upon.
-	SourceRepositoryDigest []string `json:"SourceRepositoryDigest,omitempty" yaml:"source-repository-digest,omitempty"` // 1.3.6.1.4.1.57264.1.13
+	SourceRepositoryDigest []ExtensionValue `json:"SourceRepositoryDigest,omitempty" yaml:"source-repository-digest,omitempty"` // 1.3.6.1.4.1.57264.1.13
 
 	// Source Repository Ref that the build run was based upon.
-	SourceRepositoryRef []string `json:"SourceRepositoryRef,omitempty" yaml:"source-repository-ref,omitempty"` // 1.3.6.1.4.1.57264.1.14
+	SourceRepositoryRef []ExtensionValue `json:"SourceRepositoryRef,omitempty" yaml:"source-repository-ref,omitempty"` // 1.3.6.1.4.1.57264.1.14
 
 	// Immutable identifier for the source repository the workflow was based upon.
-	SourceRepositoryIdentifier []string `json:"SourceRepositoryIdentifier,omitempty" yaml:"source-repository-identifier,omitempty"` // 1.3.6.1.4.1.57264.1.15
+	SourceRepositoryIdentifier []ExtensionValue `json:"SourceRepositoryIdentifier,omitempty" yaml:"source-repository-identifier,omitempty"` // 1.3.6.1.4.1.57264.1.15
 
 	// Source repository owner URL of the owner of the source repository that the build was based on.
-	SourceRepositoryOwnerURI []string `json:"SourceRepositoryOwnerURI,omitempty" yaml:"source-repository-owner-uri,omitempty"` // 1.3.6.1.4.1.57264.1.16
+	SourceRepositoryOwnerURI []ExtensionValue `json:"SourceRepositoryOwnerURI,omitempty" yaml:"source-repository-owner-uri,omitempty"` // 1.3.6.1.4.1.57264.1.16
 
 	// Immutable identifier for the owner of the source repository that the workflow was based upon.
-	SourceRepositoryOwnerIdentifier []string `json:"SourceRepositoryOwnerIdentifier,omitempty" yaml:"source-repository-owner-identifier,omitempty"` // 1.3.6.1.4.1.57264.1.17
+	SourceRepositoryOwnerIdentifier []ExtensionValue `json:"SourceRepositoryOwnerIdentifier,omitempty" yaml:"source-repository-owner-identifier,omitempty"` // 1.3.6.1.4.1.57264.1.17
 
 	// Build Config URL to the top-level/initiating build instructions.
-	BuildConfigURI []string `json:"BuildConfigURI,omitempty" yaml:"build-config-uri,omitempty"` // 1.3.6.1.4.1.57264.1.18
+	BuildConfigURI []ExtensionValue `json:"BuildConfigURI,omitempty" yaml:"build-config-uri,omitempty"` // 1.3.6.1.4.1.57264.1.18
 
 	// Immutable reference to the specific version of the top-level/initiating build instructions.
-	BuildConfigDigest []string `json:"BuildConfigDigest,omitempty" yaml:"build-config-digest,omitempty"` // 1.3.6.1.4.1.57264.1.19
+	BuildConfigDigest []ExtensionValue `json:"BuildConfigDigest,omitempty" yaml:"build-config-digest,omitempty"` // 1.3.6.1.4.1.57264.1.19
 
 	// Event or action that initiated the build.
-	BuildTrigger []string `json:"BuildTrigger,omitempty" yaml:"build-trigger,omitempty"` // 1.3.6.1.4.1.57264.1.20
+	BuildTrigger []ExtensionValue `json:"BuildTrigger,omitempty" yaml:"build-trigger,omitempty"` // 1.3.6.1.4.1.57264.1.20
 
 	// Run Invocation URL to uniquely identify the build execution.
-	RunInvocationURI []string `json:"RunInvocationURI,omitempty" yaml:"run-invocation-uri,omitempty"` // 1.3.6.1.4.1.57264.1.21
+	RunInvocationURI []ExtensionValue `json:"RunInvocationURI,omitempty" yaml:"run-invocation-uri,omitempty"` // 1.3.6.1.4.1.57264.1.21
 
 	// Source repository visibility at the time of signing the certificate.
-	SourceRepositoryVisibilityAtSigning []string `json:"SourceRepositoryVisibilityAtSigning,omitempty" yaml:"source-repository-visibility-at-signing,omitempty"` // 1.3.6.1.4.1.57264.1.22
+	SourceRepositoryVisibilityAtSigning []ExtensionValue `json:"SourceRepositoryVisibilityAtSigning,omitempty" yaml:"source-repository-visibility-at-signing,omitempty"` // 1.3.6.1.4.1.57264.1.22
 }
 
 // OIDExtension holds an OID field and a list of values to match on
 type OIDExtension struct {
 	ObjectIdentifier asn1.ObjectIdentifier `yaml:"objectIdentifier"`
-	ExtensionValues  []string              `yaml:"extensionValues"`
+	ExtensionValues  []ExtensionValue      `yaml:"extensionValues"`
+	// Encoding is how the extension is decoded, the default encoding of the
+	// extension if empty
+	Encoding ExtensionEncoding `yaml:"encoding"`
 }
 
 // CustomExtension holds an OID field represented in dot notation and a list of values to match on
 type CustomExtension struct {
-	ObjectIdentifier string   `yaml:"objectIdentifier"`
-	ExtensionValues  []string `yaml:"extensionValues"`
+	ObjectIdentifier string           `yaml:"objectIdentifier"`
+	ExtensionValues  []ExtensionValue `yaml:"extensionValues"`
+	// Encoding is how the extension is decoded, the default encoding of the
+	// extension if empty
+	Encoding ExtensionEncoding `yaml:"encoding"`
 }
 
 // OIDMatchers holds all FulcioExtensions, OIDMatchers, and CustomExtensions
@@ -336,28 +344,46 @@ func (e FulcioExtensions) RenderFulcioOIDMatchers() []OIDExtension {
 func (oidMatchers OIDMatchers) RenderOIDMatchers() ([]OIDExtension, error) {
 	fulcioOIDMatchers := oidMatchers.FulcioExtensions.RenderFulcioOIDMatchers()
 	// map of all OID extensions to all associated matching extension values
-	oidMap := make(map[string]map[string]bool)
+	oidMap := make(map[string]map[ExtensionValue]bool)
+	// map of OID extensions to their configured encoding
+	encodings := make(map[string]ExtensionEncoding)
+	setEncoding := func(oid string, encoding ExtensionEncoding) error {
+		if encoding == "" {
+			return nil
+		}
+		if existing, ok := encodings[oid]; ok && existing != encoding {
+			return fmt.Errorf("conflicting encodings %s and %s for OID extension %s", existing, encoding, oid)
+		}
+		encodings[oid] = encoding
+		return nil
+	}
 	// dedup OID extensions and associated values through one mapping
 	for _, oidMatcher := range oidMatchers.OIDExtensions {
 		oidMatcherString := oidMatcher.ObjectIdentifier.String()
-		oidMap[oidMatcherString] = make(map[string]bool)
+		oidMap[oidMatcherString] = make(map[ExtensionValue]bool)
 		for _, extValue := range oidMatcher.ExtensionValues {
 			oidMap[oidMatcherString][extValue] = true
 		}
+		if err := setEncoding(oidMatcherString, oidMatcher.Encoding); err != nil {
+			return nil, err
+		}
 	}
 	for _, oidMatcher := range fulcioOIDMatchers {
 		oidMatcherString := oidMatcher.ObjectIdentifier.String()
-		oidMap[oidMatcherString] = make(map[string]bool)
+		oidMap[oidMatcherString] = make(map[ExtensionValue]bool)
 		for _, extValue := range oidMatcher.ExtensionValues {
 			oidMap[oidMatcherString][extValue] = true
 		}
 	}
 	for _, customOID := range oidMatchers.CustomExtensions {
 		customOIDString := customOID.ObjectIdentifier
-		oidMap[customOIDString] = make(map[string]bool)
+		oidMap[customOIDString] = make(map[ExtensionValue]bool)
 		for _, extValue := range customOID.ExtensionValues {
 			oidMap[customOIDString][extValue] = true
 		}
+		if err := setEncoding(customOIDString, customOID.Encoding); err != nil {
+			return nil, err
+		}
 	}
 
 	// convert map into list of OIDMatchers
@@ -367,13 +393,14 @@ func (oidMatchers OIDMatchers) RenderOIDMatchers() ([]OIDExtension, error) {
 		if err != nil {
 			return nil, fmt.Errorf("error parsing OID from extension: %w", err)
 		}
-		var extValues []string
+		var extValues []ExtensionValue
 		for extValue := range extValueMap {
 			extValues = append(extValues, extValue)
 		}
 		allMatchers = append(allMatchers, OIDExtension{
 			ObjectIdentifier: parsedOID,
 			ExtensionValues:  extValues,
+			Encoding:         encodings[oidExtension],
 		})
 	}
 
@@ -400,3 +427,98 @@ func ParseObjectIdentifier(oid string) (asn1.ObjectIdentifier, error) {
 	}
 	return asn1.ObjectIdentifier(objectIdentifier), nil
 }
+
+// fulcioExtensionOIDs returns the OIDs of the Fulcio extensions, keyed by
+// their field names in FulcioExtensions
+func fulcioExtensionOIDs() map[string]asn1.ObjectIdentifier {
+	return map[string]asn1.ObjectIdentifier{
+		"Issuer":                              OIDIssuerV2,
+		"GithubWorkflowTrigger":               OIDGitHubWorkflowTrigger,
+		"GithubWorkflowSHA":                   OIDGitHubWorkflowSHA,
+		"GithubWorkflowName":                  OIDGitHubWorkflowName,
+		"GithubWorkflowRepository":            OIDGitHubWorkflowRepository,
+		"GithubWorkflowRef":                   OIDGitHubWorkflowRef,
+		"BuildSignerURI":                      OIDBuildSignerURI,
+		"BuildSignerDigest":                   OIDBuildSignerDigest,
+		"RunnerEnvironment":                   OIDRunnerEnvironment,
+		"SourceRepositoryURI":                 OIDSourceRepositoryURI,
+		"SourceRepositoryDigest":              OIDSourceRepositoryDigest,
+		"SourceRepositoryRef":                 OIDSourceRepositoryRef,
+		"SourceRepositoryIdentifier":          OIDSourceRepositoryIdentifier,
+		"SourceRepositoryOwnerURI":            OIDSourceRepositoryOwnerURI,
+		"SourceRepositoryOwnerIdentifier":     OIDSourceRepositoryOwnerIdentifier,
+		"BuildConfigURI":                      OIDBuildConfigURI,
+		"BuildConfigDigest":                   OIDBuildConfigDigest,
+		"BuildTrigger":                        OIDBuildTrigger,
+		"RunInvocationURI":                    OIDRunInvocationURI,
+		"SourceRepositoryVisibilityAtSigning": OIDSourceRepositoryVisibilityAtSigning,
+	}
+}
+
+// ParseExtension parses the name of a Fulcio extension, as named in
+// FulcioExtensions, e.g. SourceRepositoryURI, or an ObjectIdentifier in dot notation
+func ParseExtension(extension string) (asn1.ObjectIdentifier, error) {
+	if oid, ok := fulcioExtensionOIDs()[extension]; ok {
+		return oid, nil
+	}
+	oid, err := ParseObjectIdentifier(extension)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extension %s: must be a Fulcio extension name or an object identifier: %w", extension, err)
+	}
+	return oid, nil
+}
+
+// CertificateExtensions holds the values of the Fulcio extensions of a
+// certificate, named as the fields of FulcioExtensions
+type CertificateExtensions struct {
+	Issuer                              []string
+	GithubWorkflowTrigger               []string
+	GithubWorkflowSHA                   []string
+	GithubWorkflowName                  []string
+	GithubWorkflowRepository            []string
+	GithubWorkflowRef                   []string
+	BuildSignerURI                      []string
+	BuildSignerDigest                   []string
+	RunnerEnvironment                   []string
+	SourceRepositoryURI                 []string
+	SourceRepositoryDigest              []string
+	SourceRepositoryRef                 []string
+	SourceRepositoryIdentifier          []string
+	SourceRepositoryOwnerURI            []string
+	SourceRepositoryOwnerIdentifier     []string
+	BuildConfigURI                      []string
+	BuildConfigDigest                   []string
+	BuildTrigger                        []string
+	RunInvocationURI                    []string
+	SourceRepositoryVisibilityAtSigning []string
+}
+
+// NewCertificateExtensions returns the CertificateExtensions with the values
+// returned by value for the OID of each extension, e.g. the extension values
+// of a certificate. Extensions without a value are left empty.
+func NewCertificateExtensions(value func(oid asn1.ObjectIdentifier) (string, error)) (CertificateExtensions, error) {
+	var certExtensions CertificateExtensions
+	fields := reflect.ValueOf(&certExtensions).Elem()
+	for name, oid := range fulcioExtensionOIDs() {
+		extensionValue, err := value(oid)
+		if err != nil {
+			return CertificateExtensions{}, fmt.Errorf("error getting value of extension %s: %w", name, err)
+		}
+		if extensionValue != "" {
+			fields.FieldByName(name).Set(reflect.ValueOf([]string{extensionValue}))
+		}
+	}
+	return certExtensions, nil
+}
+
+// IsDeprecatedExtension returns whether the OID is one of the deprecated Fulcio
+// extensions, whose values are raw strings instead of ASN.1-encoded strings
+func IsDeprecatedExtension(oid asn1.ObjectIdentifier) bool {
+	for _, deprecated := range []asn1.ObjectIdentifier{OIDIssuer, OIDGitHubWorkflowTrigger, OIDGitHubWorkflowSHA,
+		OIDGitHubWorkflowName, OIDGitHubWorkflowRepository, OIDGitHubWorkflowRef} {
+		if oid.Equal(deprecated) {
+			return true
+		}
+	}
+	return false
+}
@@ -17,6 +17,7 @@ package extensions
 import (
 	"encoding/asn1"
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -30,7 +31,7 @@ func TestRenderOIDMatchers(t *testing.T) {
 			inputOIDMatchers: OIDMatchers{
 				OIDExtensions: []OIDExtension{{
 					ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
-					ExtensionValues:  []string{},
+					ExtensionValues:  []ExtensionValue{},
 				}},
 			},
 			expectedLen: 1,
@@ -39,7 +40,7 @@ func TestRenderOIDMatchers(t *testing.T) {
 			inputOIDMatchers: OIDMatchers{
 				OIDExtensions: []OIDExtension{{
 					ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
-					ExtensionValues:  []string{"test", "test2"},
+					ExtensionValues:  ExactValues("test", "test2"),
 				}},
 			},
 			expectedLen: 1,
@@ -48,10 +49,10 @@ func TestRenderOIDMatchers(t *testing.T) {
 			inputOIDMatchers: OIDMatchers{
 				OIDExtensions: []OIDExtension{{
 					ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
-					ExtensionValues:  []string{"test1"},
+					ExtensionValues:  ExactValues("test1"),
 				}, {
 					ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
-					ExtensionValues:  []string{"test"},
+					ExtensionValues:  ExactValues("test"),
 				}},
 			},
 			expectedLen: 1,
@@ -60,17 +61,17 @@ func TestRenderOIDMatchers(t *testing.T) {
 			inputOIDMatchers: OIDMatchers{
 				OIDExtensions: []OIDExtension{{
 					ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
-					ExtensionValues:  []string{"test1"},
+					ExtensionValues:  ExactValues("test1"),
 				}, {
 					ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 18},
-					ExtensionValues:  []string{"test"},
+					ExtensionValues:  ExactValues("test"),
 				}},
 				FulcioExtensions: FulcioExtensions{
-					BuildConfigDigest: []string{"test"},
+					BuildConfigDigest: ExactValues("test"),
 				},
 				CustomExtensions: []CustomExtension{{
 					ObjectIdentifier: "2.5.29.16",
-					ExtensionValues:  []string{"test"},
+					ExtensionValues:  ExactValues("test"),
 				}},
 			},
 			expectedLen: 4,
@@ -152,8 +153,8 @@ func TestParseObjectIdentifier(t *testing.T) {
 func TestRenderFulcioOIDMatchers(t *testing.T) {
 	extValueString := "test cert value"
 	fulcioExtensions := FulcioExtensions{
-		BuildSignerURI: []string{extValueString},
-		BuildConfigURI: []string{"1", "2", "3", "4", "5", "6"},
+		BuildSignerURI: ExactValues(extValueString),
+		BuildConfigURI: ExactValues("1", "2", "3", "4", "5", "6"),
 	}
 
 	renderedFulcioOIDMatchers := fulcioExtensions.RenderFulcioOIDMatchers()
@@ -172,7 +173,7 @@ func TestRenderFulcioOIDMatchers(t *testing.T) {
 		t.Errorf("expected BuildSignerURI extension values to have length 1, received %d", len(buildSignerURIMatcherExtValues))
 	}
 	buildSignerURIMatcherExtValue := buildSignerURIMatcherExtValues[0]
-	if buildSignerURIMatcherExtValue != extValueString {
+	if buildSignerURIMatcherExtValue.Value != extValueString {
 		t.Errorf("expected BuildSignerURI extension value to be 'test cert value', received %s", buildSignerURIMatcherExtValue)
 	}
 
@@ -191,26 +192,26 @@ func TestRenderFulcioOIDMatchers(t *testing.T) {
 func TestRenderFulcioOIDMatchersAllFields(t *testing.T) {
 	testValueString := "test"
 	fulcioExtensions := FulcioExtensions{
-		Issuer:                              []string{testValueString},
-		GithubWorkflowTrigger:               []string{testValueString},
-		GithubWorkflowSHA:                   []string{testValueString},
-		GithubWorkflowName:                  []string{testValueString},
-		GithubWorkflowRepository:            []string{testValueString},
-		GithubWorkflowRef:                   []string{testValueString},
-		BuildSignerURI:                      []string{testValueString},
-		BuildConfigURI:                      []string{testValueString},
-		BuildSignerDigest:                   []string{testValueString},
-		RunnerEnvironment:                   []string{testValueString},
-		SourceRepositoryURI:                 []string{testValueString},
-		SourceRepositoryDigest:              []string{testValueString},
-		SourceRepositoryIdentifier:          []string{testValueString},
-		SourceRepositoryRef:                 []string{testValueString},
-		SourceRepositoryOwnerURI:            []string{testValueString},
-		SourceRepositoryOwnerIdentifier:     []string{testValueString},
-		SourceRepositoryVisibilityAtSigning: []string{testValueString},
-		BuildConfigDigest:                   []string{testValueString},
-		BuildTrigger:                        []string{testValueString},
-		RunInvocationURI:                    []string{testValueString},
+		Issuer:                              ExactValues(testValueString),
+		GithubWorkflowTrigger:               ExactValues(testValueString),
+		GithubWorkflowSHA:                   ExactValues(testValueString),
+		GithubWorkflowName:                  ExactValues(testValueString),
+		GithubWorkflowRepository:            ExactValues(testValueString),
+		GithubWorkflowRef:                   ExactValues(testValueString),
+		BuildSignerURI:                      ExactValues(testValueString),
+		BuildConfigURI:                      ExactValues(testValueString),
+		BuildSignerDigest:                   ExactValues(testValueString),
+		RunnerEnvironment:                   ExactValues(testValueString),
+		SourceRepositoryURI:                 ExactValues(testValueString),
+		SourceRepositoryDigest:              ExactValues(testValueString),
+		SourceRepositoryIdentifier:          ExactValues(testValueString),
+		SourceRepositoryRef:                 ExactValues(testValueString),
+		SourceRepositoryOwnerURI:            ExactValues(testValueString),
+		SourceRepositoryOwnerIdentifier:     ExactValues(testValueString),
+		SourceRepositoryVisibilityAtSigning: ExactValues(testValueString),
+		BuildConfigDigest:                   ExactValues(testValueString),
+		BuildTrigger:                        ExactValues(testValueString),
+		RunInvocationURI:                    ExactValues(testValueString),
 	}
 
 	renderedFulcioOIDMatchers := fulcioExtensions.RenderFulcioOIDMatchers()
@@ -219,3 +220,78 @@ func TestRenderFulcioOIDMatchersAllFields(t *testing.T) {
 		t.Errorf("expected OIDMatchers to have length 21, received length %d", len(renderedFulcioOIDMatchers))
 	}
 }
+
+func TestParseExtension(t *testing.T) {
+	testCases := map[string]struct {
+		extension   string
+		expectedOID asn1.ObjectIdentifier
+		expectedErr bool
+	}{
+		"fulcio extension name": {
+			extension:   "SourceRepositoryOwnerURI",
+			expectedOID: OIDSourceRepositoryOwnerURI,
+		},
+		"issuer": {
+			extension:   "Issuer",
+			expectedOID: OIDIssuerV2,
+		},
+		"dot notation": {
+			extension:   "1.3.6.1.4.1.57264.1.16",
+			expectedOID: OIDSourceRepositoryOwnerURI,
+		},
+		"unknown name": {
+			extension:   "SourceRepositoryOwner",
+			expectedErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			oid, err := ParseExtension(tc.extension)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, received %v", tc.expectedErr, err)
+			}
+			if !oid.Equal(tc.expectedOID) {
+				t.Errorf("expected %v, received %v", tc.expectedOID, oid)
+			}
+		})
+	}
+}
+
+func TestNewCertificateExtensions(t *testing.T) {
+	values := map[string]string{
+		OIDIssuerV2.String():                 "https://token.actions.githubusercontent.com",
+		OIDSourceRepositoryOwnerURI.String(): "https://github.com/sigstore",
+	}
+	certExtensions, err := NewCertificateExtensions(func(oid asn1.ObjectIdentifier) (string, error) {
+		return values[oid.String()], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := CertificateExtensions{
+		Issuer:                   []string{"https://token.actions.githubusercontent.com"},
+		SourceRepositoryOwnerURI: []string{"https://github.com/sigstore"},
+	}
+	if !reflect.DeepEqual(certExtensions, expected) {
+		t.Errorf("expected %v, received %v", expected, certExtensions)
+	}
+
+	if _, err := NewCertificateExtensions(func(asn1.ObjectIdentifier) (string, error) {
+		return "", errors.New("malformed extension")
+	}); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestCertificateExtensionsFields(t *testing.T) {
+	fulcioFields := reflect.TypeFor[FulcioExtensions]()
+	certFields := reflect.TypeFor[CertificateExtensions]()
+	if fulcioFields.NumField() != certFields.NumField() {
+		t.Fatalf("expected %d fields, got %d", fulcioFields.NumField(), certFields.NumField())
+	}
+	for i := range fulcioFields.NumField() {
+		if name := fulcioFields.Field(i).Name; certFields.Field(i).Name != name {
+			t.Errorf("expected field %s, got %s", name, certFields.Field(i).Name)
+		}
+	}
+}
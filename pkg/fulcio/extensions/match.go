@@ -0,0 +1,196 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensions
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MatchMode is how a monitored extension value is compared with the extension
+// values of certificates
+type MatchMode string
+
+const (
+	// MatchModeExact matches values equal to the pattern
+	MatchModeExact MatchMode = "exact"
+	// MatchModePrefix matches values starting with the pattern
+	MatchModePrefix MatchMode = "prefix"
+	// MatchModeGlob matches values against a glob, where * matches any
+	// characters except /, ** matches any characters and ? matches a single
+	// character except /
+	MatchModeGlob MatchMode = "glob"
+	// MatchModeRegex matches values against a regular expression
+	MatchModeRegex MatchMode = "regex"
+)
+
+var matchModes = []MatchMode{MatchModeExact, MatchModePrefix, MatchModeGlob, MatchModeRegex}
+
+// ExtensionValue is a monitored extension value. It is configured either as a
+// string, matched exactly, or as a mapping with the value and how it is matched:
+//
+//	extensionValues:
+//	  - https://github.com/owner
+//	  - value: refs/tags/v*
+//	    matchMode: glob
+//	  - value: https://github.com/owner/
+//	    matchMode: prefix
+//	    negate: true
+type ExtensionValue struct {
+	// Value is the monitored value, reported as matched identity
+	Value string `yaml:"value"`
+	// MatchMode is how Value is matched, defaults to exact
+	MatchMode MatchMode `yaml:"matchMode"`
+	// Negate matches certificates having the extension with any value that
+	// Value doesn't match
+	Negate bool `yaml:"negate"`
+}
+
+// ExactValues returns monitored extension values matching each value exactly
+func ExactValues(values ...string) []ExtensionValue {
+	extValues := make([]ExtensionValue, 0, len(values))
+	for _, value := range values {
+		extValues = append(extValues, ExtensionValue{Value: value})
+	}
+	return extValues
+}
+
+// UnmarshalYAML decodes a monitored extension value from a string, matched
+// exactly, or from a mapping
+func (v *ExtensionValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err == nil {
+		*v = ExtensionValue{Value: value}
+		return nil
+	}
+	type plain ExtensionValue
+	return unmarshal((*plain)(v))
+}
+
+// String returns the monitored value
+func (v ExtensionValue) String() string {
+	return v.Value
+}
+
+// Matcher returns the matcher of the monitored value
+func (v ExtensionValue) Matcher() (ValueMatcher, error) {
+	mode := v.MatchMode
+	if mode == "" {
+		mode = MatchModeExact
+	}
+	return NewValueMatcher(mode, v.Value, v.Negate)
+}
+
+// ValueMatcher matches extension values of certificates against a pattern. A
+// negated matcher matches the values the pattern doesn't match.
+type ValueMatcher struct {
+	Mode    MatchMode
+	Pattern string
+	Negate  bool
+
+	regex *regexp.Regexp
+}
+
+// NewValueMatcher returns a matcher of extension values against a pattern in
+// the given mode, matching the values the pattern doesn't match if negate is set
+func NewValueMatcher(mode MatchMode, pattern string, negate bool) (ValueMatcher, error) {
+	if !slices.Contains(matchModes, mode) {
+		return ValueMatcher{}, fmt.Errorf("invalid match mode %s: must be one of exact, prefix, glob or regex", mode)
+	}
+	if pattern == "" {
+		return ValueMatcher{}, errors.New("empty pattern")
+	}
+	matcher := ValueMatcher{Mode: mode, Pattern: pattern, Negate: negate}
+
+	var err error
+	switch matcher.Mode {
+	case MatchModeGlob:
+		matcher.regex, err = regexp.Compile(globToRegex(matcher.Pattern))
+	case MatchModeRegex:
+		matcher.regex, err = regexp.Compile(matcher.Pattern)
+	}
+	if err != nil {
+		return ValueMatcher{}, fmt.Errorf("invalid %s pattern %s: %w", matcher.Mode, matcher.Pattern, err)
+	}
+	return matcher, nil
+}
+
+// globToRegex converts a glob into an anchored regular expression
+func globToRegex(glob string) string {
+	var regex strings.Builder
+	regex.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			regex.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			regex.WriteString("[^/]*")
+		case glob[i] == '?':
+			regex.WriteString("[^/]")
+		default:
+			regex.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	regex.WriteString("$")
+	return regex.String()
+}
+
+// Matches returns whether an extension value matches
+func (m ValueMatcher) Matches(value string) bool {
+	var matches bool
+	switch m.Mode {
+	case MatchModePrefix:
+		matches = strings.HasPrefix(value, m.Pattern)
+	case MatchModeGlob, MatchModeRegex:
+		matches = m.regex.MatchString(value)
+	default:
+		matches = value == m.Pattern
+	}
+	return matches != m.Negate
+}
+
+// MatchesAny returns whether the values of an extension with several values
+// match, such as the key purposes of the extended key usage extension. The
+// matcher matches if any value matches the pattern, and a negated matcher if
+// no value matches the pattern.
+func (m ValueMatcher) MatchesAny(values []string) bool {
+	negated := m
+	negated.Negate = false
+	return slices.ContainsFunc(values, negated.Matches) != m.Negate
+}
+
+// Validate checks that monitored OIDs and their encodings are valid, and that
+// monitored extension values are valid matchers
+func (oidMatchers OIDMatchers) Validate() error {
+	allMatchers, err := oidMatchers.RenderOIDMatchers()
+	if err != nil {
+		return err
+	}
+	for _, oidMatcher := range allMatchers {
+		if _, err := ResolveExtensionEncoding(oidMatcher.Encoding, oidMatcher.ObjectIdentifier); err != nil {
+			return fmt.Errorf("invalid encoding of %s: %w", oidMatcher.ObjectIdentifier, err)
+		}
+		for _, extValue := range oidMatcher.ExtensionValues {
+			if _, err := extValue.Matcher(); err != nil {
+				return fmt.Errorf("invalid extension value %s of %s: %w", extValue, oidMatcher.ObjectIdentifier, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestValueMatcherMatches(t *testing.T) {
+	testCases := map[string]struct {
+		value      ExtensionValue
+		matches    []string
+		nonMatches []string
+	}{
+		"exact": {
+			value:      ExtensionValue{Value: "https://github.com/corp/app"},
+			matches:    []string{"https://github.com/corp/app"},
+			nonMatches: []string{"https://github.com/corp/app2", "https://github.com/corp"},
+		},
+		"exact with mode name": {
+			value:      ExtensionValue{Value: "glob:*"},
+			matches:    []string{"glob:*"},
+			nonMatches: []string{"anything"},
+		},
+		"prefix": {
+			value:      ExtensionValue{Value: "https://github.com/corp/", MatchMode: MatchModePrefix},
+			matches:    []string{"https://github.com/corp/app", "https://github.com/corp/"},
+			nonMatches: []string{"https://github.com/corporate/app"},
+		},
+		"glob": {
+			value:      ExtensionValue{Value: "refs/tags/v*", MatchMode: MatchModeGlob},
+			matches:    []string{"refs/tags/v1.0.0", "refs/tags/v"},
+			nonMatches: []string{"refs/heads/main", "refs/tags/v1/nested", "xrefs/tags/v1"},
+		},
+		"glob across path segments": {
+			value:      ExtensionValue{Value: "https://github.com/corp/**@refs/tags/v?.*", MatchMode: MatchModeGlob},
+			matches:    []string{"https://github.com/corp/app/.github/workflows/release.yml@refs/tags/v1.2"},
+			nonMatches: []string{"https://github.com/other/app/.github/workflows/release.yml@refs/tags/v1.2"},
+		},
+		"regex": {
+			value:      ExtensionValue{Value: `^https://github\.com/corp/[^/]+$`, MatchMode: MatchModeRegex},
+			matches:    []string{"https://github.com/corp/app"},
+			nonMatches: []string{"https://github.com/corp/app/sub", "https://github.com/other/app"},
+		},
+		"negated prefix": {
+			value:      ExtensionValue{Value: "https://github.com/corp/", MatchMode: MatchModePrefix, Negate: true},
+			matches:    []string{"https://github.com/attacker/app"},
+			nonMatches: []string{"https://github.com/corp/app"},
+		},
+		"negated exact": {
+			value:      ExtensionValue{Value: "github-hosted", Negate: true},
+			matches:    []string{"self-hosted"},
+			nonMatches: []string{"github-hosted"},
+		},
+		"leading exclamation mark": {
+			value:      ExtensionValue{Value: "!github-hosted"},
+			matches:    []string{"!github-hosted"},
+			nonMatches: []string{"self-hosted"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			matcher, err := tc.value.Matcher()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range tc.matches {
+				if !matcher.Matches(value) {
+					t.Errorf("expected %+v to match %s", tc.value, value)
+				}
+			}
+			for _, value := range tc.nonMatches {
+				if matcher.Matches(value) {
+					t.Errorf("expected %+v not to match %s", tc.value, value)
+				}
+			}
+		})
+	}
+}
+
+func TestValueMatcherMatchesAny(t *testing.T) {
+	usages := []string{"codeSigning", "serverAuth"}
+	for _, tc := range []struct {
+		value    ExtensionValue
+		expected bool
+	}{
+		{value: ExtensionValue{Value: "codeSigning"}, expected: true},
+		{value: ExtensionValue{Value: "codeSigning", Negate: true}, expected: false},
+		{value: ExtensionValue{Value: "emailProtection", Negate: true}, expected: true},
+		{value: ExtensionValue{Value: "*Auth", MatchMode: MatchModeGlob}, expected: true},
+		{value: ExtensionValue{Value: "clientAuth"}, expected: false},
+	} {
+		matcher, err := tc.value.Matcher()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matcher.MatchesAny(usages) != tc.expected {
+			t.Errorf("expected %+v matching %v to be %v", tc.value, usages, tc.expected)
+		}
+	}
+}
+
+func TestExtensionValueMatcherErrors(t *testing.T) {
+	for errMsg, value := range map[string]ExtensionValue{
+		"invalid regex pattern (": {Value: "(", MatchMode: MatchModeRegex},
+		"empty pattern":           {MatchMode: MatchModePrefix},
+		"invalid match mode glb":  {Value: "refs/tags/v*", MatchMode: "glb"},
+	} {
+		if _, err := value.Matcher(); err == nil || !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("expected error containing %q for %+v, got %v", errMsg, value, err)
+		}
+	}
+}
+
+func TestExtensionValueUnmarshalYAML(t *testing.T) {
+	var oidMatchers OIDMatchers
+	err := yaml.Unmarshal([]byte(`
+fulcioExtensions:
+  source-repository-ref:
+    - "!refs/heads/main"
+    - glob:refs/tags/v*
+    - value: refs/tags/v*
+      matchMode: glob
+  source-repository-owner-uri:
+    - value: https://github.com/corp
+      negate: true
+customExtensions:
+  - objectIdentifier: 1.2.3
+    encoding: integer
+    extensionValues: [42]
+`), &oidMatchers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := OIDMatchers{
+		FulcioExtensions: FulcioExtensions{
+			// bare values are matched exactly
+			SourceRepositoryRef: []ExtensionValue{
+				{Value: "!refs/heads/main"},
+				{Value: "glob:refs/tags/v*"},
+				{Value: "refs/tags/v*", MatchMode: MatchModeGlob},
+			},
+			SourceRepositoryOwnerURI: []ExtensionValue{{Value: "https://github.com/corp", Negate: true}},
+		},
+		CustomExtensions: []CustomExtension{{ObjectIdentifier: "1.2.3", Encoding: EncodingInteger, ExtensionValues: ExactValues("42")}},
+	}
+	if !reflect.DeepEqual(oidMatchers, expected) {
+		t.Errorf("expected %+v, got %+v", expected, oidMatchers)
+	}
+}
+
+func TestOIDMatchersValidate(t *testing.T) {
+	valid := OIDMatchers{
+		FulcioExtensions: FulcioExtensions{SourceRepositoryRef: []ExtensionValue{{Value: "refs/tags/v*", MatchMode: MatchModeGlob}}},
+		CustomExtensions: []CustomExtension{{ObjectIdentifier: "2.5.29.17", ExtensionValues: []ExtensionValue{{Value: "https://github.com/corp/", MatchMode: MatchModePrefix, Negate: true}}}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	invalidValue := OIDMatchers{FulcioExtensions: FulcioExtensions{BuildSignerURI: []ExtensionValue{{Value: "(", MatchMode: MatchModeRegex}}}}
+	if err := invalidValue.Validate(); err == nil || !strings.Contains(err.Error(), "invalid extension value ( of 1.3.6.1.4.1.57264.1.9") {
+		t.Errorf("expected invalid extension value error, got %v", err)
+	}
+
+	invalidOID := OIDMatchers{CustomExtensions: []CustomExtension{{ObjectIdentifier: "2..5", ExtensionValues: ExactValues("value")}}}
+	if err := invalidOID.Validate(); err == nil {
+		t.Errorf("expected error for invalid OID")
+	}
+}
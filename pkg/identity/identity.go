@@ -21,13 +21,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"os"
 	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 
 	google_asn1 "github.com/google/certificate-transparency-go/asn1"
@@ -43,6 +46,11 @@ var (
 type CertificateIdentity struct {
 	CertSubject string   `yaml:"certSubject"`
 	Issuers     []string `yaml:"issuers"`
+	// Labels select notification routes for matches of this identity
+	Labels []string `yaml:"labels"`
+	// ExpectedProvenance is an optional allow-list of where the identity signs.
+	// When set, only matches violating the allow-list are reported.
+	ExpectedProvenance *ExpectedProvenance `yaml:"expectedProvenance"`
 }
 
 // MonitoredValues holds a set of values to compare against a given entry
@@ -63,6 +71,11 @@ type MonitoredValues struct {
 	// OIDMatchers represents a list of OID extension fields and associated values,
 	// which includes those constructed directly, those supported by Fulcio, and any constructed via dot notation.
 	OIDMatchers []extensions.OIDExtension `yaml:"oidMatchers"`
+	// Rules contains a list of named rules combining conditions on
+	// certificates, subjects and fingerprints
+	Rules []Rule `yaml:"rules"`
+	// Policies contains a list of named CEL expressions evaluated on each log entry
+	Policies []Policy `yaml:"policies"`
 }
 
 type MatchedIdentityType string
@@ -72,30 +85,61 @@ const (
 	MatchedIdentityTypeExtensionValue MatchedIdentityType = "extensionValue"
 	MatchedIdentityTypeFingerprint    MatchedIdentityType = "fingerprint"
 	MatchedIdentityTypeSubject        MatchedIdentityType = "subject"
+	MatchedIdentityTypeRule           MatchedIdentityType = "rule"
+	MatchedIdentityTypePolicy         MatchedIdentityType = "policy"
 )
 
-// LogEntry holds a certificate subject, issuer, OID extension and associated value, and log entry metadata
+// LogEntry holds a certificate subject, issuer, OID extension and associated value, and log entry metadata.
+// It is encoded to JSON as a LogEntryRecord.
 type LogEntry struct {
 	MatchedIdentity     string
 	MatchedIdentityType MatchedIdentityType
-	CertSubject         string
-	Issuer              string
-	Fingerprint         string
-	Subject             string
-	Index               int64
-	UUID                string
-	OIDExtension        asn1.ObjectIdentifier
-	ExtensionValue      string
+	// MatchedRules are all rules that matched the log entry, set when the
+	// matches of an entry are consolidated with ConsolidateMatches
+	MatchedRules   []MatchedRule
+	CertSubject    string
+	Issuer         string
+	Fingerprint    string
+	Subject        string
+	Index          int64
+	UUID           string
+	OIDExtension   asn1.ObjectIdentifier
+	ExtensionValue string
+	// LogOrigin is the origin or URL of the log the entry was found in
+	LogOrigin string
+	LogKind   LogKind
+	// EntryKind is the type of the log entry, e.g. hashedrekord or dsse for
+	// Rekor, or x509 or precert for CT logs
+	EntryKind string
+	// IntegratedTime is the time the entry was added to the log, if known
+	IntegratedTime time.Time
+	// CertificateSerial is the hex-encoded serial number of the matched certificate
+	CertificateSerial string
+	// Certificate is the PEM-encoded matched certificate
+	Certificate string
+	// ArtifactDigest is the digest of the signed artifact prefixed with the
+	// name of the hash algorithm, e.g. sha256:abcdef
+	ArtifactDigest string
+	// PayloadType is the payload type of the DSSE envelope of the entry, if known
+	PayloadType string
+	// SubjectDigests are the digests of the subjects of the in-toto statement
+	// of the entry, if known, prefixed with the name of the hash algorithm
+	SubjectDigests []string
+	// Violations are the violations of the expected provenance of the matched
+	// certificate identity
+	Violations []Violation
+	// DetectedAt is the time the monitor found the entry
+	DetectedAt time.Time
 }
 
+// String returns the LogEntryColumns of the log entry separated by tabs, with
+// "-" for values that aren't set
 func (e *LogEntry) String() string {
-	var parts []string
-	for _, s := range []string{e.CertSubject, e.Issuer, e.Fingerprint, e.Subject, strconv.Itoa(int(e.Index)), e.UUID, e.OIDExtension.String(), e.ExtensionValue} {
-		if strings.TrimSpace(s) != "" {
-			parts = append(parts, s)
-		}
+	columns := e.Columns()
+	for i, column := range columns {
+		columns[i] = textColumn(column)
 	}
-	return strings.Join(parts, " ")
+	return strings.Join(columns, "\t")
 }
 
 // FailedLogEntry holds a log entry that failed to be parsed/extracted
@@ -165,7 +209,17 @@ func CreateIdentitiesList(mvs MonitoredValues) []string {
 	identities = append(identities, mvs.Subjects...)
 
 	for _, oidMatcher := range mvs.OIDMatchers {
-		identities = append(identities, oidMatcher.ExtensionValues...)
+		for _, extensionValue := range oidMatcher.ExtensionValues {
+			identities = append(identities, extensionValue.Value)
+		}
+	}
+
+	for _, rule := range mvs.Rules {
+		identities = append(identities, rule.Name)
+	}
+
+	for _, policy := range mvs.Policies {
+		identities = append(identities, policy.Name)
 	}
 
 	return identities
@@ -173,7 +227,8 @@ func CreateIdentitiesList(mvs MonitoredValues) []string {
 
 // CreateMonitoredIdentities takes in a list of IdentityEntries and groups them by
 // associated identity based on an input list of identities to monitor.
-// It returns a list of MonitoredIdentities.
+// It returns a list of MonitoredIdentities sorted by identity, with entries
+// sorted by log index. Consolidated entries are grouped by their MatchedIdentity.
 func CreateMonitoredIdentities(inputIdentityEntries []LogEntry, monitoredIdentities []string) []MonitoredIdentity {
 	identityMap := make(map[string]bool)
 	for _, id := range monitoredIdentities {
@@ -183,7 +238,7 @@ func CreateMonitoredIdentities(inputIdentityEntries []LogEntry, monitoredIdentit
 	monitoredIdentityMap := make(map[string][]LogEntry)
 	for _, idEntry := range inputIdentityEntries {
 		if _, ok := identityMap[idEntry.MatchedIdentity]; !ok {
-			fmt.Fprintf(os.Stderr, "Matched identity %s not found in identity map\n", idEntry.MatchedIdentity)
+			slog.Warn("matched identity not found in identity map", slog.String(logging.KeyMatchedIdentity, idEntry.MatchedIdentity))
 			continue
 		}
 
@@ -197,6 +252,8 @@ func CreateMonitoredIdentities(inputIdentityEntries []LogEntry, monitoredIdentit
 			identityValue = idEntry.Fingerprint
 		case MatchedIdentityTypeSubject:
 			identityValue = idEntry.Subject
+		case MatchedIdentityTypeRule, MatchedIdentityTypePolicy:
+			identityValue = idEntry.MatchedIdentity
 		}
 
 		_, ok := monitoredIdentityMap[identityValue]
@@ -207,8 +264,11 @@ func CreateMonitoredIdentities(inputIdentityEntries []LogEntry, monitoredIdentit
 		}
 	}
 
+	// sort identities and their entries so that output is stable across runs
 	parsedMonitoredIdentities := []MonitoredIdentity{}
-	for id, idEntries := range monitoredIdentityMap {
+	for _, id := range slices.Sorted(maps.Keys(monitoredIdentityMap)) {
+		idEntries := monitoredIdentityMap[id]
+		slices.SortStableFunc(idEntries, compareLogEntries)
 		parsedMonitoredIdentities = append(parsedMonitoredIdentities, MonitoredIdentity{
 			Identity:             id,
 			FoundIdentityEntries: idEntries,
@@ -232,6 +292,12 @@ func MonitoredValuesExist(mvs MonitoredValues) bool {
 	if len(mvs.Subjects) > 0 {
 		return true
 	}
+	if len(mvs.Rules) > 0 {
+		return true
+	}
+	if len(mvs.Policies) > 0 {
+		return true
+	}
 	return false
 }
 
@@ -244,10 +310,16 @@ func verifyMonitoredOIDs(mvs MonitoredValues) error {
 		if len(oidMatcher.ExtensionValues) == 0 {
 			return errors.New("oid matched values empty")
 		}
+		if _, err := extensions.ResolveExtensionEncoding(oidMatcher.Encoding, oidMatcher.ObjectIdentifier); err != nil {
+			return fmt.Errorf("invalid encoding of oid %s: %w", oidMatcher.ObjectIdentifier, err)
+		}
 		for _, extensionValue := range oidMatcher.ExtensionValues {
-			if len(extensionValue) == 0 {
+			if len(extensionValue.Value) == 0 {
 				return errors.New("oid matched value empty")
 			}
+			if _, err := extensionValue.Matcher(); err != nil {
+				return fmt.Errorf("invalid oid matched value %s: %w", extensionValue, err)
+			}
 		}
 	}
 	return nil
@@ -268,6 +340,11 @@ func VerifyMonitoredValues(mvs MonitoredValues) error {
 				return errors.New("issuer empty")
 			}
 		}
+		if certID.ExpectedProvenance != nil {
+			if err := certID.ExpectedProvenance.Validate(); err != nil {
+				return err
+			}
+		}
 	}
 	for _, fp := range mvs.Fingerprints {
 		if len(fp) == 0 {
@@ -283,6 +360,12 @@ func VerifyMonitoredValues(mvs MonitoredValues) error {
 	if err != nil {
 		return err
 	}
+	if _, err := CompileRules(mvs.Rules); err != nil {
+		return err
+	}
+	if _, err := CompilePolicies(mvs.Policies); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -347,22 +430,109 @@ func getDeprecatedExtension[Certificate *x509.Certificate | *google_x509.Certifi
 	return "", errors.New("certificate was neither x509 nor google_x509")
 }
 
+// getRawExtension gets the DER-encoded value of a certificate extension by OID,
+// and whether the certificate has the extension
+func getRawExtension[Certificate *x509.Certificate | *google_x509.Certificate](certificate Certificate, oid asn1.ObjectIdentifier) ([]byte, bool, error) {
+	switch cert := any(certificate).(type) {
+	case *x509.Certificate:
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(oid) {
+				return ext.Value, true, nil
+			}
+		}
+		return nil, false, nil
+	case *google_x509.Certificate:
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal((google_asn1.ObjectIdentifier)(oid)) {
+				return ext.Value, true, nil
+			}
+		}
+		return nil, false, nil
+	}
+	return nil, false, errors.New("certificate was neither x509 nor google_x509")
+}
+
 // OIDMatchesPolicy returns if a certificate contains both a given OID field and a matching value associated with that field
 // if true, it returns the OID extension and extension value that were matched on
 func OIDMatchesPolicy[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate, oid asn1.ObjectIdentifier, extensionValues []string) (bool, asn1.ObjectIdentifier, string, error) {
-	extValue, err := getExtension(cert, oid)
+	matchedValue, extValue, err := MatchOIDExtension(cert, extensions.OIDExtension{ObjectIdentifier: oid, ExtensionValues: extensions.ExactValues(extensionValues...)})
+	if err != nil || matchedValue == "" {
+		return false, nil, "", err
+	}
+	return true, oid, extValue, nil
+}
+
+// MatchOIDExtension returns the first monitored extension value matching the
+// value of an OID extension of a certificate, and the matched value of the
+// extension. The extension is decoded with the encoding of the OID extension,
+// and monitored values are matched as configured by their match mode.
+// For extensions with several values, the matched value is the first value
+// matching the monitored value, or all values separated by commas if the
+// monitored value is negated. It returns an empty monitored value if the
+// certificate doesn't have the extension or no monitored value matches.
+func MatchOIDExtension[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate, oidExtension extensions.OIDExtension) (string, string, error) {
+	matcher, err := compileOIDMatcher(oidExtension)
 	if err != nil {
-		return false, nil, "", fmt.Errorf("error getting extension value: %w", err)
+		return "", "", err
 	}
-	if extValue == "" {
-		return false, nil, "", nil
+	rawValue, ok, err := getRawExtension(cert, oidExtension.ObjectIdentifier)
+	if err != nil || !ok {
+		return "", "", err
+	}
+	return matcher.match(rawValue)
+}
+
+// compiledOIDMatcher is an OID extension with its resolved encoding and
+// parsed value matchers
+type compiledOIDMatcher struct {
+	oid      asn1.ObjectIdentifier
+	encoding extensions.ExtensionEncoding
+	values   []string
+	matchers []extensions.ValueMatcher
+}
+
+func compileOIDMatcher(oidExtension extensions.OIDExtension) (compiledOIDMatcher, error) {
+	encoding, err := extensions.ResolveExtensionEncoding(oidExtension.Encoding, oidExtension.ObjectIdentifier)
+	if err != nil {
+		return compiledOIDMatcher{}, err
 	}
-	for _, extensionValue := range extensionValues {
-		if extValue == extensionValue {
-			return true, oid, extValue, nil
+	compiled := compiledOIDMatcher{oid: oidExtension.ObjectIdentifier, encoding: encoding}
+	for _, extensionValue := range oidExtension.ExtensionValues {
+		matcher, err := extensionValue.Matcher()
+		if err != nil {
+			return compiledOIDMatcher{}, fmt.Errorf("malformed extension value %s: %w", extensionValue, err)
 		}
+		compiled.values = append(compiled.values, extensionValue.Value)
+		compiled.matchers = append(compiled.matchers, matcher)
 	}
-	return false, nil, "", nil
+	return compiled, nil
+}
+
+// match decodes the DER-encoded value of the extension, and returns the
+// first monitored extension value matching it and the matched value
+func (m compiledOIDMatcher) match(rawValue []byte) (string, string, error) {
+	extValues, err := extensions.DecodeExtension(rawValue, m.encoding)
+	if err != nil {
+		return "", "", fmt.Errorf("error decoding %s extension %s: %w", m.encoding, m.oid, err)
+	}
+	// an empty string extension has no value to match
+	if len(extValues) == 0 || (len(extValues) == 1 && extValues[0] == "") {
+		return "", "", nil
+	}
+	for i, matcher := range m.matchers {
+		if !matcher.MatchesAny(extValues) {
+			continue
+		}
+		if matcher.Negate {
+			return m.values[i], strings.Join(extValues, ","), nil
+		}
+		for _, extValue := range extValues {
+			if matcher.Matches(extValue) {
+				return m.values[i], extValue, nil
+			}
+		}
+	}
+	return "", "", nil
 }
 
 // getSubjectAlternateNames extracts all subject alternative names from
@@ -532,20 +702,33 @@ func ValidatePreCertificateChain(certs []*google_x509.Certificate, caRoots strin
 	return errors.New("no certificate in the chain is signed by a trusted CA")
 }
 
+// getExtensionValue gets a certificate extension by OID, where the values of
+// deprecated Fulcio extensions are raw strings instead of ASN.1-encoded strings
+func getExtensionValue[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate, oid asn1.ObjectIdentifier) (string, error) {
+	if extensions.IsDeprecatedExtension(oid) {
+		return getDeprecatedExtension(cert, oid)
+	}
+	return getExtension(cert, oid)
+}
+
+// getIssuer returns the OIDC issuer of a certificate, falling back to the
+// deprecated issuer extension
+func getIssuer[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate) (string, error) {
+	issuer, err := getExtension(cert, certExtensionOIDCIssuerV2)
+	if err != nil || issuer == "" {
+		return getDeprecatedExtension(cert, certExtensionOIDCIssuer)
+	}
+	return issuer, nil
+}
+
 // CertMatchesPolicy returns true if a certificate contains a given subject and optionally a given issuer
 // expectedSub and expectedIssuers can be regular expressions
 // CertMatchesPolicy also returns the matched subject and issuer on success
 func CertMatchesPolicy[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate, expectedSub string, expectedIssuers []string) (bool, string, string, error) {
 	sans := getSubjectAlternateNames(cert)
-	var issuer string
-	var err error
-	issuer, err = getExtension(cert, certExtensionOIDCIssuerV2)
+	issuer, err := getIssuer(cert)
 	if err != nil || issuer == "" {
-		// fallback to deprecated issuer extension
-		issuer, err = getDeprecatedExtension(cert, certExtensionOIDCIssuer)
-		if err != nil || issuer == "" {
-			return false, "", "", err
-		}
+		return false, "", "", err
 	}
 	subjectMatches := false
 	regex, err := regexp.Compile(expectedSub)
@@ -37,7 +37,7 @@ func TestIdentityEntryString(t *testing.T) {
 		Index:       1,
 	}
 	identityEntryString := identityEntry.String()
-	expectedIdentityEntryString := "test-cert-subject 1 test-uuid"
+	expectedIdentityEntryString := "test-cert-subject\t-\t-\t-\t1\ttest-uuid\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-"
 	if identityEntryString != expectedIdentityEntryString {
 		t.Errorf("expected %s, received %s", expectedIdentityEntryString, identityEntryString)
 	}
@@ -226,16 +226,12 @@ func TestPrintMonitoredIdentities(t *testing.T) {
         		"identity": "test-identity",
         		"foundIdentityEntries": [
         			{
-        				"MatchedIdentity": "test-cert-subject",
-        				"MatchedIdentityType": "certSubject",
-        				"CertSubject": "test-cert-subject",
-        				"Issuer": "",
-        				"Fingerprint": "",
-        				"Subject": "",
-        				"Index": 0,
-        				"UUID": "test-uuid",
-        				"OIDExtension": null,
-        				"ExtensionValue": ""
+        				"schemaVersion": "1",
+        				"matchedIdentity": "test-cert-subject",
+        				"matchedIdentityType": "certSubject",
+        				"index": 0,
+        				"uuid": "test-uuid",
+        				"certSubject": "test-cert-subject"
         			}
         		]
         	}
@@ -283,7 +279,7 @@ func TestMonitoredValuesExist(t *testing.T) {
 				OIDMatchers: []extensions.OIDExtension{
 					{
 						ObjectIdentifier: asn1.ObjectIdentifier{1},
-						ExtensionValues:  []string{"test extension value"},
+						ExtensionValues:  extensions.ExactValues("test extension value"),
 					},
 				},
 			},
@@ -299,6 +295,82 @@ func TestMonitoredValuesExist(t *testing.T) {
 	}
 }
 
+func TestVerifyMonitoredValues(t *testing.T) {
+	monitoredValuesTests := map[string]struct {
+		input       MonitoredValues
+		errorString string
+	}{
+		"no monitored values": {
+			input:       MonitoredValues{},
+			errorString: "no identities provided to monitor",
+		},
+		"cert subject empty": {
+			input: MonitoredValues{
+				CertificateIdentities: []CertificateIdentity{
+					{
+						CertSubject: "",
+					},
+				},
+			},
+			errorString: "certificate subject empty",
+		},
+		"empty issuer": {
+			input: MonitoredValues{CertificateIdentities: []CertificateIdentity{
+				{
+					CertSubject: "s",
+					Issuers:     []string{""},
+				},
+			}},
+			errorString: "issuer empty",
+		},
+		"empty subject": {
+			input:       MonitoredValues{Subjects: []string{""}},
+			errorString: "subject empty",
+		},
+		"empty fingerprint": {
+			input:       MonitoredValues{Fingerprints: []string{""}},
+			errorString: "fingerprint empty",
+		},
+		"empty oid extension": {
+			input: MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
+				ObjectIdentifier: asn1.ObjectIdentifier{},
+				ExtensionValues:  extensions.ExactValues(""),
+			}}},
+			errorString: "oid extension empty",
+		},
+		"empty oid matched values": {
+			input: MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
+				ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
+				ExtensionValues:  extensions.ExactValues(),
+			}}},
+			errorString: "oid matched values empty",
+		},
+		"empty oid matched value": {
+			input: MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
+				ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
+				ExtensionValues:  extensions.ExactValues(""),
+			}}},
+			errorString: "oid matched value empty",
+		},
+		"empty oid field": {
+			input: MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
+				ObjectIdentifier: asn1.ObjectIdentifier{},
+				ExtensionValues:  extensions.ExactValues(""),
+			}}},
+			errorString: "oid extension empty",
+		},
+	}
+
+	for name, testCase := range monitoredValuesTests {
+		t.Run(name, func(t *testing.T) {
+			err := VerifyMonitoredValues(testCase.input)
+			if err == nil || !strings.Contains(err.Error(), testCase.errorString) {
+				t.Fatalf("expected error %v, received %v", testCase.errorString, err)
+			}
+		})
+	}
+}
+
 func TestCreateIdentitiesList(t *testing.T) {
 	testCases := map[string]struct {
 		input    MonitoredValues
@@ -321,7 +393,7 @@ func TestCreateIdentitiesList(t *testing.T) {
 				OIDMatchers: []extensions.OIDExtension{
 					{
 						ObjectIdentifier: asn1.ObjectIdentifier{1, 4, 1, 9},
-						ExtensionValues:  []string{"example-oid-matcher"},
+						ExtensionValues:  extensions.ExactValues("example-oid-matcher"),
 					},
 				},
 			},
@@ -405,6 +477,114 @@ func TestOIDMatchesValue(t *testing.T) {
 	}
 }
 
+// Test matching OID values with match modes
+func TestMatchOIDExtension(t *testing.T) {
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 14}
+	cert, err := mockCertificateWithExtension(oid, "refs/tags/v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]struct {
+		extensionValues []extensions.ExtensionValue
+		matchedValue    string
+		expectedErr     bool
+	}{
+		"exact": {extensionValues: extensions.ExactValues("refs/tags/v1.2.0"), matchedValue: "refs/tags/v1.2.0"},
+		"glob": {
+			extensionValues: []extensions.ExtensionValue{{Value: "refs/heads/main"}, {Value: "refs/tags/v*", MatchMode: extensions.MatchModeGlob}},
+			matchedValue:    "refs/tags/v*",
+		},
+		"negated prefix": {
+			extensionValues: []extensions.ExtensionValue{{Value: "refs/heads/", MatchMode: extensions.MatchModePrefix, Negate: true}},
+			matchedValue:    "refs/heads/",
+		},
+		"negated regex":       {extensionValues: []extensions.ExtensionValue{{Value: `^refs/tags/v\d`, MatchMode: extensions.MatchModeRegex, Negate: true}}},
+		"prefix not matching": {extensionValues: []extensions.ExtensionValue{{Value: "refs/heads/", MatchMode: extensions.MatchModePrefix}}},
+		"invalid regex":       {extensionValues: []extensions.ExtensionValue{{Value: "(", MatchMode: extensions.MatchModeRegex}}, expectedErr: true},
+		"invalid match mode":  {extensionValues: []extensions.ExtensionValue{{Value: "refs/tags/v1.2.0", MatchMode: "suffix"}}, expectedErr: true},
+		// bare values are matched exactly, even if they look like a pattern
+		"bare glob": {extensionValues: extensions.ExactValues("refs/tags/v*")},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			matchedValue, extValue, err := MatchOIDExtension(cert, extensions.OIDExtension{ObjectIdentifier: oid, ExtensionValues: tc.extensionValues})
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if matchedValue != tc.matchedValue {
+				t.Errorf("expected matched value %q, got %q", tc.matchedValue, matchedValue)
+			}
+			if matchedValue != "" && extValue != "refs/tags/v1.2.0" {
+				t.Errorf("expected extension value refs/tags/v1.2.0, got %s", extValue)
+			}
+		})
+	}
+}
+
+// Test matching OID values of extensions with other encodings
+func TestMatchOIDExtensionEncodings(t *testing.T) {
+	ekuValue, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 3}, {1, 3, 6, 1, 5, 5, 7, 3, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intValue, err := asn1.Marshal(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &x509.Certificate{
+		Extensions: []pkix.Extension{
+			{Id: extensions.OIDExtKeyUsage, Value: ekuValue},
+			{Id: extensions.OIDIssuer, Value: []byte("https://token.actions.githubusercontent.com")},
+			{Id: asn1.ObjectIdentifier{1, 2, 3}, Value: intValue},
+		},
+	}
+	testCases := map[string]struct {
+		oidExtension extensions.OIDExtension
+		matchedValue string
+		extValue     string
+		expectedErr  bool
+	}{
+		"extended key usage": {
+			oidExtension: extensions.OIDExtension{ObjectIdentifier: extensions.OIDExtKeyUsage, ExtensionValues: extensions.ExactValues("emailProtection")},
+			matchedValue: "emailProtection",
+			extValue:     "emailProtection",
+		},
+		"missing extended key usage": {
+			oidExtension: extensions.OIDExtension{ObjectIdentifier: extensions.OIDExtKeyUsage, ExtensionValues: []extensions.ExtensionValue{{Value: "timeStamping", Negate: true}}},
+			matchedValue: "timeStamping",
+			extValue:     "codeSigning,emailProtection",
+		},
+		"present extended key usage": {
+			oidExtension: extensions.OIDExtension{ObjectIdentifier: extensions.OIDExtKeyUsage, ExtensionValues: []extensions.ExtensionValue{{Value: "codeSigning", Negate: true}}},
+		},
+		"deprecated issuer": {
+			oidExtension: extensions.OIDExtension{ObjectIdentifier: extensions.OIDIssuer, ExtensionValues: []extensions.ExtensionValue{{Value: "https://token.actions.", MatchMode: extensions.MatchModePrefix}}},
+			matchedValue: "https://token.actions.",
+			extValue:     "https://token.actions.githubusercontent.com",
+		},
+		"integer": {
+			oidExtension: extensions.OIDExtension{ObjectIdentifier: asn1.ObjectIdentifier{1, 2, 3}, ExtensionValues: extensions.ExactValues("42"), Encoding: extensions.EncodingInteger},
+			matchedValue: "42",
+			extValue:     "42",
+		},
+		"integer as string": {
+			oidExtension: extensions.OIDExtension{ObjectIdentifier: asn1.ObjectIdentifier{1, 2, 3}, ExtensionValues: extensions.ExactValues("42")},
+			expectedErr:  true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			matchedValue, extValue, err := MatchOIDExtension(cert, tc.oidExtension)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if matchedValue != tc.matchedValue || extValue != tc.extValue {
+				t.Errorf("expected %q %q, got %q %q", tc.matchedValue, tc.extValue, matchedValue, extValue)
+			}
+		})
+	}
+}
+
 // Test when OID is present and matches value
 func TestGoogleOIDMatchesValue(t *testing.T) {
 	oid := asn1.ObjectIdentifier{2, 5, 29, 17}
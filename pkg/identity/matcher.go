@@ -0,0 +1,332 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	google_x509 "github.com/google/certificate-transparency-go/x509"
+)
+
+// Matcher holds monitored values compiled once to match many log entries:
+// regular expressions are compiled with a literal prefilter, fingerprints are
+// held in a set and OID extension matchers are indexed by OID. A Matcher is
+// safe for concurrent use.
+type Matcher struct {
+	monitoredValues MonitoredValues
+	certIdentities  []compiledCertIdentity
+	subjects        []compiledPattern
+	fingerprints    map[string]bool
+	// oidMatchers are keyed by OID in dot notation
+	oidMatchers map[string][]compiledOIDMatcher
+	// Rules are the compiled rules of the monitored values
+	Rules CompiledRules
+	// Policies are the compiled policies of the monitored values
+	Policies CompiledPolicies
+}
+
+// compiledCertIdentity is a certificate identity with compiled regular
+// expressions and expected provenance
+type compiledCertIdentity struct {
+	certSubject string
+	subject     *prefilteredRegexp
+	issuers     []*prefilteredRegexp
+	// expectsProvenance is whether matches are only reported if they violate
+	// the provenance checks
+	expectsProvenance bool
+	provenance        []compiledProvenanceCheck
+}
+
+// compiledPattern is a monitored regular expression and its compiled form
+type compiledPattern struct {
+	pattern string
+	regex   *prefilteredRegexp
+}
+
+// prefilteredRegexp is a regular expression with a literal that every match
+// contains, to skip running the regular expression on most values that don't
+// match
+type prefilteredRegexp struct {
+	regex *regexp.Regexp
+	// literal is a substring of every match, empty if there is none
+	literal string
+	// isLiteral is whether the expression is the literal itself, so that
+	// values match if they contain the literal
+	isLiteral bool
+}
+
+// compilePrefilteredRegexp compiles a regular expression and its literal prefilter
+func compilePrefilteredRegexp(expr string) (*prefilteredRegexp, error) {
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	parsed = parsed.Simplify()
+	return &prefilteredRegexp{
+		regex:     regex,
+		literal:   requiredLiteral(parsed),
+		isLiteral: parsed.Op == syntax.OpLiteral && parsed.Flags&syntax.FoldCase == 0,
+	}, nil
+}
+
+// requiredLiteral returns the longest case-sensitive literal that every match
+// of a parsed regular expression contains
+func requiredLiteral(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase == 0 {
+			return string(re.Rune)
+		}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return requiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		longest := ""
+		for _, sub := range re.Sub {
+			if literal := requiredLiteral(sub); len(literal) > len(longest) {
+				longest = literal
+			}
+		}
+		return longest
+	}
+	return ""
+}
+
+// MatchString returns whether the value matches the regular expression
+func (r *prefilteredRegexp) MatchString(value string) bool {
+	if !strings.Contains(value, r.literal) {
+		return false
+	}
+	return r.isLiteral || r.regex.MatchString(value)
+}
+
+// NewMatcher compiles monitored values into a Matcher
+func NewMatcher(mvs MonitoredValues) (*Matcher, error) {
+	matcher := &Matcher{
+		monitoredValues: mvs,
+		fingerprints:    make(map[string]bool, len(mvs.Fingerprints)),
+		oidMatchers:     make(map[string][]compiledOIDMatcher),
+	}
+	for _, certID := range mvs.CertificateIdentities {
+		compiled := compiledCertIdentity{certSubject: certID.CertSubject}
+		var err error
+		if compiled.subject, err = compilePrefilteredRegexp(certID.CertSubject); err != nil {
+			return nil, fmt.Errorf("malformed subject regex: %w", err)
+		}
+		for _, issuer := range certID.Issuers {
+			regex, err := compilePrefilteredRegexp(issuer)
+			if err != nil {
+				return nil, fmt.Errorf("malformed issuer regex: %w", err)
+			}
+			compiled.issuers = append(compiled.issuers, regex)
+		}
+		if certID.ExpectedProvenance != nil {
+			compiled.expectsProvenance = true
+			if compiled.provenance, err = compileProvenance(*certID.ExpectedProvenance); err != nil {
+				return nil, err
+			}
+		}
+		matcher.certIdentities = append(matcher.certIdentities, compiled)
+	}
+	for _, subject := range mvs.Subjects {
+		regex, err := compilePrefilteredRegexp(subject)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling regex %s: %w", subject, err)
+		}
+		matcher.subjects = append(matcher.subjects, compiledPattern{pattern: subject, regex: regex})
+	}
+	for _, fingerprint := range mvs.Fingerprints {
+		matcher.fingerprints[fingerprint] = true
+	}
+	for _, oidExtension := range mvs.OIDMatchers {
+		compiled, err := compileOIDMatcher(oidExtension)
+		if err != nil {
+			return nil, err
+		}
+		oid := oidExtension.ObjectIdentifier.String()
+		matcher.oidMatchers[oid] = append(matcher.oidMatchers[oid], compiled)
+	}
+	var err error
+	if matcher.Rules, err = CompileRules(mvs.Rules); err != nil {
+		return nil, err
+	}
+	if matcher.Policies, err = CompilePolicies(mvs.Policies); err != nil {
+		return nil, err
+	}
+	return matcher, nil
+}
+
+// MonitoredValues returns the monitored values the Matcher was compiled from
+func (m *Matcher) MonitoredValues() MonitoredValues {
+	return m.monitoredValues
+}
+
+// MatchFingerprints returns a match for every monitored fingerprint of a log entry
+func (m *Matcher) MatchFingerprints(fingerprints []string) []LogEntry {
+	matchedEntries := []LogEntry{}
+	for _, fp := range fingerprints {
+		if m.fingerprints[fp] {
+			matchedEntries = append(matchedEntries, LogEntry{
+				MatchedIdentity:     fp,
+				MatchedIdentityType: MatchedIdentityTypeFingerprint,
+				Fingerprint:         fp,
+			})
+		}
+	}
+	return matchedEntries
+}
+
+// MatchSubjects returns a match for every monitored subject matching a
+// subject of a log entry
+func (m *Matcher) MatchSubjects(subjects []string) []LogEntry {
+	matchedEntries := []LogEntry{}
+	for _, monitoredSub := range m.subjects {
+		for _, sub := range subjects {
+			if monitoredSub.regex.MatchString(sub) {
+				matchedEntries = append(matchedEntries, LogEntry{
+					MatchedIdentity:     monitoredSub.pattern,
+					MatchedIdentityType: MatchedIdentityTypeSubject,
+					Subject:             sub,
+				})
+			}
+		}
+	}
+	return matchedEntries
+}
+
+// MatchCertificateIdentities returns a match for every monitored certificate
+// identity matching a certificate of a log entry. Identities with an expected
+// provenance only match certificates violating it.
+func MatchCertificateIdentities[Certificate *x509.Certificate | *google_x509.Certificate](m *Matcher, certificates []Certificate) ([]LogEntry, error) {
+	matchedEntries := []LogEntry{}
+	if len(m.certIdentities) == 0 {
+		return matchedEntries, nil
+	}
+	for _, cert := range certificates {
+		issuer, err := getIssuer(cert)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == "" {
+			continue
+		}
+		sans := getSubjectAlternateNames(cert)
+		for _, certID := range m.certIdentities {
+			matchedSub := ""
+			for _, sub := range sans {
+				if certID.subject.MatchString(sub) {
+					matchedSub = sub
+				}
+			}
+			if matchedSub == "" {
+				continue
+			}
+			issuerMatches := len(certID.issuers) == 0
+			for _, regex := range certID.issuers {
+				if regex.MatchString(issuer) {
+					issuerMatches = true
+					break
+				}
+			}
+			if !issuerMatches {
+				continue
+			}
+			var violations []Violation
+			if certID.expectsProvenance {
+				if violations, err = provenanceViolations(cert, certID.provenance); err != nil {
+					return nil, fmt.Errorf("error with expected provenance: %w", err)
+				}
+				if len(violations) == 0 {
+					continue
+				}
+			}
+			matchedEntries = append(matchedEntries, LogEntry{
+				MatchedIdentity:     certID.certSubject,
+				MatchedIdentityType: MatchedIdentityTypeCertSubject,
+				CertSubject:         matchedSub,
+				Issuer:              issuer,
+				CertificateSerial:   CertificateSerial(cert),
+				Certificate:         CertificatePEM(cert),
+				Violations:          violations,
+			})
+		}
+	}
+	return matchedEntries, nil
+}
+
+// MatchOIDExtensions returns a match for every monitored OID extension
+// matching an extension of a certificate of a log entry
+func MatchOIDExtensions[Certificate *x509.Certificate | *google_x509.Certificate](m *Matcher, certificates []Certificate) ([]LogEntry, error) {
+	matchedEntries := []LogEntry{}
+	if len(m.oidMatchers) == 0 {
+		return matchedEntries, nil
+	}
+	for _, cert := range certificates {
+		for _, ext := range rawExtensions(cert) {
+			for _, oidMatcher := range m.oidMatchers[ext.oid.String()] {
+				matchedValue, extValue, err := oidMatcher.match(ext.value)
+				if err != nil {
+					return nil, err
+				}
+				if matchedValue == "" {
+					continue
+				}
+				matchedEntries = append(matchedEntries, LogEntry{
+					MatchedIdentity:     matchedValue,
+					MatchedIdentityType: MatchedIdentityTypeExtensionValue,
+					OIDExtension:        oidMatcher.oid,
+					ExtensionValue:      extValue,
+					CertificateSerial:   CertificateSerial(cert),
+					Certificate:         CertificatePEM(cert),
+				})
+			}
+		}
+	}
+	return matchedEntries, nil
+}
+
+// rawExtension is the OID and DER-encoded value of a certificate extension
+type rawExtension struct {
+	oid   asn1.ObjectIdentifier
+	value []byte
+}
+
+// rawExtensions returns the extensions of a certificate
+func rawExtensions[Certificate *x509.Certificate | *google_x509.Certificate](certificate Certificate) []rawExtension {
+	var exts []rawExtension
+	switch cert := any(certificate).(type) {
+	case *x509.Certificate:
+		for _, ext := range cert.Extensions {
+			exts = append(exts, rawExtension{oid: ext.Id, value: ext.Value})
+		}
+	case *google_x509.Certificate:
+		for _, ext := range cert.Extensions {
+			exts = append(exts, rawExtension{oid: asn1.ObjectIdentifier(ext.Id), value: ext.Value})
+		}
+	}
+	return exts
+}
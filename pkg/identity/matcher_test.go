@@ -0,0 +1,264 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+)
+
+func TestPrefilteredRegexp(t *testing.T) {
+	values := []string{"", "user@corp.com", "USER@CORP.COM", "xuser@corp.comx", "user@corp.org", "dev@corp.com",
+		"https://github.com/corp/app/.github/workflows/release.yml@refs/tags/v1", "user@corpXcom", "aaa"}
+	testCases := map[string]struct {
+		literal   string
+		isLiteral bool
+	}{
+		`user@corp\.com`:       {literal: "user@corp.com", isLiteral: true},
+		`^user@corp\.com$`:     {literal: "user@corp.com"},
+		`.*@corp\.com`:         {literal: "@corp.com"},
+		`user@corp.com`:        {literal: "user@corp"},
+		`(?i)user@corp\.com`:   {},
+		`user|dev`:             {},
+		`(dev|user)@corp\.com`: {literal: "@corp.com"},
+		`(a)+`:                 {literal: "a"},
+		`a{2,}`:                {literal: "a"},
+		`b*`:                   {},
+		``:                     {isLiteral: false},
+		`^https://github\.com/corp/.*@refs/tags/v.*$`: {literal: "https://github.com/corp/"},
+	}
+	for expr, tc := range testCases {
+		t.Run(expr, func(t *testing.T) {
+			prefiltered, err := compilePrefilteredRegexp(expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prefiltered.literal != tc.literal || prefiltered.isLiteral != tc.isLiteral {
+				t.Errorf("expected literal %q %v, got %q %v", tc.literal, tc.isLiteral, prefiltered.literal, prefiltered.isLiteral)
+			}
+			// the prefilter must not change which values match
+			regex := regexp.MustCompile(expr)
+			for _, value := range values {
+				if prefiltered.MatchString(value) != regex.MatchString(value) {
+					t.Errorf("expected %q matching %q to be %v", expr, value, regex.MatchString(value))
+				}
+			}
+		})
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	testCases := map[string]struct {
+		mvs MonitoredValues
+		err string
+	}{
+		"cert subject":  {mvs: MonitoredValues{CertificateIdentities: []CertificateIdentity{{CertSubject: "("}}}, err: "malformed subject regex"},
+		"issuer":        {mvs: MonitoredValues{CertificateIdentities: []CertificateIdentity{{CertSubject: ".*", Issuers: []string{"["}}}}, err: "malformed issuer regex"},
+		"provenance":    {mvs: MonitoredValues{CertificateIdentities: []CertificateIdentity{{CertSubject: ".*", ExpectedProvenance: &ExpectedProvenance{Repositories: []string{"("}}}}}, err: "malformed expected provenance regex"},
+		"subject":       {mvs: MonitoredValues{Subjects: []string{"("}}, err: "error compiling regex ("},
+		"oid value":     {mvs: MonitoredValues{OIDMatchers: []extensions.OIDExtension{{ObjectIdentifier: asn1.ObjectIdentifier{1, 2}, ExtensionValues: []extensions.ExtensionValue{{Value: "(", MatchMode: extensions.MatchModeRegex}}}}}, err: "malformed extension value ("},
+		"oid encoding":  {mvs: MonitoredValues{OIDMatchers: []extensions.OIDExtension{{ObjectIdentifier: asn1.ObjectIdentifier{1, 2}, ExtensionValues: extensions.ExactValues("1"), Encoding: "float"}}}, err: "unknown extension encoding float"},
+		"rule":          {mvs: MonitoredValues{Rules: []Rule{{Name: "a"}}}, err: "condition must set exactly one of"},
+		"policy":        {mvs: MonitoredValues{Policies: []Policy{{Name: "a", Expression: "entry.kind"}}}, err: "expression must evaluate to bool"},
+		"empty matcher": {},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMatcher(tc.mvs)
+			if tc.err == "" && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	githubIssuer := "https://token.actions.githubusercontent.com"
+	matcher, err := NewMatcher(MonitoredValues{
+		CertificateIdentities: []CertificateIdentity{
+			{CertSubject: `.*@corp\.com`, Issuers: []string{`^https://token\.actions\.githubusercontent\.com$`}},
+			{CertSubject: `dev@corp\.com`, Issuers: []string{`accounts\.google\.com`}},
+			{CertSubject: `dev@corp\.com`, ExpectedProvenance: &ExpectedProvenance{Repositories: []string{"^https://github.com/corp/"}}},
+		},
+		Subjects:     []string{`.*@example\.com`, `admin@example\.com`},
+		Fingerprints: []string{"abcd", "1234"},
+		OIDMatchers: []extensions.OIDExtension{
+			{ObjectIdentifier: ruleTestOwnerURI, ExtensionValues: []extensions.ExtensionValue{{Value: "https://github.com/", MatchMode: extensions.MatchModePrefix}}},
+			{ObjectIdentifier: ruleTestOIDCIssuer, ExtensionValues: extensions.ExactValues("https://accounts.google.com")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certs := []*x509.Certificate{mockRuleCertificate(t, "dev@corp.com", githubIssuer, "https://github.com/other")}
+	certMatches, err := MatchCertificateIdentities(matcher, certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []LogEntry{
+		{MatchedIdentity: `.*@corp\.com`, MatchedIdentityType: MatchedIdentityTypeCertSubject, CertSubject: "dev@corp.com", Issuer: githubIssuer},
+		{MatchedIdentity: `dev@corp\.com`, MatchedIdentityType: MatchedIdentityTypeCertSubject, CertSubject: "dev@corp.com", Issuer: githubIssuer,
+			Violations: []Violation{{Kind: ViolationUnexpectedRepository}}},
+	}
+	if !reflect.DeepEqual(certMatches, expected) {
+		t.Errorf("expected %+v, got %+v", expected, certMatches)
+	}
+
+	oidMatches, err := MatchOIDExtensions(matcher, certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []LogEntry{{MatchedIdentity: "https://github.com/", MatchedIdentityType: MatchedIdentityTypeExtensionValue,
+		OIDExtension: ruleTestOwnerURI, ExtensionValue: "https://github.com/other"}}
+	if !reflect.DeepEqual(oidMatches, expected) {
+		t.Errorf("expected %+v, got %+v", expected, oidMatches)
+	}
+
+	subjectMatches := matcher.MatchSubjects([]string{"admin@example.com", "user@example.org"})
+	expected = []LogEntry{
+		{MatchedIdentity: `.*@example\.com`, MatchedIdentityType: MatchedIdentityTypeSubject, Subject: "admin@example.com"},
+		{MatchedIdentity: `admin@example\.com`, MatchedIdentityType: MatchedIdentityTypeSubject, Subject: "admin@example.com"},
+	}
+	if !reflect.DeepEqual(subjectMatches, expected) {
+		t.Errorf("expected %+v, got %+v", expected, subjectMatches)
+	}
+
+	fingerprintMatches := matcher.MatchFingerprints([]string{"5678", "1234"})
+	expected = []LogEntry{{MatchedIdentity: "1234", MatchedIdentityType: MatchedIdentityTypeFingerprint, Fingerprint: "1234"}}
+	if !reflect.DeepEqual(fingerprintMatches, expected) {
+		t.Errorf("expected %+v, got %+v", expected, fingerprintMatches)
+	}
+}
+
+// benchmarkMonitoredValues returns monitored values with n values of each kind,
+// none of which match the benchmarked entries
+func benchmarkMonitoredValues(n int) MonitoredValues {
+	var mvs MonitoredValues
+	for i := range n {
+		mvs.CertificateIdentities = append(mvs.CertificateIdentities, CertificateIdentity{
+			CertSubject: fmt.Sprintf(`^https://github\.com/org%d/.*@refs/tags/v.*$`, i),
+			Issuers:     []string{`^https://token\.actions\.githubusercontent\.com$`},
+		})
+		mvs.Subjects = append(mvs.Subjects, fmt.Sprintf(`user%d@example\.com`, i))
+		mvs.Fingerprints = append(mvs.Fingerprints, fmt.Sprintf("%064x", i))
+		mvs.OIDMatchers = append(mvs.OIDMatchers, extensions.OIDExtension{
+			ObjectIdentifier: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 100 + i},
+			ExtensionValues:  []extensions.ExtensionValue{{Value: fmt.Sprintf("https://github.com/org%d/*", i), MatchMode: extensions.MatchModeGlob}},
+		})
+	}
+	mvs.OIDMatchers = append(mvs.OIDMatchers, extensions.OIDExtension{
+		ObjectIdentifier: ruleTestOwnerURI,
+		ExtensionValues:  extensions.ExactValues("https://github.com/corp"),
+	})
+	return mvs
+}
+
+// BenchmarkNewMatcher measures compiling the monitored values, including the
+// CEL policy expressions, which is done once when the configuration is loaded
+func BenchmarkNewMatcher(b *testing.B) {
+	mvs := benchmarkMonitoredValues(100)
+	for i := range 10 {
+		mvs.Policies = append(mvs.Policies, Policy{
+			Name:       fmt.Sprintf("policy%d", i),
+			Expression: fmt.Sprintf(`entry.kind == "dsse" && entry.subjects.exists(s, s.endsWith("@org%d.example.com"))`, i),
+		})
+	}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := NewMatcher(mvs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMatchCertificateIdentities(b *testing.B) {
+	matcher, err := NewMatcher(benchmarkMonitoredValues(100))
+	if err != nil {
+		b.Fatal(err)
+	}
+	certs := []*x509.Certificate{mockRuleCertificate(b, "https://github.com/other/app/.github/workflows/release.yml@refs/tags/v1",
+		"https://token.actions.githubusercontent.com", "https://github.com/other")}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := MatchCertificateIdentities(matcher, certs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCertMatchesPolicy matches the same certificate identities as
+// BenchmarkMatchCertificateIdentities without compiling them beforehand
+func BenchmarkCertMatchesPolicy(b *testing.B) {
+	mvs := benchmarkMonitoredValues(100)
+	cert := mockRuleCertificate(b, "https://github.com/other/app/.github/workflows/release.yml@refs/tags/v1",
+		"https://token.actions.githubusercontent.com", "https://github.com/other")
+	b.ResetTimer()
+	for range b.N {
+		for _, certID := range mvs.CertificateIdentities {
+			if _, _, _, err := CertMatchesPolicy(cert, certID.CertSubject, certID.Issuers); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkMatchSubjects(b *testing.B) {
+	matcher, err := NewMatcher(benchmarkMonitoredValues(100))
+	if err != nil {
+		b.Fatal(err)
+	}
+	subjects := []string{"someone@example.org", "user@example.com"}
+	b.ResetTimer()
+	for range b.N {
+		matcher.MatchSubjects(subjects)
+	}
+}
+
+func BenchmarkMatchFingerprints(b *testing.B) {
+	matcher, err := NewMatcher(benchmarkMonitoredValues(1000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	fingerprints := []string{strings.Repeat("f", 64), strings.Repeat("e", 64)}
+	b.ResetTimer()
+	for range b.N {
+		matcher.MatchFingerprints(fingerprints)
+	}
+}
+
+func BenchmarkMatchOIDExtensions(b *testing.B) {
+	matcher, err := NewMatcher(benchmarkMonitoredValues(100))
+	if err != nil {
+		b.Fatal(err)
+	}
+	certs := []*x509.Certificate{mockRuleCertificate(b, "dev@corp.com", "https://token.actions.githubusercontent.com", "https://github.com/other")}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := MatchOIDExtensions(matcher, certs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
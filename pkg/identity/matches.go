@@ -0,0 +1,165 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// MatchedRule is a monitored value that matched a log entry
+type MatchedRule struct {
+	MatchedIdentityType MatchedIdentityType `json:"matchedIdentityType"`
+	MatchedIdentity     string              `json:"matchedIdentity"`
+	// Value is the value of the log entry that matched, e.g. the certificate
+	// subject for certSubject rules
+	Value string `json:"value,omitempty"`
+	// OIDExtension is the matched certificate extension in dotted notation,
+	// for extensionValue rules
+	OIDExtension string `json:"oidExtension,omitempty"`
+}
+
+// String returns the type and monitored value of the rule
+func (r MatchedRule) String() string {
+	return string(r.MatchedIdentityType) + "=" + r.MatchedIdentity
+}
+
+func compareMatchedRules(a, b MatchedRule) int {
+	return cmp.Or(
+		cmp.Compare(a.MatchedIdentityType, b.MatchedIdentityType),
+		cmp.Compare(a.MatchedIdentity, b.MatchedIdentity),
+		cmp.Compare(a.Value, b.Value),
+		cmp.Compare(a.OIDExtension, b.OIDExtension),
+	)
+}
+
+// Rules returns the rules that matched the log entry. For log entries that
+// weren't consolidated, this is the single rule of the entry.
+func (e LogEntry) Rules() []MatchedRule {
+	if len(e.MatchedRules) > 0 {
+		return e.MatchedRules
+	}
+	rule := MatchedRule{MatchedIdentityType: e.MatchedIdentityType, MatchedIdentity: e.MatchedIdentity}
+	switch e.MatchedIdentityType {
+	case MatchedIdentityTypeCertSubject:
+		rule.Value = e.CertSubject
+	case MatchedIdentityTypeExtensionValue:
+		rule.Value = e.ExtensionValue
+		if len(e.OIDExtension) > 0 {
+			rule.OIDExtension = e.OIDExtension.String()
+		}
+	case MatchedIdentityTypeFingerprint:
+		rule.Value = e.Fingerprint
+	case MatchedIdentityTypeSubject:
+		rule.Value = e.Subject
+	case MatchedIdentityTypeRule, MatchedIdentityTypePolicy:
+		rule.Value = e.CertSubject
+		if rule.Value == "" {
+			rule.Value = e.Subject
+		}
+	}
+	return []MatchedRule{rule}
+}
+
+// matchKey identifies a log entry across matches
+type matchKey struct {
+	logOrigin string
+	index     int64
+	uuid      string
+}
+
+// ConsolidateMatches merges the matches of each log entry into a single
+// finding listing every rule that matched the entry in MatchedRules, and
+// sorts findings by log index. The first rule, in the order of rule types and
+// monitored values, is the MatchedIdentity of the finding. Fields that differ
+// between matches, e.g. when an entry has several certificates, are taken
+// from the first match that sets them.
+func ConsolidateMatches(matchedEntries []LogEntry) []LogEntry {
+	consolidated := []LogEntry{}
+	positions := make(map[matchKey]int)
+	for _, entry := range matchedEntries {
+		key := matchKey{logOrigin: entry.LogOrigin, index: entry.Index, uuid: entry.UUID}
+		rules := entry.Rules()
+		i, ok := positions[key]
+		if !ok {
+			i = len(consolidated)
+			positions[key] = i
+			entry.MatchedRules = nil
+			consolidated = append(consolidated, entry)
+		} else {
+			mergeMatch(&consolidated[i], entry)
+		}
+		for _, rule := range rules {
+			if !slices.Contains(consolidated[i].MatchedRules, rule) {
+				consolidated[i].MatchedRules = append(consolidated[i].MatchedRules, rule)
+			}
+		}
+	}
+
+	for i := range consolidated {
+		finding := &consolidated[i]
+		slices.SortFunc(finding.MatchedRules, compareMatchedRules)
+		slices.SortFunc(finding.Violations, compareViolations)
+		finding.MatchedIdentityType = finding.MatchedRules[0].MatchedIdentityType
+		finding.MatchedIdentity = finding.MatchedRules[0].MatchedIdentity
+	}
+	slices.SortStableFunc(consolidated, compareLogEntries)
+	return consolidated
+}
+
+// compareLogEntries orders log entries by log index, then by UUID and log origin
+func compareLogEntries(a, b LogEntry) int {
+	return cmp.Or(
+		cmp.Compare(a.Index, b.Index),
+		cmp.Compare(a.UUID, b.UUID),
+		cmp.Compare(a.LogOrigin, b.LogOrigin),
+	)
+}
+
+// mergeMatch sets the fields of a finding that aren't set yet from another
+// match of the same log entry
+func mergeMatch(finding *LogEntry, match LogEntry) {
+	setIfEmpty := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	setIfEmpty(&finding.CertSubject, match.CertSubject)
+	setIfEmpty(&finding.Issuer, match.Issuer)
+	setIfEmpty(&finding.Fingerprint, match.Fingerprint)
+	setIfEmpty(&finding.Subject, match.Subject)
+	setIfEmpty(&finding.ExtensionValue, match.ExtensionValue)
+	setIfEmpty(&finding.CertificateSerial, match.CertificateSerial)
+	setIfEmpty(&finding.Certificate, match.Certificate)
+	if len(finding.OIDExtension) == 0 {
+		finding.OIDExtension = match.OIDExtension
+	}
+	for _, violation := range match.Violations {
+		if !slices.Contains(finding.Violations, violation) {
+			finding.Violations = append(finding.Violations, violation)
+		}
+	}
+}
+
+// formatMatchedRules returns the rules separated by commas, for the text and
+// CSV columns of log entries
+func formatMatchedRules(rules []MatchedRule) string {
+	formatted := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		formatted = append(formatted, rule.String())
+	}
+	return strings.Join(formatted, ", ")
+}
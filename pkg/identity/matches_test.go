@@ -0,0 +1,100 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"encoding/asn1"
+	"reflect"
+	"testing"
+)
+
+func TestConsolidateMatches(t *testing.T) {
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 12}
+	matches := []LogEntry{
+		{MatchedIdentity: ".*@example.com", MatchedIdentityType: MatchedIdentityTypeSubject, Subject: "user@example.com", Index: 7, UUID: "uuid-7"},
+		{MatchedIdentity: "user@example.com", MatchedIdentityType: MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Issuer: "https://issuer.example.com", Index: 3, UUID: "uuid-3"},
+		{MatchedIdentity: "repo", MatchedIdentityType: MatchedIdentityTypeExtensionValue, OIDExtension: oid, ExtensionValue: "repo", CertificateSerial: "1a", Index: 3, UUID: "uuid-3"},
+		{MatchedIdentity: "other-repo", MatchedIdentityType: MatchedIdentityTypeExtensionValue, OIDExtension: oid, ExtensionValue: "repo", Index: 3, UUID: "uuid-3"},
+		// the same rule matching twice, e.g. when it's configured twice
+		{MatchedIdentity: "user@example.com", MatchedIdentityType: MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Index: 3, UUID: "uuid-3"},
+		{MatchedIdentity: "abcd", MatchedIdentityType: MatchedIdentityTypeFingerprint, Fingerprint: "abcd", Index: 1, UUID: "uuid-1"},
+	}
+
+	want := []LogEntry{
+		{
+			MatchedIdentity:     "abcd",
+			MatchedIdentityType: MatchedIdentityTypeFingerprint,
+			MatchedRules:        []MatchedRule{{MatchedIdentityType: MatchedIdentityTypeFingerprint, MatchedIdentity: "abcd", Value: "abcd"}},
+			Fingerprint:         "abcd",
+			Index:               1,
+			UUID:                "uuid-1",
+		},
+		{
+			MatchedIdentity:     "user@example.com",
+			MatchedIdentityType: MatchedIdentityTypeCertSubject,
+			MatchedRules: []MatchedRule{
+				{MatchedIdentityType: MatchedIdentityTypeCertSubject, MatchedIdentity: "user@example.com", Value: "user@example.com"},
+				{MatchedIdentityType: MatchedIdentityTypeExtensionValue, MatchedIdentity: "other-repo", Value: "repo", OIDExtension: oid.String()},
+				{MatchedIdentityType: MatchedIdentityTypeExtensionValue, MatchedIdentity: "repo", Value: "repo", OIDExtension: oid.String()},
+			},
+			CertSubject:       "user@example.com",
+			Issuer:            "https://issuer.example.com",
+			OIDExtension:      oid,
+			ExtensionValue:    "repo",
+			CertificateSerial: "1a",
+			Index:             3,
+			UUID:              "uuid-3",
+		},
+		{
+			MatchedIdentity:     ".*@example.com",
+			MatchedIdentityType: MatchedIdentityTypeSubject,
+			MatchedRules:        []MatchedRule{{MatchedIdentityType: MatchedIdentityTypeSubject, MatchedIdentity: ".*@example.com", Value: "user@example.com"}},
+			Subject:             "user@example.com",
+			Index:               7,
+			UUID:                "uuid-7",
+		},
+	}
+	consolidated := ConsolidateMatches(matches)
+	if !reflect.DeepEqual(consolidated, want) {
+		t.Errorf("expected %+v, got %+v", want, consolidated)
+	}
+
+	// consolidating findings again doesn't change them
+	if again := ConsolidateMatches(consolidated); !reflect.DeepEqual(again, want) {
+		t.Errorf("expected %+v, got %+v", want, again)
+	}
+
+	if consolidated := ConsolidateMatches(nil); len(consolidated) != 0 {
+		t.Errorf("expected no findings, got %+v", consolidated)
+	}
+}
+
+func TestCreateMonitoredIdentitiesOrder(t *testing.T) {
+	entries := []LogEntry{
+		{MatchedIdentity: "b", MatchedIdentityType: MatchedIdentityTypeSubject, Subject: "b", Index: 9},
+		{MatchedIdentity: "a", MatchedIdentityType: MatchedIdentityTypeSubject, Subject: "a", Index: 5},
+		{MatchedIdentity: "b", MatchedIdentityType: MatchedIdentityTypeSubject, Subject: "b", Index: 2},
+		{MatchedIdentity: "c", MatchedIdentityType: MatchedIdentityTypeSubject, Subject: "c", Index: 1},
+	}
+	for range 10 {
+		monitoredIdentities := CreateMonitoredIdentities(entries, []string{"a", "b", "c"})
+		if len(monitoredIdentities) != 3 || monitoredIdentities[0].Identity != "a" || monitoredIdentities[1].Identity != "b" || monitoredIdentities[2].Identity != "c" {
+			t.Fatalf("unexpected order of identities %+v", monitoredIdentities)
+		}
+		if found := monitoredIdentities[1].FoundIdentityEntries; found[0].Index != 2 || found[1].Index != 9 {
+			t.Fatalf("unexpected order of entries %+v", found)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+
+	google_x509 "github.com/google/certificate-transparency-go/x509"
+)
+
+// Policy is a named CEL expression evaluated on each log entry, with the entry
+// available as the variable `entry` of type PolicyEntry. Expressions must
+// evaluate to a bool, and matches are reported with the policy name as
+// MatchedIdentity.
+type Policy struct {
+	Name       string `yaml:"name"`
+	Expression string `yaml:"expression"`
+	// Labels select notification routes for matches of this policy
+	Labels []string `yaml:"labels"`
+}
+
+// PolicyEntry is the model of a log entry in policy expressions
+type PolicyEntry struct {
+	// LogKind is the kind of log the entry is from: rekorV1, rekorV2 or ct
+	LogKind string `cel:"logKind"`
+	// Kind is the entry kind, e.g. hashedrekord or dsse, or x509 or precert
+	// for CT log entries
+	Kind string `cel:"kind"`
+	// ArtifactDigest is the digest of the signed artifact as algorithm:hex,
+	// empty if the entry kind has no artifact digest
+	ArtifactDigest string `cel:"artifactDigest"`
+	// IntegratedTime is the time the entry was added to the log, the zero
+	// timestamp for Rekor v2 entries
+	IntegratedTime time.Time `cel:"integratedTime"`
+	// Fingerprints are the fingerprints of the keys and certificates of the entry
+	Fingerprints []string `cel:"fingerprints"`
+	// Subjects are the subjects of the entry, such as SSH or PGP key email
+	// addresses and the subject alternative names of certificates
+	Subjects []string `cel:"subjects"`
+	// Certificates are the certificates of the entry
+	Certificates []PolicyCertificate `cel:"certificates"`
+}
+
+// PolicyCertificate is the model of a certificate in policy expressions
+type PolicyCertificate struct {
+	// SANs are the subject alternative names of the certificate
+	SANs []string `cel:"sans"`
+	// Issuer is the OIDC issuer of the certificate
+	Issuer string `cel:"issuer"`
+	// Serial is the hex-encoded serial number of the certificate
+	Serial string `cel:"serial"`
+	// Extensions are the Fulcio extensions of the certificate, named as the
+	// fields of FulcioExtensions. Each extension holds a single value, or no
+	// value if the certificate doesn't have the extension.
+	Extensions extensions.CertificateExtensions `cel:"extensions"`
+
+	// pem returns the PEM encoding of the certificate, which is only needed
+	// for matches
+	pem func() string
+}
+
+// CompiledPolicies are type-checked policy expressions, to be evaluated on
+// many log entries
+type CompiledPolicies []compiledPolicy
+
+type compiledPolicy struct {
+	name    string
+	program cel.Program
+}
+
+// newPolicyEnv returns the CEL environment of policy expressions
+func newPolicyEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		ext.NativeTypes(reflect.TypeFor[PolicyEntry](), ext.ParseStructTags(true)),
+		cel.Variable("entry", cel.ObjectType("identity.PolicyEntry")),
+		ext.Strings(),
+	)
+}
+
+// CompilePolicies type-checks policy expressions and compiles them for evaluation
+func CompilePolicies(policies []Policy) (CompiledPolicies, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	env, err := newPolicyEnv()
+	if err != nil {
+		return nil, fmt.Errorf("error creating policy environment: %w", err)
+	}
+	compiled := make(CompiledPolicies, 0, len(policies))
+	names := make(map[string]bool)
+	for _, policy := range policies {
+		if policy.Name == "" {
+			return nil, errors.New("policy name empty")
+		}
+		if names[policy.Name] {
+			return nil, fmt.Errorf("duplicate policy name %s", policy.Name)
+		}
+		names[policy.Name] = true
+		ast, issues := env.Compile(policy.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("invalid policy %s: %w", policy.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("invalid policy %s: expression must evaluate to bool, not %s", policy.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy %s: %w", policy.Name, err)
+		}
+		compiled = append(compiled, compiledPolicy{name: policy.Name, program: program})
+	}
+	return compiled, nil
+}
+
+// NewPolicyEntry returns the model of a log entry in policy expressions from
+// its certificates, subjects, key fingerprints and metadata
+func NewPolicyEntry[Certificate *x509.Certificate | *google_x509.Certificate](certificates []Certificate, subjects []string, fingerprints []string, metadata EntryMetadata) (PolicyEntry, error) {
+	entry := PolicyEntry{
+		LogKind:        string(metadata.LogKind),
+		Kind:           metadata.EntryKind,
+		ArtifactDigest: metadata.ArtifactDigest,
+		IntegratedTime: metadata.IntegratedTime,
+		Fingerprints:   fingerprints,
+		Subjects:       subjects,
+	}
+	for _, cert := range certificates {
+		issuer, err := getIssuer(cert)
+		if err != nil {
+			return PolicyEntry{}, fmt.Errorf("error getting issuer: %w", err)
+		}
+		certExtensions, err := extensions.NewCertificateExtensions(func(oid asn1.ObjectIdentifier) (string, error) {
+			// the issuer falls back to the deprecated issuer extension
+			if oid.Equal(extensions.OIDIssuerV2) {
+				return issuer, nil
+			}
+			return getExtensionValue(cert, oid)
+		})
+		if err != nil {
+			return PolicyEntry{}, err
+		}
+		entry.Certificates = append(entry.Certificates, PolicyCertificate{
+			SANs:       getSubjectAlternateNames(cert),
+			Issuer:     issuer,
+			Serial:     CertificateSerial(cert),
+			Extensions: certExtensions,
+			pem: func() string {
+				return CertificatePEM(cert)
+			},
+		})
+	}
+	return entry, nil
+}
+
+// Match evaluates the policies on a log entry, and returns a match for every
+// policy that holds. As policies apply to the whole entry, matches hold the
+// first certificate of the entry, if any.
+func (policies CompiledPolicies) Match(entry PolicyEntry) ([]LogEntry, error) {
+	matchedEntries := []LogEntry{}
+	for _, policy := range policies {
+		result, _, err := policy.program.Eval(map[string]any{"entry": entry})
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating policy %s: %w", policy.name, err)
+		}
+		if holds, ok := result.Value().(bool); !ok || !holds {
+			continue
+		}
+		match := LogEntry{
+			MatchedIdentity:     policy.name,
+			MatchedIdentityType: MatchedIdentityTypePolicy,
+		}
+		if len(entry.Certificates) > 0 {
+			cert := entry.Certificates[0]
+			if len(cert.SANs) > 0 {
+				match.CertSubject = cert.SANs[0]
+			}
+			match.Issuer = cert.Issuer
+			match.CertificateSerial = cert.Serial
+			match.Certificate = cert.pem()
+		}
+		matchedEntries = append(matchedEntries, match)
+	}
+	return matchedEntries, nil
+}
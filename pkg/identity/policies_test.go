@@ -0,0 +1,155 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicyMatch(t *testing.T) {
+	githubIssuer := "https://token.actions.githubusercontent.com"
+	cert := mockRuleCertificate(t, "dev@corp.com", githubIssuer, "https://github.com/other")
+	cert.SerialNumber = big.NewInt(42)
+	metadata := EntryMetadata{
+		LogKind:        LogKindRekorV1,
+		EntryKind:      "hashedrekord",
+		ArtifactDigest: "sha256:abcd",
+		IntegratedTime: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	entry, err := NewPolicyEntry([]*x509.Certificate{cert}, []string{"dev@corp.com"}, []string{"0123"}, metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		expression string
+		matches    bool
+	}{
+		"outside repository": {
+			expression: `entry.certificates.exists(c, c.sans.exists(s, s.endsWith("@corp.com")) && c.issuer == "` + githubIssuer + `" && !("https://github.com/corp" in c.extensions.SourceRepositoryOwnerURI))`,
+			matches:    true,
+		},
+		"own repository": {
+			expression: `entry.certificates.exists(c, "https://github.com/other" in c.extensions.SourceRepositoryOwnerURI) == false`,
+			matches:    false,
+		},
+		"issuer extension": {
+			expression: `entry.certificates[0].extensions.Issuer == ["` + githubIssuer + `"]`,
+			matches:    true,
+		},
+		"missing extension": {
+			expression: `size(entry.certificates[0].extensions.BuildSignerURI) == 0`,
+			matches:    true,
+		},
+		"entry metadata": {
+			expression: `entry.logKind == "rekorV1" && entry.kind == "hashedrekord" && entry.artifactDigest.startsWith("sha256:") && entry.integratedTime > timestamp("2024-01-01T00:00:00Z")`,
+			matches:    true,
+		},
+		"fingerprints and subjects": {
+			expression: `"0123" in entry.fingerprints && entry.subjects.exists(s, s.lowerAscii() == "dev@corp.com")`,
+			matches:    true,
+		},
+		"serial": {
+			expression: `entry.certificates[0].serial == "2a"`,
+			matches:    true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			policies, err := CompilePolicies([]Policy{{Name: "policy", Expression: tc.expression}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches, err := policies.Match(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := []LogEntry{}
+			if tc.matches {
+				expected = []LogEntry{{
+					MatchedIdentity:     "policy",
+					MatchedIdentityType: MatchedIdentityTypePolicy,
+					CertSubject:         "dev@corp.com",
+					Issuer:              githubIssuer,
+					CertificateSerial:   "2a",
+				}}
+			}
+			if !reflect.DeepEqual(matches, expected) {
+				t.Errorf("expected %+v, got %+v", expected, matches)
+			}
+		})
+	}
+}
+
+func TestPolicyEvaluationError(t *testing.T) {
+	policies, err := CompilePolicies([]Policy{{Name: "first-certificate", Expression: `entry.certificates[0].issuer == ""`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := NewPolicyEntry[*x509.Certificate](nil, nil, []string{"0123"}, EntryMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policies.Match(entry); err == nil || !strings.Contains(err.Error(), "error evaluating policy first-certificate") {
+		t.Errorf("expected evaluation error, got %v", err)
+	}
+}
+
+func TestCompilePolicies(t *testing.T) {
+	testCases := map[string]struct {
+		policies []Policy
+		err      string
+	}{
+		"valid": {
+			policies: []Policy{{Name: "a", Expression: `entry.kind == "dsse"`}, {Name: "b", Expression: `size(entry.fingerprints) > 1`}},
+		},
+		"empty name": {
+			policies: []Policy{{Expression: `true`}},
+			err:      "policy name empty",
+		},
+		"duplicate name": {
+			policies: []Policy{{Name: "a", Expression: `true`}, {Name: "a", Expression: `false`}},
+			err:      "duplicate policy name a",
+		},
+		"syntax error": {
+			policies: []Policy{{Name: "a", Expression: `entry.kind ==`}},
+			err:      "invalid policy a: ERROR",
+		},
+		"unknown extension": {
+			policies: []Policy{{Name: "a", Expression: `size(entry.certificates[0].extensions.SourceRepositoryOwner) > 0`}},
+			err:      "undefined field 'SourceRepositoryOwner'",
+		},
+		"not a bool": {
+			policies: []Policy{{Name: "a", Expression: `entry.fingerprints`}},
+			err:      "expression must evaluate to bool",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := CompilePolicies(tc.policies)
+			if tc.err == "" && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
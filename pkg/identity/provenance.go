@@ -0,0 +1,198 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"cmp"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+
+	google_x509 "github.com/google/certificate-transparency-go/x509"
+)
+
+// ExpectedProvenance is an allow-list of where signatures of a certificate
+// identity are expected to be produced. Values are regular expressions, and
+// an empty list allows any value. When set, only entries of the identity that
+// violate the allow-list are reported.
+type ExpectedProvenance struct {
+	// Issuers are the allowed OIDC issuers
+	Issuers []string `yaml:"issuers"`
+	// Repositories are the allowed source repository URIs (SourceRepositoryURI)
+	Repositories []string `yaml:"repositories"`
+	// WorkflowRefs are the allowed references to the workflows that signed,
+	// e.g. https://github.com/owner/repo/.github/workflows/release.yml@refs/heads/main
+	// (BuildSignerURI)
+	WorkflowRefs []string `yaml:"workflowRefs"`
+	// RunnerEnvironments are the allowed runner environments, e.g.
+	// github-hosted (RunnerEnvironment)
+	RunnerEnvironments []string `yaml:"runnerEnvironments"`
+}
+
+// ViolationKind classifies how a certificate violates the expected provenance
+// of its identity
+type ViolationKind string
+
+const (
+	ViolationUnexpectedIssuer            ViolationKind = "unexpectedIssuer"
+	ViolationUnexpectedRepository        ViolationKind = "unexpectedRepository"
+	ViolationUnexpectedWorkflowRef       ViolationKind = "unexpectedWorkflowRef"
+	ViolationUnexpectedRunnerEnvironment ViolationKind = "unexpectedRunnerEnvironment"
+)
+
+// Violation is a value of a certificate that is not in the expected provenance
+// of its identity. Value is empty if the certificate has no value, e.g. when
+// it doesn't have the extension.
+type Violation struct {
+	Kind  ViolationKind `json:"kind"`
+	Value string        `json:"value,omitempty"`
+}
+
+// String returns the kind and value of the violation
+func (v Violation) String() string {
+	return string(v.Kind) + "=" + v.Value
+}
+
+func compareViolations(a, b Violation) int {
+	return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Value, b.Value))
+}
+
+// formatViolations returns the violations separated by commas, for the text
+// and CSV columns of log entries
+func formatViolations(violations []Violation) string {
+	formatted := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		formatted = append(formatted, violation.String())
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// provenanceCheck is the allow-list of a kind of violation. The checked value
+// is the issuer, or the value of the extension for extension checks.
+type provenanceCheck struct {
+	kind    ViolationKind
+	oid     asn1.ObjectIdentifier
+	allowed []string
+}
+
+func (p ExpectedProvenance) provenanceChecks() []provenanceCheck {
+	return []provenanceCheck{
+		{kind: ViolationUnexpectedIssuer, allowed: p.Issuers},
+		{kind: ViolationUnexpectedRepository, oid: extensions.OIDSourceRepositoryURI, allowed: p.Repositories},
+		{kind: ViolationUnexpectedWorkflowRef, oid: extensions.OIDBuildSignerURI, allowed: p.WorkflowRefs},
+		{kind: ViolationUnexpectedRunnerEnvironment, oid: extensions.OIDRunnerEnvironment, allowed: p.RunnerEnvironments},
+	}
+}
+
+// Validate checks that the expected provenance allows some values, and that
+// allowed values are valid regular expressions
+func (p ExpectedProvenance) Validate() error {
+	empty := true
+	for _, check := range p.provenanceChecks() {
+		for _, allowed := range check.allowed {
+			empty = false
+			if _, err := regexp.Compile(allowed); err != nil {
+				return fmt.Errorf("invalid expected provenance regex %s: %w", allowed, err)
+			}
+		}
+	}
+	if empty {
+		return errors.New("expected provenance must set issuers, repositories, workflowRefs or runnerEnvironments")
+	}
+	return nil
+}
+
+// ProvenanceViolations returns how a certificate violates the expected
+// provenance of its identity, with a violation for every value of the
+// certificate that no allowed value matches
+func ProvenanceViolations[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate, expected ExpectedProvenance) ([]Violation, error) {
+	checks, err := compileProvenance(expected)
+	if err != nil {
+		return nil, err
+	}
+	return provenanceViolations(cert, checks)
+}
+
+// compiledProvenanceCheck is a provenance check with compiled allowed values
+type compiledProvenanceCheck struct {
+	kind    ViolationKind
+	oid     asn1.ObjectIdentifier
+	allowed []*regexp.Regexp
+}
+
+// compileProvenance compiles the allowed values of the checks of an expected
+// provenance that allow some values
+func compileProvenance(expected ExpectedProvenance) ([]compiledProvenanceCheck, error) {
+	var checks []compiledProvenanceCheck
+	for _, check := range expected.provenanceChecks() {
+		if len(check.allowed) == 0 {
+			continue
+		}
+		compiled := compiledProvenanceCheck{kind: check.kind, oid: check.oid}
+		for _, expr := range check.allowed {
+			regex, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("malformed expected provenance regex: %w", err)
+			}
+			compiled.allowed = append(compiled.allowed, regex)
+		}
+		checks = append(checks, compiled)
+	}
+	return checks, nil
+}
+
+func provenanceViolations[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate, checks []compiledProvenanceCheck) ([]Violation, error) {
+	violations := []Violation{}
+	for _, check := range checks {
+		var value string
+		var err error
+		if check.oid == nil {
+			value, err = getIssuer(cert)
+		} else {
+			value, err = getExtension(cert, check.oid)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting value for %s check: %w", check.kind, err)
+		}
+		allowed := value != "" && slices.ContainsFunc(check.allowed, func(regex *regexp.Regexp) bool {
+			return regex.MatchString(value)
+		})
+		if !allowed {
+			violations = append(violations, Violation{Kind: check.kind, Value: value})
+		}
+	}
+	return violations, nil
+}
+
+// CheckExpectedProvenance returns whether a match of a certificate identity is
+// reported, and how the certificate violates the expected provenance of the
+// identity. Matches of identities without an expected provenance are always
+// reported.
+func CheckExpectedProvenance[Certificate *x509.Certificate | *google_x509.Certificate](cert Certificate, certID CertificateIdentity) (bool, []Violation, error) {
+	if certID.ExpectedProvenance == nil {
+		return true, nil, nil
+	}
+	violations, err := ProvenanceViolations(cert, *certID.ExpectedProvenance)
+	if err != nil {
+		return false, nil, err
+	}
+	return len(violations) > 0, violations, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+)
+
+func mockProvenanceCertificate(t *testing.T, values map[string]string) *x509.Certificate {
+	t.Helper()
+	cert := &x509.Certificate{EmailAddresses: []string{"release@corp.com"}}
+	for oid, value := range values {
+		parsed, err := extensions.ParseObjectIdentifier(oid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extValue, err := asn1.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert.Extensions = append(cert.Extensions, pkix.Extension{Id: parsed, Value: extValue})
+	}
+	return cert
+}
+
+func TestProvenanceViolations(t *testing.T) {
+	githubIssuer := "https://token.actions.githubusercontent.com"
+	expected := ExpectedProvenance{
+		Issuers:            []string{`^https://token\.actions\.githubusercontent\.com$`},
+		Repositories:       []string{`^https://github\.com/corp/app$`},
+		WorkflowRefs:       []string{`^https://github\.com/corp/app/\.github/workflows/release\.yml@refs/tags/v.*$`},
+		RunnerEnvironments: []string{`^github-hosted$`},
+	}
+	expectedValues := map[string]string{
+		extensions.OIDIssuerV2.String():            githubIssuer,
+		extensions.OIDSourceRepositoryURI.String(): "https://github.com/corp/app",
+		extensions.OIDBuildSignerURI.String():      "https://github.com/corp/app/.github/workflows/release.yml@refs/tags/v1.0.0",
+		extensions.OIDRunnerEnvironment.String():   "github-hosted",
+	}
+	withValues := func(values map[string]string) map[string]string {
+		merged := map[string]string{}
+		for oid, value := range expectedValues {
+			merged[oid] = value
+		}
+		for oid, value := range values {
+			if value == "" {
+				delete(merged, oid)
+			} else {
+				merged[oid] = value
+			}
+		}
+		return merged
+	}
+
+	testCases := map[string]struct {
+		values     map[string]string
+		violations []Violation
+	}{
+		"expected provenance": {
+			values:     expectedValues,
+			violations: []Violation{},
+		},
+		"fork": {
+			values:     withValues(map[string]string{extensions.OIDSourceRepositoryURI.String(): "https://github.com/attacker/app"}),
+			violations: []Violation{{Kind: ViolationUnexpectedRepository, Value: "https://github.com/attacker/app"}},
+		},
+		"other workflow on a self-hosted runner": {
+			values: withValues(map[string]string{
+				extensions.OIDBuildSignerURI.String():    "https://github.com/corp/app/.github/workflows/test.yml@refs/heads/main",
+				extensions.OIDRunnerEnvironment.String(): "self-hosted",
+			}),
+			violations: []Violation{
+				{Kind: ViolationUnexpectedWorkflowRef, Value: "https://github.com/corp/app/.github/workflows/test.yml@refs/heads/main"},
+				{Kind: ViolationUnexpectedRunnerEnvironment, Value: "self-hosted"},
+			},
+		},
+		"other issuer without extensions": {
+			values: map[string]string{extensions.OIDIssuerV2.String(): "https://accounts.google.com"},
+			violations: []Violation{
+				{Kind: ViolationUnexpectedIssuer, Value: "https://accounts.google.com"},
+				{Kind: ViolationUnexpectedRepository},
+				{Kind: ViolationUnexpectedWorkflowRef},
+				{Kind: ViolationUnexpectedRunnerEnvironment},
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			violations, err := ProvenanceViolations(mockProvenanceCertificate(t, tc.values), expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(violations, tc.violations) {
+				t.Errorf("expected %v, received %v", tc.violations, violations)
+			}
+		})
+	}
+}
+
+func TestCheckExpectedProvenance(t *testing.T) {
+	cert := mockProvenanceCertificate(t, map[string]string{extensions.OIDRunnerEnvironment.String(): "self-hosted"})
+
+	report, violations, err := CheckExpectedProvenance(cert, CertificateIdentity{CertSubject: ".*"})
+	if err != nil || !report || violations != nil {
+		t.Errorf("expected identity without expected provenance to be reported, got %v %v %v", report, violations, err)
+	}
+
+	allowed := CertificateIdentity{CertSubject: ".*", ExpectedProvenance: &ExpectedProvenance{RunnerEnvironments: []string{"self-hosted"}}}
+	report, _, err = CheckExpectedProvenance(cert, allowed)
+	if err != nil || report {
+		t.Errorf("expected allowed provenance not to be reported, got %v %v", report, err)
+	}
+
+	disallowed := CertificateIdentity{CertSubject: ".*", ExpectedProvenance: &ExpectedProvenance{RunnerEnvironments: []string{"github-hosted"}}}
+	report, violations, err = CheckExpectedProvenance(cert, disallowed)
+	if err != nil || !report || !reflect.DeepEqual(violations, []Violation{{Kind: ViolationUnexpectedRunnerEnvironment, Value: "self-hosted"}}) {
+		t.Errorf("expected violation to be reported, got %v %v %v", report, violations, err)
+	}
+}
+
+func TestExpectedProvenanceValidate(t *testing.T) {
+	if err := (ExpectedProvenance{Repositories: []string{"^https://github.com/corp/.*$"}}).Validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := (ExpectedProvenance{}).Validate(); err == nil {
+		t.Errorf("expected error for empty expected provenance")
+	}
+	if err := (ExpectedProvenance{WorkflowRefs: []string{"("}}).Validate(); err == nil {
+		t.Errorf("expected error for invalid regex")
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	_ "embed"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+
+	google_x509 "github.com/google/certificate-transparency-go/x509"
+)
+
+// LogEntrySchemaVersion is the version of the JSON representation of log
+// entries, which is incremented on breaking changes
+const LogEntrySchemaVersion = "1"
+
+// LogEntryJSONSchema is the JSON Schema of the JSON representation of log entries
+//
+//go:embed log-entry.schema.json
+var LogEntryJSONSchema []byte
+
+// LogKind is the kind of transparency log of a log entry
+type LogKind string
+
+const (
+	LogKindRekorV1 LogKind = "rekorV1"
+	LogKindRekorV2 LogKind = "rekorV2"
+	LogKindCT      LogKind = "ct"
+)
+
+// LogEntryRecord is the JSON representation of a LogEntry, as described by
+// LogEntryJSONSchema. It is written to the identities file and sent by every
+// notification platform.
+type LogEntryRecord struct {
+	SchemaVersion       string              `json:"schemaVersion"`
+	MatchedIdentity     string              `json:"matchedIdentity"`
+	MatchedIdentityType MatchedIdentityType `json:"matchedIdentityType"`
+	MatchedRules        []MatchedRule       `json:"matchedRules,omitempty"`
+	LogOrigin           string              `json:"logOrigin,omitempty"`
+	LogKind             LogKind             `json:"logKind,omitempty"`
+	EntryKind           string              `json:"entryKind,omitempty"`
+	Index               int64               `json:"index"`
+	UUID                string              `json:"uuid,omitempty"`
+	IntegratedTime      time.Time           `json:"integratedTime,omitzero"`
+	CertSubject         string              `json:"certSubject,omitempty"`
+	Issuer              string              `json:"issuer,omitempty"`
+	CertificateSerial   string              `json:"certificateSerial,omitempty"`
+	Fingerprint         string              `json:"fingerprint,omitempty"`
+	Subject             string              `json:"subject,omitempty"`
+	OIDExtension        string              `json:"oidExtension,omitempty"`
+	ExtensionValue      string              `json:"extensionValue,omitempty"`
+	Certificate         string              `json:"certificate,omitempty"`
+	ArtifactDigest      string              `json:"artifactDigest,omitempty"`
+	PayloadType         string              `json:"payloadType,omitempty"`
+	SubjectDigests      []string            `json:"subjectDigests,omitempty"`
+	Violations          []Violation         `json:"violations,omitempty"`
+	DetectedAt          time.Time           `json:"detectedAt,omitzero"`
+}
+
+// Record returns the JSON representation of the log entry
+func (e LogEntry) Record() LogEntryRecord {
+	record := LogEntryRecord{
+		SchemaVersion:       LogEntrySchemaVersion,
+		MatchedIdentity:     e.MatchedIdentity,
+		MatchedIdentityType: e.MatchedIdentityType,
+		MatchedRules:        e.MatchedRules,
+		LogOrigin:           e.LogOrigin,
+		LogKind:             e.LogKind,
+		EntryKind:           e.EntryKind,
+		Index:               e.Index,
+		UUID:                e.UUID,
+		IntegratedTime:      e.IntegratedTime.UTC(),
+		CertSubject:         e.CertSubject,
+		Issuer:              e.Issuer,
+		CertificateSerial:   e.CertificateSerial,
+		Fingerprint:         e.Fingerprint,
+		Subject:             e.Subject,
+		ExtensionValue:      e.ExtensionValue,
+		Certificate:         e.Certificate,
+		ArtifactDigest:      e.ArtifactDigest,
+		PayloadType:         e.PayloadType,
+		SubjectDigests:      e.SubjectDigests,
+		Violations:          e.Violations,
+		DetectedAt:          e.DetectedAt.UTC(),
+	}
+	if len(e.OIDExtension) > 0 {
+		record.OIDExtension = e.OIDExtension.String()
+	}
+	return record
+}
+
+// MarshalJSON encodes the log entry as a LogEntryRecord
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Record())
+}
+
+// UnmarshalJSON decodes a LogEntryRecord. Log entries written before the
+// schema was versioned, with Go field names and OID extensions as arrays of
+// integers, are decoded as well.
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+	var record struct {
+		LogEntryRecord
+		OIDExtension json.RawMessage `json:"oidExtension"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	if record.SchemaVersion != "" && record.SchemaVersion != LogEntrySchemaVersion {
+		return fmt.Errorf("unsupported log entry schema version %s", record.SchemaVersion)
+	}
+	var oid asn1.ObjectIdentifier
+	if len(record.OIDExtension) > 0 && string(record.OIDExtension) != "null" {
+		var dotted string
+		if err := json.Unmarshal(record.OIDExtension, &dotted); err == nil {
+			if dotted != "" {
+				parsed, err := extensions.ParseObjectIdentifier(dotted)
+				if err != nil {
+					return fmt.Errorf("invalid oidExtension: %w", err)
+				}
+				oid = parsed
+			}
+		} else if err := json.Unmarshal(record.OIDExtension, &oid); err != nil {
+			return fmt.Errorf("invalid oidExtension: %w", err)
+		}
+	}
+	*e = LogEntry{
+		MatchedIdentity:     record.MatchedIdentity,
+		MatchedIdentityType: record.MatchedIdentityType,
+		MatchedRules:        record.MatchedRules,
+		LogOrigin:           record.LogOrigin,
+		LogKind:             record.LogKind,
+		EntryKind:           record.EntryKind,
+		Index:               record.Index,
+		UUID:                record.UUID,
+		IntegratedTime:      record.IntegratedTime,
+		CertSubject:         record.CertSubject,
+		Issuer:              record.Issuer,
+		CertificateSerial:   record.CertificateSerial,
+		Fingerprint:         record.Fingerprint,
+		Subject:             record.Subject,
+		OIDExtension:        oid,
+		ExtensionValue:      record.ExtensionValue,
+		Certificate:         record.Certificate,
+		ArtifactDigest:      record.ArtifactDigest,
+		PayloadType:         record.PayloadType,
+		SubjectDigests:      record.SubjectDigests,
+		Violations:          record.Violations,
+		DetectedAt:          record.DetectedAt,
+	}
+	return nil
+}
+
+// LogEntryColumns are the names of the columns of log entries in the text
+// format of the identities file, and in CSV attachments. The certificate is
+// only part of the JSON representation.
+var LogEntryColumns = []string{
+	"certSubject", "issuer", "fingerprint", "subject", "index", "uuid", "oidExtension", "extensionValue",
+	"matchedIdentityType", "matchedIdentity", "logOrigin", "logKind", "entryKind", "integratedTime",
+	"certificateSerial", "artifactDigest", "detectedAt", "payloadType", "subjectDigests", "matchedRules",
+	"violations",
+}
+
+// Columns returns the values of the LogEntryColumns of the log entry. Values
+// that aren't set are empty, subject digests are separated by spaces, and
+// matched rules and violations are formatted as type=value separated by commas.
+func (e LogEntry) Columns() []string {
+	record := e.Record()
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return []string{
+		record.CertSubject, record.Issuer, record.Fingerprint, record.Subject, strconv.FormatInt(record.Index, 10),
+		record.UUID, record.OIDExtension, record.ExtensionValue, string(record.MatchedIdentityType), record.MatchedIdentity,
+		record.LogOrigin, string(record.LogKind), record.EntryKind, formatTime(record.IntegratedTime),
+		record.CertificateSerial, record.ArtifactDigest, formatTime(record.DetectedAt), record.PayloadType,
+		strings.Join(record.SubjectDigests, " "), formatMatchedRules(record.MatchedRules),
+		formatViolations(record.Violations),
+	}
+}
+
+// EntryMetadata is the metadata of a log entry, shared by all matches of the entry
+type EntryMetadata struct {
+	LogKind        LogKind
+	EntryKind      string
+	IntegratedTime time.Time
+	ArtifactDigest string
+	PayloadType    string
+	SubjectDigests []string
+}
+
+// WithEntryMetadata sets the metadata of the log entry on its matches
+func WithEntryMetadata(matchedEntries []LogEntry, metadata EntryMetadata) []LogEntry {
+	for i := range matchedEntries {
+		matchedEntries[i].LogKind = metadata.LogKind
+		matchedEntries[i].EntryKind = metadata.EntryKind
+		matchedEntries[i].IntegratedTime = metadata.IntegratedTime
+		matchedEntries[i].ArtifactDigest = metadata.ArtifactDigest
+		matchedEntries[i].PayloadType = metadata.PayloadType
+		matchedEntries[i].SubjectDigests = metadata.SubjectDigests
+	}
+	return matchedEntries
+}
+
+// CertificateSerial returns the hex-encoded serial number of a certificate
+func CertificateSerial[Certificate *x509.Certificate | *google_x509.Certificate](certificate Certificate) string {
+	switch cert := any(certificate).(type) {
+	case *x509.Certificate:
+		if cert != nil && cert.SerialNumber != nil {
+			return cert.SerialNumber.Text(16)
+		}
+	case *google_x509.Certificate:
+		if cert != nil && cert.SerialNumber != nil {
+			return cert.SerialNumber.Text(16)
+		}
+	}
+	return ""
+}
+
+// CertificatePEM returns the PEM encoding of a certificate
+func CertificatePEM[Certificate *x509.Certificate | *google_x509.Certificate](certificate Certificate) string {
+	var raw []byte
+	switch cert := any(certificate).(type) {
+	case *x509.Certificate:
+		if cert != nil {
+			raw = cert.Raw
+		}
+	case *google_x509.Certificate:
+		if cert != nil {
+			raw = cert.Raw
+		}
+	}
+	if len(raw) == 0 {
+		return ""
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}))
+}
+
+// InTotoSubjectDigests returns the digests of the subjects of an in-toto
+// statement, prefixed with the name of the hash algorithm, e.g. sha256:abcdef
+func InTotoSubjectDigests(statement []byte) ([]string, error) {
+	var parsed struct {
+		Type    string `json:"_type"`
+		Subject []struct {
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+	}
+	if err := json.Unmarshal(statement, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid in-toto statement: %w", err)
+	}
+	if !strings.HasPrefix(parsed.Type, "https://in-toto.io/Statement/") {
+		return nil, fmt.Errorf("invalid in-toto statement type %s", parsed.Type)
+	}
+	var digests []string
+	for _, subject := range parsed.Subject {
+		algorithms := make([]string, 0, len(subject.Digest))
+		for algorithm := range subject.Digest {
+			algorithms = append(algorithms, algorithm)
+		}
+		sort.Strings(algorithms)
+		for _, algorithm := range algorithms {
+			digests = append(digests, algorithm+":"+strings.ToLower(subject.Digest[algorithm]))
+		}
+	}
+	return digests, nil
+}
+
+// SetDetected sets the origin of the searched log and the time of detection
+// on matched log entries
+func SetDetected(matchedEntries []LogEntry, logOrigin string, detectedAt time.Time) {
+	for i := range matchedEntries {
+		matchedEntries[i].LogOrigin = logOrigin
+		matchedEntries[i].DetectedAt = detectedAt
+	}
+}
+
+// textColumn returns the value of a column in the text format, where values
+// that aren't set are written as "-" so that columns don't shift
+func textColumn(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"encoding/asn1"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func recordTestEntry() LogEntry {
+	return LogEntry{
+		MatchedIdentity:     "test cert value",
+		MatchedIdentityType: MatchedIdentityTypeExtensionValue,
+		MatchedRules: []MatchedRule{
+			{MatchedIdentityType: MatchedIdentityTypeExtensionValue, MatchedIdentity: "test cert value", Value: "test cert value", OIDExtension: "1.3.6.1.4.1.57264.1.12"},
+			{MatchedIdentityType: MatchedIdentityTypeFingerprint, MatchedIdentity: "abcd", Value: "abcd"},
+		},
+		Index:             42,
+		UUID:              "test-uuid",
+		OIDExtension:      asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 12},
+		ExtensionValue:    "test cert value",
+		LogOrigin:         "rekor.sigstore.dev",
+		LogKind:           LogKindRekorV1,
+		EntryKind:         "hashedrekord",
+		IntegratedTime:    time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		CertificateSerial: "1a2b",
+		Certificate:       "-----BEGIN CERTIFICATE-----\nMA==\n-----END CERTIFICATE-----\n",
+		ArtifactDigest:    "sha256:abcd",
+		PayloadType:       "application/vnd.in-toto+json",
+		SubjectDigests:    []string{"sha256:ab", "sha256:cd"},
+		DetectedAt:        time.Date(2025, 1, 1, 10, 5, 0, 0, time.UTC),
+	}
+}
+
+func TestLogEntryJSON(t *testing.T) {
+	entry := recordTestEntry()
+	marshalled, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"schemaVersion":"1","matchedIdentity":"test cert value","matchedIdentityType":"extensionValue",` +
+		`"matchedRules":[{"matchedIdentityType":"extensionValue","matchedIdentity":"test cert value","value":"test cert value",` +
+		`"oidExtension":"1.3.6.1.4.1.57264.1.12"},{"matchedIdentityType":"fingerprint","matchedIdentity":"abcd","value":"abcd"}],` +
+		`"logOrigin":"rekor.sigstore.dev","logKind":"rekorV1","entryKind":"hashedrekord","index":42,"uuid":"test-uuid",` +
+		`"integratedTime":"2025-01-01T10:00:00Z","certificateSerial":"1a2b","oidExtension":"1.3.6.1.4.1.57264.1.12",` +
+		`"extensionValue":"test cert value","certificate":"-----BEGIN CERTIFICATE-----\nMA==\n-----END CERTIFICATE-----\n",` +
+		`"artifactDigest":"sha256:abcd","payloadType":"application/vnd.in-toto+json","subjectDigests":["sha256:ab","sha256:cd"],` +
+		`"detectedAt":"2025-01-01T10:05:00Z"}`
+	if string(marshalled) != want {
+		t.Errorf("expected %s, got %s", want, marshalled)
+	}
+
+	var decoded LogEntry
+	if err := json.Unmarshal(marshalled, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, entry) {
+		t.Errorf("expected %+v, got %+v", entry, decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"schemaVersion":"2","index":1}`), &decoded); err == nil || !strings.Contains(err.Error(), "unsupported log entry schema version 2") {
+		t.Errorf("expected unsupported schema version error, got %v", err)
+	}
+}
+
+func TestLogEntryUnmarshalJSONLegacy(t *testing.T) {
+	legacy := `{"MatchedIdentity":"test cert value","MatchedIdentityType":"extensionValue","CertSubject":"","Issuer":"","Fingerprint":"",` +
+		`"Subject":"","Index":42,"UUID":"test-uuid","OIDExtension":[1,3,6,1,4,1,57264,1,12],"ExtensionValue":"test cert value"}`
+	var decoded LogEntry
+	if err := json.Unmarshal([]byte(legacy), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	want := LogEntry{
+		MatchedIdentity:     "test cert value",
+		MatchedIdentityType: MatchedIdentityTypeExtensionValue,
+		Index:               42,
+		UUID:                "test-uuid",
+		OIDExtension:        asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 12},
+		ExtensionValue:      "test cert value",
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("expected %+v, got %+v", want, decoded)
+	}
+}
+
+// The JSON Schema must describe every field of LogEntryRecord
+func TestLogEntryJSONSchema(t *testing.T) {
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(LogEntryJSONSchema, &schema); err != nil {
+		t.Fatalf("invalid JSON Schema: %v", err)
+	}
+
+	var fields, required []string
+	recordType := reflect.TypeOf(LogEntryRecord{})
+	for i := 0; i < recordType.NumField(); i++ {
+		name, options, _ := strings.Cut(recordType.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+		if options == "" {
+			required = append(required, name)
+		}
+	}
+	properties := make([]string, 0, len(schema.Properties))
+	for property := range schema.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(fields)
+	sort.Strings(properties)
+	sort.Strings(required)
+	sort.Strings(schema.Required)
+	if !reflect.DeepEqual(fields, properties) {
+		t.Errorf("expected schema properties %v, got %v", fields, properties)
+	}
+	if !reflect.DeepEqual(required, schema.Required) {
+		t.Errorf("expected required schema properties %v, got %v", required, schema.Required)
+	}
+}
+
+func TestLogEntryColumns(t *testing.T) {
+	entry := recordTestEntry()
+	columns := entry.Columns()
+	if len(columns) != len(LogEntryColumns) {
+		t.Fatalf("expected %d columns, got %d", len(LogEntryColumns), len(columns))
+	}
+	if columns[4] != "42" || columns[6] != "1.3.6.1.4.1.57264.1.12" || columns[13] != "2025-01-01T10:00:00Z" || columns[18] != "sha256:ab sha256:cd" ||
+		columns[19] != "extensionValue=test cert value, fingerprint=abcd" {
+		t.Errorf("unexpected columns %v", columns)
+	}
+
+	// empty values don't shift the columns of the text format
+	entry.CertSubject = ""
+	entry.ExtensionValue = "value with\ttab"
+	fields := strings.Split(entry.String(), "\t")
+	if len(fields) != len(LogEntryColumns) || fields[0] != "-" || fields[7] != "value with tab" {
+		t.Errorf("unexpected text format %q", entry.String())
+	}
+}
+
+func TestInTotoSubjectDigests(t *testing.T) {
+	statement := `{"_type":"https://in-toto.io/Statement/v0.1","subject":[{"name":"a","digest":{"sha512":"CD","sha256":"ab"}},{"name":"b","digest":{"sha256":"ef"}}]}`
+	digests, err := InTotoSubjectDigests([]byte(statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sha256:ab", "sha512:cd", "sha256:ef"}; !reflect.DeepEqual(digests, want) {
+		t.Errorf("expected %v, got %v", want, digests)
+	}
+
+	if _, err := InTotoSubjectDigests([]byte(`{"_type":"https://example.com/Statement","subject":[]}`)); err == nil {
+		t.Errorf("expected error for statement type")
+	}
+	if _, err := InTotoSubjectDigests([]byte(`not json`)); err == nil {
+		t.Errorf("expected error for invalid statement")
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+
+	google_x509 "github.com/google/certificate-transparency-go/x509"
+)
+
+// Rule is a named condition on the identities of a log entry. Matches of the
+// rule are reported with the rule name as MatchedIdentity.
+type Rule struct {
+	Name string `yaml:"name"`
+	// Labels select notification routes for matches of this rule
+	Labels        []string `yaml:"labels"`
+	RuleCondition `yaml:",inline"`
+}
+
+// RuleCondition is a condition of a rule. Exactly one field is set: All, Any
+// and Not combine conditions, and the other fields are conditions on a
+// certificate of the log entry, or on the subjects and fingerprints of the
+// entry. Conditions on certificates must hold for the same certificate.
+type RuleCondition struct {
+	// All holds if all of its conditions hold
+	All []RuleCondition `yaml:"all"`
+	// Any holds if any of its conditions holds
+	Any []RuleCondition `yaml:"any"`
+	// Not holds if its condition doesn't hold
+	Not *RuleCondition `yaml:"not"`
+	// CertSubject is a regular expression matching a subject alternative name of the certificate
+	CertSubject string `yaml:"certSubject"`
+	// Issuer is a regular expression matching the OIDC issuer of the certificate
+	Issuer string `yaml:"issuer"`
+	// Extension matches the value of an extension of the certificate
+	Extension *ExtensionCondition `yaml:"extension"`
+	// Subject is a regular expression matching a subject that is not specified
+	// in a certificate, such as a SSH key or PGP key email address
+	Subject string `yaml:"subject"`
+	// Fingerprint is the fingerprint of a key or certificate of the entry
+	Fingerprint string `yaml:"fingerprint"`
+}
+
+// ExtensionCondition matches the value of a certificate extension. The
+// extension is decoded and matched like monitored OID extensions.
+type ExtensionCondition struct {
+	// Name is the name of a Fulcio extension, e.g. SourceRepositoryOwnerURI,
+	// or an ObjectIdentifier in dot notation
+	Name string `yaml:"name"`
+	// Value is the pattern matching the extension value
+	Value string `yaml:"value"`
+	// MatchMode is how Value is matched: exact, prefix, glob or regex.
+	// Defaults to regex.
+	MatchMode extensions.MatchMode `yaml:"matchMode"`
+	// Encoding is how the extension value is decoded, defaults to the
+	// default encoding of the extension
+	Encoding extensions.ExtensionEncoding `yaml:"encoding"`
+}
+
+// CompiledRules are rules with parsed extensions and compiled regular
+// expressions, to be evaluated on many log entries
+type CompiledRules []compiledRule
+
+type compiledRule struct {
+	name      string
+	condition compiledCondition
+}
+
+type compiledCondition struct {
+	all         []compiledCondition
+	any         []compiledCondition
+	not         *compiledCondition
+	certSubject *regexp.Regexp
+	issuer      *regexp.Regexp
+	extension   *compiledOIDMatcher
+	subject     *regexp.Regexp
+	fingerprint string
+}
+
+// CompileRules validates rules and compiles them for evaluation
+func CompileRules(rules []Rule) (CompiledRules, error) {
+	compiled := make(CompiledRules, 0, len(rules))
+	names := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, errors.New("rule name empty")
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule name %s", rule.Name)
+		}
+		names[rule.Name] = true
+		condition, err := compileCondition(rule.RuleCondition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %s: %w", rule.Name, err)
+		}
+		compiled = append(compiled, compiledRule{name: rule.Name, condition: condition})
+	}
+	return compiled, nil
+}
+
+func compileCondition(condition RuleCondition) (compiledCondition, error) {
+	var compiled compiledCondition
+	set := 0
+	compileRegex := func(name, expr string) (*regexp.Regexp, error) {
+		set++
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s regex %s: %w", name, expr, err)
+		}
+		return regex, nil
+	}
+	var err error
+	if len(condition.All) > 0 {
+		set++
+		for _, c := range condition.All {
+			child, err := compileCondition(c)
+			if err != nil {
+				return compiled, err
+			}
+			compiled.all = append(compiled.all, child)
+		}
+	}
+	if len(condition.Any) > 0 {
+		set++
+		for _, c := range condition.Any {
+			child, err := compileCondition(c)
+			if err != nil {
+				return compiled, err
+			}
+			compiled.any = append(compiled.any, child)
+		}
+	}
+	if condition.Not != nil {
+		set++
+		child, err := compileCondition(*condition.Not)
+		if err != nil {
+			return compiled, err
+		}
+		compiled.not = &child
+	}
+	if condition.CertSubject != "" {
+		if compiled.certSubject, err = compileRegex("certSubject", condition.CertSubject); err != nil {
+			return compiled, err
+		}
+	}
+	if condition.Issuer != "" {
+		if compiled.issuer, err = compileRegex("issuer", condition.Issuer); err != nil {
+			return compiled, err
+		}
+	}
+	if condition.Extension != nil {
+		set++
+		if compiled.extension, err = compileExtensionCondition(*condition.Extension); err != nil {
+			return compiled, err
+		}
+	}
+	if condition.Subject != "" {
+		if compiled.subject, err = compileRegex("subject", condition.Subject); err != nil {
+			return compiled, err
+		}
+	}
+	if condition.Fingerprint != "" {
+		set++
+		compiled.fingerprint = condition.Fingerprint
+	}
+	if set != 1 {
+		return compiled, errors.New("condition must set exactly one of all, any, not, certSubject, issuer, extension, subject or fingerprint")
+	}
+	return compiled, nil
+}
+
+// compileExtensionCondition resolves the extension and encoding of an extension
+// condition and parses its value matcher
+func compileExtensionCondition(condition ExtensionCondition) (*compiledOIDMatcher, error) {
+	oid, err := extensions.ParseExtension(condition.Name)
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := extensions.ResolveExtensionEncoding(condition.Encoding, oid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoding of extension %s: %w", condition.Name, err)
+	}
+	mode := condition.MatchMode
+	if mode == "" {
+		mode = extensions.MatchModeRegex
+	}
+	matcher, err := extensions.NewValueMatcher(mode, condition.Value, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s of extension %s: %w", condition.Value, condition.Name, err)
+	}
+	return &compiledOIDMatcher{
+		oid:      oid,
+		encoding: encoding,
+		values:   []string{condition.Value},
+		matchers: []extensions.ValueMatcher{matcher},
+	}, nil
+}
+
+// RuleEntry holds the identities of a log entry that rules are evaluated on
+type RuleEntry struct {
+	certificates []ruleCertificate
+	subjects     []string
+	fingerprints []string
+}
+
+type ruleCertificate struct {
+	sans      []string
+	issuer    string
+	issuerErr error
+	// extension returns the DER-encoded value of an extension, and whether
+	// the certificate has the extension
+	extension func(oid asn1.ObjectIdentifier) ([]byte, bool, error)
+	serial    string
+	// pem returns the PEM encoding of the certificate, which is only needed
+	// for matches
+	pem func() string
+}
+
+// NewRuleEntry returns the identities of a log entry from its certificates,
+// subjects and key fingerprints
+func NewRuleEntry[Certificate *x509.Certificate | *google_x509.Certificate](certificates []Certificate, subjects []string, fingerprints []string) RuleEntry {
+	entry := RuleEntry{subjects: subjects, fingerprints: fingerprints}
+	for _, cert := range certificates {
+		issuer, err := getIssuer(cert)
+		entry.certificates = append(entry.certificates, ruleCertificate{
+			sans:      getSubjectAlternateNames(cert),
+			issuer:    issuer,
+			issuerErr: err,
+			extension: func(oid asn1.ObjectIdentifier) ([]byte, bool, error) {
+				return getRawExtension(cert, oid)
+			},
+			serial: CertificateSerial(cert),
+			pem: func() string {
+				return CertificatePEM(cert)
+			},
+		})
+	}
+	return entry
+}
+
+// ruleEvidence holds the values of a log entry that a rule matched on
+type ruleEvidence struct {
+	certSubject    string
+	subject        string
+	fingerprint    string
+	oidExtension   asn1.ObjectIdentifier
+	extensionValue string
+}
+
+// merge sets the values of the evidence that aren't set yet
+func (e *ruleEvidence) merge(other ruleEvidence) {
+	if e.certSubject == "" {
+		e.certSubject = other.certSubject
+	}
+	if e.subject == "" {
+		e.subject = other.subject
+	}
+	if e.fingerprint == "" {
+		e.fingerprint = other.fingerprint
+	}
+	if e.extensionValue == "" {
+		e.oidExtension = other.oidExtension
+		e.extensionValue = other.extensionValue
+	}
+}
+
+// Match evaluates the rules on a log entry, and returns a match for every rule
+// that holds. Conditions on certificates are evaluated on each certificate of
+// the entry, and the match holds the first certificate the rule holds for.
+func (rules CompiledRules) Match(entry RuleEntry) ([]LogEntry, error) {
+	// entries without certificates are evaluated once, with conditions on
+	// certificates not holding
+	certificates := entry.certificates
+	if len(certificates) == 0 {
+		certificates = []ruleCertificate{{}}
+	}
+	matchedEntries := []LogEntry{}
+	for _, rule := range rules {
+		for _, cert := range certificates {
+			var evidence ruleEvidence
+			holds, err := rule.condition.eval(entry, cert, &evidence)
+			if err != nil {
+				return nil, fmt.Errorf("error evaluating rule %s: %w", rule.name, err)
+			}
+			if !holds {
+				continue
+			}
+			match := LogEntry{
+				MatchedIdentity:     rule.name,
+				MatchedIdentityType: MatchedIdentityTypeRule,
+				CertSubject:         evidence.certSubject,
+				Issuer:              cert.issuer,
+				CertificateSerial:   cert.serial,
+				Subject:             evidence.subject,
+				Fingerprint:         evidence.fingerprint,
+				OIDExtension:        evidence.oidExtension,
+				ExtensionValue:      evidence.extensionValue,
+			}
+			if cert.pem != nil {
+				match.Certificate = cert.pem()
+			}
+			matchedEntries = append(matchedEntries, match)
+			break
+		}
+	}
+	return matchedEntries, nil
+}
+
+func (c compiledCondition) eval(entry RuleEntry, cert ruleCertificate, evidence *ruleEvidence) (bool, error) {
+	switch {
+	case len(c.all) > 0:
+		var all ruleEvidence
+		for _, child := range c.all {
+			var childEvidence ruleEvidence
+			holds, err := child.eval(entry, cert, &childEvidence)
+			if err != nil || !holds {
+				return false, err
+			}
+			all.merge(childEvidence)
+		}
+		evidence.merge(all)
+		return true, nil
+	case len(c.any) > 0:
+		for _, child := range c.any {
+			var childEvidence ruleEvidence
+			holds, err := child.eval(entry, cert, &childEvidence)
+			if err != nil {
+				return false, err
+			}
+			if holds {
+				evidence.merge(childEvidence)
+				return true, nil
+			}
+		}
+		return false, nil
+	case c.not != nil:
+		// values matched under a negation are not evidence of the match
+		holds, err := c.not.eval(entry, cert, &ruleEvidence{})
+		return !holds, err
+	case c.certSubject != nil:
+		for _, san := range cert.sans {
+			if c.certSubject.MatchString(san) {
+				evidence.certSubject = san
+				return true, nil
+			}
+		}
+		return false, nil
+	case c.issuer != nil:
+		if cert.issuerErr != nil {
+			return false, fmt.Errorf("error getting issuer: %w", cert.issuerErr)
+		}
+		return cert.issuer != "" && c.issuer.MatchString(cert.issuer), nil
+	case c.extension != nil:
+		if cert.extension == nil {
+			return false, nil
+		}
+		rawValue, ok, err := cert.extension(c.extension.oid)
+		if err != nil {
+			return false, fmt.Errorf("error getting extension value: %w", err)
+		}
+		if !ok {
+			return false, nil
+		}
+		matched, value, err := c.extension.match(rawValue)
+		if err != nil || matched == "" {
+			return false, err
+		}
+		evidence.oidExtension = c.extension.oid
+		evidence.extensionValue = value
+		return true, nil
+	case c.subject != nil:
+		for _, subject := range entry.subjects {
+			if c.subject.MatchString(subject) {
+				evidence.subject = subject
+				return true, nil
+			}
+		}
+		return false, nil
+	case c.fingerprint != "":
+		if slices.Contains(entry.fingerprints, c.fingerprint) {
+			evidence.fingerprint = c.fingerprint
+			return true, nil
+		}
+		return false, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,266 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	ruleTestOIDCIssuer = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	ruleTestOwnerURI   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 16}
+)
+
+const outsideContributorRule = `
+name: outside-contributor
+labels: [security]
+all:
+  - certSubject: .*@corp.com
+  - issuer: ^https://token.actions.githubusercontent.com$
+  - not:
+      extension:
+        name: SourceRepositoryOwnerURI
+        value: ^https://github.com/corp$
+`
+
+func mockRuleCertificate(t testing.TB, san, issuer, ownerURI string) *x509.Certificate {
+	t.Helper()
+	cert := &x509.Certificate{EmailAddresses: []string{san}}
+	for _, ext := range []struct {
+		oid   asn1.ObjectIdentifier
+		value string
+	}{{ruleTestOIDCIssuer, issuer}, {ruleTestOwnerURI, ownerURI}} {
+		if ext.value == "" {
+			continue
+		}
+		extValue, err := asn1.Marshal(ext.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert.Extensions = append(cert.Extensions, pkix.Extension{Id: ext.oid, Value: extValue})
+	}
+	return cert
+}
+
+func TestRuleMatch(t *testing.T) {
+	var rule Rule
+	if err := yaml.Unmarshal([]byte(outsideContributorRule), &rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Name != "outside-contributor" || !reflect.DeepEqual(rule.Labels, []string{"security"}) || len(rule.All) != 3 {
+		t.Fatalf("unexpected rule %+v", rule)
+	}
+	rules, err := CompileRules([]Rule{rule})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	githubIssuer := "https://token.actions.githubusercontent.com"
+	testCases := map[string]struct {
+		certificates []*x509.Certificate
+		expected     []LogEntry
+	}{
+		"outside repository": {
+			certificates: []*x509.Certificate{mockRuleCertificate(t, "dev@corp.com", githubIssuer, "https://github.com/other")},
+			expected: []LogEntry{{
+				MatchedIdentity:     "outside-contributor",
+				MatchedIdentityType: MatchedIdentityTypeRule,
+				CertSubject:         "dev@corp.com",
+				Issuer:              githubIssuer,
+			}},
+		},
+		"missing owner extension": {
+			certificates: []*x509.Certificate{mockRuleCertificate(t, "dev@corp.com", githubIssuer, "")},
+			expected: []LogEntry{{
+				MatchedIdentity:     "outside-contributor",
+				MatchedIdentityType: MatchedIdentityTypeRule,
+				CertSubject:         "dev@corp.com",
+				Issuer:              githubIssuer,
+			}},
+		},
+		"own repository": {
+			certificates: []*x509.Certificate{mockRuleCertificate(t, "dev@corp.com", githubIssuer, "https://github.com/corp")},
+			expected:     []LogEntry{},
+		},
+		"other issuer": {
+			certificates: []*x509.Certificate{mockRuleCertificate(t, "dev@corp.com", "https://accounts.google.com", "https://github.com/other")},
+			expected:     []LogEntry{},
+		},
+		"conditions hold for different certificates": {
+			certificates: []*x509.Certificate{
+				mockRuleCertificate(t, "dev@corp.com", "https://accounts.google.com", ""),
+				mockRuleCertificate(t, "dev@example.com", githubIssuer, "https://github.com/other"),
+			},
+			expected: []LogEntry{},
+		},
+		"no certificates": {
+			expected: []LogEntry{},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			matches, err := rules.Match(NewRuleEntry(tc.certificates, nil, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(matches, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, matches)
+			}
+		})
+	}
+}
+
+func TestRuleMatchSubjectsAndFingerprints(t *testing.T) {
+	rules, err := CompileRules([]Rule{{
+		Name: "ssh-key",
+		RuleCondition: RuleCondition{Any: []RuleCondition{
+			{Fingerprint: "abcd"},
+			{All: []RuleCondition{{Subject: ".*@example.com"}, {Not: &RuleCondition{Fingerprint: "1234"}}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := rules.Match(NewRuleEntry[*x509.Certificate](nil, []string{"user@example.com"}, []string{"5678"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []LogEntry{{MatchedIdentity: "ssh-key", MatchedIdentityType: MatchedIdentityTypeRule, Subject: "user@example.com"}}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("expected %+v, got %+v", expected, matches)
+	}
+
+	matches, err = rules.Match(NewRuleEntry[*x509.Certificate](nil, []string{"user@example.com"}, []string{"1234"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no matches, got %+v", matches)
+	}
+
+	matches, err = rules.Match(NewRuleEntry[*x509.Certificate](nil, nil, []string{"abcd"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []LogEntry{{MatchedIdentity: "ssh-key", MatchedIdentityType: MatchedIdentityTypeRule, Fingerprint: "abcd"}}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("expected %+v, got %+v", expected, matches)
+	}
+}
+
+func TestRuleMatchExtensionMatchMode(t *testing.T) {
+	rules, err := CompileRules([]Rule{{
+		Name: "corp-repository",
+		RuleCondition: RuleCondition{Extension: &ExtensionCondition{
+			Name:      "SourceRepositoryOwnerURI",
+			Value:     "https://github.com/corp*",
+			MatchMode: extensions.MatchModeGlob,
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	githubIssuer := "https://token.actions.githubusercontent.com"
+	matches, err := rules.Match(NewRuleEntry([]*x509.Certificate{mockRuleCertificate(t, "dev@corp.com", githubIssuer, "https://github.com/corp-tools")}, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []LogEntry{{
+		MatchedIdentity:     "corp-repository",
+		MatchedIdentityType: MatchedIdentityTypeRule,
+		Issuer:              githubIssuer,
+		OIDExtension:        ruleTestOwnerURI,
+		ExtensionValue:      "https://github.com/corp-tools",
+	}}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("expected %+v, got %+v", expected, matches)
+	}
+
+	matches, err = rules.Match(NewRuleEntry([]*x509.Certificate{mockRuleCertificate(t, "dev@corp.com", githubIssuer, "https://gitlab.com/corp")}, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no matches, got %+v", matches)
+	}
+}
+
+func TestCompileRules(t *testing.T) {
+	testCases := map[string]struct {
+		rules []Rule
+		err   string
+	}{
+		"valid": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Extension: &ExtensionCondition{Name: "1.3.6.1.4.1.57264.1.16", Value: ".*"}}}},
+		},
+		"empty name": {
+			rules: []Rule{{RuleCondition: RuleCondition{Subject: ".*"}}},
+			err:   "rule name empty",
+		},
+		"duplicate name": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Subject: ".*"}}, {Name: "a", RuleCondition: RuleCondition{Fingerprint: "abcd"}}},
+			err:   "duplicate rule name a",
+		},
+		"no condition": {
+			rules: []Rule{{Name: "a"}},
+			err:   "condition must set exactly one of",
+		},
+		"several conditions": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Subject: ".*", Fingerprint: "abcd"}}},
+			err:   "condition must set exactly one of",
+		},
+		"invalid nested regex": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Not: &RuleCondition{Issuer: "("}}}},
+			err:   "invalid issuer regex",
+		},
+		"unknown extension": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Extension: &ExtensionCondition{Name: "NotAnExtension", Value: ".*"}}}},
+			err:   "invalid extension NotAnExtension",
+		},
+		"invalid extension match mode": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Extension: &ExtensionCondition{Name: "SourceRepositoryOwnerURI", Value: "x", MatchMode: "suffix"}}}},
+			err:   "invalid match mode suffix",
+		},
+		"invalid extension encoding": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Extension: &ExtensionCondition{Name: "SourceRepositoryOwnerURI", Value: "x", Encoding: "base64"}}}},
+			err:   "invalid encoding of extension SourceRepositoryOwnerURI",
+		},
+		"invalid extension regex": {
+			rules: []Rule{{Name: "a", RuleCondition: RuleCondition{Extension: &ExtensionCondition{Name: "SourceRepositoryOwnerURI", Value: "("}}}},
+			err:   "invalid value ( of extension SourceRepositoryOwnerURI",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := CompileRules(tc.rules)
+			if tc.err == "" && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+// defaultRekorLogEntryURL links to a Rekor log entry on the Sigstore search UI
+const defaultRekorLogEntryURL = "https://search.sigstore.dev/?logIndex={index}"
+
+// chatField is a named value of a chat message item
+type chatField struct {
+	Name  string
+	Value string
+}
+
+// chatItem is a single log entry rendered by the chat notification platforms
+type chatItem struct {
+	Title  string
+	Fields []chatField
+	Link   string
+}
+
+// chatMessage is the platform independent content of a chat notification
+type chatMessage struct {
+	Title   string
+	Summary string
+	Items   []chatItem
+	// Body holds the raw notification body for payloads without structured items
+	Body string
+}
+
+// logEntryLink returns the link to a log entry. The template may contain the
+// {index} and {uuid} placeholders. Without a template, Rekor entries link to
+// the Sigstore search UI and other entries are not linked.
+func logEntryLink(template string, monitorType string, index int64, uuid string) string {
+	if template == "" {
+		if monitorType != "rekor-monitor" {
+			return ""
+		}
+		template = defaultRekorLogEntryURL
+	}
+	return strings.NewReplacer("{index}", strconv.FormatInt(index, 10), "{uuid}", uuid).Replace(template)
+}
+
+// appendField appends a field to the item if its value is set
+func (item *chatItem) appendField(name, value string) {
+	if value != "" {
+		item.Fields = append(item.Fields, chatField{Name: name, Value: value})
+	}
+}
+
+// newChatMessage converts notification data to the content of a chat message
+func newChatMessage(data NotificationData, logEntryURL string) (chatMessage, error) {
+	message := chatMessage{
+		Title:   data.Context.Subject,
+		Summary: data.Payload.ToNotificationHeader(),
+	}
+	switch p := data.Payload.(type) {
+	case identity.MonitoredIdentityList:
+		for _, monitoredIdentity := range p {
+			for _, entry := range monitoredIdentity.FoundIdentityEntries {
+				item := chatItem{
+					Title: monitoredIdentity.Identity,
+					Link:  logEntryLink(logEntryURL, data.Context.MonitorType, entry.Index, entry.UUID),
+				}
+				item.appendField("Log index", strconv.FormatInt(entry.Index, 10))
+				item.appendField("UUID", entry.UUID)
+				item.appendField("Certificate subject", entry.CertSubject)
+				item.appendField("Issuer", entry.Issuer)
+				item.appendField("Fingerprint", entry.Fingerprint)
+				item.appendField("Subject", entry.Subject)
+				if len(entry.OIDExtension) > 0 {
+					item.appendField("OID extension", entry.OIDExtension.String())
+				}
+				item.appendField("Extension value", entry.ExtensionValue)
+				item.appendField("Certificate serial", entry.CertificateSerial)
+				item.appendField("Entry kind", entry.EntryKind)
+				item.appendField("Artifact digest", entry.ArtifactDigest)
+				item.appendField("Payload type", entry.PayloadType)
+				item.appendField("Subject digests", strings.Join(entry.SubjectDigests, ", "))
+				var rules []string
+				for _, rule := range entry.MatchedRules {
+					rules = append(rules, rule.String())
+				}
+				item.appendField("Matched rules", strings.Join(rules, ", "))
+				var violations []string
+				for _, violation := range entry.Violations {
+					violations = append(violations, violation.String())
+				}
+				item.appendField("Provenance violations", strings.Join(violations, ", "))
+				message.Items = append(message.Items, item)
+			}
+		}
+	case identity.FailedLogEntryList:
+		for _, failedEntry := range p {
+			item := chatItem{
+				Title: "Failed log entry",
+				Link:  logEntryLink(logEntryURL, data.Context.MonitorType, failedEntry.Index, failedEntry.UUID),
+			}
+			item.appendField("Log index", strconv.FormatInt(failedEntry.Index, 10))
+			item.appendField("UUID", failedEntry.UUID)
+			item.appendField("Error", failedEntry.Error)
+			message.Items = append(message.Items, item)
+		}
+	default:
+		body, err := data.Payload.ToNotificationBody()
+		if err != nil {
+			return chatMessage{}, err
+		}
+		message.Body = string(body)
+	}
+	return message, nil
+}
+
+// split splits the message into messages with at most maxItems items each.
+// The summary of every part notes its position.
+func (m chatMessage) split(maxItems int) []chatMessage {
+	if maxItems <= 0 || len(m.Items) <= maxItems {
+		return []chatMessage{m}
+	}
+	parts := (len(m.Items) + maxItems - 1) / maxItems
+	messages := make([]chatMessage, 0, parts)
+	for i := 0; i < parts; i++ {
+		part := m
+		part.Items = m.Items[i*maxItems : min((i+1)*maxItems, len(m.Items))]
+		part.Summary = m.Summary + " (part " + strconv.Itoa(i+1) + " of " + strconv.Itoa(parts) + ")"
+		messages = append(messages, part)
+	}
+	return messages
+}
+
+// truncate shortens a string to at most n bytes, respecting UTF-8 boundaries
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	const ellipsis = "…"
+	s = strings.ToValidUTF8(s[:n-len(ellipsis)], "")
+	return s + ellipsis
+}
@@ -16,7 +16,7 @@ package notifications
 
 import (
 	"context"
-	"strings"
+	"fmt"
 
 	"github.com/wneessen/go-mail"
 )
@@ -30,34 +30,34 @@ type EmailNotificationInput struct {
 	SenderSMTPPassword    string        `yaml:"senderSMTPPassword"`
 	SMTPHostURL           string        `yaml:"SMTPHostURL"`
 	SMTPCustomOptions     []mail.Option `yaml:"SMTPCustomOptions"`
+	NotificationTemplates `yaml:",inline"`
+	EmailOptions          `yaml:",inline"`
 }
 
-// generateEmailBody generates email body for generic notification data
-func generateEmailBody(data NotificationData) (string, error) {
-	header := data.Payload.ToNotificationHeader()
-	body, err := data.Payload.ToNotificationBody()
-	if err != nil {
-		return "", err
+// generateEmailBody generates the HTML email body for notification data,
+// with the configured body template or DefaultEmailBodyTemplate
+func generateEmailBody(templates NotificationTemplates, data NotificationData) (string, error) {
+	return templates.renderBody(data, DefaultEmailBodyTemplate, true)
+}
+
+// Validate checks the email recipients, templates and signing keys
+func (emailNotificationInput EmailNotificationInput) Validate() error {
+	if err := emailNotificationInput.NotificationTemplates.validate("emailNotificationSMTP", true); err != nil {
+		return err
 	}
-	return strings.Join([]string{header, "<pre>" + string(body) + "</pre>"}, "\n"), nil
+	return emailNotificationInput.EmailOptions.validate("emailNotificationSMTP", emailNotificationInput.RecipientEmailAddress)
 }
 
 // Send implements the NotificationPlatform interface
 func (emailNotificationInput EmailNotificationInput) Send(ctx context.Context, data NotificationData) error {
-	email := mail.NewMsg()
-	if err := email.From(emailNotificationInput.SenderEmailAddress); err != nil {
-		return err
-	}
-	if err := email.To(emailNotificationInput.RecipientEmailAddress); err != nil {
+	content, err := emailNotificationInput.content(emailNotificationInput.NotificationTemplates, data)
+	if err != nil {
 		return err
 	}
-	emailSubject := data.Context.Subject
-	email.Subject(emailSubject)
-	emailBody, err := generateEmailBody(data)
+	email, err := emailNotificationInput.newEmailMessage(emailNotificationInput.SenderEmailAddress, emailNotificationInput.RecipientEmailAddress, content)
 	if err != nil {
 		return err
 	}
-	email.SetBodyString(mail.TypeTextHTML, emailBody)
 	var client *mail.Client
 	defaultOpts := []mail.Option{
 		mail.WithUsername(emailNotificationInput.SenderSMTPUsername),
@@ -68,7 +68,48 @@ func (emailNotificationInput EmailNotificationInput) Send(ctx context.Context, d
 	if err != nil {
 		return err
 	}
-	err = client.DialAndSendWithContext(ctx, email)
+	return emailNotificationInput.sendSMTP(ctx, client, emailNotificationInput.SenderEmailAddress, emailNotificationInput.RecipientEmailAddress, email)
+}
+
+// sendSMTP sends an email through the SMTP server of the client
+func (o EmailOptions) sendSMTP(ctx context.Context, client *mail.Client, from, recipientEmailAddress string, email *mail.Msg) error {
+	if o.pgpSigned() {
+		// go-mail can't sign with PGP, so the signed message is sent as is
+		message, err := o.rawEmailMessage(email)
+		if err != nil {
+			return err
+		}
+		return sendRawEmail(ctx, client, from, o.envelopeRecipients(recipientEmailAddress), message)
+	}
+	err := client.DialAndSendWithContext(ctx, email)
 	client.Close()
 	return err
 }
+
+// sendRawEmail sends a MIME encoded email through the SMTP server of the client
+func sendRawEmail(ctx context.Context, client *mail.Client, from string, recipients []string, message []byte) error {
+	smtpClient, err := client.DialToSMTPClientWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.CloseWithSMTPClient(smtpClient) //nolint:errcheck
+	if err := smtpClient.Mail(from); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	for _, recipient := range recipients {
+		if err := smtpClient.Rcpt(recipient); err != nil {
+			return fmt.Errorf("error sending email to %s: %w", recipient, err)
+		}
+	}
+	w, err := smtpClient.Data()
+	if err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return nil
+}
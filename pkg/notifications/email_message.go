@@ -0,0 +1,418 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	netmail "net/mail"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/wneessen/go-mail"
+)
+
+const (
+	// DefaultEmailTextBodyTemplate renders the plaintext alternative of the HTML email body
+	DefaultEmailTextBodyTemplate = "{{.Header}}\n\n{{.Body}}"
+	// DefaultEmailAttachmentThreshold is the size in bytes of the JSON payload
+	// above which the log entries are attached to emails instead of inlined
+	DefaultEmailAttachmentThreshold = 100 * 1024
+
+	emailAttachmentFormatJSON = "json"
+	emailAttachmentFormatCSV  = "csv"
+)
+
+// EmailOptions configures the recipients, the content and the signing of
+// emails, for all email notification platforms
+type EmailOptions struct {
+	// To, Cc and Bcc are recipients in addition to RecipientEmailAddress
+	To  []string `yaml:"to"`
+	Cc  []string `yaml:"cc"`
+	Bcc []string `yaml:"bcc"`
+	// TextBodyTemplate is the text/template of the plaintext alternative of
+	// the HTML body, defaults to DefaultEmailTextBodyTemplate
+	TextBodyTemplate string `yaml:"textBodyTemplate"`
+	// AttachmentThreshold is the size in bytes of the JSON payload above which
+	// the log entries are attached instead of inlined in the body, defaults to
+	// DefaultEmailAttachmentThreshold. Log entries are never attached if negative.
+	AttachmentThreshold int `yaml:"attachmentThreshold"`
+	// AttachmentFormat of the attached log entries, json (default) or csv
+	AttachmentFormat string `yaml:"attachmentFormat"`
+	// Signing signs emails with S/MIME or PGP, so that recipients can verify
+	// that they were sent by the monitor
+	Signing *EmailSigning `yaml:"signing"`
+}
+
+// EmailSigning configures either an S/MIME certificate and private key, or a
+// PGP private key to sign emails with
+type EmailSigning struct {
+	// SMIMECertificateFile is a PEM certificate chain, starting with the signing certificate
+	SMIMECertificateFile string `yaml:"smimeCertificateFile"`
+	// SMIMEKeyFile is the PEM private key of the signing certificate
+	SMIMEKeyFile string `yaml:"smimeKeyFile"`
+	// PGPKeyFile is an ASCII-armored PGP private key
+	PGPKeyFile string `yaml:"pgpKeyFile"`
+	// PGPPassphrase decrypts the PGP private key, if it is encrypted
+	PGPPassphrase string `yaml:"pgpPassphrase"`
+}
+
+// emailAttachment is a file attached to an email
+type emailAttachment struct {
+	name        string
+	contentType string
+	content     []byte
+}
+
+// emailContent is the rendered subject and bodies of an email
+type emailContent struct {
+	subject    string
+	text       string
+	html       string
+	attachment *emailAttachment
+}
+
+// to returns the To recipients of emails
+func (o EmailOptions) to(recipientEmailAddress string) []string {
+	to := []string{}
+	if recipientEmailAddress != "" {
+		to = append(to, recipientEmailAddress)
+	}
+	return append(to, o.To...)
+}
+
+// envelopeRecipients returns all recipients of emails, including Bcc
+func (o EmailOptions) envelopeRecipients(recipientEmailAddress string) []string {
+	return slices.Concat(o.to(recipientEmailAddress), o.Cc, o.Bcc)
+}
+
+func (o *EmailOptions) secretFields() []secretField {
+	if o.Signing == nil {
+		return nil
+	}
+	return []secretField{{"signing.pgpPassphrase", &o.Signing.PGPPassphrase}}
+}
+
+// validate checks the recipients, the plaintext body template, the attachment
+// format and the signing keys of emails
+func (o EmailOptions) validate(platform string, recipientEmailAddress string) error {
+	for _, address := range o.envelopeRecipients(recipientEmailAddress) {
+		if _, err := netmail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid %s recipient %s: %w", platform, address, err)
+		}
+	}
+	if o.TextBodyTemplate != "" {
+		for _, sample := range templateSamples() {
+			if _, err := renderTemplate("text body", o.TextBodyTemplate, false, sample); err != nil {
+				return fmt.Errorf("invalid %s textBodyTemplate: %w", platform, err)
+			}
+		}
+	}
+	switch o.AttachmentFormat {
+	case "", emailAttachmentFormatJSON, emailAttachmentFormatCSV:
+	default:
+		return fmt.Errorf("invalid %s attachmentFormat %s: must be 'json' or 'csv'", platform, o.AttachmentFormat)
+	}
+	if o.Signing != nil {
+		if err := o.Signing.validate(); err != nil {
+			return fmt.Errorf("invalid %s signing: %w", platform, err)
+		}
+	}
+	return nil
+}
+
+// content renders the subject, the HTML and plaintext bodies and the
+// attachment of an email. If the log entries are attached, the Body of the
+// templates is a short reference to the attachment.
+func (o EmailOptions) content(templates NotificationTemplates, data NotificationData) (emailContent, error) {
+	subject, err := templates.renderSubject(data)
+	if err != nil {
+		return emailContent{}, err
+	}
+	templateData, err := NewTemplateData(data)
+	if err != nil {
+		return emailContent{}, err
+	}
+	attachment, err := o.attachment(templateData)
+	if err != nil {
+		return emailContent{}, err
+	}
+	if attachment != nil {
+		templateData.Body = fmt.Sprintf("The %d log entries are attached as %s.", len(templateData.Entries)+len(templateData.FailedEntries), attachment.name)
+	}
+	htmlTemplate, escape := templates.BodyTemplate, true
+	if htmlTemplate == "" {
+		htmlTemplate, escape = DefaultEmailBodyTemplate, false
+	}
+	html, err := executeTemplate("body", htmlTemplate, escape, templateData)
+	if err != nil {
+		return emailContent{}, err
+	}
+	textTemplate := o.TextBodyTemplate
+	if textTemplate == "" {
+		textTemplate = DefaultEmailTextBodyTemplate
+	}
+	text, err := executeTemplate("text body", textTemplate, false, templateData)
+	if err != nil {
+		return emailContent{}, err
+	}
+	return emailContent{subject: subject, text: text, html: html, attachment: attachment}, nil
+}
+
+// attachment returns the log entries of found identities and failed entries
+// as an attachment, if the JSON payload exceeds the attachment threshold
+func (o EmailOptions) attachment(templateData TemplateData) (*emailAttachment, error) {
+	threshold := o.AttachmentThreshold
+	if threshold == 0 {
+		threshold = DefaultEmailAttachmentThreshold
+	}
+	if threshold < 0 || len(templateData.Body) <= threshold {
+		return nil, nil
+	}
+	if templateData.Kind != NotificationKindIdentityMatch && templateData.Kind != NotificationKindFailedEntry {
+		return nil, nil
+	}
+	if o.AttachmentFormat == emailAttachmentFormatCSV {
+		content, err := logEntriesCSV(templateData)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding log entries as CSV: %w", err)
+		}
+		return &emailAttachment{name: templateData.Kind + ".csv", contentType: "text/csv", content: content}, nil
+	}
+	return &emailAttachment{name: templateData.Kind + ".json", contentType: "application/json", content: []byte(templateData.Body)}, nil
+}
+
+// logEntriesCSV formats the log entries of found identities or failed entries as CSV
+func logEntriesCSV(templateData TemplateData) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if templateData.Kind == NotificationKindFailedEntry {
+		if err := w.Write([]string{"index", "uuid", "error"}); err != nil {
+			return nil, err
+		}
+		for _, entry := range templateData.FailedEntries {
+			if err := w.Write([]string{strconv.FormatInt(entry.Index, 10), entry.UUID, entry.Error}); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if err := w.Write(append([]string{"identity"}, identity.LogEntryColumns...)); err != nil {
+			return nil, err
+		}
+		for _, monitoredIdentity := range templateData.Identities {
+			for _, entry := range monitoredIdentity.FoundIdentityEntries {
+				if err := w.Write(append([]string{monitoredIdentity.Identity}, entry.Columns()...)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// newEmailMessage builds a multipart email with plaintext and HTML bodies and
+// the attachment, signed with S/MIME if configured
+func (o EmailOptions) newEmailMessage(from, recipientEmailAddress string, content emailContent) (*mail.Msg, error) {
+	email := mail.NewMsg()
+	if err := email.From(from); err != nil {
+		return nil, err
+	}
+	to := o.to(recipientEmailAddress)
+	if len(to) == 0 {
+		return nil, errors.New("no email recipients configured")
+	}
+	if err := email.To(to...); err != nil {
+		return nil, err
+	}
+	if len(o.Cc) > 0 {
+		if err := email.Cc(o.Cc...); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.Bcc) > 0 {
+		if err := email.Bcc(o.Bcc...); err != nil {
+			return nil, err
+		}
+	}
+	email.Subject(content.subject)
+	email.SetBodyString(mail.TypeTextPlain, content.text)
+	email.AddAlternativeString(mail.TypeTextHTML, content.html)
+	if content.attachment != nil {
+		if err := email.AttachReader(content.attachment.name, bytes.NewReader(content.attachment.content),
+			mail.WithFileContentType(mail.ContentType(content.attachment.contentType))); err != nil {
+			return nil, err
+		}
+	}
+	if o.Signing != nil && o.Signing.SMIMECertificateFile != "" {
+		certificate, err := o.Signing.smimeCertificate()
+		if err != nil {
+			return nil, err
+		}
+		if err := email.SignWithTLSCertificate(&certificate); err != nil {
+			return nil, fmt.Errorf("error signing email with S/MIME: %w", err)
+		}
+	}
+	return email, nil
+}
+
+// pgpSigned returns whether emails are signed with PGP, which go-mail doesn't support
+func (o EmailOptions) pgpSigned() bool {
+	return o.Signing != nil && o.Signing.PGPKeyFile != ""
+}
+
+// signed returns whether emails are signed
+func (o EmailOptions) signed() bool {
+	return o.Signing != nil
+}
+
+// rawEmailMessage returns the MIME encoding of an email, signed with PGP if configured
+func (o EmailOptions) rawEmailMessage(email *mail.Msg) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := email.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("error encoding email: %w", err)
+	}
+	if !o.pgpSigned() {
+		return buf.Bytes(), nil
+	}
+	return o.Signing.signPGP(buf.Bytes())
+}
+
+// validate checks that either S/MIME or PGP is configured, and that the keys can be loaded
+func (s EmailSigning) validate() error {
+	smime := s.SMIMECertificateFile != "" || s.SMIMEKeyFile != ""
+	pgp := s.PGPKeyFile != ""
+	switch {
+	case smime && pgp:
+		return errors.New("only one of S/MIME and PGP signing can be configured")
+	case smime:
+		_, err := s.smimeCertificate()
+		return err
+	case pgp:
+		_, err := s.pgpSigner()
+		return err
+	default:
+		return errors.New("smimeCertificateFile and smimeKeyFile, or pgpKeyFile must be set")
+	}
+}
+
+// smimeCertificate loads the S/MIME certificate and private key
+func (s EmailSigning) smimeCertificate() (tls.Certificate, error) {
+	if s.SMIMECertificateFile == "" || s.SMIMEKeyFile == "" {
+		return tls.Certificate{}, errors.New("both smimeCertificateFile and smimeKeyFile must be set")
+	}
+	certificate, err := tls.LoadX509KeyPair(s.SMIMECertificateFile, s.SMIMEKeyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error loading S/MIME certificate: %w", err)
+	}
+	return certificate, nil
+}
+
+// pgpSigner loads the PGP private key, decrypting it with the passphrase if needed
+func (s EmailSigning) pgpSigner() (*openpgp.Entity, error) {
+	keyFile, err := os.Open(s.PGPKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PGP key: %w", err)
+	}
+	defer keyFile.Close()
+	entities, err := openpgp.ReadArmoredKeyRing(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing PGP key %s: %w", s.PGPKeyFile, err)
+	}
+	if len(entities) != 1 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("error parsing PGP key %s: must contain a single private key", s.PGPKeyFile)
+	}
+	signer := entities[0]
+	if signer.PrivateKey.Encrypted {
+		if s.PGPPassphrase == "" {
+			return nil, fmt.Errorf("PGP key %s is encrypted: pgpPassphrase must be set", s.PGPKeyFile)
+		}
+		if err := signer.PrivateKey.Decrypt([]byte(s.PGPPassphrase)); err != nil {
+			return nil, fmt.Errorf("error decrypting PGP key %s: %w", s.PGPKeyFile, err)
+		}
+	}
+	return signer, nil
+}
+
+// signPGP wraps a MIME message into a PGP/MIME signed message (RFC 3156).
+// The top-level entity of the message is signed as is, and sent as the first
+// part of a multipart/signed entity, followed by the detached signature.
+func (s EmailSigning) signPGP(message []byte) ([]byte, error) {
+	signer, err := s.pgpSigner()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := netmail.ReadMessage(bytes.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing email: %w", err)
+	}
+	headerEnd := bytes.Index(message, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return nil, errors.New("error parsing email: missing header")
+	}
+	body := bytes.TrimSuffix(message[headerEnd+len("\r\n\r\n"):], []byte("\r\n"))
+
+	var signed bytes.Buffer
+	fmt.Fprintf(&signed, "Content-Type: %s\r\n", parsed.Header.Get("Content-Type"))
+	if encoding := parsed.Header.Get("Content-Transfer-Encoding"); encoding != "" {
+		fmt.Fprintf(&signed, "Content-Transfer-Encoding: %s\r\n", encoding)
+	}
+	signed.WriteString("\r\n")
+	signed.Write(body)
+
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, signer, bytes.NewReader(signed.Bytes()), &packet.Config{DefaultHash: crypto.SHA256}); err != nil {
+		return nil, fmt.Errorf("error signing email with PGP: %w", err)
+	}
+
+	boundary := multipart.NewWriter(nil).Boundary()
+	var out bytes.Buffer
+	keys := make([]string, 0, len(parsed.Header))
+	for key := range parsed.Header {
+		switch key {
+		case "Content-Type", "Content-Transfer-Encoding", "Mime-Version":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range parsed.Header[key] {
+			fmt.Fprintf(&out, "%s: %s\r\n", key, value)
+		}
+	}
+	out.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&out, "Content-Type: multipart/signed; micalg=pgp-sha256; protocol=\"application/pgp-signature\"; boundary=\"%s\"\r\n\r\n", boundary)
+	fmt.Fprintf(&out, "--%s\r\n", boundary)
+	out.Write(signed.Bytes())
+	fmt.Fprintf(&out, "\r\n--%s\r\n", boundary)
+	out.WriteString("Content-Type: application/pgp-signature; name=\"signature.asc\"\r\n")
+	out.WriteString("Content-Description: OpenPGP digital signature\r\n")
+	out.WriteString("Content-Disposition: attachment; filename=\"signature.asc\"\r\n\r\n")
+	out.WriteString(strings.ReplaceAll(signature.String(), "\n", "\r\n"))
+	fmt.Fprintf(&out, "\r\n--%s--\r\n", boundary)
+	return out.Bytes(), nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+func emailTestData() NotificationData {
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "rekor-monitor workflow results"),
+		Payload: identity.MonitoredIdentityList{
+			{
+				Identity: "user@example.com",
+				FoundIdentityEntries: []identity.LogEntry{
+					{MatchedIdentity: "user@example.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Index: 1, UUID: "uuid-1"},
+					{MatchedIdentity: "user@example.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Index: 2, UUID: "uuid-2"},
+				},
+			},
+		},
+	}
+}
+
+func TestEmailContent(t *testing.T) {
+	content, err := EmailOptions{}.content(NotificationTemplates{}, emailTestData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.attachment != nil {
+		t.Errorf("expected no attachment below the default threshold")
+	}
+	if content.subject != "rekor-monitor workflow results" {
+		t.Errorf("unexpected subject %q", content.subject)
+	}
+	if !strings.HasPrefix(content.text, "Found the following pairs of monitored identities") || !strings.Contains(content.text, `"uuid-2"`) {
+		t.Errorf("expected plaintext body with JSON entries, got %q", content.text)
+	}
+	if !strings.Contains(content.html, "<pre>") || strings.Contains(content.text, "<pre>") {
+		t.Errorf("unexpected HTML body %q", content.html)
+	}
+}
+
+func TestEmailContentAttachment(t *testing.T) {
+	content, err := EmailOptions{AttachmentThreshold: 10}.content(NotificationTemplates{}, emailTestData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.attachment == nil || content.attachment.name != "identityMatch.json" || !strings.Contains(string(content.attachment.content), `"uuid-1"`) {
+		t.Fatalf("expected JSON attachment, got %+v", content.attachment)
+	}
+	if strings.Contains(content.text, "uuid-1") || !strings.Contains(content.text, "The 2 log entries are attached as identityMatch.json.") {
+		t.Errorf("expected body to reference the attachment, got %q", content.text)
+	}
+
+	content, err = EmailOptions{AttachmentThreshold: 10, AttachmentFormat: "csv"}.content(NotificationTemplates{}, emailTestData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "identity,certSubject,issuer,fingerprint,subject,index,uuid,oidExtension,extensionValue,matchedIdentityType,matchedIdentity," +
+		"logOrigin,logKind,entryKind,integratedTime,certificateSerial,artifactDigest,detectedAt,payloadType,subjectDigests,matchedRules,violations\n" +
+		"user@example.com,user@example.com,,,,1,uuid-1,,,certSubject,user@example.com,,,,,,,,,,,\n" +
+		"user@example.com,user@example.com,,,,2,uuid-2,,,certSubject,user@example.com,,,,,,,,,,,\n"
+	if content.attachment == nil || content.attachment.name != "identityMatch.csv" || string(content.attachment.content) != want {
+		t.Errorf("unexpected CSV attachment %+v", content.attachment)
+	}
+
+	failed := NotificationData{Context: emailTestData().Context, Payload: identity.FailedLogEntryList{{Index: 3, UUID: "uuid-3", Error: "bad entry"}}}
+	content, err = EmailOptions{AttachmentThreshold: 10, AttachmentFormat: "csv"}.content(NotificationTemplates{}, failed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.attachment == nil || string(content.attachment.content) != "index,uuid,error\n3,uuid-3,bad entry\n" {
+		t.Errorf("unexpected failed entries attachment %+v", content.attachment)
+	}
+
+	content, err = EmailOptions{AttachmentThreshold: -1}.content(NotificationTemplates{}, emailTestData())
+	if err != nil || content.attachment != nil {
+		t.Errorf("expected no attachment with a negative threshold, got %+v, %v", content.attachment, err)
+	}
+}
+
+func TestEmailOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   EmailOptions
+		recipient string
+		errMsg    string
+	}{
+		{"invalid recipient", EmailOptions{Cc: []string{"not an address"}}, "security@example.com", "invalid test recipient not an address"},
+		{"invalid attachment format", EmailOptions{AttachmentFormat: "xml"}, "security@example.com", "invalid test attachmentFormat xml"},
+		{"invalid text body template", EmailOptions{TextBodyTemplate: "{{.Missing}}"}, "security@example.com", "invalid test textBodyTemplate"},
+		{"missing signing keys", EmailOptions{Signing: &EmailSigning{}}, "security@example.com", "smimeCertificateFile and smimeKeyFile, or pgpKeyFile must be set"},
+		{"S/MIME and PGP", EmailOptions{Signing: &EmailSigning{SMIMECertificateFile: "cert.pem", PGPKeyFile: "key.asc"}}, "security@example.com", "only one of S/MIME and PGP signing"},
+		{"missing PGP key", EmailOptions{Signing: &EmailSigning{PGPKeyFile: "/nonexistent/key.asc"}}, "security@example.com", "error reading PGP key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.validate("test", tt.recipient)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+
+	valid := EmailOptions{To: []string{"Security Team <security@example.com>"}, Bcc: []string{"audit@example.com"}, AttachmentFormat: "csv"}
+	if err := valid.validate("test", ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewEmailMessage(t *testing.T) {
+	options := EmailOptions{To: []string{"dev@example.com"}, Cc: []string{"cc@example.com"}, Bcc: []string{"audit@example.com"}, AttachmentThreshold: 10}
+	content, err := options.content(NotificationTemplates{}, emailTestData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, err := options.newEmailMessage("monitor@example.com", "security@example.com", content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	message, err := options.rawEmailMessage(email)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(message))
+	if err != nil {
+		t.Fatalf("error parsing email: %v", err)
+	}
+	if to := parsed.Header.Get("To"); !strings.Contains(to, "security@example.com") || !strings.Contains(to, "dev@example.com") {
+		t.Errorf("unexpected To header %q", to)
+	}
+	if parsed.Header.Get("Cc") != "<cc@example.com>" || parsed.Header.Get("Bcc") != "" {
+		t.Errorf("unexpected Cc %q and Bcc %q headers", parsed.Header.Get("Cc"), parsed.Header.Get("Bcc"))
+	}
+	for _, want := range []string{"multipart/mixed", "multipart/alternative", "text/plain", "text/html", `filename="identityMatch.json"`} {
+		if !bytes.Contains(message, []byte(want)) {
+			t.Errorf("expected email to contain %q", want)
+		}
+	}
+	if got := options.envelopeRecipients("security@example.com"); len(got) != 4 {
+		t.Errorf("expected 4 envelope recipients, got %v", got)
+	}
+}
+
+// writeSMIMECertificate writes a self-signed S/MIME certificate and its private key
+func writeSMIMECertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: "rekor-monitor"},
+		EmailAddresses: []string{"monitor@example.com"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestEmailSigningSMIME(t *testing.T) {
+	certFile, keyFile := writeSMIMECertificate(t)
+	options := EmailOptions{Signing: &EmailSigning{SMIMECertificateFile: certFile, SMIMEKeyFile: keyFile}}
+	if err := options.validate("test", "security@example.com"); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	content, err := options.content(NotificationTemplates{}, emailTestData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, err := options.newEmailMessage("monitor@example.com", "security@example.com", content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	message, err := options.rawEmailMessage(email)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"multipart/signed", "application/pkcs7-signature"} {
+		if !bytes.Contains(message, []byte(want)) {
+			t.Errorf("expected S/MIME signed email to contain %q", want)
+		}
+	}
+}
+
+// writePGPKey writes an ASCII-armored PGP private key, and returns the key ring to verify signatures
+func writePGPKey(t *testing.T) (string, openpgp.EntityList) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("rekor-monitor", "", "monitor@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.asc")
+	if err := os.WriteFile(keyFile, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return keyFile, openpgp.EntityList{entity}
+}
+
+func TestEmailSigningPGP(t *testing.T) {
+	keyFile, keyRing := writePGPKey(t)
+	options := EmailOptions{Signing: &EmailSigning{PGPKeyFile: keyFile}}
+	if err := options.validate("test", "security@example.com"); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	content, err := options.content(NotificationTemplates{}, emailTestData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, err := options.newEmailMessage("monitor@example.com", "security@example.com", content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	message, err := options.rawEmailMessage(email)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(message))
+	if err != nil {
+		t.Fatalf("error parsing email: %v", err)
+	}
+	if parsed.Header.Get("Subject") != "rekor-monitor workflow results" || parsed.Header.Get("To") != "<security@example.com>" {
+		t.Errorf("expected headers to be kept, got %v", parsed.Header)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/signed" || params["protocol"] != "application/pgp-signature" || params["micalg"] != "pgp-sha256" {
+		t.Fatalf("unexpected content type %q: %v", parsed.Header.Get("Content-Type"), err)
+	}
+
+	// the first part is signed as is, from its headers to the CRLF before the next boundary
+	body, err := io.ReadAll(parsed.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delimiter := "--" + params["boundary"]
+	signedStart := bytes.Index(body, []byte(delimiter+"\r\n")) + len(delimiter) + 2
+	signedEnd := bytes.Index(body[signedStart:], []byte("\r\n"+delimiter)) + signedStart
+	signed := body[signedStart:signedEnd]
+	if !bytes.HasPrefix(signed, []byte("Content-Type: multipart/alternative")) {
+		t.Errorf("expected the multipart body to be signed, got %q", signed[:40])
+	}
+
+	parts := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	if _, err := parts.NextPart(); err != nil {
+		t.Fatal(err)
+	}
+	signaturePart, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signaturePart.Header.Get("Content-Type") != `application/pgp-signature; name="signature.asc"` {
+		t.Errorf("unexpected signature part %v", signaturePart.Header)
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyRing, bytes.NewReader(signed), signaturePart, nil); err != nil {
+		t.Errorf("error verifying signature: %v", err)
+	}
+}
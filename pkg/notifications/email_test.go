@@ -18,6 +18,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
@@ -146,3 +147,40 @@ func TestEmailSendMockSMTPServerFailure(t *testing.T) {
 		t.Errorf("expected 421 Service not available, received error %v", err)
 	}
 }
+
+func TestEmailSendMockSMTPServerSigned(t *testing.T) {
+	server := smtpmock.New(smtpmock.ConfigurationAttr{
+		HostAddress:    "127.0.0.1",
+		MultipleRcptto: true,
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("error starting server: %v", err)
+	}
+	defer server.Stop() //nolint:errcheck
+	keyFile, _ := writePGPKey(t)
+	emailNotificationInput := EmailNotificationInput{
+		RecipientEmailAddress: "test-recipient@mail.com",
+		SenderEmailAddress:    "example-sender@mail.com",
+		SMTPHostURL:           "127.0.0.1",
+		SMTPCustomOptions:     []mail.Option{mail.WithPort(server.PortNumber()), mail.WithTLSPolicy(mail.NoTLS), mail.WithHELO("example.com")},
+		EmailOptions: EmailOptions{
+			Cc:      []string{"cc@mail.com"},
+			Bcc:     []string{"bcc@mail.com"},
+			Signing: &EmailSigning{PGPKeyFile: keyFile},
+		},
+	}
+
+	if err := emailNotificationInput.Send(context.Background(), emailTestData()); err != nil {
+		t.Fatalf("expected nil, received error %v", err)
+	}
+	messages, err := server.WaitForMessages(1, time.Second)
+	if err != nil {
+		t.Fatalf("error waiting for messages: %v", err)
+	}
+	if got := len(messages[0].RcpttoRequestResponse()); got != 3 {
+		t.Errorf("expected 3 recipients, got %d", got)
+	}
+	if !strings.Contains(messages[0].MsgRequest(), "application/pgp-signature") {
+		t.Errorf("expected a PGP signed email, got %s", messages[0].MsgRequest())
+	}
+}
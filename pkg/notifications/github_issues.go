@@ -16,13 +16,33 @@ package notifications
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v65/github"
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util"
+)
+
+const (
+	// githubIssueMarkerName is searched to find the issues with a marker
+	githubIssueMarkerName = "rekor-monitor-issue"
+	// githubIssueMarkerPrefix starts the hidden marker identifying the issue of
+	// a monitor type and identity in the issue body
+	githubIssueMarkerPrefix = "<!-- " + githubIssueMarkerName + ": "
+	githubIssueMarkerSuffix = " -->"
+
+	defaultGitHubRateLimitMaxWait = time.Minute
 )
 
 // GitHubIssueInput extends the NotificationPlatform interface to support found identity
 // notification via creating new GitHub issues in a given repo.
+// By default, one issue is kept open per monitor type and identity: new log
+// entries of an identity with an open issue are added to it as a comment.
 type GitHubIssueInput struct {
 	AssigneeUsername string `yaml:"assigneeUsername"`
 	RepositoryOwner  string `yaml:"repositoryOwner"`
@@ -33,40 +53,372 @@ type GitHubIssueInput struct {
 	// For users who want to pass in a custom client.
 	// If nil, a default client with the given authentication token will be instantiated.
 	GitHubClient *github.Client `yaml:"githubClient"`
+	// DisableDeduplication creates a new issue for every notification
+	DisableDeduplication bool `yaml:"disableDeduplication"`
+	// AcknowledgedLabel, if set, acknowledges an issue: open issues with this
+	// label are closed by the monitor, and new log entries open a new issue
+	AcknowledgedLabel string `yaml:"acknowledgedLabel"`
+	// RateLimitMaxWait is the longest time to wait for a GitHub rate limit to
+	// reset before failing the notification, defaults to 1m
+	RateLimitMaxWait time.Duration `yaml:"rateLimitMaxWait"`
+	// Templates of the issue title and body. With deduplication, they are
+	// executed with the data of each issue.
+	NotificationTemplates `yaml:",inline"`
+}
+
+// githubIssue is the content of an issue, identified by its deduplication key
+type githubIssue struct {
+	key   string
+	title string
+	data  NotificationData
 }
 
-// generateGitHubIssueBody generates a GitHub issue body for generic notification data
-func generateGitHubIssueBody(data NotificationData) (string, error) {
-	header := data.Payload.ToNotificationHeader()
-	body, err := data.Payload.ToNotificationBody()
+// githubRateLimitError is returned when a GitHub rate limit doesn't reset in time.
+// It implements StatusCode() so that util.Retry retries the notification.
+type githubRateLimitError struct {
+	err   error
+	reset time.Time
+}
+
+func (e githubRateLimitError) Error() string {
+	return fmt.Sprintf("GitHub rate limit exceeded until %s: %v", e.reset.Format(time.RFC3339), e.err)
+}
+
+func (e githubRateLimitError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+// generateGitHubIssueBody generates a GitHub issue body for notification data,
+// with the configured body template or DefaultGitHubIssueBodyTemplate
+func generateGitHubIssueBody(templates NotificationTemplates, data NotificationData) (string, error) {
+	return templates.renderBody(data, DefaultGitHubIssueBodyTemplate, false)
+}
+
+// Validate checks the GitHub issue templates
+func (gitHubIssueInput GitHubIssueInput) Validate() error {
+	return gitHubIssueInput.NotificationTemplates.validate("githubIssue", false)
+}
+
+// githubIssueMarker returns the hidden marker of the issue with the deduplication key
+func githubIssueMarker(key string) string {
+	return githubIssueMarkerPrefix + key + githubIssueMarkerSuffix
+}
+
+// githubIssues splits notification data into issues, one per monitored identity
+// for found identities, and one per kind of notification otherwise
+func githubIssues(data NotificationData) []githubIssue {
+	if monitoredIdentities, ok := data.Payload.(identity.MonitoredIdentityList); ok && len(monitoredIdentities) > 0 {
+		issues := make([]githubIssue, 0, len(monitoredIdentities))
+		for _, monitoredIdentity := range monitoredIdentities {
+			issues = append(issues, githubIssue{
+				key:   fmt.Sprintf("%s:%s:%s", data.Context.MonitorType, NotificationKindIdentityMatch, monitoredIdentity.Identity),
+				title: fmt.Sprintf("%s: %s", data.Context.Subject, monitoredIdentity.Identity),
+				data: NotificationData{
+					Context: data.Context,
+					Payload: identity.MonitoredIdentityList{monitoredIdentity},
+				},
+			})
+		}
+		return issues
+	}
+	return []githubIssue{{
+		key:   fmt.Sprintf("%s:%s", data.Context.MonitorType, notificationKind(data.Payload)),
+		title: data.Context.Subject,
+		data:  data,
+	}}
+}
+
+// client returns the configured GitHub client, or a client authenticated with the token
+func (gitHubIssueInput GitHubIssueInput) client() *github.Client {
+	if gitHubIssueInput.GitHubClient != nil {
+		return gitHubIssueInput.GitHubClient
+	}
+	return github.NewClient(nil).WithAuthToken(gitHubIssueInput.AuthenticationToken)
+}
+
+// githubIssueProgress records the issues a notification was already sent to,
+// so that retrying a notification that failed part way doesn't comment again
+// on the issues it was sent to before the failure. The issues of a
+// notification are forgotten once it was sent to all of them.
+var githubIssueProgress = struct {
+	sync.Mutex
+	sent map[string]bool
+}{sent: map[string]bool{}}
+
+// githubIssueSender sends a notification to GitHub issues. It caches the open
+// issues of the monitor and the rate limits returned by GitHub for the
+// duration of a Send.
+type githubIssueSender struct {
+	input  GitHubIssueInput
+	client *github.Client
+	labels []string
+	// openIssues are the open issues by deduplication key, nil until searched
+	openIssues map[string]*github.Issue
+	// rates are the last rate limits returned by GitHub, by API category
+	rates map[string]github.Rate
+}
+
+const (
+	githubCoreAPI   = "core"
+	githubSearchAPI = "search"
+)
+
+// withRateLimit calls a GitHub API until it succeeds or fails for another reason
+// than a rate limit, waiting for rate limits to reset for at most RateLimitMaxWait.
+// If the last response of the API category had no requests remaining, the call
+// is delayed until the rate limit resets instead of being rejected by GitHub.
+func (s *githubIssueSender) withRateLimit(ctx context.Context, category string, call func() (*github.Response, error)) error {
+	maxWait := s.input.RateLimitMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultGitHubRateLimitMaxWait
+	}
+	for {
+		if rate, ok := s.rates[category]; ok && rate.Remaining == 0 {
+			if err := waitForRateLimit(ctx, time.Until(rate.Reset.Time), maxWait, errors.New("no requests remaining")); err != nil {
+				return err
+			}
+			delete(s.rates, category)
+		}
+
+		resp, err := call()
+		if resp != nil && resp.Rate.Limit > 0 {
+			s.rates[category] = resp.Rate
+		}
+		var wait time.Duration
+		var rateLimitErr *github.RateLimitError
+		var abuseRateLimitErr *github.AbuseRateLimitError
+		switch {
+		case errors.As(err, &rateLimitErr):
+			wait = time.Until(rateLimitErr.Rate.Reset.Time)
+		case errors.As(err, &abuseRateLimitErr):
+			wait = time.Minute
+			if abuseRateLimitErr.RetryAfter != nil {
+				wait = *abuseRateLimitErr.RetryAfter
+			}
+		default:
+			return err
+		}
+		delete(s.rates, category)
+		if err := waitForRateLimit(ctx, wait, maxWait, err); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForRateLimit waits for a rate limit to reset, or returns a retryable
+// error if it doesn't reset within maxWait
+func waitForRateLimit(ctx context.Context, wait, maxWait time.Duration, err error) error {
+	if wait <= 0 {
+		return nil
+	}
+	if wait > maxWait {
+		return util.WrapError(githubRateLimitError{err: err, reset: time.Now().Add(wait)})
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// searchOpenIssues searches the open issues of the monitor carrying a
+// deduplication marker, and indexes them by deduplication key
+func (s *githubIssueSender) searchOpenIssues(ctx context.Context) error {
+	query := fmt.Sprintf("repo:%s/%s is:issue is:open in:body %q",
+		s.input.RepositoryOwner, s.input.RepositoryName, githubIssueMarkerName)
+	for _, label := range s.labels {
+		query += fmt.Sprintf(" label:%q", label)
+	}
+	opts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	openIssues := map[string]*github.Issue{}
+	for {
+		var result *github.IssuesSearchResult
+		var resp *github.Response
+		err := s.withRateLimit(ctx, githubSearchAPI, func() (*github.Response, error) {
+			var err error
+			result, resp, err = s.client.Search.Issues(ctx, query, opts)
+			return resp, err
+		})
+		if err != nil {
+			return fmt.Errorf("error searching issues: %w", err)
+		}
+		for _, issue := range result.Issues {
+			if key, ok := githubIssueKey(issue.GetBody()); ok {
+				openIssues[key] = issue
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	s.openIssues = openIssues
+	return nil
+}
+
+// githubIssueKey returns the deduplication key of the marker in an issue body
+func githubIssueKey(body string) (string, bool) {
+	_, rest, ok := strings.Cut(body, githubIssueMarkerPrefix)
+	if !ok {
+		return "", false
+	}
+	key, _, ok := strings.Cut(rest, githubIssueMarkerSuffix)
+	return key, ok
+}
+
+// findOpenIssue returns the open issue with the marker of the deduplication key, or nil.
+// The open issues are searched once per Send.
+func (s *githubIssueSender) findOpenIssue(ctx context.Context, key string) (*github.Issue, error) {
+	if s.openIssues == nil {
+		if err := s.searchOpenIssues(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.openIssues[key], nil
+}
+
+// isAcknowledged returns whether the issue carries the acknowledged label
+func (gitHubIssueInput GitHubIssueInput) isAcknowledged(issue *github.Issue) bool {
+	if gitHubIssueInput.AcknowledgedLabel == "" {
+		return false
+	}
+	for _, label := range issue.Labels {
+		if label.GetName() == gitHubIssueInput.AcknowledgedLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// createIssue creates a new issue
+func (s *githubIssueSender) createIssue(ctx context.Context, title, body string) (*github.Issue, error) {
+	issueRequest := &github.IssueRequest{
+		Title:    &title,
+		Body:     &body,
+		Labels:   &s.labels,
+		Assignee: &s.input.AssigneeUsername,
+	}
+	var created *github.Issue
+	err := s.withRateLimit(ctx, githubCoreAPI, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		created, resp, err = s.client.Issues.Create(ctx, s.input.RepositoryOwner, s.input.RepositoryName, issueRequest)
+		return resp, err
+	})
+	return created, err
+}
+
+// sendIssue comments on the open issue of the deduplication key, or creates
+// a new issue if there is none
+func (s *githubIssueSender) sendIssue(ctx context.Context, issue githubIssue) error {
+	body, err := generateGitHubIssueBody(s.input.NotificationTemplates, issue.data)
+	if err != nil {
+		return err
+	}
+	title := issue.title
+	if s.input.SubjectTemplate != "" {
+		if title, err = s.input.renderSubject(issue.data); err != nil {
+			return err
+		}
+	}
+	owner, repo := s.input.RepositoryOwner, s.input.RepositoryName
+
+	openIssue, err := s.findOpenIssue(ctx, issue.key)
+	if err != nil {
+		return err
+	}
+	if openIssue != nil && s.input.isAcknowledged(openIssue) {
+		closeRequest := &github.IssueRequest{State: github.String("closed"), StateReason: github.String("completed")}
+		err := s.withRateLimit(ctx, githubCoreAPI, func() (*github.Response, error) {
+			_, resp, err := s.client.Issues.Edit(ctx, owner, repo, openIssue.GetNumber(), closeRequest)
+			return resp, err
+		})
+		if err != nil {
+			return fmt.Errorf("error closing acknowledged issue #%d: %w", openIssue.GetNumber(), err)
+		}
+		delete(s.openIssues, issue.key)
+		body += fmt.Sprintf("\n\nPrevious acknowledged issue: #%d", openIssue.GetNumber())
+		openIssue = nil
+	}
+	if openIssue == nil {
+		created, err := s.createIssue(ctx, title, body+"\n\n"+githubIssueMarker(issue.key))
+		if err != nil {
+			return err
+		}
+		// search results lag behind, remember the new issue for the rest of the Send
+		s.openIssues[issue.key] = created
+		return nil
+	}
+
+	comment := &github.IssueComment{Body: &body}
+	err = s.withRateLimit(ctx, githubCoreAPI, func() (*github.Response, error) {
+		_, resp, err := s.client.Issues.CreateComment(ctx, owner, repo, openIssue.GetNumber(), comment)
+		return resp, err
+	})
 	if err != nil {
-		return "", err
+		return fmt.Errorf("error commenting on issue #%d: %w", openIssue.GetNumber(), err)
 	}
-	return strings.Join([]string{header, "```\n" + string(body) + "\n```"}, "\n"), nil
+	return nil
+}
+
+// progressKey identifies an issue of a notification in githubIssueProgress
+func (gitHubIssueInput GitHubIssueInput) progressKey(issue githubIssue) string {
+	return strings.Join([]string{
+		gitHubIssueInput.RepositoryOwner,
+		gitHubIssueInput.RepositoryName,
+		issue.key,
+		notificationID(issue.data.Context, notificationKind(issue.data.Payload), issue.data.Payload),
+	}, "\x00")
 }
 
 // Send implements the NotificationPlatform interface
 func (gitHubIssueInput GitHubIssueInput) Send(ctx context.Context, data NotificationData) error {
-	issueTitle := data.Context.Subject
-	issueBody, err := generateGitHubIssueBody(data)
-	if err != nil {
+	sender := &githubIssueSender{
+		input:  gitHubIssueInput,
+		client: gitHubIssueInput.client(),
+		labels: []string{data.Context.MonitorType, "automatically generated"},
+		rates:  map[string]github.Rate{},
+	}
+
+	if gitHubIssueInput.DisableDeduplication {
+		issueTitle, err := gitHubIssueInput.renderSubject(data)
+		if err != nil {
+			return err
+		}
+		issueBody, err := generateGitHubIssueBody(gitHubIssueInput.NotificationTemplates, data)
+		if err != nil {
+			return err
+		}
+		_, err = sender.createIssue(ctx, issueTitle, issueBody)
 		return err
 	}
 
-	var client *github.Client
-	if gitHubIssueInput.GitHubClient == nil {
-		client = github.NewClient(nil).WithAuthToken(gitHubIssueInput.AuthenticationToken)
-	} else {
-		client = gitHubIssueInput.GitHubClient
+	issues := githubIssues(data)
+	keys := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		key := gitHubIssueInput.progressKey(issue)
+		keys = append(keys, key)
+		githubIssueProgress.Lock()
+		sent := githubIssueProgress.sent[key]
+		githubIssueProgress.Unlock()
+		if sent {
+			continue
+		}
+		if err := sender.sendIssue(ctx, issue); err != nil {
+			return err
+		}
+		githubIssueProgress.Lock()
+		githubIssueProgress.sent[key] = true
+		githubIssueProgress.Unlock()
 	}
-	labels := []string{data.Context.MonitorType, "automatically generated"}
 
-	issueRequest := &github.IssueRequest{
-		Title:    &issueTitle,
-		Body:     &issueBody,
-		Labels:   &labels,
-		Assignee: &gitHubIssueInput.AssigneeUsername,
+	githubIssueProgress.Lock()
+	for _, key := range keys {
+		delete(githubIssueProgress.sent, key)
 	}
-	_, _, err = client.Issues.Create(ctx, gitHubIssueInput.RepositoryOwner, gitHubIssueInput.RepositoryName, issueRequest)
-	return err
+	githubIssueProgress.Unlock()
+	return nil
 }
@@ -16,12 +16,17 @@ package notifications
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v65/github"
 	"github.com/migueleliasweb/go-github-mock/src/mock"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util"
 
 	"net/http"
 )
@@ -47,6 +52,10 @@ func TestGitHubIssueInputSend401BadCredentialsFailure(t *testing.T) {
 func TestGitHubIssueInputMockSendSuccess(t *testing.T) {
 	testIssueTitle := "test-issue"
 	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetSearchIssues,
+			github.IssuesSearchResult{},
+		),
 		mock.WithRequestMatch(
 			mock.PostReposIssuesByOwnerByRepo,
 			&github.Issue{
@@ -83,6 +92,10 @@ func TestGitHubIssueInputMockSendSuccess(t *testing.T) {
 
 func TestGitHubIssueInputMockSendFailure(t *testing.T) {
 	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetSearchIssues,
+			github.IssuesSearchResult{},
+		),
 		mock.WithRequestMatchHandler(
 			mock.PostReposIssuesByOwnerByRepo,
 			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -112,3 +125,309 @@ func TestGitHubIssueInputMockSendFailure(t *testing.T) {
 		t.Errorf("expected 400 Bad Request, received %v", err)
 	}
 }
+
+func githubIssueTestData() NotificationData {
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "rekor-monitor found identities"),
+		Payload: identity.MonitoredIdentityList{
+			{
+				Identity:             "release@example.com",
+				FoundIdentityEntries: []identity.LogEntry{{CertSubject: "release@example.com", Index: 10}},
+			},
+			{
+				Identity:             "dev@example.com",
+				FoundIdentityEntries: []identity.LogEntry{{CertSubject: "dev@example.com", Index: 11}},
+			},
+		},
+	}
+}
+
+func TestGitHubIssueInputDeduplication(t *testing.T) {
+	openIssue := &github.Issue{
+		Number: github.Int(7),
+		Body:   github.String("previous entries\n\n" + githubIssueMarker("rekor-monitor:identityMatch:release@example.com")),
+	}
+	var created []github.IssueRequest
+	var commented []int
+	var searches int
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetSearchIssues,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				searches++
+				want := `repo:test-owner/test-repo is:issue is:open in:body "rekor-monitor-issue" label:"rekor-monitor" label:"automatically generated"`
+				if got := r.URL.Query().Get("q"); got != want {
+					t.Errorf("unexpected search query %q", got)
+				}
+				w.Write(mock.MustMarshal(github.IssuesSearchResult{
+					Issues: []*github.Issue{{Number: github.Int(3), Body: github.String("other issue")}, openIssue},
+				}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostReposIssuesCommentsByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var comment github.IssueComment
+				if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(comment.GetBody(), "release@example.com") || strings.Contains(comment.GetBody(), "dev@example.com") {
+					t.Errorf("unexpected comment %q", comment.GetBody())
+				}
+				commented = append(commented, 7)
+				w.Write(mock.MustMarshal(comment))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var issue github.IssueRequest
+				if err := json.NewDecoder(r.Body).Decode(&issue); err != nil {
+					t.Fatal(err)
+				}
+				created = append(created, issue)
+				w.Write(mock.MustMarshal(github.Issue{Number: github.Int(8)}))
+			}),
+		),
+	)
+	gitHubIssuesInput := GitHubIssueInput{
+		RepositoryOwner: "test-owner",
+		RepositoryName:  "test-repo",
+		GitHubClient:    github.NewClient(mockedHTTPClient),
+	}
+	if err := gitHubIssuesInput.Send(context.Background(), githubIssueTestData()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if searches != 1 {
+		t.Errorf("expected the open issues to be searched once, got %d searches", searches)
+	}
+	if len(commented) != 1 {
+		t.Errorf("expected a comment on the open issue, got %v", commented)
+	}
+	if len(created) != 1 {
+		t.Fatalf("expected a new issue for the identity without open issue, got %d", len(created))
+	}
+	if got := created[0].GetTitle(); got != "rekor-monitor found identities: dev@example.com" {
+		t.Errorf("unexpected issue title %q", got)
+	}
+	if !strings.Contains(created[0].GetBody(), githubIssueMarker("rekor-monitor:identityMatch:dev@example.com")) {
+		t.Errorf("expected issue body to contain the marker, got %q", created[0].GetBody())
+	}
+}
+
+func TestGitHubIssueInputClosesAcknowledgedIssue(t *testing.T) {
+	openIssue := &github.Issue{
+		Number: github.Int(7),
+		Body:   github.String(githubIssueMarker("rekor-monitor:identityMatch:release@example.com")),
+		Labels: []*github.Label{{Name: github.String("acknowledged")}},
+	}
+	var closed bool
+	var created []github.IssueRequest
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetSearchIssues,
+			github.IssuesSearchResult{Issues: []*github.Issue{openIssue}},
+		),
+		mock.WithRequestMatchHandler(
+			mock.PatchReposIssuesByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var edit github.IssueRequest
+				if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+					t.Fatal(err)
+				}
+				closed = edit.GetState() == "closed" && strings.HasSuffix(r.URL.Path, "/issues/7")
+				w.Write(mock.MustMarshal(openIssue))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var issue github.IssueRequest
+				if err := json.NewDecoder(r.Body).Decode(&issue); err != nil {
+					t.Fatal(err)
+				}
+				created = append(created, issue)
+				w.Write(mock.MustMarshal(github.Issue{Number: github.Int(8)}))
+			}),
+		),
+	)
+	gitHubIssuesInput := GitHubIssueInput{
+		RepositoryOwner:   "test-owner",
+		RepositoryName:    "test-repo",
+		GitHubClient:      github.NewClient(mockedHTTPClient),
+		AcknowledgedLabel: "acknowledged",
+	}
+	data := githubIssueTestData()
+	data.Payload = data.Payload.(identity.MonitoredIdentityList)[:1]
+	if err := gitHubIssuesInput.Send(context.Background(), data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !closed {
+		t.Errorf("expected the acknowledged issue to be closed")
+	}
+	if len(created) != 1 || !strings.Contains(created[0].GetBody(), "Previous acknowledged issue: #7") {
+		t.Errorf("expected a new issue referencing the acknowledged issue, got %+v", created)
+	}
+}
+
+func TestGitHubIssueInputDisableDeduplication(t *testing.T) {
+	var created int
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				created++
+				w.Write(mock.MustMarshal(github.Issue{Number: github.Int(1)}))
+			}),
+		),
+	)
+	gitHubIssuesInput := GitHubIssueInput{
+		RepositoryOwner:      "test-owner",
+		RepositoryName:       "test-repo",
+		GitHubClient:         github.NewClient(mockedHTTPClient),
+		DisableDeduplication: true,
+	}
+	if err := gitHubIssuesInput.Send(context.Background(), githubIssueTestData()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created != 1 {
+		t.Errorf("expected a single issue, got %d", created)
+	}
+}
+
+func TestGitHubIssueInputRateLimit(t *testing.T) {
+	var attempts int
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				attempts++
+				if attempts == 1 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`{"message": "You have exceeded a secondary rate limit", "documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`))
+					return
+				}
+				w.Write(mock.MustMarshal(github.Issue{Number: github.Int(1)}))
+			}),
+		),
+	)
+	gitHubIssuesInput := GitHubIssueInput{
+		RepositoryOwner:      "test-owner",
+		RepositoryName:       "test-repo",
+		GitHubClient:         github.NewClient(mockedHTTPClient),
+		DisableDeduplication: true,
+	}
+	if err := gitHubIssuesInput.Send(context.Background(), githubIssueTestData()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected the request to be retried after the rate limit, got %d attempts", attempts)
+	}
+
+	mockedHTTPClient = mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("X-RateLimit-Limit", "5000")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+				mock.WriteError(w, http.StatusForbidden, "API rate limit exceeded")
+			}),
+		),
+	)
+	gitHubIssuesInput.GitHubClient = github.NewClient(mockedHTTPClient)
+	err := gitHubIssuesInput.Send(context.Background(), githubIssueTestData())
+	var retryErr util.RetryError
+	if !errors.As(err, &retryErr) || !retryErr.ShouldRetry() {
+		t.Errorf("expected a retryable rate limit error, got %v", err)
+	}
+}
+
+func TestGitHubIssueInputRetryAfterPartialFailure(t *testing.T) {
+	created := map[string]int{}
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetSearchIssues,
+			github.IssuesSearchResult{},
+			github.IssuesSearchResult{},
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var issue github.IssueRequest
+				if err := json.NewDecoder(r.Body).Decode(&issue); err != nil {
+					t.Fatal(err)
+				}
+				created[issue.GetTitle()]++
+				// the second issue fails the first time
+				if strings.HasSuffix(issue.GetTitle(), "dev@example.com") && created[issue.GetTitle()] == 1 {
+					mock.WriteError(w, http.StatusBadGateway, "bad gateway")
+					return
+				}
+				w.Write(mock.MustMarshal(github.Issue{Number: github.Int(len(created))}))
+			}),
+		),
+	)
+	gitHubIssuesInput := GitHubIssueInput{
+		RepositoryOwner: "test-owner",
+		RepositoryName:  "test-repo",
+		GitHubClient:    github.NewClient(mockedHTTPClient),
+	}
+	data := githubIssueTestData()
+	if err := gitHubIssuesInput.Send(context.Background(), data); err == nil {
+		t.Fatal("expected the first send to fail")
+	}
+	if err := gitHubIssuesInput.Send(context.Background(), data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := created["rekor-monitor found identities: release@example.com"]; got != 1 {
+		t.Errorf("expected the issue sent before the failure not to be sent again, got %d", got)
+	}
+	if got := created["rekor-monitor found identities: dev@example.com"]; got != 2 {
+		t.Errorf("expected the failed issue to be sent again, got %d", got)
+	}
+	if len(githubIssueProgress.sent) != 0 {
+		t.Errorf("expected the progress of the sent notification to be forgotten, got %v", githubIssueProgress.sent)
+	}
+}
+
+func TestGitHubIssueSenderWaitsForRateLimitReset(t *testing.T) {
+	sender := &githubIssueSender{
+		input: GitHubIssueInput{RateLimitMaxWait: time.Minute},
+		rates: map[string]github.Rate{},
+	}
+	var calls int
+	call := func() (*github.Response, error) {
+		calls++
+		return &github.Response{Rate: github.Rate{Limit: 30, Remaining: 0, Reset: github.Timestamp{Time: time.Now().Add(50 * time.Millisecond)}}}, nil
+	}
+
+	if err := sender.withRateLimit(context.Background(), githubSearchAPI, call); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	if err := sender.withRateLimit(context.Background(), githubSearchAPI, call); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("expected the second call to wait for the rate limit to reset, got %d calls after %s", calls, time.Since(start))
+	}
+
+	// requests of other API categories are not delayed
+	if err := sender.withRateLimit(context.Background(), githubCoreAPI, func() (*github.Response, error) { return nil, nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a rate limit resetting after the maximum wait fails without calling GitHub
+	sender.rates[githubSearchAPI] = github.Rate{Limit: 30, Remaining: 0, Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}}
+	err := sender.withRateLimit(context.Background(), githubSearchAPI, call)
+	var retryErr util.RetryError
+	if !errors.As(err, &retryErr) || !retryErr.ShouldRetry() {
+		t.Errorf("expected a retryable rate limit error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected GitHub not to be called while rate limited, got %d calls", calls)
+	}
+}
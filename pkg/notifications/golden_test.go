@@ -0,0 +1,131 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"encoding/asn1"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenMatches are the raw matches of three log entries, one of which
+// matches four rules
+func goldenMatches() []identity.LogEntry {
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 12}
+	metadata := identity.EntryMetadata{
+		LogKind:        identity.LogKindRekorV1,
+		EntryKind:      "hashedrekord",
+		IntegratedTime: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		ArtifactDigest: "sha256:abcdef",
+	}
+	matches := identity.WithEntryMetadata([]identity.LogEntry{
+		{MatchedIdentity: "user@example.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Issuer: "https://accounts.example.com", CertificateSerial: "1a2b", Index: 20, UUID: "uuid-20"},
+		{MatchedIdentity: "https://github.com/org/repo", MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue, OIDExtension: oid, ExtensionValue: "https://github.com/org/repo", CertificateSerial: "1a2b", Index: 20, UUID: "uuid-20"},
+		{MatchedIdentity: ".*@example.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Issuer: "https://accounts.example.com", CertificateSerial: "1a2b", Index: 20, UUID: "uuid-20"},
+		{MatchedIdentity: "https://github.com/org/.*", MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue, OIDExtension: oid, ExtensionValue: "https://github.com/org/repo", CertificateSerial: "1a2b", Index: 20, UUID: "uuid-20"},
+		{MatchedIdentity: "user@example.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Issuer: "https://accounts.example.com", CertificateSerial: "3c4d", Index: 12, UUID: "uuid-12"},
+		{MatchedIdentity: "0123456789abcdef", MatchedIdentityType: identity.MatchedIdentityTypeFingerprint, Fingerprint: "0123456789abcdef", Index: 31, UUID: "uuid-31"},
+	}, metadata)
+	detectedAt := time.Date(2025, 1, 1, 10, 5, 0, 0, time.UTC)
+	identity.SetDetected(matches, "rekor.sigstore.dev", detectedAt)
+	return matches
+}
+
+// goldenNotificationData consolidates matches as the monitors do
+func goldenNotificationData(matches []identity.LogEntry) NotificationData {
+	monitoredValues := identity.MonitoredValues{
+		CertificateIdentities: []identity.CertificateIdentity{{CertSubject: "user@example.com"}, {CertSubject: ".*@example.com"}},
+		Fingerprints:          []string{"0123456789abcdef"},
+	}
+	identities := append(identity.CreateIdentitiesList(monitoredValues), "https://github.com/org/repo", "https://github.com/org/.*")
+	monitoredIdentities := identity.CreateMonitoredIdentities(identity.ConsolidateMatches(matches), identities)
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "rekor-monitor workflow results").WithLogOrigin("rekor.sigstore.dev").WithCheckpoint(10, 40),
+		Payload: identity.MonitoredIdentityList(monitoredIdentities),
+	}
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run the tests with -update to create it: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s doesn't match the golden file, run the tests with -update if the change is expected:\n%s", name, got)
+	}
+}
+
+func TestNotificationsGolden(t *testing.T) {
+	matches := goldenMatches()
+	reversed := slices.Clone(matches)
+	slices.Reverse(reversed)
+
+	// notifications don't depend on the order matches were found in
+	for _, matches := range [][]identity.LogEntry{matches, reversed} {
+		data := goldenNotificationData(matches)
+
+		payload, err := NewWebhookPayload(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload.SentAt = time.Time{}
+		webhookBody, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, "webhook.golden.json", append(webhookBody, '\n'))
+
+		issueBody, err := generateGitHubIssueBody(NotificationTemplates{}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, "github_issue.golden.md", []byte(issueBody+"\n"))
+
+		message, err := newChatMessage(data, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		slackBody, err := json.MarshalIndent(renderSlackMessage(message), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, "slack.golden.json", append(slackBody, '\n'))
+
+		content, err := EmailOptions{}.content(NotificationTemplates{}, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, "email.golden.txt", []byte(content.text))
+	}
+}
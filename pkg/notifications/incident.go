@@ -0,0 +1,185 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+// Kinds of notifications, used to map notifications to severities
+const (
+	NotificationKindIdentityMatch      = "identityMatch"
+	NotificationKindFailedEntry        = "failedEntry"
+	NotificationKindConsistencyFailure = "consistencyFailure"
+)
+
+// ConsistencyFailure is the payload of a notification about a failed log consistency check
+type ConsistencyFailure struct {
+	LogOrigin  string    `json:"logOrigin"`
+	Error      string    `json:"error"`
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// ToNotificationBody implements the NotificationBodyConverter interface for ConsistencyFailure
+func (f ConsistencyFailure) ToNotificationBody() ([]byte, error) {
+	return json.MarshalIndent(f, "", "\t")
+}
+
+// ToNotificationHeader implements the NotificationBodyConverter interface for ConsistencyFailure
+func (f ConsistencyFailure) ToNotificationHeader() string {
+	return "The consistency check of the log failed: "
+}
+
+// Resolver is implemented by notification platforms that track incidents.
+// These platforms are notified about consistency failures, and Resolve is
+// called once the consistency check of the log succeeds again.
+type Resolver interface {
+	NotificationPlatform
+	Resolve(ctx context.Context, notificationContext NotificationContext) error
+}
+
+// CreateResolverPool returns the configured notification platforms that track incidents
+func CreateResolverPool(config IdentityMonitorConfiguration) []NotificationPlatform {
+	resolvers := []NotificationPlatform{}
+	for _, platform := range CreateNotificationPool(config) {
+		if _, ok := platform.(Resolver); ok {
+			resolvers = append(resolvers, platform)
+		}
+	}
+	return resolvers
+}
+
+// ResolveIncidents resolves the consistency failure incidents on all platforms,
+// and returns the errors of all platforms that failed to resolve them
+func ResolveIncidents(ctx context.Context, notificationPlatforms []NotificationPlatform, notificationContext NotificationContext) error {
+	var errs []error
+	for _, platform := range notificationPlatforms {
+		if resolver, ok := platform.(Resolver); ok {
+			if err := resolver.Resolve(ctx, notificationContext); err != nil {
+				errs = append(errs, fmt.Errorf("error resolving incident: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SeverityMapping configures the severity of an incident per kind of notification
+type SeverityMapping struct {
+	IdentityMatch      string `yaml:"identityMatch"`
+	FailedEntry        string `yaml:"failedEntry"`
+	ConsistencyFailure string `yaml:"consistencyFailure"`
+}
+
+// severity returns the severity of the notification kind, or the default
+// severity if it's not configured
+func (m SeverityMapping) severity(kind string, defaults SeverityMapping) string {
+	var configured, fallback string
+	switch kind {
+	case NotificationKindIdentityMatch:
+		configured, fallback = m.IdentityMatch, defaults.IdentityMatch
+	case NotificationKindFailedEntry:
+		configured, fallback = m.FailedEntry, defaults.FailedEntry
+	default:
+		configured, fallback = m.ConsistencyFailure, defaults.ConsistencyFailure
+	}
+	if configured == "" {
+		return fallback
+	}
+	return configured
+}
+
+// validate checks that all configured severities are allowed
+func (m SeverityMapping) validate(platform string, allowed ...string) error {
+	for _, severity := range []string{m.IdentityMatch, m.FailedEntry, m.ConsistencyFailure} {
+		if severity == "" {
+			continue
+		}
+		if !slices.Contains(allowed, severity) {
+			return fmt.Errorf("invalid %s severity %s: must be one of %v", platform, severity, allowed)
+		}
+	}
+	return nil
+}
+
+// notificationKind returns the kind of a notification payload
+func notificationKind(payload NotificationBodyConverter) string {
+	switch payload.(type) {
+	case identity.MonitoredIdentityList:
+		return NotificationKindIdentityMatch
+	case identity.FailedLogEntryList:
+		return NotificationKindFailedEntry
+	default:
+		return NotificationKindConsistencyFailure
+	}
+}
+
+// consistencyDedupKey returns the deduplication key of the consistency failure
+// incident of a log, which is resolved once the log is consistent again
+func consistencyDedupKey(notificationContext NotificationContext) string {
+	return fmt.Sprintf("%s:%s:consistency", notificationContext.MonitorType, notificationContext.LogOrigin)
+}
+
+// incidentDedupKey returns the deduplication key of an incident, derived from
+// the log origin and the log indices of the notification
+func incidentDedupKey(data NotificationData) string {
+	var first, last int64 = -1, -1
+	track := func(index int64) {
+		if first == -1 || index < first {
+			first = index
+		}
+		if index > last {
+			last = index
+		}
+	}
+	switch p := data.Payload.(type) {
+	case identity.MonitoredIdentityList:
+		for _, monitoredIdentity := range p {
+			for _, entry := range monitoredIdentity.FoundIdentityEntries {
+				track(entry.Index)
+			}
+		}
+	case identity.FailedLogEntryList:
+		for _, failedEntry := range p {
+			track(failedEntry.Index)
+		}
+	default:
+		return consistencyDedupKey(data.Context)
+	}
+	return fmt.Sprintf("%s:%s:%s:%d-%d", data.Context.MonitorType, data.Context.LogOrigin, notificationKind(data.Payload), first, last)
+}
+
+// incidentDetails returns the details of a notification attached to an incident
+func incidentDetails(data NotificationData) (map[string]any, error) {
+	body, err := data.Payload.ToNotificationBody()
+	if err != nil {
+		return nil, err
+	}
+	var details any
+	if err := json.Unmarshal(body, &details); err != nil {
+		details = string(body)
+	}
+	return map[string]any{
+		"monitorType": data.Context.MonitorType,
+		"logOrigin":   data.Context.LogOrigin,
+		"details":     details,
+	}, nil
+}
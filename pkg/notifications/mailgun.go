@@ -15,7 +15,9 @@
 package notifications
 
 import (
+	"bytes"
 	"context"
+	"io"
 
 	"github.com/mailgun/mailgun-go/v4"
 )
@@ -27,18 +29,57 @@ type MailgunNotificationInput struct {
 	SenderEmailAddress    string `yaml:"senderEmailAddress"`
 	MailgunAPIKey         string `yaml:"mailgunAPIKey"`
 	MailgunDomainName     string `yaml:"mailgunDomainName"`
+	NotificationTemplates `yaml:",inline"`
+	EmailOptions          `yaml:",inline"`
+}
+
+// Validate checks the email recipients, templates and signing keys
+func (mailgunNotificationInput MailgunNotificationInput) Validate() error {
+	if err := mailgunNotificationInput.NotificationTemplates.validate("emailNotificationMailgun", true); err != nil {
+		return err
+	}
+	return mailgunNotificationInput.EmailOptions.validate("emailNotificationMailgun", mailgunNotificationInput.RecipientEmailAddress)
+}
+
+// newMailgunMessage builds the Mailgun message of an email. Signed emails are
+// sent as MIME messages, since Mailgun would otherwise build the MIME message.
+func (mailgunNotificationInput MailgunNotificationInput) newMailgunMessage(content emailContent) (*mailgun.Message, error) {
+	if mailgunNotificationInput.signed() {
+		email, err := mailgunNotificationInput.newEmailMessage(mailgunNotificationInput.SenderEmailAddress, mailgunNotificationInput.RecipientEmailAddress, content)
+		if err != nil {
+			return nil, err
+		}
+		message, err := mailgunNotificationInput.rawEmailMessage(email)
+		if err != nil {
+			return nil, err
+		}
+		return mailgun.NewMIMEMessage(io.NopCloser(bytes.NewReader(message)), mailgunNotificationInput.envelopeRecipients(mailgunNotificationInput.RecipientEmailAddress)...), nil
+	}
+	email := mailgun.NewMessage(mailgunNotificationInput.SenderEmailAddress, content.subject, content.text, mailgunNotificationInput.to(mailgunNotificationInput.RecipientEmailAddress)...)
+	email.SetHTML(content.html)
+	for _, cc := range mailgunNotificationInput.Cc {
+		email.AddCC(cc)
+	}
+	for _, bcc := range mailgunNotificationInput.Bcc {
+		email.AddBCC(bcc)
+	}
+	if content.attachment != nil {
+		email.AddBufferAttachment(content.attachment.name, content.attachment.content)
+	}
+	return email, nil
 }
 
 // Send implements the NotificationPlatform interface
 func (mailgunNotificationInput MailgunNotificationInput) Send(ctx context.Context, data NotificationData) error {
-	subject := data.Context.Subject
-	emailHTMLBody, err := generateEmailBody(data)
+	content, err := mailgunNotificationInput.content(mailgunNotificationInput.NotificationTemplates, data)
+	if err != nil {
+		return err
+	}
+	email, err := mailgunNotificationInput.newMailgunMessage(content)
 	if err != nil {
 		return err
 	}
 	mg := mailgun.NewMailgun(mailgunNotificationInput.MailgunDomainName, mailgunNotificationInput.MailgunAPIKey)
-	email := mailgun.NewMessage(mailgunNotificationInput.SenderEmailAddress, subject, "", mailgunNotificationInput.RecipientEmailAddress)
-	email.SetHTML(emailHTMLBody)
 	_, _, err = mg.Send(ctx, email)
 	return err
 }
@@ -16,6 +16,7 @@ package notifications
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/sigstore/rekor-monitor/pkg/identity"
@@ -48,3 +49,43 @@ func TestMailgunSendFailure(t *testing.T) {
 		t.Errorf("expected error, received nil")
 	}
 }
+
+func TestNewMailgunMessage(t *testing.T) {
+	mailgunNotificationInput := MailgunNotificationInput{
+		RecipientEmailAddress: "security@example.com",
+		SenderEmailAddress:    "monitor@example.com",
+		EmailOptions: EmailOptions{
+			To:                  []string{"dev@example.com"},
+			Bcc:                 []string{"audit@example.com"},
+			AttachmentThreshold: 10,
+		},
+	}
+	content, err := mailgunNotificationInput.content(mailgunNotificationInput.NotificationTemplates, emailTestData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, err := mailgunNotificationInput.newMailgunMessage(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if to := email.To(); len(to) != 2 || to[1] != "dev@example.com" {
+		t.Errorf("unexpected recipients %v", to)
+	}
+	if attachments := email.BufferAttachments(); len(attachments) != 1 || attachments[0].Filename != "identityMatch.json" {
+		t.Errorf("expected JSON attachment, got %+v", attachments)
+	}
+
+	// signed emails are sent as MIME messages to all recipients
+	keyFile, _ := writePGPKey(t)
+	mailgunNotificationInput.Signing = &EmailSigning{PGPKeyFile: keyFile}
+	email, err = mailgunNotificationInput.newMailgunMessage(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if email.Endpoint() != "messages.mime" {
+		t.Errorf("expected a MIME message, got endpoint %s", email.Endpoint())
+	}
+	if to := email.To(); len(to) != 3 || !strings.Contains(strings.Join(to, ","), "audit@example.com") {
+		t.Errorf("unexpected recipients %v", to)
+	}
+}
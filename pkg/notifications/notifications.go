@@ -24,20 +24,41 @@ package notifications
 import (
 	"context"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/textproto"
 	"os"
 	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/google/go-github/v65/github"
 	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util/file"
 )
 
+// ErrInvalidConfiguration is wrapped by the errors of an invalid notification
+// configuration, which retrying cannot fix
+var ErrInvalidConfiguration = errors.New("invalid configuration")
+
 type NotificationContextNew func() NotificationContext
 
 // NotificationContext provides context information for notifications
 type NotificationContext struct {
-	MonitorType string `json:"monitorType"` // e.g., "rekor-monitor", "ct-monitor"
-	Subject     string `json:"subject"`     // Custom subject line
+	MonitorType string `json:"monitorType"`         // e.g., "rekor-monitor", "ct-monitor"
+	Subject     string `json:"subject"`             // Custom subject line
+	LogOrigin   string `json:"logOrigin,omitempty"` // Origin or URL of the monitored log
+	// Range of log indices searched up to the latest verified checkpoint
+	Checkpoint *NotificationCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// NotificationCheckpoint is the range of log indices searched for a notification,
+// up to the latest verified checkpoint
+type NotificationCheckpoint struct {
+	StartIndex int64 `json:"startIndex"`
+	EndIndex   int64 `json:"endIndex"`
 }
 
 // NotificationBodyConverter defines an interface for payloads that can convert themselves to a notification body string
@@ -66,6 +87,18 @@ func CreateNotificationContext(monitorType, subject string) NotificationContext
 	}
 }
 
+// WithLogOrigin returns a copy of the notification context for the given log
+func (c NotificationContext) WithLogOrigin(logOrigin string) NotificationContext {
+	c.LogOrigin = logOrigin
+	return c
+}
+
+// WithCheckpoint returns a copy of the notification context for the given range of log indices
+func (c NotificationContext) WithCheckpoint(startIndex, endIndex int64) NotificationContext {
+	c.Checkpoint = &NotificationCheckpoint{StartIndex: startIndex, EndIndex: endIndex}
+	return c
+}
+
 // ConfigMonitoredValues holds a set of values to compare against a given entry.
 // ConfigMonitoredValues holds Object Identifier extensions and associated values
 // that can be constructed either directly from asn1.ObjectIdentifier,
@@ -88,23 +121,46 @@ type ConfigMonitoredValues struct {
 	// which includes those constructed directly, those supported by Fulcio, and any constructed via dot notation.
 	// These OIDMatchers are parsed into one list of OID extensions and matching values before being passed into MatchedIndices.
 	OIDMatchers extensions.OIDMatchers `yaml:"oidMatchers"`
+	// Rules contains a list of named rules combining conditions on
+	// certificates, subjects and fingerprints with all, any and not
+	Rules []identity.Rule `yaml:"rules"`
+	// Policies contains a list of named CEL expressions evaluated on each log entry
+	Policies []identity.Policy `yaml:"policies"`
+	// Labels attaches labels to fingerprints, subjects and OID extension values,
+	// keyed by the monitored value. Labels select notification routes.
+	Labels map[string][]string `yaml:"labels"`
 }
 
 // IdentityMonitorConfiguration holds the configuration settings for an identity monitor workflow run.
 type IdentityMonitorConfiguration struct {
-	StartIndex                *int64                     `yaml:"startIndex"`
-	EndIndex                  *int64                     `yaml:"endIndex"`
-	MonitoredValues           ConfigMonitoredValues      `yaml:"monitoredValues"`
-	OutputIdentitiesFile      string                     `yaml:"outputIdentities"`
-	OutputIdentitiesFormat    string                     `yaml:"outputIdentitiesFormat"`
-	LogInfoFile               string                     `yaml:"logInfoFile"`
-	IdentityMetadataFile      *string                    `yaml:"identityMetadataFile"`
-	GitHubIssue               *GitHubIssueInput          `yaml:"githubIssue"`
-	EmailNotificationSMTP     *EmailNotificationInput    `yaml:"emailNotificationSMTP"`
-	EmailNotificationMailgun  *MailgunNotificationInput  `yaml:"emailNotificationMailgun"`
-	EmailNotificationSendGrid *SendGridNotificationInput `yaml:"emailNotificationSendGrid"`
-	CARootsFile               string                     `yaml:"caRootsFile"`
-	CAIntermediatesFile       string                     `yaml:"caIntermediatesFile"`
+	StartIndex             *int64                `yaml:"startIndex"`
+	EndIndex               *int64                `yaml:"endIndex"`
+	MonitoredValues        ConfigMonitoredValues `yaml:"monitoredValues"`
+	OutputIdentitiesFile   string                `yaml:"outputIdentities"`
+	OutputIdentitiesFormat string                `yaml:"outputIdentitiesFormat"`
+	// Outputs are additional output files of found identities, each with its own format
+	Outputs                   []file.IdentityOutput       `yaml:"outputs"`
+	LogInfoFile               string                      `yaml:"logInfoFile"`
+	IdentityMetadataFile      *string                     `yaml:"identityMetadataFile"`
+	GitHubIssue               *GitHubIssueInput           `yaml:"githubIssue"`
+	EmailNotificationSMTP     *EmailNotificationInput     `yaml:"emailNotificationSMTP"`
+	EmailNotificationMailgun  *MailgunNotificationInput   `yaml:"emailNotificationMailgun"`
+	EmailNotificationSendGrid *SendGridNotificationInput  `yaml:"emailNotificationSendGrid"`
+	Webhook                   *WebhookNotificationInput   `yaml:"webhook"`
+	Slack                     *SlackNotificationInput     `yaml:"slack"`
+	Teams                     *TeamsNotificationInput     `yaml:"teams"`
+	PagerDuty                 *PagerDutyNotificationInput `yaml:"pagerDuty"`
+	Opsgenie                  *OpsgenieNotificationInput  `yaml:"opsgenie"`
+	CARootsFile               string                      `yaml:"caRootsFile"`
+	CAIntermediatesFile       string                      `yaml:"caIntermediatesFile"`
+	NotificationOutbox        *NotificationOutboxInput    `yaml:"notificationOutbox"`
+	NotificationRoutes        []NotificationRoute         `yaml:"notificationRoutes"`
+	NotificationPolicies      *NotificationPoliciesInput  `yaml:"notificationPolicies"`
+	// Notifiers are named notification platforms, in addition to the top-level ones
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+
+	// secretReferences are the credentials configured with secret references
+	secretReferences []secretReference
 }
 
 func validatePEMFile(pemFile string) error {
@@ -127,6 +183,16 @@ func validatePEMFile(pemFile string) error {
 	return nil
 }
 
+// IdentityOutputs returns the output files of found identities: the
+// outputIdentities file, if set, followed by the configured outputs
+func (c *IdentityMonitorConfiguration) IdentityOutputs() []file.IdentityOutput {
+	var outputs []file.IdentityOutput
+	if c.OutputIdentitiesFile != "" {
+		outputs = append(outputs, file.IdentityOutput{Path: c.OutputIdentitiesFile, Format: c.OutputIdentitiesFormat})
+	}
+	return append(outputs, c.Outputs...)
+}
+
 func (c *IdentityMonitorConfiguration) Validate() error {
 	// Validate CertificateIdentities CertSubject and Issuers regexes
 	for _, certIdentity := range c.MonitoredValues.CertificateIdentities {
@@ -138,6 +204,11 @@ func (c *IdentityMonitorConfiguration) Validate() error {
 				return fmt.Errorf("invalid issuer regex %s: %v", issuer, err)
 			}
 		}
+		if certIdentity.ExpectedProvenance != nil {
+			if err := certIdentity.ExpectedProvenance.Validate(); err != nil {
+				return fmt.Errorf("invalid expectedProvenance of %s: %w", certIdentity.CertSubject, err)
+			}
+		}
 	}
 	// Validate Subjects regexes
 	for _, subject := range c.MonitoredValues.Subjects {
@@ -145,6 +216,18 @@ func (c *IdentityMonitorConfiguration) Validate() error {
 			return fmt.Errorf("invalid subject regex %s: %v", subject, err)
 		}
 	}
+	// Validate OID extensions and extension value matchers
+	if err := c.MonitoredValues.OIDMatchers.Validate(); err != nil {
+		return fmt.Errorf("invalid oidMatchers: %w", err)
+	}
+	// Validate rules
+	if _, err := identity.CompileRules(c.MonitoredValues.Rules); err != nil {
+		return fmt.Errorf("invalid rules: %w", err)
+	}
+	// Compile and type-check policy expressions
+	if _, err := identity.CompilePolicies(c.MonitoredValues.Policies); err != nil {
+		return fmt.Errorf("invalid policies: %w", err)
+	}
 	// Validate CARoots and CAIntermediates files
 	if c.CAIntermediatesFile != "" && c.CARootsFile == "" {
 		return fmt.Errorf("intermediates CA file is set but roots CA file is not set")
@@ -156,43 +239,227 @@ func (c *IdentityMonitorConfiguration) Validate() error {
 		return fmt.Errorf("invalid CAIntermediates file %s: %v", c.CAIntermediatesFile, err)
 	}
 	// Validate OutputIdentitiesFormat
-	switch c.OutputIdentitiesFormat {
-	case "text", "json", "":
-	default:
-		return fmt.Errorf("invalid OutputIdentitiesFormat %s: must be 'text' or 'json'", c.OutputIdentitiesFormat)
+	if formats := file.IdentityWriterFormats(); c.OutputIdentitiesFormat != "" && !slices.Contains(formats, c.OutputIdentitiesFormat) {
+		return fmt.Errorf("invalid OutputIdentitiesFormat %s: must be one of %s", c.OutputIdentitiesFormat, strings.Join(formats, ", "))
+	}
+	// SARIF results need an artifactURI, which only outputs can set
+	if c.OutputIdentitiesFormat == "sarif" {
+		return errors.New("invalid OutputIdentitiesFormat sarif: sarif outputs must be configured in outputs with an artifactURI")
+	}
+	for i, output := range c.Outputs {
+		if err := output.Validate(); err != nil {
+			return fmt.Errorf("invalid output %d: %w", i, err)
+		}
+	}
+	if c.GitHubIssue != nil {
+		if err := c.GitHubIssue.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.EmailNotificationSMTP != nil {
+		if err := c.EmailNotificationSMTP.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.EmailNotificationSendGrid != nil {
+		if err := c.EmailNotificationSendGrid.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.EmailNotificationMailgun != nil {
+		if err := c.EmailNotificationMailgun.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Webhook != nil {
+		if err := c.Webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Slack != nil {
+		if err := c.Slack.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Teams != nil {
+		if err := c.Teams.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.PagerDuty != nil {
+		if err := c.PagerDuty.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Opsgenie != nil {
+		if err := c.Opsgenie.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.NotificationOutbox != nil {
+		if err := c.NotificationOutbox.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.validateNotifiers(); err != nil {
+		return err
+	}
+	if err := c.validateNotificationRoutes(); err != nil {
+		return err
+	}
+	if c.NotificationPolicies != nil {
+		if err := c.NotificationPolicies.Validate(); err != nil {
+			return err
+		}
+		platforms := CreateNamedNotificationPool(*c)
+		for _, name := range sortedKeys(c.NotificationPolicies.Policies) {
+			if _, ok := platforms[name]; !ok {
+				return fmt.Errorf("invalid notification policy for %s: notifier is not configured", name)
+			}
+		}
 	}
 	return nil
 }
 
+// notificationPlatformNames lists the configuration keys of the notification
+// platforms, in the order in which notifications are sent
+var notificationPlatformNames = []string{
+	"githubIssue",
+	"emailNotificationSMTP",
+	"emailNotificationSendGrid",
+	"emailNotificationMailgun",
+	"webhook",
+	"slack",
+	"teams",
+	"pagerDuty",
+	"opsgenie",
+}
+
+// CreateNotificationPool returns the configured notification platforms, in the
+// order in which notifications are sent
 func CreateNotificationPool(config IdentityMonitorConfiguration) []NotificationPlatform {
-	// update this as new notification platforms are implemented within rekor-monitor
+	namedPlatforms := CreateNamedNotificationPool(config)
 	notificationPlatforms := []NotificationPlatform{}
+	for _, name := range notifierNames(config) {
+		notificationPlatforms = append(notificationPlatforms, namedPlatforms[name])
+	}
+	return notificationPlatforms
+}
+
+// CreateNamedNotificationPool returns the configured notification platforms,
+// keyed by the configuration key of the top-level notification platforms and
+// by the name of the notifiers
+func CreateNamedNotificationPool(config IdentityMonitorConfiguration) map[string]NotificationPlatform {
+	notificationPlatforms := legacyNotificationPool(config)
+	for _, notifier := range config.Notifiers {
+		if notifier.Platform != nil {
+			notificationPlatforms[notifier.Name] = notifier.Platform
+		}
+	}
+	return notificationPlatforms
+}
+
+// legacyNotificationPool returns the notification platforms configured with
+// top-level configuration keys, keyed by the name of their configuration key
+func legacyNotificationPool(config IdentityMonitorConfiguration) map[string]NotificationPlatform {
+	// update this as new notification platforms are implemented within rekor-monitor
+	notificationPlatforms := map[string]NotificationPlatform{}
 	if config.GitHubIssue != nil {
-		notificationPlatforms = append(notificationPlatforms, config.GitHubIssue)
+		notificationPlatforms["githubIssue"] = config.GitHubIssue
 	}
 
 	if config.EmailNotificationSMTP != nil {
-		notificationPlatforms = append(notificationPlatforms, config.EmailNotificationSMTP)
+		notificationPlatforms["emailNotificationSMTP"] = config.EmailNotificationSMTP
 	}
 
 	if config.EmailNotificationSendGrid != nil {
-		notificationPlatforms = append(notificationPlatforms, config.EmailNotificationSendGrid)
+		notificationPlatforms["emailNotificationSendGrid"] = config.EmailNotificationSendGrid
 	}
 
 	if config.EmailNotificationMailgun != nil {
-		notificationPlatforms = append(notificationPlatforms, config.EmailNotificationMailgun)
+		notificationPlatforms["emailNotificationMailgun"] = config.EmailNotificationMailgun
+	}
+
+	if config.Webhook != nil {
+		notificationPlatforms["webhook"] = config.Webhook
+	}
+
+	if config.Slack != nil {
+		notificationPlatforms["slack"] = config.Slack
+	}
+
+	if config.Teams != nil {
+		notificationPlatforms["teams"] = config.Teams
+	}
+
+	if config.PagerDuty != nil {
+		notificationPlatforms["pagerDuty"] = config.PagerDuty
+	}
+
+	if config.Opsgenie != nil {
+		notificationPlatforms["opsgenie"] = config.Opsgenie
 	}
 
 	return notificationPlatforms
 }
 
+// TriggerNotifications sends the notification from every platform, and returns
+// the errors of all platforms that failed to send it
 func TriggerNotifications(notificationPlatforms []NotificationPlatform, data NotificationData) error {
-	// update this as new notification platforms are implemented within rekor-monitor
+	var errs []error
 	for _, notificationPlatform := range notificationPlatforms {
 		if err := notificationPlatform.Send(context.Background(), data); err != nil {
-			return fmt.Errorf("error sending notification from platform: %v", err)
+			errs = append(errs, fmt.Errorf("error sending notification from platform: %w", err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// IsAuthFailure reports whether a notification platform failed to send a
+// notification because it rejected the credentials of the monitor
+func IsAuthFailure(err error) bool {
+	isAuthStatus := func(statusCode int) bool {
+		return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+	}
+	// HTTP errors of the notification platforms
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) && isAuthStatus(statusErr.StatusCode()) {
+		return true
+	}
+	var githubErr *github.ErrorResponse
+	if errors.As(err, &githubErr) && githubErr.Response != nil && isAuthStatus(githubErr.Response.StatusCode) {
+		return true
+	}
+	// SMTP 535: authentication credentials invalid
+	var smtpErr *textproto.Error
+	return errors.As(err, &smtpErr) && smtpErr.Code == 535
+}
+
+// isTransientError reports whether sending a notification may succeed when it
+// is retried right away: client errors of the notification platforms, such as
+// rejected credentials or an invalid request, fail again until the configuration
+// is fixed
+func isTransientError(err error) bool {
+	if errors.Is(err, ErrInvalidConfiguration) || IsAuthFailure(err) {
+		return false
+	}
+	isTransientStatus := func(statusCode int) bool {
+		return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+	}
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		return isTransientStatus(statusErr.StatusCode())
+	}
+	var githubErr *github.ErrorResponse
+	if errors.As(err, &githubErr) && githubErr.Response != nil {
+		return isTransientStatus(githubErr.Response.StatusCode)
+	}
+	// SMTP 4xx replies are temporary, 5xx replies are permanent
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code < 500
+	}
+	// network errors and timeouts
+	return true
 }
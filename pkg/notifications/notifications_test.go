@@ -20,7 +20,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util/file"
 )
 
 type MockNotificationPlatform struct {
@@ -129,6 +131,94 @@ func TestIdentityMonitorConfiguration_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid issuer regex [invalid issuer regex",
 		},
+		{
+			name: "invalid outputIdentitiesFormat",
+			config: IdentityMonitorConfiguration{
+				OutputIdentitiesFormat: "xml",
+			},
+			wantErr: true,
+			errMsg:  "invalid OutputIdentitiesFormat xml: must be one of csv, json, ndjson, otlp, sarif, text",
+		},
+		{
+			name: "sarif outputIdentitiesFormat",
+			config: IdentityMonitorConfiguration{
+				OutputIdentitiesFormat: "sarif",
+			},
+			wantErr: true,
+			errMsg:  "invalid OutputIdentitiesFormat sarif: sarif outputs must be configured in outputs with an artifactURI",
+		},
+		{
+			name: "invalid output",
+			config: IdentityMonitorConfiguration{
+				Outputs: []file.IdentityOutput{{Path: "identities.sarif", Format: "sarif", ArtifactURI: "config.yaml"}, {Format: "csv"}},
+			},
+			wantErr: true,
+			errMsg:  "invalid output 1: output path must be set",
+		},
+		{
+			name: "sarif output without artifactURI",
+			config: IdentityMonitorConfiguration{
+				Outputs: []file.IdentityOutput{{Path: "identities.sarif", Format: "sarif"}},
+			},
+			wantErr: true,
+			errMsg:  "invalid output 0: artifactURI must be set for the sarif format",
+		},
+		{
+			name: "invalid extension value matcher",
+			config: IdentityMonitorConfiguration{
+				MonitoredValues: ConfigMonitoredValues{
+					OIDMatchers: extensions.OIDMatchers{
+						FulcioExtensions: extensions.FulcioExtensions{SourceRepositoryRef: []extensions.ExtensionValue{
+							{Value: "refs/tags/v*", MatchMode: extensions.MatchModeGlob},
+							{Value: "(", MatchMode: extensions.MatchModeRegex},
+						}},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid oidMatchers: invalid extension value ( of 1.3.6.1.4.1.57264.1.14: invalid regex pattern (: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			name: "invalid expected provenance",
+			config: IdentityMonitorConfiguration{
+				MonitoredValues: ConfigMonitoredValues{
+					CertificateIdentities: []identity.CertificateIdentity{
+						{CertSubject: `release@example\.com`, ExpectedProvenance: &identity.ExpectedProvenance{}},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid expectedProvenance of release@example\\.com: expected provenance must set issuers, repositories, workflowRefs or runnerEnvironments",
+		},
+		{
+			name: "valid policy",
+			config: IdentityMonitorConfiguration{
+				MonitoredValues: ConfigMonitoredValues{
+					Policies: []identity.Policy{{Name: "corp", Expression: `entry.certificates.exists(c, c.sans.exists(s, s.endsWith("@corp.com")))`}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "policy with unknown field",
+			config: IdentityMonitorConfiguration{
+				MonitoredValues: ConfigMonitoredValues{
+					Policies: []identity.Policy{{Name: "corp", Expression: `entry.digest == "sha256:abcd"`}},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid policies: invalid policy corp: ERROR: <input>:1:6: undefined field 'digest'",
+		},
+		{
+			name: "policy not evaluating to bool",
+			config: IdentityMonitorConfiguration{
+				MonitoredValues: ConfigMonitoredValues{
+					Policies: []identity.Policy{{Name: "corp", Expression: `entry.kind`}},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid policies: invalid policy corp: expression must evaluate to bool, not string",
+		},
 		{
 			name: "invalid subject regex",
 			config: IdentityMonitorConfiguration{
@@ -226,3 +316,19 @@ func TestIdentityMonitorConfiguration_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestTriggerNotificationsSendsToAllPlatforms(t *testing.T) {
+	failing, ok := &recordingPlatform{fail: true}, &recordingPlatform{}
+	notificationData := NotificationData{
+		Context: CreateNotificationContext("test-monitor", "test-subject"),
+		Payload: identity.MonitoredIdentityList{},
+	}
+
+	err := TriggerNotifications([]NotificationPlatform{failing, ok}, notificationData)
+	if err == nil || !strings.Contains(err.Error(), "platform unavailable") {
+		t.Errorf("expected error of the failing platform, got %v", err)
+	}
+	if len(ok.sent) != 1 {
+		t.Errorf("expected notification to be sent from the platform after the failing one")
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"fmt"
+	"slices"
+)
+
+// notifierTypes maps the type of a notifier to a function returning a pointer
+// to the zero configuration of its notification platform. The types are named
+// after the top-level configuration keys of the notification platforms.
+var notifierTypes = map[string]func() NotificationPlatform{
+	"githubIssue":               func() NotificationPlatform { return &GitHubIssueInput{} },
+	"emailNotificationSMTP":     func() NotificationPlatform { return &EmailNotificationInput{} },
+	"emailNotificationSendGrid": func() NotificationPlatform { return &SendGridNotificationInput{} },
+	"emailNotificationMailgun":  func() NotificationPlatform { return &MailgunNotificationInput{} },
+	"webhook":                   func() NotificationPlatform { return &WebhookNotificationInput{} },
+	"slack":                     func() NotificationPlatform { return &SlackNotificationInput{} },
+	"teams":                     func() NotificationPlatform { return &TeamsNotificationInput{} },
+	"pagerDuty":                 func() NotificationPlatform { return &PagerDutyNotificationInput{} },
+	"opsgenie":                  func() NotificationPlatform { return &OpsgenieNotificationInput{} },
+}
+
+// RegisterNotifierType registers a notification platform type for the notifiers
+// configuration. newPlatform must return a pointer to the zero configuration of
+// the platform, which the settings of the notifier are decoded into. It must be
+// called before the configuration is loaded, e.g. from an init function.
+func RegisterNotifierType(notifierType string, newPlatform func() NotificationPlatform) {
+	notifierTypes[notifierType] = newPlatform
+}
+
+// NotifierTypes returns the registered notifier types
+func NotifierTypes() []string {
+	return sortedKeys(notifierTypes)
+}
+
+// NotifierConfig is a named instance of a notification platform. The settings
+// of the platform are set next to the name and type of the notifier:
+//
+//	notifiers:
+//	  - name: release-issues
+//	    type: githubIssue
+//	    repositoryOwner: sigstore
+//	    repositoryName: release
+type NotifierConfig struct {
+	Name     string
+	Type     string
+	Platform NotificationPlatform
+}
+
+// UnmarshalYAML decodes the settings of the notifier into the configuration
+// of the notification platform registered for its type
+func (n *NotifierConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var header struct {
+		Name string `yaml:"name"`
+		Type string `yaml:"type"`
+	}
+	if err := unmarshal(&header); err != nil {
+		return err
+	}
+	newPlatform, ok := notifierTypes[header.Type]
+	if !ok {
+		return fmt.Errorf("unknown type %q of notifier %q: must be one of %v", header.Type, header.Name, NotifierTypes())
+	}
+	platform := newPlatform()
+	if err := unmarshal(platform); err != nil {
+		return fmt.Errorf("error decoding notifier %q: %w", header.Name, err)
+	}
+	n.Name = header.Name
+	n.Type = header.Type
+	n.Platform = platform
+	return nil
+}
+
+// validateNotifiers checks that notifiers have unique names and valid settings.
+// The configuration keys of the top-level notification platforms are reserved.
+func (c *IdentityMonitorConfiguration) validateNotifiers() error {
+	names := map[string]bool{}
+	for _, notifier := range c.Notifiers {
+		if notifier.Name == "" {
+			return fmt.Errorf("name of %s notifier must be set", notifier.Type)
+		}
+		if slices.Contains(notificationPlatformNames, notifier.Name) {
+			return fmt.Errorf("invalid notifier name %s: reserved for the top-level %s configuration", notifier.Name, notifier.Name)
+		}
+		if names[notifier.Name] {
+			return fmt.Errorf("invalid notifier name %s: used by more than one notifier", notifier.Name)
+		}
+		names[notifier.Name] = true
+		if notifier.Platform == nil {
+			return fmt.Errorf("invalid notifier %s: type must be set", notifier.Name)
+		}
+		if v, ok := notifier.Platform.(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("invalid notifier %s: %w", notifier.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// notifierNames returns the names of the configured notification platforms,
+// in the order in which notifications are sent: the top-level notification
+// platforms first, followed by the notifiers in the order they are listed
+func notifierNames(config IdentityMonitorConfiguration) []string {
+	names := []string{}
+	legacy := legacyNotificationPool(config)
+	for _, name := range notificationPlatformNames {
+		if _, ok := legacy[name]; ok {
+			names = append(names, name)
+		}
+	}
+	for _, notifier := range config.Notifiers {
+		if notifier.Platform != nil {
+			names = append(names, notifier.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const notifiersTestConfig = `
+githubIssue:
+  repositoryOwner: sigstore
+  repositoryName: rekor-monitor
+notifiers:
+  - name: release-issues
+    type: githubIssue
+    repositoryOwner: sigstore
+    repositoryName: release
+    assigneeUsername: releaser
+  - name: dev-issues
+    type: githubIssue
+    repositoryOwner: sigstore
+    repositoryName: dev
+  - name: security-email
+    type: emailNotificationSMTP
+    recipientEmailAddress: security@example.com
+    senderEmailAddress: monitor@example.com
+  - name: release-pager
+    type: pagerDuty
+    routingKey: key
+notificationRoutes:
+  - monitoredValues: [release]
+    notifiers: [release-issues, release-pager]
+`
+
+func TestNotifiersConfiguration(t *testing.T) {
+	var config IdentityMonitorConfiguration
+	if err := yaml.Unmarshal([]byte(notifiersTestConfig), &config); err != nil {
+		t.Fatalf("error decoding configuration: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	if got, want := notifierNames(config), []string{"githubIssue", "release-issues", "dev-issues", "security-email", "release-pager"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notifierNames() = %v, want %v", got, want)
+	}
+	if got := len(CreateNotificationPool(config)); got != 5 {
+		t.Errorf("expected 5 notification platforms, got %d", got)
+	}
+
+	pool := CreateNamedNotificationPool(config)
+	releaseIssues, ok := pool["release-issues"].(*GitHubIssueInput)
+	if !ok {
+		t.Fatalf("expected release-issues to be a GitHub issue notifier, got %T", pool["release-issues"])
+	}
+	if releaseIssues.RepositoryName != "release" || releaseIssues.AssigneeUsername != "releaser" {
+		t.Errorf("unexpected release-issues settings: %+v", releaseIssues)
+	}
+	if devIssues := pool["dev-issues"].(*GitHubIssueInput); devIssues.RepositoryName != "dev" {
+		t.Errorf("unexpected dev-issues settings: %+v", devIssues)
+	}
+	if legacy := pool["githubIssue"].(*GitHubIssueInput); legacy.RepositoryName != "rekor-monitor" {
+		t.Errorf("unexpected githubIssue settings: %+v", legacy)
+	}
+	if _, ok := pool["release-pager"].(Resolver); !ok {
+		t.Errorf("expected release-pager to resolve incidents")
+	}
+}
+
+func TestNotifiersConfigurationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errMsg string
+	}{
+		{
+			name:   "unknown type",
+			config: "notifiers:\n  - name: pager\n    type: pager\n",
+			errMsg: `unknown type "pager" of notifier "pager"`,
+		},
+		{
+			name:   "missing name",
+			config: "notifiers:\n  - type: webhook\n    url: https://example.com\n",
+			errMsg: "name of webhook notifier must be set",
+		},
+		{
+			name:   "duplicate name",
+			config: "notifiers:\n  - name: hook\n    type: webhook\n    url: https://example.com\n  - name: hook\n    type: webhook\n    url: https://example.org\n",
+			errMsg: "invalid notifier name hook: used by more than one notifier",
+		},
+		{
+			name:   "reserved name",
+			config: "notifiers:\n  - name: slack\n    type: webhook\n    url: https://example.com\n",
+			errMsg: "invalid notifier name slack: reserved",
+		},
+		{
+			name:   "invalid settings",
+			config: "notifiers:\n  - name: hook\n    type: webhook\n    url: example.com\n",
+			errMsg: "invalid notifier hook: invalid webhook URL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config IdentityMonitorConfiguration
+			err := yaml.Unmarshal([]byte(tt.config), &config)
+			if err == nil {
+				err = config.Validate()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+type registeredPlatform struct {
+	Target string `yaml:"target"`
+}
+
+func (registeredPlatform) Send(context.Context, NotificationData) error {
+	return nil
+}
+
+func TestRegisterNotifierType(t *testing.T) {
+	RegisterNotifierType("registered", func() NotificationPlatform { return &registeredPlatform{} })
+	defer delete(notifierTypes, "registered")
+
+	var config IdentityMonitorConfiguration
+	if err := yaml.Unmarshal([]byte("notifiers:\n  - name: custom\n    type: registered\n    target: somewhere\n"), &config); err != nil {
+		t.Fatalf("error decoding configuration: %v", err)
+	}
+	platform, ok := CreateNamedNotificationPool(config)["custom"].(*registeredPlatform)
+	if !ok || platform.Target != "somewhere" {
+		t.Errorf("expected registered platform with target, got %+v", CreateNamedNotificationPool(config)["custom"])
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultOpsgenieAPIURL = "https://api.opsgenie.com"
+
+// defaultOpsgeniePriorities are the Opsgenie priorities used if none are configured
+var defaultOpsgeniePriorities = SeverityMapping{
+	IdentityMatch:      "P1",
+	FailedEntry:        "P3",
+	ConsistencyFailure: "P1",
+}
+
+// OpsgenieNotificationInput extends the NotificationPlatform interface to support
+// found identity and consistency failure notification by creating Opsgenie alerts.
+type OpsgenieNotificationInput struct {
+	APIKey string `yaml:"apiKey"`
+	// Priorities of the alerts per kind of notification. Can be one of P1 to P5.
+	Priorities SeverityMapping `yaml:"priorities"`
+	// Responders are the names of the teams the alerts are routed to
+	Responders []string `yaml:"responders"`
+	Tags       []string `yaml:"tags"`
+	// APIURL overrides the Opsgenie API, e.g. https://api.eu.opsgenie.com for the EU instance
+	APIURL string `yaml:"apiURL"`
+	// Timeout of a request, defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// opsgenieResponder is a responder of an Opsgenie alert
+type opsgenieResponder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// opsgenieAlert is the body of an Opsgenie create alert request
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description"`
+	Priority    string              `json:"priority"`
+	Source      string              `json:"source"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Details     map[string]string   `json:"details,omitempty"`
+}
+
+// Validate checks the Opsgenie configuration
+func (opsgenieNotificationInput OpsgenieNotificationInput) Validate() error {
+	if opsgenieNotificationInput.APIKey == "" {
+		return errors.New("opsgenie apiKey must be set")
+	}
+	if opsgenieNotificationInput.APIURL != "" {
+		if err := validateHTTPURL("opsgenie API", opsgenieNotificationInput.APIURL); err != nil {
+			return err
+		}
+	}
+	return opsgenieNotificationInput.Priorities.validate("opsgenie", "P1", "P2", "P3", "P4", "P5")
+}
+
+func (opsgenieNotificationInput OpsgenieNotificationInput) post(ctx context.Context, path string, request any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error encoding opsgenie request: %w", err)
+	}
+	client, err := newHTTPClient(opsgenieNotificationInput.Timeout, "")
+	if err != nil {
+		return err
+	}
+	apiURL := opsgenieNotificationInput.APIURL
+	if apiURL == "" {
+		apiURL = defaultOpsgenieAPIURL
+	}
+	headers := map[string]string{"Authorization": "GenieKey " + opsgenieNotificationInput.APIKey}
+	return postJSON(ctx, client, strings.TrimSuffix(apiURL, "/")+path, body, headers)
+}
+
+// Send implements the NotificationPlatform interface
+func (opsgenieNotificationInput OpsgenieNotificationInput) Send(ctx context.Context, data NotificationData) error {
+	body, err := data.Payload.ToNotificationBody()
+	if err != nil {
+		return err
+	}
+	kind := notificationKind(data.Payload)
+	alert := opsgenieAlert{
+		Message:     truncate(data.Context.Subject, 130),
+		Alias:       truncate(incidentDedupKey(data), 512),
+		Description: truncate(data.Payload.ToNotificationHeader()+"\n"+string(body), 15000),
+		Priority:    opsgenieNotificationInput.Priorities.severity(kind, defaultOpsgeniePriorities),
+		Source:      data.Context.MonitorType,
+		Tags:        append([]string{data.Context.MonitorType, kind}, opsgenieNotificationInput.Tags...),
+		Details: map[string]string{
+			"monitorType": data.Context.MonitorType,
+			"logOrigin":   data.Context.LogOrigin,
+			"kind":        kind,
+		},
+	}
+	for _, responder := range opsgenieNotificationInput.Responders {
+		alert.Responders = append(alert.Responders, opsgenieResponder{Name: responder, Type: "team"})
+	}
+	return opsgenieNotificationInput.post(ctx, "/v2/alerts", alert)
+}
+
+// Resolve implements the Resolver interface
+func (opsgenieNotificationInput OpsgenieNotificationInput) Resolve(ctx context.Context, notificationContext NotificationContext) error {
+	alias := url.PathEscape(truncate(consistencyDedupKey(notificationContext), 512))
+	return opsgenieNotificationInput.post(ctx, "/v2/alerts/"+alias+"/close?identifierType=alias", map[string]string{
+		"source": notificationContext.MonitorType,
+		"note":   "The consistency check of the log succeeded again",
+	})
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+func TestOpsgenieSendAndResolve(t *testing.T) {
+	var paths []string
+	var alert opsgenieAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey test-key" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+		paths = append(paths, r.URL.RequestURI())
+		if r.URL.Path == "/v2/alerts" {
+			if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	opsgenie := OpsgenieNotificationInput{
+		APIKey:     "test-key",
+		Priorities: SeverityMapping{IdentityMatch: "P2"},
+		Responders: []string{"security"},
+		APIURL:     server.URL + "/",
+	}
+	notificationContext := CreateNotificationContext("ct-monitor", "test-subject").WithLogOrigin("ctfe.sigstore.dev")
+	data := NotificationData{
+		Context: notificationContext,
+		Payload: identity.MonitoredIdentityList{
+			{Identity: "test-identity", FoundIdentityEntries: []identity.LogEntry{{Index: 3}}},
+		},
+	}
+	if err := opsgenie.Send(context.Background(), data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := opsgenie.Resolve(context.Background(), notificationContext); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	if alert.Priority != "P2" || alert.Alias != "ct-monitor:ctfe.sigstore.dev:identityMatch:3-3" || alert.Message != "test-subject" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if len(alert.Responders) != 1 || alert.Responders[0] != (opsgenieResponder{Name: "security", Type: "team"}) {
+		t.Errorf("unexpected responders %+v", alert.Responders)
+	}
+	if len(paths) != 2 || paths[1] != "/v2/alerts/ct-monitor:ctfe.sigstore.dev:consistency/close?identifierType=alias" {
+		t.Errorf("unexpected requests %v", paths)
+	}
+}
+
+func TestOpsgenieValidate(t *testing.T) {
+	if err := (OpsgenieNotificationInput{}).Validate(); err == nil {
+		t.Error("expected error without API key")
+	}
+	invalid := OpsgenieNotificationInput{APIKey: "key", Priorities: SeverityMapping{ConsistencyFailure: "critical"}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for invalid priority")
+	}
+}
@@ -0,0 +1,524 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util"
+	"github.com/sigstore/rekor-monitor/pkg/util/file"
+)
+
+const (
+	defaultOutboxMaxAttempts  = 5
+	defaultOutboxDeliveredTTL = 30 * 24 * time.Hour
+
+	outboxPendingDir     = "pending"
+	outboxDeliveredDir   = "delivered"
+	outboxDeadLetterFile = "dead-letter.jsonl"
+
+	payloadKindMonitoredIdentities = "monitoredIdentities"
+	payloadKindFailedLogEntries    = "failedLogEntries"
+	payloadKindConsistencyFailure  = "consistencyFailure"
+)
+
+// NotificationOutboxInput configures the persistent notification outbox.
+// Notifications are queued in Directory and delivered to every notification
+// platform independently. A notification that could not be delivered to a
+// platform after MaxAttempts deliveries is moved to the dead-letter file.
+// Delivered notifications are remembered for DeliveredTTL, so that they are
+// not delivered again when the same log entries are found again.
+type NotificationOutboxInput struct {
+	Directory    string        `yaml:"directory"`
+	MaxAttempts  int           `yaml:"maxAttempts"`
+	DeliveredTTL time.Duration `yaml:"deliveredTTL"`
+}
+
+// Validate checks the outbox configuration
+func (o *NotificationOutboxInput) Validate() error {
+	if o.Directory == "" {
+		return errors.New("notification outbox directory must be set")
+	}
+	if o.MaxAttempts < 0 {
+		return fmt.Errorf("invalid notification outbox maxAttempts %d: must not be negative", o.MaxAttempts)
+	}
+	if o.DeliveredTTL < 0 {
+		return fmt.Errorf("invalid notification outbox deliveredTTL %s: must not be negative", o.DeliveredTTL)
+	}
+	return nil
+}
+
+// platformDelivery tracks the delivery of a queued notification to a platform
+type platformDelivery struct {
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastAttempt time.Time `json:"lastAttempt,omitzero"`
+}
+
+// outboxRecord is a queued notification, with the platforms it still has to be delivered to
+type outboxRecord struct {
+	ID          string                       `json:"id"`
+	CreatedAt   time.Time                    `json:"createdAt"`
+	Context     NotificationContext          `json:"context"`
+	PayloadKind string                       `json:"payloadKind"`
+	Payload     json.RawMessage              `json:"payload"`
+	Platforms   map[string]*platformDelivery `json:"platforms"`
+}
+
+// deadLetter is a notification that could not be delivered to a platform
+type deadLetter struct {
+	Record   outboxRecord `json:"record"`
+	Platform string       `json:"platform"`
+	Error    string       `json:"error"`
+	DeadAt   time.Time    `json:"deadAt"`
+}
+
+// Outbox persists notifications to disk until they are delivered to every platform
+type Outbox struct {
+	directory    string
+	maxAttempts  int
+	deliveredTTL time.Duration
+	retryOpts    []func(*util.RetryConfig)
+}
+
+// NewOutbox creates an outbox from its configuration. The retry options
+// configure the retries of each delivery to a platform.
+func NewOutbox(input NotificationOutboxInput, retryOpts ...func(*util.RetryConfig)) (*Outbox, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfiguration, err)
+	}
+	maxAttempts := input.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultOutboxMaxAttempts
+	}
+	deliveredTTL := input.DeliveredTTL
+	if deliveredTTL == 0 {
+		deliveredTTL = defaultOutboxDeliveredTTL
+	}
+	for _, dir := range []string{outboxPendingDir, outboxDeliveredDir} {
+		if err := os.MkdirAll(filepath.Join(input.Directory, dir), 0700); err != nil {
+			return nil, fmt.Errorf("error creating notification outbox directory: %w", err)
+		}
+	}
+	return &Outbox{
+		directory:    input.Directory,
+		maxAttempts:  maxAttempts,
+		deliveredTTL: deliveredTTL,
+		retryOpts:    retryOpts,
+	}, nil
+}
+
+// encodePayload returns the kind and JSON encoding of a notification payload
+func encodePayload(payload NotificationBodyConverter) (string, json.RawMessage, error) {
+	var kind string
+	switch payload.(type) {
+	case identity.MonitoredIdentityList:
+		kind = payloadKindMonitoredIdentities
+	case identity.FailedLogEntryList:
+		kind = payloadKindFailedLogEntries
+	case ConsistencyFailure:
+		kind = payloadKindConsistencyFailure
+	default:
+		return "", nil, fmt.Errorf("unsupported notification payload type %T", payload)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("error encoding notification payload: %w", err)
+	}
+	return kind, raw, nil
+}
+
+// decodePayload decodes a notification payload from its kind and JSON encoding
+func decodePayload(kind string, raw json.RawMessage) (NotificationBodyConverter, error) {
+	var payload NotificationBodyConverter
+	var err error
+	switch kind {
+	case payloadKindMonitoredIdentities:
+		var identities identity.MonitoredIdentityList
+		err = json.Unmarshal(raw, &identities)
+		payload = identities
+	case payloadKindFailedLogEntries:
+		var failedEntries identity.FailedLogEntryList
+		err = json.Unmarshal(raw, &failedEntries)
+		payload = failedEntries
+	case payloadKindConsistencyFailure:
+		var failure ConsistencyFailure
+		err = json.Unmarshal(raw, &failure)
+		payload = failure
+	default:
+		return nil, fmt.Errorf("unsupported notification payload kind %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding notification payload: %w", err)
+	}
+	return payload, nil
+}
+
+// notificationID returns the key used to deduplicate notifications. It is
+// derived from the monitor, the log and the log entries of the notification
+// only, so that the same log entries found again in a later run, with a
+// different subject or detection time, get the same key.
+func notificationID(ctx NotificationContext, kind string, payload NotificationBodyConverter) string {
+	h := sha256.New()
+	for _, part := range []string{ctx.MonitorType, ctx.LogOrigin, kind} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	for _, key := range payloadKeys(payload) {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// payloadKeys returns the sorted keys identifying the log entries of a
+// notification payload
+func payloadKeys(payload NotificationBodyConverter) []string {
+	tuples := [][]any{}
+	switch p := payload.(type) {
+	case identity.MonitoredIdentityList:
+		for _, monitoredIdentity := range p {
+			for _, entry := range monitoredIdentity.FoundIdentityEntries {
+				rules := []string{string(entry.MatchedIdentityType) + ":" + entry.MatchedIdentity}
+				for _, rule := range entry.MatchedRules {
+					rules = append(rules, string(rule.MatchedIdentityType)+":"+rule.MatchedIdentity)
+				}
+				sort.Strings(rules)
+				tuples = append(tuples, []any{entry.Index, entry.UUID, monitoredIdentity.Identity, rules})
+			}
+		}
+	case identity.FailedLogEntryList:
+		for _, entry := range p {
+			tuples = append(tuples, []any{entry.Index, entry.UUID})
+		}
+	case ConsistencyFailure:
+		tuples = append(tuples, []any{p.LogOrigin, p.Error})
+	}
+	keys := make([]string, 0, len(tuples))
+	for _, tuple := range tuples {
+		// encoding a tuple of strings, integers and string slices cannot fail
+		key, _ := json.Marshal(tuple)
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (o *Outbox) pendingPath(id string) string {
+	return filepath.Join(o.directory, outboxPendingDir, id+".json")
+}
+
+func (o *Outbox) deliveredPath(id string) string {
+	return filepath.Join(o.directory, outboxDeliveredDir, id)
+}
+
+func (o *Outbox) deadLetterPath() string {
+	return filepath.Join(o.directory, outboxDeadLetterFile)
+}
+
+func (o *Outbox) readRecord(path string) (*outboxRecord, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var record outboxRecord
+	if err := json.Unmarshal(content, &record); err != nil {
+		return nil, fmt.Errorf("error parsing queued notification %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+func (o *Outbox) writeRecord(record *outboxRecord) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := file.WriteFileAtomic(o.pendingPath(record.ID), content); err != nil {
+		return fmt.Errorf("error writing queued notification: %w", err)
+	}
+	return nil
+}
+
+// Enqueue queues a notification for delivery to the given platforms.
+// A notification with the same content that is already queued or was
+// already delivered is not queued again.
+func (o *Outbox) Enqueue(data NotificationData, platforms []string) error {
+	kind, raw, err := encodePayload(data.Payload)
+	if err != nil {
+		return err
+	}
+	id := notificationID(data.Context, kind, data.Payload)
+
+	if _, err := os.Stat(o.deliveredPath(id)); err == nil {
+		slog.Debug("skipping already delivered notification", "notificationId", id)
+		return nil
+	}
+	if _, err := os.Stat(o.pendingPath(id)); err == nil {
+		slog.Debug("skipping already queued notification", "notificationId", id)
+		return nil
+	}
+
+	record := &outboxRecord{
+		ID:          id,
+		CreatedAt:   time.Now().UTC(),
+		Context:     data.Context,
+		PayloadKind: kind,
+		Payload:     raw,
+		Platforms:   map[string]*platformDelivery{},
+	}
+	for _, platform := range platforms {
+		record.Platforms[platform] = &platformDelivery{}
+	}
+	if len(record.Platforms) == 0 {
+		return nil
+	}
+	return o.writeRecord(record)
+}
+
+// pendingRecords returns the queued notifications, oldest first
+func (o *Outbox) pendingRecords() ([]*outboxRecord, error) {
+	paths, err := filepath.Glob(filepath.Join(o.directory, outboxPendingDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	records := []*outboxRecord{}
+	for _, path := range paths {
+		record, err := o.readRecord(path)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records, nil
+}
+
+// Deliver sends the queued notifications to their pending platforms, retrying
+// each platform independently. A notification whose delivery to a platform
+// failed is kept queued for the next call, until it reached the maximum
+// number of attempts and is moved to the dead-letter file.
+// Deliver returns the errors of all failed deliveries.
+func (o *Outbox) Deliver(ctx context.Context, platforms map[string]NotificationPlatform) error {
+	records, err := o.pendingRecords()
+	if err != nil {
+		return fmt.Errorf("error reading notification outbox: %w", err)
+	}
+
+	var errs []error
+	if err := o.pruneDelivered(time.Now()); err != nil {
+		errs = append(errs, err)
+	}
+	for _, record := range records {
+		if err := o.deliverRecord(ctx, record, platforms); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o *Outbox) deliverRecord(ctx context.Context, record *outboxRecord, platforms map[string]NotificationPlatform) error {
+	payload, err := decodePayload(record.PayloadKind, record.Payload)
+	if err != nil {
+		return err
+	}
+	data := NotificationData{Context: record.Context, Payload: payload}
+
+	var errs []error
+	deadLetters := []deadLetter{}
+	for _, name := range sortedKeys(record.Platforms) {
+		delivery := record.Platforms[name]
+		platform, ok := platforms[name]
+		if !ok {
+			err = fmt.Errorf("notification platform %s is not configured", name)
+		} else {
+			var sendErr error
+			_, err = util.Retry(ctx, func() (any, error) {
+				sendErr = platform.Send(ctx, data)
+				if sendErr != nil && !isTransientError(sendErr) {
+					// stops retrying, the delivery is attempted again on the next run
+					return nil, util.WrapError(sendErr)
+				}
+				return nil, sendErr
+			}, o.retryOpts...)
+			if err != nil && sendErr != nil {
+				err = fmt.Errorf("%v: %w", err, sendErr)
+			}
+		}
+		if err == nil {
+			delete(record.Platforms, name)
+			continue
+		}
+
+		delivery.Attempts++
+		delivery.LastError = err.Error()
+		delivery.LastAttempt = time.Now().UTC()
+		errs = append(errs, fmt.Errorf("error sending notification %s to %s: %w", record.ID, name, err))
+		if delivery.Attempts >= o.maxAttempts {
+			deadLetters = append(deadLetters, deadLetter{
+				Platform: name,
+				Error:    delivery.LastError,
+				DeadAt:   delivery.LastAttempt,
+			})
+			delete(record.Platforms, name)
+		}
+	}
+
+	if len(deadLetters) > 0 {
+		if err := o.appendDeadLetters(record, deadLetters); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	if len(record.Platforms) > 0 {
+		if err := o.writeRecord(record); err != nil {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+
+	if err := os.WriteFile(o.deliveredPath(record.ID), nil, 0600); err != nil {
+		errs = append(errs, fmt.Errorf("error marking notification as delivered: %w", err))
+	}
+	if err := os.Remove(o.pendingPath(record.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("error removing delivered notification: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// pruneDelivered removes the markers of notifications delivered longer than
+// the delivered TTL ago
+func (o *Outbox) pruneDelivered(now time.Time) error {
+	entries, err := os.ReadDir(filepath.Join(o.directory, outboxDeliveredDir))
+	if err != nil {
+		return fmt.Errorf("error reading delivered notifications: %w", err)
+	}
+	var errs []error
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if now.Sub(info.ModTime()) < o.deliveredTTL {
+			continue
+		}
+		if err := os.Remove(o.deliveredPath(entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("error removing delivered notification marker: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o *Outbox) appendDeadLetters(record *outboxRecord, deadLetters []deadLetter) error {
+	f, err := os.OpenFile(o.deadLetterPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening dead-letter file: %w", err)
+	}
+	defer f.Close()
+
+	for _, letter := range deadLetters {
+		letter.Record = *record
+		letter.Record.Platforms = nil
+		content, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(content, '\n')); err != nil {
+			return fmt.Errorf("error writing dead-letter file: %w", err)
+		}
+		slog.Error("giving up on notification delivery, moved to dead-letter file",
+			"notificationId", record.ID, "platform", letter.Platform, "deadLetterFile", o.deadLetterPath())
+	}
+	return nil
+}
+
+// ReplayDeadLetters queues the dead-lettered notifications again for delivery
+// to the platforms they failed on, and empties the dead-letter file.
+// It returns the number of replayed notifications.
+func (o *Outbox) ReplayDeadLetters() (int, error) {
+	f, err := os.Open(o.deadLetterPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error opening dead-letter file: %w", err)
+	}
+
+	letters := []deadLetter{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var letter deadLetter
+		if err := json.Unmarshal([]byte(line), &letter); err != nil {
+			f.Close()
+			return 0, fmt.Errorf("error parsing dead-letter file: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+	f.Close()
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("error reading dead-letter file: %w", err)
+	}
+
+	for _, letter := range letters {
+		record, err := o.readRecord(o.pendingPath(letter.Record.ID))
+		if errors.Is(err, os.ErrNotExist) {
+			record = &letter.Record
+		} else if err != nil {
+			return 0, err
+		}
+		if record.Platforms == nil {
+			record.Platforms = map[string]*platformDelivery{}
+		}
+		record.Platforms[letter.Platform] = &platformDelivery{}
+		if err := o.writeRecord(record); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := os.Remove(o.deadLetterPath()); err != nil {
+		return 0, fmt.Errorf("error removing dead-letter file: %w", err)
+	}
+	return len(letters), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
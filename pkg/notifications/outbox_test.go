@@ -0,0 +1,284 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util"
+)
+
+type recordingPlatform struct {
+	fail bool
+	// err is returned instead of the default error when failing
+	err      error
+	attempts int
+	sent     []NotificationData
+}
+
+func (p *recordingPlatform) Send(_ context.Context, data NotificationData) error {
+	p.attempts++
+	if p.fail {
+		if p.err != nil {
+			return p.err
+		}
+		return errors.New("platform unavailable")
+	}
+	p.sent = append(p.sent, data)
+	return nil
+}
+
+func newTestOutbox(t *testing.T, maxAttempts int) (*Outbox, string) {
+	dir := t.TempDir()
+	outbox, err := NewOutbox(NotificationOutboxInput{Directory: dir, MaxAttempts: maxAttempts},
+		util.WithMaxAttempts(1), util.WithInitialInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewOutbox() error = %v", err)
+	}
+	return outbox, dir
+}
+
+func testNotificationData() NotificationData {
+	return NotificationData{
+		Context: CreateNotificationContext("test-monitor", "test-subject"),
+		Payload: identity.MonitoredIdentityList{
+			{
+				Identity: "test-identity",
+				FoundIdentityEntries: []identity.LogEntry{
+					{CertSubject: "test-subject", Index: 5, UUID: "test-uuid"},
+				},
+			},
+		},
+	}
+}
+
+func countPending(t *testing.T, dir string) int {
+	paths, err := filepath.Glob(filepath.Join(dir, outboxPendingDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(paths)
+}
+
+func TestOutboxDeliverAndDeduplicate(t *testing.T) {
+	outbox, dir := newTestOutbox(t, 3)
+	data := testNotificationData()
+
+	for range 2 {
+		if err := outbox.Enqueue(data, []string{"a", "b"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if got := countPending(t, dir); got != 1 {
+		t.Fatalf("expected 1 queued notification, got %d", got)
+	}
+
+	a, b := &recordingPlatform{}, &recordingPlatform{}
+	if err := outbox.Deliver(context.Background(), map[string]NotificationPlatform{"a": a, "b": b}); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if len(a.sent) != 1 || len(b.sent) != 1 {
+		t.Fatalf("expected one delivery per platform, got %d and %d", len(a.sent), len(b.sent))
+	}
+	sent, ok := a.sent[0].Payload.(identity.MonitoredIdentityList)
+	if !ok || len(sent) != 1 || sent[0].FoundIdentityEntries[0].UUID != "test-uuid" {
+		t.Errorf("unexpected delivered payload %#v", a.sent[0].Payload)
+	}
+	if got := countPending(t, dir); got != 0 {
+		t.Errorf("expected no queued notification, got %d", got)
+	}
+
+	// an already delivered notification is not queued again
+	if err := outbox.Enqueue(data, []string{"a", "b"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if got := countPending(t, dir); got != 0 {
+		t.Errorf("expected delivered notification not to be queued again, got %d", got)
+	}
+}
+
+func TestOutboxDeduplicateAcrossRuns(t *testing.T) {
+	outbox, dir := newTestOutbox(t, 3)
+	platforms := map[string]NotificationPlatform{"a": &recordingPlatform{}}
+
+	first := testNotificationData()
+	first.Context.Subject = "rekor-monitor found identities at 2025-01-01T00:00:00Z"
+	if err := outbox.Enqueue(first, []string{"a"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := outbox.Deliver(context.Background(), platforms); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	// the same entries found in a later run, with another subject and detection time
+	again := testNotificationData()
+	again.Context.Subject = "rekor-monitor found identities at 2025-01-02T00:00:00Z"
+	again.Payload.(identity.MonitoredIdentityList)[0].FoundIdentityEntries[0].DetectedAt = time.Now()
+	if err := outbox.Enqueue(again, []string{"a"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if got := countPending(t, dir); got != 0 {
+		t.Errorf("expected notification of the same entries not to be queued again, got %d", got)
+	}
+
+	// other entries are queued
+	other := testNotificationData()
+	other.Payload.(identity.MonitoredIdentityList)[0].FoundIdentityEntries[0].Index = 6
+	if err := outbox.Enqueue(other, []string{"a"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if got := countPending(t, dir); got != 1 {
+		t.Errorf("expected notification of other entries to be queued, got %d", got)
+	}
+}
+
+func TestOutboxPruneDelivered(t *testing.T) {
+	outbox, dir := newTestOutbox(t, 3)
+	data := testNotificationData()
+	if err := outbox.Enqueue(data, []string{"a"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := outbox.Deliver(context.Background(), map[string]NotificationPlatform{"a": &recordingPlatform{}}); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	markers, err := os.ReadDir(filepath.Join(dir, outboxDeliveredDir))
+	if err != nil || len(markers) != 1 {
+		t.Fatalf("expected 1 delivered marker, got %d: %v", len(markers), err)
+	}
+	if err := outbox.pruneDelivered(time.Now()); err != nil {
+		t.Fatalf("pruneDelivered() error = %v", err)
+	}
+	if markers, _ = os.ReadDir(filepath.Join(dir, outboxDeliveredDir)); len(markers) != 1 {
+		t.Fatalf("expected recent delivered marker to be kept, got %d", len(markers))
+	}
+
+	if err := outbox.pruneDelivered(time.Now().Add(defaultOutboxDeliveredTTL)); err != nil {
+		t.Fatalf("pruneDelivered() error = %v", err)
+	}
+	if markers, _ = os.ReadDir(filepath.Join(dir, outboxDeliveredDir)); len(markers) != 0 {
+		t.Errorf("expected expired delivered marker to be removed, got %d", len(markers))
+	}
+}
+
+func TestOutboxDeadLetterAndReplay(t *testing.T) {
+	outbox, dir := newTestOutbox(t, 2)
+	if err := outbox.Enqueue(testNotificationData(), []string{"ok", "failing"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	ok, failing := &recordingPlatform{}, &recordingPlatform{fail: true}
+	platforms := map[string]NotificationPlatform{"ok": ok, "failing": failing}
+
+	// the failing platform doesn't prevent delivery to the other one
+	if err := outbox.Deliver(context.Background(), platforms); err == nil {
+		t.Fatal("expected Deliver() to return the failed delivery")
+	}
+	if len(ok.sent) != 1 {
+		t.Fatalf("expected delivery to the working platform, got %d", len(ok.sent))
+	}
+	if got := countPending(t, dir); got != 1 {
+		t.Fatalf("expected notification to stay queued, got %d", got)
+	}
+
+	// the working platform is not notified twice, the failing one is dead-lettered
+	if err := outbox.Deliver(context.Background(), platforms); err == nil {
+		t.Fatal("expected Deliver() to return the failed delivery")
+	}
+	if len(ok.sent) != 1 {
+		t.Errorf("expected no duplicate delivery, got %d", len(ok.sent))
+	}
+	if got := countPending(t, dir); got != 0 {
+		t.Fatalf("expected notification to be dead-lettered, got %d queued", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, outboxDeadLetterFile)); err != nil {
+		t.Fatalf("expected dead-letter file: %v", err)
+	}
+
+	failing.fail = false
+	replayed, err := outbox.ReplayDeadLetters()
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if replayed != 1 {
+		t.Errorf("expected 1 replayed notification, got %d", replayed)
+	}
+	if err := outbox.Deliver(context.Background(), platforms); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if len(failing.sent) != 1 || len(ok.sent) != 1 {
+		t.Errorf("expected replay to only deliver to the dead-lettered platform, got %d and %d", len(failing.sent), len(ok.sent))
+	}
+	if _, err := os.Stat(filepath.Join(dir, outboxDeadLetterFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected dead-letter file to be removed, got %v", err)
+	}
+}
+
+func TestOutboxRetriesTransientErrors(t *testing.T) {
+	tests := map[string]struct {
+		err          error
+		wantAttempts int
+	}{
+		"network error":       {err: errors.New("connection reset"), wantAttempts: 3},
+		"server error":        {err: util.WrapError(httpStatusError{statusCode: http.StatusBadGateway}), wantAttempts: 3},
+		"rate limited":        {err: githubRateLimitError{err: errors.New("rate limited"), reset: time.Now()}, wantAttempts: 3},
+		"smtp unavailable":    {err: &textproto.Error{Code: 421, Msg: "service not available"}, wantAttempts: 3},
+		"unauthorized":        {err: util.WrapError(httpStatusError{statusCode: http.StatusUnauthorized}), wantAttempts: 1},
+		"bad request":         {err: util.WrapError(httpStatusError{statusCode: http.StatusBadRequest}), wantAttempts: 1},
+		"smtp authentication": {err: fmt.Errorf("error sending email: %w", &textproto.Error{Code: 535, Msg: "authentication failed"}), wantAttempts: 1},
+		"invalid config":      {err: fmt.Errorf("%w: missing token", ErrInvalidConfiguration), wantAttempts: 1},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			outbox, err := NewOutbox(NotificationOutboxInput{Directory: t.TempDir()},
+				util.WithMaxAttempts(3), util.WithInitialInterval(time.Millisecond))
+			if err != nil {
+				t.Fatalf("NewOutbox() error = %v", err)
+			}
+			if err := outbox.Enqueue(testNotificationData(), []string{"failing"}); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+			failing := &recordingPlatform{fail: true, err: tt.err}
+			if err := outbox.Deliver(context.Background(), map[string]NotificationPlatform{"failing": failing}); err == nil {
+				t.Fatal("expected Deliver() to return the failed delivery")
+			}
+			if failing.attempts != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, failing.attempts)
+			}
+		})
+	}
+}
+
+func TestOutboxUnsupportedPayload(t *testing.T) {
+	outbox, _ := newTestOutbox(t, 1)
+	err := outbox.Enqueue(NotificationData{Payload: MockPayload{}}, []string{"a"})
+	if err == nil {
+		t.Error("expected error for unsupported payload")
+	}
+}
+
+type MockPayload struct{}
+
+func (MockPayload) ToNotificationBody() ([]byte, error) { return []byte("body"), nil }
+func (MockPayload) ToNotificationHeader() string        { return "header" }
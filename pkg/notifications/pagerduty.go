@@ -0,0 +1,132 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// defaultPagerDutySeverities are the PagerDuty severities used if none are configured
+var defaultPagerDutySeverities = SeverityMapping{
+	IdentityMatch:      "critical",
+	FailedEntry:        "warning",
+	ConsistencyFailure: "critical",
+}
+
+// PagerDutyNotificationInput extends the NotificationPlatform interface to support
+// found identity and consistency failure notification by triggering PagerDuty
+// incidents through the Events API v2.
+type PagerDutyNotificationInput struct {
+	// RoutingKey is the integration key of a PagerDuty service
+	RoutingKey string `yaml:"routingKey"`
+	// Severities of the incidents per kind of notification. Can be one of
+	// critical, error, warning or info.
+	Severities SeverityMapping `yaml:"severities"`
+	// EventsURL overrides the Events API v2 endpoint
+	EventsURL string `yaml:"eventsURL"`
+	// Timeout of a request, defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// pagerDutyPayload is the payload of a PagerDuty trigger event
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Timestamp     string         `json:"timestamp"`
+	Component     string         `json:"component"`
+	Class         string         `json:"class"`
+	CustomDetails map[string]any `json:"custom_details,omitempty"`
+}
+
+// pagerDutyEvent is a PagerDuty Events API v2 event
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+// Validate checks the PagerDuty configuration
+func (pagerDutyNotificationInput PagerDutyNotificationInput) Validate() error {
+	if pagerDutyNotificationInput.RoutingKey == "" {
+		return errors.New("pagerduty routingKey must be set")
+	}
+	if pagerDutyNotificationInput.EventsURL != "" {
+		if err := validateHTTPURL("pagerduty events", pagerDutyNotificationInput.EventsURL); err != nil {
+			return err
+		}
+	}
+	return pagerDutyNotificationInput.Severities.validate("pagerduty", "critical", "error", "warning", "info")
+}
+
+func (pagerDutyNotificationInput PagerDutyNotificationInput) sendEvent(ctx context.Context, event pagerDutyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding pagerduty event: %w", err)
+	}
+	client, err := newHTTPClient(pagerDutyNotificationInput.Timeout, "")
+	if err != nil {
+		return err
+	}
+	eventsURL := pagerDutyNotificationInput.EventsURL
+	if eventsURL == "" {
+		eventsURL = defaultPagerDutyEventsURL
+	}
+	return postJSON(ctx, client, eventsURL, body, nil)
+}
+
+// Send implements the NotificationPlatform interface
+func (pagerDutyNotificationInput PagerDutyNotificationInput) Send(ctx context.Context, data NotificationData) error {
+	details, err := incidentDetails(data)
+	if err != nil {
+		return err
+	}
+	kind := notificationKind(data.Payload)
+	summary := truncate(data.Context.Subject+": "+data.Payload.ToNotificationHeader(), 1024)
+	source := data.Context.LogOrigin
+	if source == "" {
+		source = data.Context.MonitorType
+	}
+	return pagerDutyNotificationInput.sendEvent(ctx, pagerDutyEvent{
+		RoutingKey:  pagerDutyNotificationInput.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    incidentDedupKey(data),
+		Payload: &pagerDutyPayload{
+			Summary:       summary,
+			Source:        source,
+			Severity:      pagerDutyNotificationInput.Severities.severity(kind, defaultPagerDutySeverities),
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+			Component:     data.Context.MonitorType,
+			Class:         kind,
+			CustomDetails: details,
+		},
+	})
+}
+
+// Resolve implements the Resolver interface
+func (pagerDutyNotificationInput PagerDutyNotificationInput) Resolve(ctx context.Context, notificationContext NotificationContext) error {
+	return pagerDutyNotificationInput.sendEvent(ctx, pagerDutyEvent{
+		RoutingKey:  pagerDutyNotificationInput.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    consistencyDedupKey(notificationContext),
+	})
+}
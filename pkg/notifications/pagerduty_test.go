@@ -0,0 +1,120 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+func TestPagerDutySendAndResolve(t *testing.T) {
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pagerDuty := PagerDutyNotificationInput{
+		RoutingKey: "test-routing-key",
+		Severities: SeverityMapping{FailedEntry: "info"},
+		EventsURL:  server.URL,
+	}
+	notificationContext := CreateNotificationContext("rekor-monitor", "test-subject").WithLogOrigin("rekor.sigstore.dev")
+
+	matches := NotificationData{
+		Context: notificationContext,
+		Payload: identity.MonitoredIdentityList{
+			{Identity: "test-identity", FoundIdentityEntries: []identity.LogEntry{{Index: 12}, {Index: 4}}},
+		},
+	}
+	failed := NotificationData{
+		Context: notificationContext,
+		Payload: identity.FailedLogEntryList{{Index: 7, Error: "parse error"}},
+	}
+	consistency := NotificationData{
+		Context: notificationContext,
+		Payload: ConsistencyFailure{LogOrigin: "rekor.sigstore.dev", Error: "inconsistent", DetectedAt: time.Now()},
+	}
+	for _, data := range []NotificationData{matches, failed, consistency} {
+		if err := pagerDuty.Send(context.Background(), data); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := pagerDuty.Resolve(context.Background(), notificationContext); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+	expected := []struct {
+		action, dedupKey, severity string
+	}{
+		{"trigger", "rekor-monitor:rekor.sigstore.dev:identityMatch:4-12", "critical"},
+		{"trigger", "rekor-monitor:rekor.sigstore.dev:failedEntry:7-7", "info"},
+		{"trigger", "rekor-monitor:rekor.sigstore.dev:consistency", "critical"},
+		{"resolve", "rekor-monitor:rekor.sigstore.dev:consistency", ""},
+	}
+	for i, want := range expected {
+		event := events[i]
+		if event.RoutingKey != "test-routing-key" || event.EventAction != want.action || event.DedupKey != want.dedupKey {
+			t.Errorf("unexpected event %d: %+v", i, event)
+		}
+		if want.severity != "" && (event.Payload == nil || event.Payload.Severity != want.severity) {
+			t.Errorf("expected severity %s for event %d, got %+v", want.severity, i, event.Payload)
+		}
+	}
+	if events[0].Payload.Source != "rekor.sigstore.dev" || events[0].Payload.Class != NotificationKindIdentityMatch {
+		t.Errorf("unexpected payload %+v", events[0].Payload)
+	}
+	if events[3].Payload != nil {
+		t.Errorf("expected no payload for resolve event")
+	}
+}
+
+func TestPagerDutyValidate(t *testing.T) {
+	if err := (PagerDutyNotificationInput{}).Validate(); err == nil {
+		t.Error("expected error without routing key")
+	}
+	invalid := PagerDutyNotificationInput{RoutingKey: "key", Severities: SeverityMapping{IdentityMatch: "P1"}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for invalid severity")
+	}
+	if err := (PagerDutyNotificationInput{RoutingKey: "key"}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestCreateResolverPool(t *testing.T) {
+	config := IdentityMonitorConfiguration{
+		GitHubIssue: &GitHubIssueInput{},
+		PagerDuty:   &PagerDutyNotificationInput{RoutingKey: "key"},
+		Opsgenie:    &OpsgenieNotificationInput{APIKey: "key"},
+	}
+	if resolvers := CreateResolverPool(config); len(resolvers) != 2 {
+		t.Errorf("expected 2 incident platforms, got %d", len(resolvers))
+	}
+}
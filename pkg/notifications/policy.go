@@ -0,0 +1,388 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util/file"
+)
+
+// NotificationPoliciesInput configures how often notifications are sent to
+// notification platforms. Notifications to a platform with a policy are held
+// in StateFile, and sent as one merged notification once the policy allows it.
+type NotificationPoliciesInput struct {
+	StateFile string `yaml:"stateFile"`
+	// Policies are keyed by the name of the notification platform
+	Policies map[string]NotificationPolicy `yaml:"policies"`
+}
+
+// NotificationPolicy limits the notifications sent to a notification platform
+type NotificationPolicy struct {
+	// DigestInterval, if set, holds notifications for this long after the
+	// first held notification, and sends them as a single digest
+	DigestInterval time.Duration `yaml:"digestInterval"`
+	// MaxNotifications, if set, is the maximum number of notifications sent
+	// within RateLimitWindow. Further notifications are held until the window allows them.
+	MaxNotifications int           `yaml:"maxNotifications"`
+	RateLimitWindow  time.Duration `yaml:"rateLimitWindow"`
+	// QuietHours, if set, hold notifications during a daily time range
+	QuietHours *QuietHours `yaml:"quietHours"`
+}
+
+// QuietHours is a daily time range, from Start to End in 24h "15:04" format.
+// The range wraps around midnight if End is before Start.
+type QuietHours struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// TimeZone is an IANA time zone name, defaults to UTC
+	TimeZone string `yaml:"timeZone"`
+}
+
+// Validate checks the notification policies configuration
+func (p *NotificationPoliciesInput) Validate() error {
+	if p.StateFile == "" {
+		return errors.New("notification policies stateFile must be set")
+	}
+	for _, name := range sortedKeys(p.Policies) {
+		if err := p.Policies[name].validate(); err != nil {
+			return fmt.Errorf("invalid notification policy for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p NotificationPolicy) validate() error {
+	if p.DigestInterval < 0 {
+		return fmt.Errorf("digestInterval %s must not be negative", p.DigestInterval)
+	}
+	if p.MaxNotifications < 0 {
+		return fmt.Errorf("maxNotifications %d must not be negative", p.MaxNotifications)
+	}
+	if p.MaxNotifications > 0 && p.RateLimitWindow <= 0 {
+		return errors.New("rateLimitWindow must be set with maxNotifications")
+	}
+	if p.QuietHours != nil {
+		if _, err := p.QuietHours.contains(time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// minuteOfDay parses a "15:04" time of day
+func minuteOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quiet hours time %q: must be formatted as 15:04", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains returns whether the time is within the quiet hours
+func (q QuietHours) contains(now time.Time) (bool, error) {
+	start, err := minuteOfDay(q.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := minuteOfDay(q.End)
+	if err != nil {
+		return false, err
+	}
+	if start == end {
+		return false, fmt.Errorf("invalid quiet hours: start and end must differ")
+	}
+	location := time.UTC
+	if q.TimeZone != "" {
+		if location, err = time.LoadLocation(q.TimeZone); err != nil {
+			return false, fmt.Errorf("invalid quiet hours time zone %s: %w", q.TimeZone, err)
+		}
+	}
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end, nil
+	}
+	return minute >= start || minute < end, nil
+}
+
+// heldNotifications are the notifications held for a notification platform,
+// merged into a single notification, and the times notifications were sent
+type heldNotifications struct {
+	Since         time.Time                      `json:"since,omitzero"`
+	Count         int                            `json:"count"`
+	Context       *NotificationContext           `json:"context,omitempty"`
+	Identities    identity.MonitoredIdentityList `json:"identities,omitempty"`
+	FailedEntries identity.FailedLogEntryList    `json:"failedEntries,omitempty"`
+	Sent          []time.Time                    `json:"sent,omitempty"`
+}
+
+// NotificationPolicies holds notifications according to the configured policies,
+// persisting held notifications so that they survive restarts
+type NotificationPolicies struct {
+	input     NotificationPoliciesInput
+	platforms map[string]*heldNotifications
+}
+
+// LoadNotificationPolicies loads the held notifications from the state file
+func LoadNotificationPolicies(input NotificationPoliciesInput) (*NotificationPolicies, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfiguration, err)
+	}
+	p := &NotificationPolicies{input: input, platforms: map[string]*heldNotifications{}}
+	content, err := os.ReadFile(input.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading notification policies state: %w", err)
+	}
+	if err := json.Unmarshal(content, &p.platforms); err != nil {
+		return nil, fmt.Errorf("error parsing notification policies state %s: %w", input.StateFile, err)
+	}
+	return p, nil
+}
+
+// save persists the held notifications to the state file
+func (p *NotificationPolicies) save() error {
+	content, err := json.MarshalIndent(p.platforms, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error encoding notification policies state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.input.StateFile), 0700); err != nil {
+		return fmt.Errorf("error creating notification policies state directory: %w", err)
+	}
+	return file.WriteFileAtomic(p.input.StateFile, content)
+}
+
+// Applies returns whether notifications to the platform are subject to a policy
+func (p *NotificationPolicies) Applies(platform string) bool {
+	_, ok := p.input.Policies[platform]
+	return ok
+}
+
+// held returns the held notifications of a platform
+func (p *NotificationPolicies) held(platform string) *heldNotifications {
+	held, ok := p.platforms[platform]
+	if !ok {
+		held = &heldNotifications{}
+		p.platforms[platform] = held
+	}
+	return held
+}
+
+// Hold holds a notification to a platform until its policy allows sending it
+func (p *NotificationPolicies) Hold(platform string, data NotificationData, now time.Time) error {
+	return p.HoldAll(map[string][]NotificationData{platform: {data}}, now)
+}
+
+// HoldAll holds notifications, keyed by platform, until the policies of their
+// platforms allow sending them. The notifications are held at once: if one of
+// them can't be held, none of them is.
+func (p *NotificationPolicies) HoldAll(notifications map[string][]NotificationData, now time.Time) error {
+	for _, platform := range sortedKeys(notifications) {
+		for _, data := range notifications[platform] {
+			switch data.Payload.(type) {
+			case identity.MonitoredIdentityList, identity.FailedLogEntryList:
+			default:
+				return fmt.Errorf("notifications of type %T can't be held", data.Payload)
+			}
+		}
+	}
+	previous, err := json.Marshal(p.platforms)
+	if err != nil {
+		return fmt.Errorf("error encoding notification policies state: %w", err)
+	}
+	for _, platform := range sortedKeys(notifications) {
+		for _, data := range notifications[platform] {
+			p.hold(platform, data, now)
+		}
+	}
+	if err := p.save(); err != nil {
+		restored := map[string]*heldNotifications{}
+		if json.Unmarshal(previous, &restored) == nil {
+			p.platforms = restored
+		}
+		return err
+	}
+	return nil
+}
+
+// hold merges a notification into the held notifications of a platform
+func (p *NotificationPolicies) hold(platform string, data NotificationData, now time.Time) {
+	held := p.held(platform)
+	switch payload := data.Payload.(type) {
+	case identity.MonitoredIdentityList:
+		held.Identities = mergeMonitoredIdentities(held.Identities, payload)
+	case identity.FailedLogEntryList:
+		held.FailedEntries = mergeFailedEntries(held.FailedEntries, payload)
+	}
+	if held.Count == 0 {
+		held.Since = now
+	}
+	held.Count++
+	notificationContext := data.Context
+	if held.Context != nil && held.Context.Checkpoint != nil && notificationContext.Checkpoint != nil {
+		notificationContext = notificationContext.WithCheckpoint(
+			min(held.Context.Checkpoint.StartIndex, notificationContext.Checkpoint.StartIndex),
+			max(held.Context.Checkpoint.EndIndex, notificationContext.Checkpoint.EndIndex))
+	}
+	held.Context = &notificationContext
+}
+
+// Due returns the held notifications that the policies allow sending now,
+// keyed by platform. They stay held until MarkSent is called.
+func (p *NotificationPolicies) Due(now time.Time) map[string][]NotificationData {
+	due := map[string][]NotificationData{}
+	for _, platform := range sortedKeys(p.platforms) {
+		held := p.platforms[platform]
+		policy, ok := p.input.Policies[platform]
+		if held.Count == 0 {
+			continue
+		}
+		// notifications held for a platform without policy are sent right away
+		if ok && !policy.allows(held, now) {
+			continue
+		}
+		notificationContext := *held.Context
+		if held.Count > 1 {
+			notificationContext.Subject = fmt.Sprintf("%s digest of %d notifications since %s", notificationContext.MonitorType, held.Count, held.Since.Format(time.RFC822))
+		}
+		if len(held.Identities) > 0 {
+			due[platform] = append(due[platform], NotificationData{Context: notificationContext, Payload: held.Identities})
+		}
+		if len(held.FailedEntries) > 0 {
+			due[platform] = append(due[platform], NotificationData{Context: notificationContext, Payload: held.FailedEntries})
+		}
+	}
+	return due
+}
+
+// allows returns whether the held notifications can be sent now
+func (p NotificationPolicy) allows(held *heldNotifications, now time.Time) bool {
+	if p.DigestInterval > 0 && now.Before(held.Since.Add(p.DigestInterval)) {
+		return false
+	}
+	if p.MaxNotifications > 0 && len(recentSends(held.Sent, now, p.RateLimitWindow)) >= p.MaxNotifications {
+		return false
+	}
+	if p.QuietHours != nil {
+		// quiet hours are validated with the configuration
+		if quiet, _ := p.QuietHours.contains(now); quiet {
+			return false
+		}
+	}
+	return true
+}
+
+// recentSends returns the send times within the window before now
+func recentSends(sent []time.Time, now time.Time, window time.Duration) []time.Time {
+	recent := []time.Time{}
+	for _, t := range sent {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// MarkDelivered releases a due notification of a platform after it was sent,
+// so that it is not sent again when sending the other due notifications of
+// the platform fails. MarkSent must be called once all of them were sent.
+func (p *NotificationPolicies) MarkDelivered(platform string, data NotificationData) error {
+	held := p.held(platform)
+	switch data.Payload.(type) {
+	case identity.MonitoredIdentityList:
+		held.Identities = nil
+	case identity.FailedLogEntryList:
+		held.FailedEntries = nil
+	default:
+		return fmt.Errorf("notifications of type %T can't be held", data.Payload)
+	}
+	return p.save()
+}
+
+// MarkSent releases the held notifications of a platform after they were sent
+func (p *NotificationPolicies) MarkSent(platform string, now time.Time) error {
+	held := p.held(platform)
+	sent := held.Sent
+	if policy, ok := p.input.Policies[platform]; ok && policy.MaxNotifications > 0 {
+		sent = append(recentSends(sent, now, policy.RateLimitWindow), now)
+	} else {
+		sent = nil
+	}
+	*held = heldNotifications{Sent: sent}
+	return p.save()
+}
+
+// mergeMonitoredIdentities merges found identities, skipping entries already held
+func mergeMonitoredIdentities(held, found identity.MonitoredIdentityList) identity.MonitoredIdentityList {
+	type entryKey struct {
+		identity string
+		matched  string
+		index    int64
+		uuid     string
+	}
+	seen := map[entryKey]bool{}
+	positions := map[string]int{}
+	for i, monitoredIdentity := range held {
+		positions[monitoredIdentity.Identity] = i
+		for _, entry := range monitoredIdentity.FoundIdentityEntries {
+			seen[entryKey{monitoredIdentity.Identity, entry.MatchedIdentity, entry.Index, entry.UUID}] = true
+		}
+	}
+	for _, monitoredIdentity := range found {
+		for _, entry := range monitoredIdentity.FoundIdentityEntries {
+			key := entryKey{monitoredIdentity.Identity, entry.MatchedIdentity, entry.Index, entry.UUID}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			i, ok := positions[monitoredIdentity.Identity]
+			if !ok {
+				i = len(held)
+				positions[monitoredIdentity.Identity] = i
+				held = append(held, identity.MonitoredIdentity{Identity: monitoredIdentity.Identity})
+			}
+			held[i].FoundIdentityEntries = append(held[i].FoundIdentityEntries, entry)
+		}
+	}
+	return held
+}
+
+// mergeFailedEntries merges failed entries, skipping entries already held
+func mergeFailedEntries(held, failed identity.FailedLogEntryList) identity.FailedLogEntryList {
+	type entryKey struct {
+		index int64
+		uuid  string
+	}
+	seen := map[entryKey]bool{}
+	for _, entry := range held {
+		seen[entryKey{entry.Index, entry.UUID}] = true
+	}
+	for _, entry := range failed {
+		if !seen[entryKey{entry.Index, entry.UUID}] {
+			seen[entryKey{entry.Index, entry.UUID}] = true
+			held = append(held, entry)
+		}
+	}
+	return held
+}
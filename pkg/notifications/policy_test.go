@@ -0,0 +1,330 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+func policyTestData(identityValue string, indices ...int64) NotificationData {
+	entries := []identity.LogEntry{}
+	for _, index := range indices {
+		entries = append(entries, identity.LogEntry{CertSubject: identityValue, MatchedIdentity: identityValue, Index: index})
+	}
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "rekor-monitor workflow results").WithCheckpoint(indices[0], indices[len(indices)-1]),
+		Payload: identity.MonitoredIdentityList{{Identity: identityValue, FoundIdentityEntries: entries}},
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name       string
+		quietHours QuietHours
+		now        time.Time
+		want       bool
+	}{
+		{"within daytime range", QuietHours{Start: "12:00", End: "13:30"}, at(13, 0), true},
+		{"at end of range", QuietHours{Start: "12:00", End: "13:30"}, at(13, 30), false},
+		{"before overnight range", QuietHours{Start: "22:00", End: "07:00"}, at(21, 59), false},
+		{"within overnight range before midnight", QuietHours{Start: "22:00", End: "07:00"}, at(23, 0), true},
+		{"within overnight range after midnight", QuietHours{Start: "22:00", End: "07:00"}, at(6, 59), true},
+		{"time zone", QuietHours{Start: "22:00", End: "07:00", TimeZone: "America/New_York"}, at(4, 0), true},
+		{"outside range in time zone", QuietHours{Start: "22:00", End: "07:00", TimeZone: "America/New_York"}, at(23, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.quietHours.contains(tt.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("contains(%s) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationPoliciesDigest(t *testing.T) {
+	input := NotificationPoliciesInput{
+		StateFile: filepath.Join(t.TempDir(), "state", "policies.json"),
+		Policies:  map[string]NotificationPolicy{"githubIssue": {DigestInterval: time.Hour}},
+	}
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	policies, err := LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policies.Applies("githubIssue") || policies.Applies("slack") {
+		t.Errorf("unexpected policies: %+v", input.Policies)
+	}
+	if err := policies.Hold("githubIssue", policyTestData("a@example.com", 1, 2), start); err != nil {
+		t.Fatal(err)
+	}
+	if due := policies.Due(start.Add(30 * time.Minute)); len(due) != 0 {
+		t.Errorf("expected no due notifications within the digest interval, got %v", due)
+	}
+
+	// held notifications survive restarts, and entries are merged without duplicates
+	policies, err = LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policies.Hold("githubIssue", policyTestData("a@example.com", 2, 3), start.Add(40*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := policies.Hold("githubIssue", policyTestData("b@example.com", 4), start.Add(50*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	due := policies.Due(start.Add(time.Hour))["githubIssue"]
+	if len(due) != 1 {
+		t.Fatalf("expected a single digest, got %v", due)
+	}
+	digest := due[0].Payload.(identity.MonitoredIdentityList)
+	if len(digest) != 2 || len(digest[0].FoundIdentityEntries) != 3 || len(digest[1].FoundIdentityEntries) != 1 {
+		t.Errorf("unexpected digest: %+v", digest)
+	}
+	if !strings.HasPrefix(due[0].Context.Subject, "rekor-monitor digest of 3 notifications since") {
+		t.Errorf("unexpected digest subject %q", due[0].Context.Subject)
+	}
+	if checkpoint := due[0].Context.Checkpoint; checkpoint == nil || checkpoint.StartIndex != 1 || checkpoint.EndIndex != 4 {
+		t.Errorf("expected digest to cover indices 1-4, got %+v", checkpoint)
+	}
+
+	if err := policies.MarkSent("githubIssue", start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	policies, err = LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due := policies.Due(start.Add(2 * time.Hour)); len(due) != 0 {
+		t.Errorf("expected no due notifications after sending the digest, got %v", due)
+	}
+}
+
+func TestNotificationPoliciesMarkDelivered(t *testing.T) {
+	input := NotificationPoliciesInput{
+		StateFile: filepath.Join(t.TempDir(), "policies.json"),
+		Policies:  map[string]NotificationPolicy{"slack": {DigestInterval: time.Hour}},
+	}
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	policies, err := LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policies.Hold("slack", policyTestData("a@example.com", 1), start); err != nil {
+		t.Fatal(err)
+	}
+	failed := NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "failed entries"),
+		Payload: identity.FailedLogEntryList{{Index: 2, UUID: "uuid", Error: "error"}},
+	}
+	if err := policies.Hold("slack", failed, start); err != nil {
+		t.Fatal(err)
+	}
+	due := policies.Due(start.Add(time.Hour))["slack"]
+	if len(due) != 2 {
+		t.Fatalf("expected two due notifications, got %v", due)
+	}
+
+	// the found identities were sent, sending the failed entries failed
+	if err := policies.MarkDelivered("slack", due[0]); err != nil {
+		t.Fatal(err)
+	}
+	policies, err = LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due = policies.Due(start.Add(time.Hour))["slack"]
+	if len(due) != 1 {
+		t.Fatalf("expected only the unsent notification to be due, got %v", due)
+	}
+	if _, ok := due[0].Payload.(identity.FailedLogEntryList); !ok {
+		t.Errorf("expected the failed entries to be due, got %T", due[0].Payload)
+	}
+}
+
+func TestNotificationPoliciesRateLimit(t *testing.T) {
+	input := NotificationPoliciesInput{
+		StateFile: filepath.Join(t.TempDir(), "policies.json"),
+		Policies:  map[string]NotificationPolicy{"slack": {MaxNotifications: 2, RateLimitWindow: time.Hour}},
+	}
+	policies, err := LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for i, now := range []time.Time{start, start.Add(10 * time.Minute)} {
+		if err := policies.Hold("slack", policyTestData("a@example.com", int64(i)), now); err != nil {
+			t.Fatal(err)
+		}
+		if len(policies.Due(now)["slack"]) != 1 {
+			t.Fatalf("expected notification %d to be sent right away", i)
+		}
+		if err := policies.MarkSent("slack", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := policies.Hold("slack", policyTestData("a@example.com", 2), start.Add(20*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if due := policies.Due(start.Add(20 * time.Minute)); len(due) != 0 {
+		t.Errorf("expected the rate limit to hold the notification, got %v", due)
+	}
+	if len(policies.Due(start.Add(time.Hour))["slack"]) != 1 {
+		t.Errorf("expected the notification to be sent once the window allows it")
+	}
+}
+
+func TestNotificationPoliciesQuietHours(t *testing.T) {
+	input := NotificationPoliciesInput{
+		StateFile: filepath.Join(t.TempDir(), "policies.json"),
+		Policies:  map[string]NotificationPolicy{"teams": {QuietHours: &QuietHours{Start: "22:00", End: "07:00"}}},
+	}
+	policies, err := LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	night := time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC)
+	if err := policies.Hold("teams", policyTestData("a@example.com", 1), night); err != nil {
+		t.Fatal(err)
+	}
+	if err := policies.Hold("teams", NotificationData{Context: CreateNotificationContext("rekor-monitor", "s"), Payload: identity.FailedLogEntryList{{Index: 5}}}, night); err != nil {
+		t.Fatal(err)
+	}
+	if due := policies.Due(night); len(due) != 0 {
+		t.Errorf("expected quiet hours to hold the notifications, got %v", due)
+	}
+	if due := policies.Due(night.Add(8 * time.Hour)); len(due["teams"]) != 2 {
+		t.Errorf("expected found identities and failed entries after quiet hours, got %v", due)
+	}
+}
+
+func TestNotificationPoliciesHoldAll(t *testing.T) {
+	dir := t.TempDir()
+	input := NotificationPoliciesInput{
+		StateFile: filepath.Join(dir, "policies.json"),
+		Policies: map[string]NotificationPolicy{
+			"slack": {DigestInterval: time.Hour},
+			"teams": {DigestInterval: time.Hour},
+		},
+	}
+	policies, err := LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	if err := policies.HoldAll(map[string][]NotificationData{
+		"slack": {policyTestData("a@example.com", 1)},
+		"teams": {policyTestData("a@example.com", 1)},
+	}, start); err != nil {
+		t.Fatal(err)
+	}
+
+	// none of the notifications is held if one of them can't be
+	err = policies.HoldAll(map[string][]NotificationData{
+		"slack": {policyTestData("b@example.com", 2)},
+		"teams": {{Payload: MockPayload{}}},
+	}, start)
+	if err == nil {
+		t.Fatal("expected error for a notification that can't be held")
+	}
+	// nor if the state can't be saved
+	policies.input.StateFile = filepath.Join(dir, "policies.json", "policies.json")
+	if err := policies.HoldAll(map[string][]NotificationData{
+		"slack": {policyTestData("c@example.com", 3)},
+	}, start); err == nil {
+		t.Fatal("expected error saving the state")
+	}
+
+	for _, p := range []*NotificationPolicies{policies, mustLoadNotificationPolicies(t, input)} {
+		due := p.Due(start.Add(time.Hour))
+		for _, platform := range []string{"slack", "teams"} {
+			if len(due[platform]) != 1 || due[platform][0].Context.Checkpoint.EndIndex != 1 {
+				t.Fatalf("expected only the first notification to be held for %s, got %+v", platform, due[platform])
+			}
+			held := due[platform][0].Payload.(identity.MonitoredIdentityList)
+			if len(held) != 1 || held[0].Identity != "a@example.com" {
+				t.Errorf("expected only the first notification to be held for %s, got %+v", platform, held)
+			}
+		}
+	}
+}
+
+func mustLoadNotificationPolicies(t *testing.T, input NotificationPoliciesInput) *NotificationPolicies {
+	t.Helper()
+	policies, err := LoadNotificationPolicies(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policies
+}
+
+func TestNotificationPoliciesValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  NotificationPoliciesInput
+		errMsg string
+	}{
+		{
+			name:   "missing state file",
+			input:  NotificationPoliciesInput{},
+			errMsg: "stateFile must be set",
+		},
+		{
+			name:   "missing rate limit window",
+			input:  NotificationPoliciesInput{StateFile: "state.json", Policies: map[string]NotificationPolicy{"slack": {MaxNotifications: 1}}},
+			errMsg: "invalid notification policy for slack: rateLimitWindow must be set",
+		},
+		{
+			name:   "invalid quiet hours",
+			input:  NotificationPoliciesInput{StateFile: "state.json", Policies: map[string]NotificationPolicy{"slack": {QuietHours: &QuietHours{Start: "10pm", End: "07:00"}}}},
+			errMsg: `invalid quiet hours time "10pm"`,
+		},
+		{
+			name:   "invalid time zone",
+			input:  NotificationPoliciesInput{StateFile: "state.json", Policies: map[string]NotificationPolicy{"slack": {QuietHours: &QuietHours{Start: "22:00", End: "07:00", TimeZone: "Mars/Olympus"}}}},
+			errMsg: "invalid quiet hours time zone Mars/Olympus",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+
+	config := IdentityMonitorConfiguration{
+		NotificationPolicies: &NotificationPoliciesInput{StateFile: "state.json", Policies: map[string]NotificationPolicy{"slack": {}}},
+	}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "invalid notification policy for slack: notifier is not configured") {
+		t.Errorf("expected unconfigured notifier error, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+// NotificationRoute sends the matches of some monitored values only to the
+// listed notification platforms. A route without monitored values and labels
+// is a default route, used for matches that no other route selects and for
+// notifications that are not about a monitored value, such as failed entries.
+type NotificationRoute struct {
+	// MonitoredValues are values as written in monitoredValues, e.g. a
+	// certSubject regex, a fingerprint, a subject or an OID extension value
+	MonitoredValues []string `yaml:"monitoredValues"`
+	// Labels select the monitored values carrying any of these labels
+	Labels []string `yaml:"labels"`
+	// Notifiers are the names of the notification platforms, e.g. pagerDuty or githubIssue
+	Notifiers []string `yaml:"notifiers"`
+}
+
+// RoutedNotification is notification data to be sent to the named notification platforms
+type RoutedNotification struct {
+	Data      NotificationData
+	Notifiers []string
+}
+
+// isDefault returns whether the route applies to unrouted notifications
+func (r NotificationRoute) isDefault() bool {
+	return len(r.MonitoredValues) == 0 && len(r.Labels) == 0
+}
+
+// selects returns whether the route applies to matches of the monitored value with the given labels
+func (r NotificationRoute) selects(monitoredValue string, labels []string) bool {
+	if slices.Contains(r.MonitoredValues, monitoredValue) {
+		return true
+	}
+	for _, label := range labels {
+		if slices.Contains(r.Labels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNotificationRoutes checks that routes only reference configured
+// notification platforms and defined labels
+func (c *IdentityMonitorConfiguration) validateNotificationRoutes() error {
+	if len(c.NotificationRoutes) == 0 {
+		return nil
+	}
+	platforms := CreateNamedNotificationPool(*c)
+	definedLabels := map[string]bool{}
+	for _, labels := range c.MonitoredValues.monitoredValueLabels() {
+		for _, label := range labels {
+			definedLabels[label] = true
+		}
+	}
+	for i, route := range c.NotificationRoutes {
+		if len(route.Notifiers) == 0 {
+			return fmt.Errorf("invalid notification route %d: notifiers must be set", i)
+		}
+		for _, notifier := range route.Notifiers {
+			if _, ok := platforms[notifier]; !ok {
+				return fmt.Errorf("invalid notification route %d: notifier %s is not configured", i, notifier)
+			}
+		}
+		for _, label := range route.Labels {
+			if !definedLabels[label] {
+				return fmt.Errorf("invalid notification route %d: label %s is not attached to any monitored value", i, label)
+			}
+		}
+	}
+	return nil
+}
+
+// monitoredValueLabels returns the labels of each labeled monitored value
+func (v ConfigMonitoredValues) monitoredValueLabels() map[string][]string {
+	labels := map[string][]string{}
+	for value, valueLabels := range v.Labels {
+		labels[value] = append(labels[value], valueLabels...)
+	}
+	for _, certIdentity := range v.CertificateIdentities {
+		labels[certIdentity.CertSubject] = append(labels[certIdentity.CertSubject], certIdentity.Labels...)
+	}
+	for _, rule := range v.Rules {
+		labels[rule.Name] = append(labels[rule.Name], rule.Labels...)
+	}
+	for _, policy := range v.Policies {
+		labels[policy.Name] = append(labels[policy.Name], policy.Labels...)
+	}
+	return labels
+}
+
+// RouteNotification splits notification data by the notification platforms it
+// should be sent to, according to the configured notification routes. Entries
+// matching a monitored value that no route selects, and notifications that are
+// not about monitored values, go to the default routes, or to all notification
+// platforms if there is no default route.
+func RouteNotification(config IdentityMonitorConfiguration, data NotificationData) []RoutedNotification {
+	allNotifiers := notifierNames(config)
+	if len(config.NotificationRoutes) == 0 {
+		return []RoutedNotification{{Data: data, Notifiers: allNotifiers}}
+	}
+
+	// orderedNotifiers returns the selected notifiers in the order of allNotifiers
+	orderedNotifiers := func(selected map[string]bool) []string {
+		notifiers := []string{}
+		for _, name := range allNotifiers {
+			if selected[name] {
+				notifiers = append(notifiers, name)
+			}
+		}
+		return notifiers
+	}
+
+	defaultNotifiers := map[string]bool{}
+	for _, route := range config.NotificationRoutes {
+		if route.isDefault() {
+			for _, notifier := range route.Notifiers {
+				defaultNotifiers[notifier] = true
+			}
+		}
+	}
+	unrouted := allNotifiers
+	if len(defaultNotifiers) > 0 {
+		unrouted = orderedNotifiers(defaultNotifiers)
+	}
+
+	monitoredIdentities, ok := data.Payload.(identity.MonitoredIdentityList)
+	if !ok {
+		return []RoutedNotification{{Data: data, Notifiers: unrouted}}
+	}
+
+	labels := config.MonitoredValues.monitoredValueLabels()
+	notifiersOf := func(entry identity.LogEntry) []string {
+		selected := map[string]bool{}
+		// consolidated entries are routed by every rule that matched them
+		for _, rule := range entry.Rules() {
+			for _, route := range config.NotificationRoutes {
+				if !route.isDefault() && route.selects(rule.MatchedIdentity, labels[rule.MatchedIdentity]) {
+					for _, notifier := range route.Notifiers {
+						selected[notifier] = true
+					}
+				}
+			}
+		}
+		if len(selected) == 0 {
+			return unrouted
+		}
+		return orderedNotifiers(selected)
+	}
+
+	// group entries by the notifiers they are sent to, keeping the order of
+	// identities and entries
+	var routed []RoutedNotification
+	groups := map[string]int{}
+	for _, monitoredIdentity := range monitoredIdentities {
+		for _, entry := range monitoredIdentity.FoundIdentityEntries {
+			notifiers := notifiersOf(entry)
+			if len(notifiers) == 0 {
+				continue
+			}
+			key := strings.Join(notifiers, ",")
+			i, ok := groups[key]
+			if !ok {
+				i = len(routed)
+				groups[key] = i
+				routed = append(routed, RoutedNotification{
+					Data:      NotificationData{Context: data.Context, Payload: identity.MonitoredIdentityList{}},
+					Notifiers: notifiers,
+				})
+			}
+			list := routed[i].Data.Payload.(identity.MonitoredIdentityList)
+			if n := len(list); n > 0 && list[n-1].Identity == monitoredIdentity.Identity {
+				list[n-1].FoundIdentityEntries = append(list[n-1].FoundIdentityEntries, entry)
+			} else {
+				list = append(list, identity.MonitoredIdentity{
+					Identity:             monitoredIdentity.Identity,
+					FoundIdentityEntries: []identity.LogEntry{entry},
+				})
+			}
+			routed[i].Data.Payload = list
+		}
+	}
+	return routed
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+func routingTestConfig(routes ...NotificationRoute) IdentityMonitorConfiguration {
+	return IdentityMonitorConfiguration{
+		MonitoredValues: ConfigMonitoredValues{
+			CertificateIdentities: []identity.CertificateIdentity{
+				{CertSubject: "release@example\\.com", Labels: []string{"release"}},
+				{CertSubject: "dev@example\\.com"},
+			},
+			Fingerprints: []string{"dev-fingerprint"},
+			Labels:       map[string][]string{"dev-fingerprint": {"dev"}},
+		},
+		GitHubIssue:        &GitHubIssueInput{RepositoryOwner: "owner", RepositoryName: "repo"},
+		Webhook:            &WebhookNotificationInput{URL: "https://example.com/hook"},
+		PagerDuty:          &PagerDutyNotificationInput{RoutingKey: "key"},
+		NotificationRoutes: routes,
+	}
+}
+
+func routingTestData() NotificationData {
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "subject"),
+		Payload: identity.MonitoredIdentityList{
+			{
+				Identity: "release@example.com",
+				FoundIdentityEntries: []identity.LogEntry{
+					{MatchedIdentity: "release@example\\.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "release@example.com", Index: 1},
+				},
+			},
+			{
+				Identity: "dev-fingerprint",
+				FoundIdentityEntries: []identity.LogEntry{
+					{MatchedIdentity: "dev-fingerprint", MatchedIdentityType: identity.MatchedIdentityTypeFingerprint, Fingerprint: "dev-fingerprint", Index: 2},
+					{MatchedIdentity: "dev-fingerprint", MatchedIdentityType: identity.MatchedIdentityTypeFingerprint, Fingerprint: "dev-fingerprint", Index: 3},
+				},
+			},
+			{
+				Identity: "dev@example.com",
+				FoundIdentityEntries: []identity.LogEntry{
+					{MatchedIdentity: "dev@example\\.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "dev@example.com", Index: 4},
+				},
+			},
+		},
+	}
+}
+
+// routedIndices returns the log indices of each routed notification, keyed by its notifiers
+func routedIndices(t *testing.T, routed []RoutedNotification) map[string][]int64 {
+	t.Helper()
+	indices := map[string][]int64{}
+	for _, r := range routed {
+		key := strings.Join(r.Notifiers, ",")
+		if _, ok := indices[key]; ok {
+			t.Fatalf("notifiers %s routed more than once", key)
+		}
+		indices[key] = []int64{}
+		for _, monitoredIdentity := range r.Data.Payload.(identity.MonitoredIdentityList) {
+			for _, entry := range monitoredIdentity.FoundIdentityEntries {
+				indices[key] = append(indices[key], entry.Index)
+			}
+		}
+	}
+	return indices
+}
+
+func TestRouteNotification(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []NotificationRoute
+		want   map[string][]int64
+	}{
+		{
+			name: "no routes",
+			want: map[string][]int64{"githubIssue,webhook,pagerDuty": {1, 2, 3, 4}},
+		},
+		{
+			name: "routes by label, unrouted to all",
+			routes: []NotificationRoute{
+				{Labels: []string{"release"}, Notifiers: []string{"pagerDuty"}},
+				{Labels: []string{"dev"}, Notifiers: []string{"githubIssue"}},
+			},
+			want: map[string][]int64{
+				"pagerDuty":                     {1},
+				"githubIssue":                   {2, 3},
+				"githubIssue,webhook,pagerDuty": {4},
+			},
+		},
+		{
+			name: "routes by monitored value with default route",
+			routes: []NotificationRoute{
+				{MonitoredValues: []string{"release@example\\.com"}, Notifiers: []string{"pagerDuty", "webhook"}},
+				{Labels: []string{"release"}, Notifiers: []string{"githubIssue"}},
+				{Notifiers: []string{"webhook"}},
+			},
+			want: map[string][]int64{
+				"githubIssue,webhook,pagerDuty": {1},
+				"webhook":                       {2, 3, 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := routingTestConfig(tt.routes...)
+			if err := config.Validate(); err != nil {
+				t.Fatalf("unexpected validation error: %v", err)
+			}
+			got := routedIndices(t, RouteNotification(config, routingTestData()))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RouteNotification() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteNotificationKeepsIdentities(t *testing.T) {
+	config := routingTestConfig(NotificationRoute{Labels: []string{"dev"}, Notifiers: []string{"githubIssue"}})
+	routed := RouteNotification(config, routingTestData())
+	for _, r := range routed {
+		if !reflect.DeepEqual(r.Notifiers, []string{"githubIssue"}) {
+			continue
+		}
+		list := r.Data.Payload.(identity.MonitoredIdentityList)
+		if len(list) != 1 || list[0].Identity != "dev-fingerprint" || len(list[0].FoundIdentityEntries) != 2 {
+			t.Errorf("unexpected routed identities: %+v", list)
+		}
+		if r.Data.Context.Subject != "subject" {
+			t.Errorf("expected notification context to be kept, got %+v", r.Data.Context)
+		}
+		return
+	}
+	t.Fatalf("no notification routed to githubIssue")
+}
+
+func TestRouteNotificationRuleLabels(t *testing.T) {
+	config := routingTestConfig(NotificationRoute{Labels: []string{"security"}, Notifiers: []string{"pagerDuty"}})
+	config.MonitoredValues.Rules = []identity.Rule{{
+		Name:          "corp-signer",
+		Labels:        []string{"security"},
+		RuleCondition: identity.RuleCondition{CertSubject: ".*@example\\.com"},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	// the rule is not the primary rule of the consolidated entry
+	data := NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "subject"),
+		Payload: identity.MonitoredIdentityList{{
+			Identity: "dev@example.com",
+			FoundIdentityEntries: []identity.LogEntry{{
+				MatchedIdentity:     "dev@example\\.com",
+				MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
+				MatchedRules: []identity.MatchedRule{
+					{MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, MatchedIdentity: "dev@example\\.com", Value: "dev@example.com"},
+					{MatchedIdentityType: identity.MatchedIdentityTypeRule, MatchedIdentity: "corp-signer", Value: "dev@example.com"},
+				},
+				CertSubject: "dev@example.com",
+				Index:       4,
+			}},
+		}},
+	}
+	got := routedIndices(t, RouteNotification(config, data))
+	if want := map[string][]int64{"pagerDuty": {4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RouteNotification() = %v, want %v", got, want)
+	}
+}
+
+func TestRouteNotificationFailedEntries(t *testing.T) {
+	data := NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "subject"),
+		Payload: identity.FailedLogEntryList{{Index: 1, Error: "error"}},
+	}
+
+	routed := RouteNotification(routingTestConfig(NotificationRoute{Labels: []string{"dev"}, Notifiers: []string{"githubIssue"}}), data)
+	if len(routed) != 1 || !reflect.DeepEqual(routed[0].Notifiers, []string{"githubIssue", "webhook", "pagerDuty"}) {
+		t.Errorf("expected failed entries to be sent to all notifiers, got %+v", routed)
+	}
+
+	routed = RouteNotification(routingTestConfig(NotificationRoute{Notifiers: []string{"webhook"}}), data)
+	if len(routed) != 1 || !reflect.DeepEqual(routed[0].Notifiers, []string{"webhook"}) {
+		t.Errorf("expected failed entries to be sent to the default route, got %+v", routed)
+	}
+}
+
+func TestValidateNotificationRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  NotificationRoute
+		errMsg string
+	}{
+		{
+			name:   "missing notifiers",
+			route:  NotificationRoute{Labels: []string{"dev"}},
+			errMsg: "notifiers must be set",
+		},
+		{
+			name:   "unconfigured notifier",
+			route:  NotificationRoute{Labels: []string{"dev"}, Notifiers: []string{"slack"}},
+			errMsg: "notifier slack is not configured",
+		},
+		{
+			name:   "undefined label",
+			route:  NotificationRoute{Labels: []string{"prod"}, Notifiers: []string{"webhook"}},
+			errMsg: "label prod is not attached to any monitored value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := routingTestConfig(tt.route)
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sigstore/rekor-monitor/pkg/secrets"
+)
+
+// secretField is a credential of a notification platform, which can be set
+// to a secret reference such as env:NAME or file:/path
+type secretField struct {
+	name  string
+	value *string
+}
+
+// secretsHolder is implemented by notification platforms with credentials
+type secretsHolder interface {
+	secretFields() []secretField
+}
+
+func (e *EmailNotificationInput) secretFields() []secretField {
+	return append([]secretField{{"senderSMTPPassword", &e.SenderSMTPPassword}}, e.EmailOptions.secretFields()...)
+}
+
+func (m *MailgunNotificationInput) secretFields() []secretField {
+	return append([]secretField{{"mailgunAPIKey", &m.MailgunAPIKey}}, m.EmailOptions.secretFields()...)
+}
+
+func (s *SendGridNotificationInput) secretFields() []secretField {
+	return append([]secretField{{"sendGridAPIKey", &s.SendGridAPIKey}}, s.EmailOptions.secretFields()...)
+}
+
+func (g *GitHubIssueInput) secretFields() []secretField {
+	return []secretField{{"authenticationToken", &g.AuthenticationToken}}
+}
+
+func (w *WebhookNotificationInput) secretFields() []secretField {
+	return []secretField{{"secret", &w.Secret}}
+}
+
+func (s *SlackNotificationInput) secretFields() []secretField {
+	return []secretField{{"webhookURL", &s.WebhookURL}, {"botToken", &s.BotToken}}
+}
+
+func (t *TeamsNotificationInput) secretFields() []secretField {
+	return []secretField{{"webhookURL", &t.WebhookURL}}
+}
+
+func (p *PagerDutyNotificationInput) secretFields() []secretField {
+	return []secretField{{"routingKey", &p.RoutingKey}}
+}
+
+func (o *OpsgenieNotificationInput) secretFields() []secretField {
+	return []secretField{{"apiKey", &o.APIKey}}
+}
+
+// secretReference is a credential configured with a secret reference, which
+// is resolved again on every refresh to pick up rotated secrets
+type secretReference struct {
+	field     string
+	reference string
+	value     *string
+}
+
+// ResolveSecrets replaces the secret references in the credentials of the
+// notification platforms with the referenced secrets. Credentials configured
+// in plaintext are kept as is, without a secrets.LiteralPrefix. The referenced
+// secrets are redacted from log output.
+func (c *IdentityMonitorConfiguration) ResolveSecrets(ctx context.Context) error {
+	c.secretReferences = nil
+	pool := CreateNamedNotificationPool(*c)
+	for _, name := range notifierNames(*c) {
+		holder, ok := pool[name].(secretsHolder)
+		if !ok {
+			continue
+		}
+		for _, field := range holder.secretFields() {
+			if *field.value == "" {
+				continue
+			}
+			fieldName := name + "." + field.name
+			if secrets.IsReference(*field.value) {
+				c.secretReferences = append(c.secretReferences, secretReference{field: fieldName, reference: *field.value, value: field.value})
+			}
+			secret, err := secrets.Resolve(ctx, *field.value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", fieldName, err)
+			}
+			*field.value = secret
+		}
+	}
+	return nil
+}
+
+// RefreshSecrets resolves the secret references of the configuration again,
+// so that rotated secrets are used for the following notifications. Secrets
+// that can't be resolved keep their previous value.
+func (c *IdentityMonitorConfiguration) RefreshSecrets(ctx context.Context) error {
+	var errs []error
+	for _, ref := range c.secretReferences {
+		secret, err := secrets.Resolve(ctx, ref.reference)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", ref.field, err))
+			continue
+		}
+		*ref.value = secret
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error refreshing secrets: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/secrets"
+	"gopkg.in/yaml.v2"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("REKOR_MONITOR_TEST_SMTP_PASSWORD", "smtp-password")
+	tokenFile := filepath.Join(t.TempDir(), "github-token")
+	if err := os.WriteFile(tokenFile, []byte("ghp_first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configYAML := `
+emailNotificationSMTP:
+  recipientEmailAddress: security@example.com
+  senderEmailAddress: monitor@example.com
+  senderSMTPUsername: monitor
+  senderSMTPPassword: env:REKOR_MONITOR_TEST_SMTP_PASSWORD
+notifiers:
+  - name: issues
+    type: githubIssue
+    repositoryOwner: sigstore
+    repositoryName: rekor-monitor
+    authenticationToken: file:` + tokenFile + `
+  - name: pager
+    type: pagerDuty
+    routingKey: plaintext-routing-key
+`
+	var config IdentityMonitorConfiguration
+	if err := yaml.Unmarshal([]byte(configYAML), &config); err != nil {
+		t.Fatalf("error decoding configuration: %v", err)
+	}
+	if err := config.ResolveSecrets(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pool := CreateNamedNotificationPool(config)
+	if got := config.EmailNotificationSMTP.SenderSMTPPassword; got != "smtp-password" {
+		t.Errorf("senderSMTPPassword = %q, want smtp-password", got)
+	}
+	issues := pool["issues"].(*GitHubIssueInput)
+	if issues.AuthenticationToken != "ghp_first" {
+		t.Errorf("authenticationToken = %q, want ghp_first", issues.AuthenticationToken)
+	}
+	if got := pool["pager"].(*PagerDutyNotificationInput).RoutingKey; got != "plaintext-routing-key" {
+		t.Errorf("routingKey = %q, want plaintext-routing-key", got)
+	}
+	if got := secrets.Redact("password smtp-password"); got != "password [REDACTED]" {
+		t.Errorf("expected referenced credentials to be redacted, got %q", got)
+	}
+	if got := secrets.Redact("key plaintext-routing-key"); got != "key plaintext-routing-key" {
+		t.Errorf("expected plaintext credentials not to be redacted, got %q", got)
+	}
+
+	// rotated secrets are picked up on refresh
+	if err := os.WriteFile(tokenFile, []byte("ghp_second\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.RefreshSecrets(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if issues.AuthenticationToken != "ghp_second" {
+		t.Errorf("authenticationToken = %q after rotation, want ghp_second", issues.AuthenticationToken)
+	}
+
+	// secrets that can't be resolved keep their previous value
+	if err := os.Remove(tokenFile); err != nil {
+		t.Fatal(err)
+	}
+	err := config.RefreshSecrets(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid issues.authenticationToken") {
+		t.Errorf("expected refresh error, got %v", err)
+	}
+	if issues.AuthenticationToken != "ghp_second" {
+		t.Errorf("authenticationToken = %q after failed refresh, want ghp_second", issues.AuthenticationToken)
+	}
+}
+
+func TestResolveSecretsError(t *testing.T) {
+	config := IdentityMonitorConfiguration{
+		GitHubIssue: &GitHubIssueInput{AuthenticationToken: "env:REKOR_MONITOR_TEST_UNSET_TOKEN"},
+	}
+	err := config.ResolveSecrets(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid githubIssue.authenticationToken: error resolving secret env:REKOR_MONITOR_TEST_UNSET_TOKEN") {
+		t.Errorf("expected unresolved secret error, got %v", err)
+	}
+}
@@ -16,9 +16,21 @@ package notifications
 
 import (
 	"context"
+	"encoding/base64"
+	netmail "net/mail"
 
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	gomail "github.com/wneessen/go-mail"
+)
+
+const (
+	// defaultSendGridSMTPHostURL is the SendGrid SMTP relay, which signed
+	// emails are sent through as MIME messages
+	defaultSendGridSMTPHostURL = "smtp.sendgrid.net"
+	// sendGridSMTPUsername is the username of the SendGrid SMTP relay, which
+	// authenticates with the API key as password
+	sendGridSMTPUsername = "apikey"
 )
 
 // SendGrid extends the NotificationPlatform interface to support
@@ -29,19 +41,96 @@ type SendGridNotificationInput struct {
 	SenderName            string `yaml:"senderName"`
 	SenderEmailAddress    string `yaml:"senderEmailAddress"`
 	SendGridAPIKey        string `yaml:"sendGridAPIKey"`
+	// SMTPHostURL is the SendGrid SMTP relay that signed emails are sent
+	// through, since the SendGrid API builds the MIME message of other
+	// emails. Defaults to smtp.sendgrid.net.
+	SMTPHostURL string `yaml:"SMTPHostURL"`
+	// SMTPCustomOptions configure the connection to the SMTP relay
+	SMTPCustomOptions     []gomail.Option `yaml:"SMTPCustomOptions"`
+	NotificationTemplates `yaml:",inline"`
+	EmailOptions          `yaml:",inline"`
+}
+
+// Validate checks the email recipients, templates and signing keys
+func (sendGridNotificationInput SendGridNotificationInput) Validate() error {
+	if err := sendGridNotificationInput.NotificationTemplates.validate("emailNotificationSendGrid", true); err != nil {
+		return err
+	}
+	return sendGridNotificationInput.EmailOptions.validate("emailNotificationSendGrid", sendGridNotificationInput.RecipientEmailAddress)
+}
+
+// newSendGridMail builds the SendGrid message of an email
+func (sendGridNotificationInput SendGridNotificationInput) newSendGridMail(content emailContent) *mail.SGMailV3 {
+	email := mail.NewV3Mail()
+	email.SetFrom(mail.NewEmail(sendGridNotificationInput.SenderName, sendGridNotificationInput.SenderEmailAddress))
+	email.Subject = content.subject
+	personalization := mail.NewPersonalization()
+	if sendGridNotificationInput.RecipientEmailAddress != "" {
+		personalization.AddTos(mail.NewEmail(sendGridNotificationInput.RecipientName, sendGridNotificationInput.RecipientEmailAddress))
+	}
+	for _, to := range sendGridNotificationInput.To {
+		personalization.AddTos(mail.NewEmail("", to))
+	}
+	for _, cc := range sendGridNotificationInput.Cc {
+		personalization.AddCCs(mail.NewEmail("", cc))
+	}
+	for _, bcc := range sendGridNotificationInput.Bcc {
+		personalization.AddBCCs(mail.NewEmail("", bcc))
+	}
+	email.AddPersonalizations(personalization)
+	email.AddContent(mail.NewContent("text/plain", content.text), mail.NewContent("text/html", content.html))
+	if content.attachment != nil {
+		attachment := mail.NewAttachment()
+		attachment.SetContent(base64.StdEncoding.EncodeToString(content.attachment.content))
+		attachment.SetType(content.attachment.contentType)
+		attachment.SetFilename(content.attachment.name)
+		attachment.SetDisposition("attachment")
+		email.AddAttachment(attachment)
+	}
+	return email
 }
 
-// Send implements the NotificationPlatform interface
+// sendSigned signs an email and sends the MIME message through the SendGrid
+// SMTP relay, authenticated with the API key
+func (sendGridNotificationInput SendGridNotificationInput) sendSigned(ctx context.Context, content emailContent) error {
+	from := sendGridNotificationInput.SenderEmailAddress
+	if sendGridNotificationInput.SenderName != "" {
+		from = (&netmail.Address{Name: sendGridNotificationInput.SenderName, Address: from}).String()
+	}
+	email, err := sendGridNotificationInput.newEmailMessage(from, sendGridNotificationInput.RecipientEmailAddress, content)
+	if err != nil {
+		return err
+	}
+	host := sendGridNotificationInput.SMTPHostURL
+	if host == "" {
+		host = defaultSendGridSMTPHostURL
+	}
+	opts := []gomail.Option{
+		gomail.WithPort(gomail.DefaultPortTLS),
+		gomail.WithTLSPolicy(gomail.TLSMandatory),
+		gomail.WithSMTPAuth(gomail.SMTPAuthPlain),
+		gomail.WithUsername(sendGridSMTPUsername),
+		gomail.WithPassword(sendGridNotificationInput.SendGridAPIKey),
+	}
+	opts = append(opts, sendGridNotificationInput.SMTPCustomOptions...)
+	client, err := gomail.NewClient(host, opts...)
+	if err != nil {
+		return err
+	}
+	return sendGridNotificationInput.sendSMTP(ctx, client, sendGridNotificationInput.SenderEmailAddress, sendGridNotificationInput.RecipientEmailAddress, email)
+}
+
+// Send implements the NotificationPlatform interface. Signed emails are sent
+// through the SendGrid SMTP relay.
 func (sendGridNotificationInput SendGridNotificationInput) Send(ctx context.Context, data NotificationData) error {
-	from := mail.NewEmail(sendGridNotificationInput.SenderName, sendGridNotificationInput.SenderEmailAddress)
-	to := mail.NewEmail(sendGridNotificationInput.RecipientName, sendGridNotificationInput.RecipientEmailAddress)
-	subject := data.Context.Subject
-	emailHTMLBody, err := generateEmailBody(data)
+	content, err := sendGridNotificationInput.content(sendGridNotificationInput.NotificationTemplates, data)
 	if err != nil {
 		return err
 	}
-	email := mail.NewSingleEmail(from, subject, to, "", emailHTMLBody)
+	if sendGridNotificationInput.signed() {
+		return sendGridNotificationInput.sendSigned(ctx, content)
+	}
 	client := sendgrid.NewSendClient(sendGridNotificationInput.SendGridAPIKey)
-	_, err = client.SendWithContext(ctx, email)
+	_, err = client.SendWithContext(ctx, sendGridNotificationInput.newSendGridMail(content))
 	return err
 }
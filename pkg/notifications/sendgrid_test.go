@@ -16,9 +16,15 @@ package notifications
 
 import (
 	"context"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
+	gomail "github.com/wneessen/go-mail"
 )
 
 func TestSendGridSendFailure(t *testing.T) {
@@ -49,3 +55,97 @@ func TestSendGridSendFailure(t *testing.T) {
 		t.Errorf("expected nil, received error %v", err)
 	}
 }
+
+func TestNewSendGridMail(t *testing.T) {
+	sendGridNotificationInput := SendGridNotificationInput{
+		RecipientName:         "Security",
+		RecipientEmailAddress: "security@example.com",
+		SenderEmailAddress:    "monitor@example.com",
+		EmailOptions: EmailOptions{
+			To:                  []string{"dev@example.com"},
+			Cc:                  []string{"cc@example.com"},
+			Bcc:                 []string{"audit@example.com"},
+			AttachmentThreshold: 10,
+		},
+	}
+	content, err := sendGridNotificationInput.content(sendGridNotificationInput.NotificationTemplates, emailTestData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := sendGridNotificationInput.newSendGridMail(content)
+
+	personalization := email.Personalizations[0]
+	if len(personalization.To) != 2 || personalization.To[0].Name != "Security" || personalization.To[1].Address != "dev@example.com" {
+		t.Errorf("unexpected To recipients %+v", personalization.To)
+	}
+	if len(personalization.CC) != 1 || len(personalization.BCC) != 1 {
+		t.Errorf("unexpected Cc %+v and Bcc %+v recipients", personalization.CC, personalization.BCC)
+	}
+	if len(email.Content) != 2 || email.Content[0].Type != "text/plain" || email.Content[1].Type != "text/html" {
+		t.Errorf("expected plaintext and HTML content, got %+v", email.Content)
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Filename != "identityMatch.json" {
+		t.Fatalf("expected JSON attachment, got %+v", email.Attachments)
+	}
+	attachment, err := base64.StdEncoding.DecodeString(email.Attachments[0].Content)
+	if err != nil || !strings.Contains(string(attachment), "uuid-1") {
+		t.Errorf("unexpected attachment content %q: %v", attachment, err)
+	}
+}
+
+func TestSendGridValidateSigning(t *testing.T) {
+	keyFile, _ := writePGPKey(t)
+	sendGridNotificationInput := SendGridNotificationInput{
+		RecipientEmailAddress: "security@example.com",
+		EmailOptions:          EmailOptions{Signing: &EmailSigning{PGPKeyFile: keyFile}},
+	}
+	if err := sendGridNotificationInput.Validate(); err != nil {
+		t.Errorf("expected signing to be supported, got %v", err)
+	}
+	sendGridNotificationInput.Signing = &EmailSigning{PGPKeyFile: filepath.Join(t.TempDir(), "missing.asc")}
+	if err := sendGridNotificationInput.Validate(); err == nil || !strings.Contains(err.Error(), "invalid emailNotificationSendGrid signing") {
+		t.Errorf("expected invalid signing key to be rejected, got %v", err)
+	}
+}
+
+func TestSendGridSendSigned(t *testing.T) {
+	server := smtpmock.New(smtpmock.ConfigurationAttr{
+		HostAddress:    "127.0.0.1",
+		MultipleRcptto: true,
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("error starting server: %v", err)
+	}
+	defer server.Stop() //nolint:errcheck
+	keyFile, _ := writePGPKey(t)
+	sendGridNotificationInput := SendGridNotificationInput{
+		RecipientEmailAddress: "security@example.com",
+		SenderName:            "Rekor Monitor",
+		SenderEmailAddress:    "monitor@example.com",
+		SendGridAPIKey:        "SG.test",
+		SMTPHostURL:           "127.0.0.1",
+		SMTPCustomOptions:     []gomail.Option{gomail.WithPort(server.PortNumber()), gomail.WithTLSPolicy(gomail.NoTLS), gomail.WithSMTPAuth(gomail.SMTPAuthNoAuth), gomail.WithHELO("example.com")},
+		EmailOptions: EmailOptions{
+			Bcc:     []string{"audit@example.com"},
+			Signing: &EmailSigning{PGPKeyFile: keyFile},
+		},
+	}
+
+	if err := sendGridNotificationInput.Send(context.Background(), emailTestData()); err != nil {
+		t.Fatalf("expected nil, received error %v", err)
+	}
+	messages, err := server.WaitForMessages(1, time.Second)
+	if err != nil {
+		t.Fatalf("error waiting for messages: %v", err)
+	}
+	if got := len(messages[0].RcpttoRequestResponse()); got != 2 {
+		t.Errorf("expected 2 recipients, got %d", got)
+	}
+	if got := messages[0].MailfromRequest(); !strings.HasSuffix(got, "monitor@example.com") || strings.Contains(got, "Rekor Monitor") {
+		t.Errorf("unexpected envelope sender %q", got)
+	}
+	message := messages[0].MsgRequest()
+	if !strings.Contains(message, "application/pgp-signature") || !strings.Contains(message, `"Rekor Monitor" <monitor@example.com>`) {
+		t.Errorf("expected a PGP signed email from the sender, got %s", message)
+	}
+}
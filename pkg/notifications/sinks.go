@@ -0,0 +1,290 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSyslogNetwork       = "unixgram"
+	defaultSyslogAddress       = "/dev/log"
+	defaultSyslogFacility      = "daemon"
+	defaultSyslogEnterpriseID  = 32473
+	syslogStructuredDataPrefix = "rekorMonitor@"
+)
+
+// syslogFacilities are the syslog facility codes, keyed by name
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslog severities of the kinds of notifications
+const (
+	syslogSeverityCritical = 2
+	syslogSeverityError    = 3
+	syslogSeverityWarning  = 4
+)
+
+// FileNotificationInput extends the NotificationPlatform interface to support
+// writing notifications as JSON lines to a local file, with the same schema
+// as webhook payloads. Notifications are appended to the file.
+type FileNotificationInput struct {
+	Path string `yaml:"path"`
+}
+
+// StdoutNotificationInput extends the NotificationPlatform interface to support
+// writing notifications as JSON lines to the standard output, with the same
+// schema as webhook payloads. It has no settings.
+type StdoutNotificationInput struct{}
+
+// SyslogNotificationInput extends the NotificationPlatform interface to support
+// sending notifications to a syslog server as RFC 5424 messages. The message
+// is the JSON webhook payload, and the notification kind, log origin, number
+// of entries and searched log indices are set as structured data.
+type SyslogNotificationInput struct {
+	// Network is unixgram (default), unix, udp or tcp
+	Network string `yaml:"network"`
+	// Address of the syslog server, defaults to /dev/log
+	Address string `yaml:"address"`
+	// Facility name, defaults to daemon
+	Facility string `yaml:"facility"`
+	// AppName defaults to the monitor type of the notification
+	AppName string `yaml:"appName"`
+	// EnterpriseID is the private enterprise number of the structured data ID
+	// rekorMonitor@<EnterpriseID>, defaults to 32473, reserved for documentation
+	EnterpriseID int `yaml:"enterpriseID"`
+	// Timeout of a connection, defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// The local sinks are only configured as notifiers, e.g.
+//
+//	notifiers:
+//	  - name: audit-log
+//	    type: file
+//	    path: /var/log/rekor-monitor/notifications.jsonl
+func init() {
+	RegisterNotifierType("file", func() NotificationPlatform { return &FileNotificationInput{} })
+	RegisterNotifierType("stdout", func() NotificationPlatform { return &StdoutNotificationInput{} })
+	RegisterNotifierType("syslog", func() NotificationPlatform { return &SyslogNotificationInput{} })
+}
+
+// stdout is the writer of the stdout notification platform, replaced in tests
+var stdout io.Writer = os.Stdout
+
+// sinkMutex serializes the writes of JSON lines, so that concurrent
+// notifications aren't interleaved
+var sinkMutex sync.Mutex
+
+// jsonLine encodes notification data as a JSON webhook payload, terminated by a newline
+func jsonLine(data NotificationData) ([]byte, error) {
+	payload, err := NewWebhookPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding notification: %w", err)
+	}
+	return append(line, '\n'), nil
+}
+
+// Validate checks the file configuration
+func (fileNotificationInput FileNotificationInput) Validate() error {
+	if fileNotificationInput.Path == "" {
+		return errors.New("file path must be set")
+	}
+	return nil
+}
+
+// Send implements the NotificationPlatform interface
+func (fileNotificationInput FileNotificationInput) Send(_ context.Context, data NotificationData) error {
+	line, err := jsonLine(data)
+	if err != nil {
+		return err
+	}
+	sinkMutex.Lock()
+	defer sinkMutex.Unlock()
+	if err := os.MkdirAll(filepath.Dir(fileNotificationInput.Path), 0700); err != nil {
+		return fmt.Errorf("error creating notification file directory: %w", err)
+	}
+	f, err := os.OpenFile(fileNotificationInput.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening notification file: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing notification file: %w", err)
+	}
+	return f.Close()
+}
+
+// Send implements the NotificationPlatform interface
+func (StdoutNotificationInput) Send(_ context.Context, data NotificationData) error {
+	line, err := jsonLine(data)
+	if err != nil {
+		return err
+	}
+	sinkMutex.Lock()
+	defer sinkMutex.Unlock()
+	_, err = stdout.Write(line)
+	return err
+}
+
+// Validate checks the syslog configuration
+func (syslogNotificationInput SyslogNotificationInput) Validate() error {
+	if network := syslogNotificationInput.Network; network != "" && !slices.Contains([]string{"unixgram", "unix", "udp", "tcp"}, network) {
+		return fmt.Errorf("invalid syslog network %s: must be one of unixgram, unix, udp or tcp", network)
+	}
+	if network := syslogNotificationInput.Network; (network == "udp" || network == "tcp") && syslogNotificationInput.Address == "" {
+		return fmt.Errorf("syslog address must be set for network %s", network)
+	}
+	if facility := syslogNotificationInput.Facility; facility != "" {
+		if _, ok := syslogFacilities[facility]; !ok {
+			return fmt.Errorf("invalid syslog facility %s", facility)
+		}
+	}
+	if syslogNotificationInput.EnterpriseID < 0 {
+		return fmt.Errorf("invalid syslog enterpriseID %d: must not be negative", syslogNotificationInput.EnterpriseID)
+	}
+	return nil
+}
+
+// Send implements the NotificationPlatform interface
+func (syslogNotificationInput SyslogNotificationInput) Send(ctx context.Context, data NotificationData) error {
+	message, err := syslogNotificationInput.message(data, time.Now())
+	if err != nil {
+		return err
+	}
+	network := syslogNotificationInput.Network
+	if network == "" {
+		network = defaultSyslogNetwork
+	}
+	address := syslogNotificationInput.Address
+	if address == "" {
+		address = defaultSyslogAddress
+	}
+	timeout := syslogNotificationInput.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPNotificationTimeout
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return fmt.Errorf("error connecting to syslog: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return fmt.Errorf("error connecting to syslog: %w", err)
+	}
+	// stream transports need framing, with octet counting (RFC 6587)
+	if network == "tcp" || network == "unix" {
+		message = append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+	}
+	if _, err := conn.Write(message); err != nil {
+		return fmt.Errorf("error writing to syslog: %w", err)
+	}
+	return nil
+}
+
+// message formats notification data as an RFC 5424 syslog message
+func (syslogNotificationInput SyslogNotificationInput) message(data NotificationData, now time.Time) ([]byte, error) {
+	line, err := jsonLine(data)
+	if err != nil {
+		return nil, err
+	}
+	facility := syslogFacilities[defaultSyslogFacility]
+	if syslogNotificationInput.Facility != "" {
+		facility = syslogFacilities[syslogNotificationInput.Facility]
+	}
+	kind := notificationKind(data.Payload)
+	severity := syslogSeverityWarning
+	switch kind {
+	case NotificationKindFailedEntry:
+		severity = syslogSeverityError
+	case NotificationKindConsistencyFailure:
+		severity = syslogSeverityCritical
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	appName := syslogNotificationInput.AppName
+	if appName == "" {
+		appName = data.Context.MonitorType
+	}
+	enterpriseID := syslogNotificationInput.EnterpriseID
+	if enterpriseID == 0 {
+		enterpriseID = defaultSyslogEnterpriseID
+	}
+
+	params := []string{fmt.Sprintf(`kind="%s"`, kind)}
+	if data.Context.LogOrigin != "" {
+		params = append(params, fmt.Sprintf(`logOrigin="%s"`, escapeSyslogParam(data.Context.LogOrigin)))
+	}
+	if templateData, err := NewTemplateData(data); err == nil {
+		params = append(params, fmt.Sprintf(`entries="%d"`, len(templateData.Entries)+len(templateData.FailedEntries)))
+	}
+	if checkpoint := data.Context.Checkpoint; checkpoint != nil {
+		params = append(params, fmt.Sprintf(`startIndex="%d"`, checkpoint.StartIndex), fmt.Sprintf(`endIndex="%d"`, checkpoint.EndIndex))
+	}
+	structuredData := fmt.Sprintf("[%s%d %s]", syslogStructuredDataPrefix, enterpriseID, strings.Join(params, " "))
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s %s ",
+		facility*8+severity,
+		now.UTC().Format(time.RFC3339Nano),
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(appName, 48),
+		os.Getpid(),
+		kind,
+		structuredData)
+	return append([]byte(header), line[:len(line)-1]...), nil
+}
+
+// syslogHeaderField returns a header field of printable ASCII characters, or "-" if empty
+func syslogHeaderField(value string, maxLength int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if field == "" {
+		return "-"
+	}
+	if len(field) > maxLength {
+		field = field[:maxLength]
+	}
+	return field
+}
+
+// escapeSyslogParam escapes the characters of a structured data parameter value
+func escapeSyslogParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"gopkg.in/yaml.v2"
+)
+
+func sinkTestData() NotificationData {
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "rekor-monitor workflow results").WithLogOrigin(`rekor.example.com "prod"`).WithCheckpoint(10, 20),
+		Payload: identity.MonitoredIdentityList{{
+			Identity:             "a@example.com",
+			FoundIdentityEntries: []identity.LogEntry{{CertSubject: "a@example.com", MatchedIdentity: "a@example.com", Index: 15}},
+		}},
+	}
+}
+
+func TestFileNotificationSend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications", "notifications.jsonl")
+	file := FileNotificationInput{Path: path}
+	if err := file.Send(context.Background(), sinkTestData()); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Send(context.Background(), NotificationData{Context: CreateNotificationContext("rekor-monitor", "s"), Payload: identity.FailedLogEntryList{{Index: 5}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %q", content)
+	}
+	var payloads [2]WebhookPayload
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &payloads[i]); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+	}
+	if len(payloads[0].MatchedEntries) != 1 || payloads[0].Context.LogOrigin != `rekor.example.com "prod"` {
+		t.Errorf("unexpected first notification: %+v", payloads[0])
+	}
+	if len(payloads[1].FailedEntries) != 1 {
+		t.Errorf("unexpected second notification: %+v", payloads[1])
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected notification file with mode 0600, got %v, %v", info, err)
+	}
+}
+
+func TestStdoutNotificationSend(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = &buf
+
+	if err := (StdoutNotificationInput{}).Send(context.Background(), sinkTestData()); err != nil {
+		t.Fatal(err)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(buf.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", buf.String(), err)
+	}
+	if !strings.HasSuffix(buf.String(), "}\n") || len(payload.MatchedEntries) != 1 {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestSyslogNotificationMessage(t *testing.T) {
+	syslog := SyslogNotificationInput{Facility: "local3", AppName: "identity monitor"}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	message, err := syslog.message(sinkTestData(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local3 (19) * 8 + warning (4)
+	pattern := regexp.MustCompile(`^<156>1 2025-01-01T10:00:00Z \S+ identitymonitor \d+ identityMatch ` +
+		`\[rekorMonitor@32473 kind="identityMatch" logOrigin="rekor.example.com \\"prod\\"" entries="1" startIndex="10" endIndex="20"\] \{.*\}$`)
+	if !pattern.Match(message) {
+		t.Errorf("unexpected syslog message %q", message)
+	}
+
+	message, err = SyslogNotificationInput{}.message(NotificationData{Context: CreateNotificationContext("ct-monitor", "s"), Payload: ConsistencyFailure{LogOrigin: "ct.example.com", Error: "inconsistent log"}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// daemon (3) * 8 + critical (2)
+	if !bytes.HasPrefix(message, []byte("<26>1 ")) || !bytes.Contains(message, []byte(" ct-monitor ")) || !bytes.Contains(message, []byte(" consistencyFailure [")) {
+		t.Errorf("unexpected syslog message %q", message)
+	}
+}
+
+func TestSyslogNotificationSend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	syslog := SyslogNotificationInput{Network: "udp", Address: conn.LocalAddr().String()}
+	if err := syslog.Send(context.Background(), sinkTestData()); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64*1024)
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message := string(buf[:n]); !strings.HasPrefix(message, "<28>1 ") || !strings.Contains(message, `"matchedEntries"`) {
+		t.Errorf("unexpected syslog message %q", message)
+	}
+}
+
+func TestSinksValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errMsg string
+	}{
+		{"missing file path", "- {name: audit, type: file}", "invalid notifier audit: file path must be set"},
+		{"invalid syslog network", "- {name: local, type: syslog, network: http}", "invalid syslog network http"},
+		{"missing syslog address", "- {name: local, type: syslog, network: tcp}", "syslog address must be set for network tcp"},
+		{"invalid syslog facility", "- {name: local, type: syslog, facility: local9}", "invalid syslog facility local9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config IdentityMonitorConfiguration
+			if err := yaml.Unmarshal([]byte("notifiers:\n"+tt.config), &config); err != nil {
+				t.Fatal(err)
+			}
+			err := config.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+
+	var config IdentityMonitorConfiguration
+	sinks := `
+notifiers:
+  - name: audit
+    type: file
+    path: notifications.jsonl
+  - name: debug
+    type: stdout
+  - name: local
+    type: syslog
+`
+	if err := yaml.Unmarshal([]byte(sinks), &config); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if names := notifierNames(config); strings.Join(names, ",") != "audit,debug,local" {
+		t.Errorf("unexpected notifiers %v", names)
+	}
+	if _, ok := config.Notifiers[0].Platform.(*FileNotificationInput); !ok {
+		t.Errorf("unexpected platform %T of file notifier", config.Notifiers[0].Platform)
+	}
+}
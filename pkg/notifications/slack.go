@@ -0,0 +1,202 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSlackAPIURL               = "https://slack.com/api/chat.postMessage"
+	defaultSlackMaxEntriesPerMessage = 20
+	// Slack allows 50 blocks per message: a header and a summary block,
+	// plus a divider and a section block per entry
+	slackMaxEntriesPerMessage = 24
+	slackHeaderTextLimit      = 150
+	slackSectionTextLimit     = 3000
+	slackFieldTextLimit       = 2000
+	slackMaxFieldsPerSection  = 10
+	slackCodeBlockOverhead    = len("```\n\n```")
+)
+
+// SlackNotificationInput extends the NotificationPlatform interface to support
+// found identity notification via Slack messages, either through an incoming
+// webhook or by posting as a bot to a channel.
+type SlackNotificationInput struct {
+	// WebhookURL of a Slack incoming webhook. Either WebhookURL or BotToken must be set.
+	WebhookURL string `yaml:"webhookURL"`
+	// BotToken of a Slack app with the chat:write scope, used to post to Channel
+	BotToken string `yaml:"botToken"`
+	Channel  string `yaml:"channel"`
+	// APIURL overrides the chat.postMessage endpoint used with BotToken
+	APIURL string `yaml:"apiURL"`
+	// LogEntryURL is a link template for log entries, with {index} and {uuid} placeholders.
+	// Rekor entries link to https://search.sigstore.dev by default.
+	LogEntryURL string `yaml:"logEntryURL"`
+	// MaxEntriesPerMessage splits large notifications into several messages, defaults to 20
+	MaxEntriesPerMessage int `yaml:"maxEntriesPerMessage"`
+	// Timeout of a request, defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Validate checks the Slack configuration
+func (slackNotificationInput SlackNotificationInput) Validate() error {
+	switch {
+	case slackNotificationInput.WebhookURL != "" && slackNotificationInput.BotToken != "":
+		return errors.New("only one of slack webhookURL and botToken should be set")
+	case slackNotificationInput.WebhookURL != "":
+		if err := validateHTTPURL("slack webhook", slackNotificationInput.WebhookURL); err != nil {
+			return err
+		}
+	case slackNotificationInput.BotToken != "":
+		if slackNotificationInput.Channel == "" {
+			return errors.New("slack channel must be set when using a bot token")
+		}
+		if slackNotificationInput.APIURL != "" {
+			if err := validateHTTPURL("slack API", slackNotificationInput.APIURL); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("one of slack webhookURL and botToken must be set")
+	}
+	if slackNotificationInput.MaxEntriesPerMessage < 0 || slackNotificationInput.MaxEntriesPerMessage > slackMaxEntriesPerMessage {
+		return fmt.Errorf("invalid slack maxEntriesPerMessage %d: must be between 0 and %d", slackNotificationInput.MaxEntriesPerMessage, slackMaxEntriesPerMessage)
+	}
+	return nil
+}
+
+// slackText is a Slack text object
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackBlock is a Slack layout block
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+// slackMessage is the body of a message posted to Slack
+type slackMessage struct {
+	Channel string       `json:"channel,omitempty"`
+	Text    string       `json:"text"`
+	Blocks  []slackBlock `json:"blocks"`
+}
+
+// slackAPIResponse is the response of the Slack Web API
+type slackAPIResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// slackEscaper escapes the control characters of Slack mrkdwn
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackEscape escapes the control characters of Slack mrkdwn
+func slackEscape(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+// renderSlackMessage renders a chat message as Slack blocks
+func renderSlackMessage(message chatMessage) slackMessage {
+	blocks := []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: truncate(message.Title, slackHeaderTextLimit)}},
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(slackEscape(message.Summary), slackSectionTextLimit)}},
+	}
+	for _, item := range message.Items {
+		text := "*" + slackEscape(item.Title) + "*"
+		if item.Link != "" {
+			text += "\n<" + item.Link + "|View log entry>"
+		}
+		block := slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(text, slackSectionTextLimit)}}
+		for _, field := range item.Fields {
+			if len(block.Fields) == slackMaxFieldsPerSection {
+				break
+			}
+			fieldText := "*" + field.Name + "*\n" + slackEscape(field.Value)
+			block.Fields = append(block.Fields, slackText{Type: "mrkdwn", Text: truncate(fieldText, slackFieldTextLimit)})
+		}
+		blocks = append(blocks, slackBlock{Type: "divider"}, block)
+	}
+	if message.Body != "" {
+		body := truncate(message.Body, slackSectionTextLimit-slackCodeBlockOverhead)
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "```\n" + body + "\n```"}})
+	}
+	return slackMessage{
+		Text:   message.Title + ": " + message.Summary,
+		Blocks: blocks,
+	}
+}
+
+// Send implements the NotificationPlatform interface
+func (slackNotificationInput SlackNotificationInput) Send(ctx context.Context, data NotificationData) error {
+	message, err := newChatMessage(data, slackNotificationInput.LogEntryURL)
+	if err != nil {
+		return err
+	}
+	client, err := newHTTPClient(slackNotificationInput.Timeout, "")
+	if err != nil {
+		return err
+	}
+	maxEntries := slackNotificationInput.MaxEntriesPerMessage
+	if maxEntries == 0 {
+		maxEntries = defaultSlackMaxEntriesPerMessage
+	}
+
+	for _, part := range message.split(maxEntries) {
+		slackMsg := renderSlackMessage(part)
+		if slackNotificationInput.BotToken == "" {
+			body, err := json.Marshal(slackMsg)
+			if err != nil {
+				return fmt.Errorf("error encoding slack message: %w", err)
+			}
+			if err := postJSON(ctx, client, slackNotificationInput.WebhookURL, body, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		slackMsg.Channel = slackNotificationInput.Channel
+		body, err := json.Marshal(slackMsg)
+		if err != nil {
+			return fmt.Errorf("error encoding slack message: %w", err)
+		}
+		apiURL := slackNotificationInput.APIURL
+		if apiURL == "" {
+			apiURL = defaultSlackAPIURL
+		}
+		headers := map[string]string{"Authorization": "Bearer " + slackNotificationInput.BotToken}
+		respBody, err := postJSONWithResponse(ctx, client, apiURL, body, headers)
+		if err != nil {
+			return err
+		}
+		var resp slackAPIResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return fmt.Errorf("error parsing slack response: %w", err)
+		}
+		if !resp.OK {
+			return fmt.Errorf("error sending slack message: %s", resp.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+func manyMatchesNotificationData(n int) NotificationData {
+	entries := []identity.LogEntry{}
+	for i := range n {
+		entries = append(entries, identity.LogEntry{
+			CertSubject: "user@example.com",
+			Issuer:      "https://accounts.google.com",
+			Index:       int64(i),
+			UUID:        fmt.Sprintf("uuid-%d", i),
+		})
+	}
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "test-subject"),
+		Payload: identity.MonitoredIdentityList{{Identity: "user@example.com", FoundIdentityEntries: entries}},
+	}
+}
+
+func TestSlackSendWebhook(t *testing.T) {
+	var messages []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}))
+	defer server.Close()
+
+	slack := SlackNotificationInput{WebhookURL: server.URL, MaxEntriesPerMessage: 2}
+	if err := slack.Send(context.Background(), manyMatchesNotificationData(3)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	first := messages[0]
+	if first.Blocks[0].Type != "header" || first.Blocks[0].Text.Text != "test-subject" {
+		t.Errorf("unexpected header block %+v", first.Blocks[0])
+	}
+	if !strings.Contains(first.Blocks[1].Text.Text, "(part 1 of 2)") {
+		t.Errorf("expected part in summary, got %q", first.Blocks[1].Text.Text)
+	}
+	// header, summary, and a divider and section per entry
+	if len(first.Blocks) != 6 || len(messages[1].Blocks) != 4 {
+		t.Errorf("unexpected number of blocks %d and %d", len(first.Blocks), len(messages[1].Blocks))
+	}
+	entry := first.Blocks[3]
+	if !strings.Contains(entry.Text.Text, "<https://search.sigstore.dev/?logIndex=0|View log entry>") {
+		t.Errorf("expected link to log entry, got %q", entry.Text.Text)
+	}
+	fields := []string{}
+	for _, field := range entry.Fields {
+		fields = append(fields, field.Text)
+	}
+	for _, want := range []string{"*Log index*\n0", "*UUID*\nuuid-0", "*Issuer*\nhttps://accounts.google.com"} {
+		if !strings.Contains(strings.Join(fields, "|"), want) {
+			t.Errorf("expected field %q in %v", want, fields)
+		}
+	}
+}
+
+func TestSlackSendBotToken(t *testing.T) {
+	var msg slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Channel == "#missing" {
+			fmt.Fprint(w, `{"ok":false,"error":"channel_not_found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	data := NotificationData{
+		Context: CreateNotificationContext("ct-monitor", "test-subject"),
+		Payload: identity.FailedLogEntryList{{Index: 7, Error: "<parse error>"}},
+	}
+	slack := SlackNotificationInput{BotToken: "xoxb-test", Channel: "#alerts", APIURL: server.URL}
+	if err := slack.Send(context.Background(), data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if msg.Channel != "#alerts" {
+		t.Errorf("expected channel #alerts, got %q", msg.Channel)
+	}
+	if !strings.Contains(msg.Blocks[3].Fields[1].Text, "&lt;parse error&gt;") {
+		t.Errorf("expected escaped error field, got %+v", msg.Blocks[3].Fields)
+	}
+	if strings.Contains(msg.Blocks[3].Text.Text, "View log entry") {
+		t.Errorf("expected no link for CT entries without template")
+	}
+
+	slack.Channel = "#missing"
+	if err := slack.Send(context.Background(), data); err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("expected Slack API error, got %v", err)
+	}
+}
+
+func TestSlackValidate(t *testing.T) {
+	invalid := []SlackNotificationInput{
+		{},
+		{WebhookURL: "https://hooks.slack.com/x", BotToken: "xoxb"},
+		{BotToken: "xoxb"},
+		{WebhookURL: "https://hooks.slack.com/x", MaxEntriesPerMessage: 100},
+	}
+	for _, slack := range invalid {
+		if err := slack.Validate(); err == nil {
+			t.Errorf("expected error for %+v", slack)
+		}
+	}
+	if err := (SlackNotificationInput{BotToken: "xoxb", Channel: "#alerts"}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestChatMessageSplitAndTruncate(t *testing.T) {
+	message, err := newChatMessage(manyMatchesNotificationData(5), "https://example.com/{index}/{uuid}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Items[4].Link != "https://example.com/4/uuid-4" {
+		t.Errorf("unexpected link %q", message.Items[4].Link)
+	}
+	parts := message.split(2)
+	if len(parts) != 3 || len(parts[2].Items) != 1 {
+		t.Errorf("unexpected split %d parts", len(parts))
+	}
+	if got := truncate("ééé", 4); got != "…" {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := truncate("abcdef", 5); got != "ab…" {
+		t.Errorf("truncate() = %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// Teams limits the size of a message to 28 KB
+	defaultTeamsMaxEntriesPerMessage = 10
+	teamsBodyTextLimit               = 10000
+)
+
+// TeamsNotificationInput extends the NotificationPlatform interface to support
+// found identity notification via Microsoft Teams, posting Adaptive Cards to an
+// incoming webhook or a Workflows webhook.
+type TeamsNotificationInput struct {
+	WebhookURL string `yaml:"webhookURL"`
+	// LogEntryURL is a link template for log entries, with {index} and {uuid} placeholders.
+	// Rekor entries link to https://search.sigstore.dev by default.
+	LogEntryURL string `yaml:"logEntryURL"`
+	// MaxEntriesPerMessage splits large notifications into several messages, defaults to 10
+	MaxEntriesPerMessage int `yaml:"maxEntriesPerMessage"`
+	// Timeout of a request, defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Validate checks the Teams configuration
+func (teamsNotificationInput TeamsNotificationInput) Validate() error {
+	if teamsNotificationInput.MaxEntriesPerMessage < 0 {
+		return fmt.Errorf("invalid teams maxEntriesPerMessage %d: must not be negative", teamsNotificationInput.MaxEntriesPerMessage)
+	}
+	return validateHTTPURL("teams webhook", teamsNotificationInput.WebhookURL)
+}
+
+// teamsElement is an element of an Adaptive Card
+type teamsElement struct {
+	Type      string         `json:"type"`
+	Text      string         `json:"text,omitempty"`
+	Size      string         `json:"size,omitempty"`
+	Weight    string         `json:"weight,omitempty"`
+	Wrap      bool           `json:"wrap,omitempty"`
+	FontType  string         `json:"fontType,omitempty"`
+	Separator bool           `json:"separator,omitempty"`
+	Items     []teamsElement `json:"items,omitempty"`
+	Facts     []teamsFact    `json:"facts,omitempty"`
+}
+
+// teamsFact is a fact of an Adaptive Card FactSet
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// teamsCard is an Adaptive Card
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+}
+
+// teamsAttachment is a message attachment holding an Adaptive Card
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+// teamsMessage is the body of a message posted to a Teams webhook
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+// renderTeamsMessage renders a chat message as an Adaptive Card
+func renderTeamsMessage(message chatMessage) teamsMessage {
+	body := []teamsElement{
+		{Type: "TextBlock", Text: message.Title, Size: "Large", Weight: "Bolder", Wrap: true},
+		{Type: "TextBlock", Text: message.Summary, Wrap: true},
+	}
+	for _, item := range message.Items {
+		container := teamsElement{Type: "Container", Separator: true}
+		container.Items = append(container.Items, teamsElement{Type: "TextBlock", Text: item.Title, Weight: "Bolder", Wrap: true})
+		facts := teamsElement{Type: "FactSet"}
+		for _, field := range item.Fields {
+			facts.Facts = append(facts.Facts, teamsFact{Title: field.Name, Value: field.Value})
+		}
+		container.Items = append(container.Items, facts)
+		if item.Link != "" {
+			container.Items = append(container.Items, teamsElement{Type: "TextBlock", Text: "[View log entry](" + item.Link + ")"})
+		}
+		body = append(body, container)
+	}
+	if message.Body != "" {
+		body = append(body, teamsElement{Type: "TextBlock", Text: truncate(message.Body, teamsBodyTextLimit), FontType: "Monospace", Wrap: true})
+	}
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: teamsCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body:    body,
+				},
+			},
+		},
+	}
+}
+
+// Send implements the NotificationPlatform interface
+func (teamsNotificationInput TeamsNotificationInput) Send(ctx context.Context, data NotificationData) error {
+	message, err := newChatMessage(data, teamsNotificationInput.LogEntryURL)
+	if err != nil {
+		return err
+	}
+	client, err := newHTTPClient(teamsNotificationInput.Timeout, "")
+	if err != nil {
+		return err
+	}
+	maxEntries := teamsNotificationInput.MaxEntriesPerMessage
+	if maxEntries == 0 {
+		maxEntries = defaultTeamsMaxEntriesPerMessage
+	}
+
+	for _, part := range message.split(maxEntries) {
+		body, err := json.Marshal(renderTeamsMessage(part))
+		if err != nil {
+			return fmt.Errorf("error encoding teams message: %w", err)
+		}
+		if err := postJSON(ctx, client, teamsNotificationInput.WebhookURL, body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTeamsSend(t *testing.T) {
+	var messages []teamsMessage
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var msg teamsMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}))
+	defer server.Close()
+
+	teams := TeamsNotificationInput{WebhookURL: server.URL}
+	if err := teams.Send(context.Background(), manyMatchesNotificationData(11)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	card := messages[0].Attachments[0]
+	if card.ContentType != "application/vnd.microsoft.card.adaptive" || card.Content.Type != "AdaptiveCard" {
+		t.Errorf("unexpected attachment %+v", card)
+	}
+	// title, summary and a container per entry
+	if len(card.Content.Body) != 12 {
+		t.Fatalf("expected 12 elements, got %d", len(card.Content.Body))
+	}
+	container := card.Content.Body[2]
+	if container.Items[0].Text != "user@example.com" {
+		t.Errorf("unexpected entry title %q", container.Items[0].Text)
+	}
+	if facts := container.Items[1].Facts; len(facts) != 4 || facts[0] != (teamsFact{Title: "Log index", Value: "0"}) {
+		t.Errorf("unexpected facts %+v", facts)
+	}
+	if container.Items[2].Text != "[View log entry](https://search.sigstore.dev/?logIndex=0)" {
+		t.Errorf("unexpected link %q", container.Items[2].Text)
+	}
+}
+
+func TestTeamsValidate(t *testing.T) {
+	if err := (TeamsNotificationInput{}).Validate(); err == nil {
+		t.Error("expected error without webhook URL")
+	}
+	if err := (TeamsNotificationInput{WebhookURL: "https://example.webhook.office.com/x"}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+const (
+	// DefaultSubjectTemplate renders the subject of the notification context
+	DefaultSubjectTemplate = "{{.Context.Subject}}"
+	// DefaultEmailBodyTemplate renders the payload as JSON in an HTML email.
+	// It is executed as a text/template template, so that the payload is not
+	// escaped, as in the emails sent before body templates were supported.
+	DefaultEmailBodyTemplate = "{{.Header}}\n<pre>{{.Body}}</pre>"
+	// DefaultGitHubIssueBodyTemplate renders the payload as JSON in a Markdown code block
+	DefaultGitHubIssueBodyTemplate = "{{.Header}}\n```\n{{.Body}}\n```"
+)
+
+// NotificationTemplates configures the subject and body of notifications with
+// Go templates, executed with TemplateData. Subjects are text/template
+// templates. Bodies are html/template templates for emails, and text/template
+// templates otherwise. The default templates are text/template templates.
+type NotificationTemplates struct {
+	SubjectTemplate string `yaml:"subjectTemplate"`
+	BodyTemplate    string `yaml:"bodyTemplate"`
+}
+
+// TemplateData is the data model of notification templates
+type TemplateData struct {
+	// Context of the notification, with the monitor type, the default subject and the log origin
+	Context NotificationContext
+	// Kind of the notification: identityMatch, failedEntry or consistencyFailure
+	Kind string
+	// Header is the one line summary of the payload
+	Header string
+	// Body is the payload formatted as JSON
+	Body string
+	// Identities are the monitored identities found in the log, for identityMatch notifications
+	Identities []identity.MonitoredIdentity
+	// Entries are the log entries of all Identities
+	Entries []identity.LogEntry
+	// FailedEntries are the log entries that could not be parsed, for failedEntry notifications
+	FailedEntries []identity.FailedLogEntry
+	// ConsistencyFailure is set for consistencyFailure notifications
+	ConsistencyFailure *ConsistencyFailure
+	// LogOrigin is the origin or URL of the monitored log
+	LogOrigin string
+	// Checkpoint is the range of log indices searched up to the latest verified checkpoint, if known
+	Checkpoint *NotificationCheckpoint
+}
+
+// NewTemplateData returns the data model of notification templates for notification data
+func NewTemplateData(data NotificationData) (TemplateData, error) {
+	body, err := data.Payload.ToNotificationBody()
+	if err != nil {
+		return TemplateData{}, err
+	}
+	templateData := TemplateData{
+		Context:    data.Context,
+		Kind:       notificationKind(data.Payload),
+		Header:     data.Payload.ToNotificationHeader(),
+		Body:       string(body),
+		LogOrigin:  data.Context.LogOrigin,
+		Checkpoint: data.Context.Checkpoint,
+	}
+	switch p := data.Payload.(type) {
+	case identity.MonitoredIdentityList:
+		templateData.Identities = p
+		for _, monitoredIdentity := range p {
+			templateData.Entries = append(templateData.Entries, monitoredIdentity.FoundIdentityEntries...)
+		}
+	case identity.FailedLogEntryList:
+		templateData.FailedEntries = p
+	case ConsistencyFailure:
+		templateData.ConsistencyFailure = &p
+	}
+	return templateData, nil
+}
+
+// executor is implemented by text/template and html/template templates
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// parseTemplate parses a text/template or html/template template
+func parseTemplate(name, text string, html bool) (executor, error) {
+	if html {
+		return htmltemplate.New(name).Parse(text)
+	}
+	return texttemplate.New(name).Parse(text)
+}
+
+// renderTemplate executes a template with the data model of the notification data
+func renderTemplate(name, text string, html bool, data NotificationData) (string, error) {
+	templateData, err := NewTemplateData(data)
+	if err != nil {
+		return "", err
+	}
+	return executeTemplate(name, text, html, templateData)
+}
+
+// executeTemplate executes a template with the template data
+func executeTemplate(name, text string, html bool, templateData TemplateData) (string, error) {
+	tmpl, err := parseTemplate(name, text, html)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData); err != nil {
+		return "", fmt.Errorf("error executing %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// renderSubject renders the subject of a notification, using the default
+// template if no subject template is configured
+func (t NotificationTemplates) renderSubject(data NotificationData) (string, error) {
+	text := t.SubjectTemplate
+	if text == "" {
+		text = DefaultSubjectTemplate
+	}
+	subject, err := renderTemplate("subject", text, false, data)
+	if err != nil {
+		return "", err
+	}
+	// subjects are single lines
+	return strings.Join(strings.Fields(subject), " "), nil
+}
+
+// renderBody renders the body of a notification, using the default template
+// if no body template is configured
+func (t NotificationTemplates) renderBody(data NotificationData, defaultTemplate string, html bool) (string, error) {
+	if t.BodyTemplate == "" {
+		return renderTemplate("body", defaultTemplate, false, data)
+	}
+	return renderTemplate("body", t.BodyTemplate, html, data)
+}
+
+// templateSamples returns sample notification data of every kind, used to validate templates
+func templateSamples() []NotificationData {
+	notificationContext := NotificationContext{
+		MonitorType: "rekor-monitor",
+		Subject:     "rekor-monitor workflow results for " + time.Now().Format(time.RFC822),
+		LogOrigin:   "rekor.sigstore.dev",
+		Checkpoint:  &NotificationCheckpoint{StartIndex: 1, EndIndex: 2},
+	}
+	return []NotificationData{
+		{
+			Context: notificationContext,
+			Payload: identity.MonitoredIdentityList{
+				{
+					Identity: "user@example.com",
+					FoundIdentityEntries: []identity.LogEntry{
+						{MatchedIdentity: "user@example\\.com", MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, CertSubject: "user@example.com", Index: 1, UUID: "uuid"},
+					},
+				},
+			},
+		},
+		{
+			Context: notificationContext,
+			Payload: identity.FailedLogEntryList{{Index: 2, UUID: "uuid", Error: "error parsing entry"}},
+		},
+		{
+			Context: notificationContext,
+			Payload: ConsistencyFailure{LogOrigin: "rekor.sigstore.dev", Error: "inconsistent checkpoints", DetectedAt: time.Now()},
+		},
+	}
+}
+
+// validate checks that the templates parse, and execute with sample data of every kind
+func (t NotificationTemplates) validate(platform string, html bool) error {
+	for _, sample := range templateSamples() {
+		if t.SubjectTemplate != "" {
+			if _, err := renderTemplate("subject", t.SubjectTemplate, false, sample); err != nil {
+				return fmt.Errorf("invalid %s subjectTemplate: %w", platform, err)
+			}
+		}
+		if t.BodyTemplate != "" {
+			if _, err := renderTemplate("body", t.BodyTemplate, html, sample); err != nil {
+				return fmt.Errorf("invalid %s bodyTemplate: %w", platform, err)
+			}
+		}
+	}
+	return nil
+}
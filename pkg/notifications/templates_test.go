@@ -0,0 +1,172 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"gopkg.in/yaml.v2"
+)
+
+func templateTestData() NotificationData {
+	return NotificationData{
+		Context: CreateNotificationContext("rekor-monitor", "rekor-monitor workflow results").
+			WithLogOrigin("rekor.sigstore.dev").
+			WithCheckpoint(100, 200),
+		Payload: identity.MonitoredIdentityList{
+			{
+				Identity: "user@example.com",
+				FoundIdentityEntries: []identity.LogEntry{
+					{CertSubject: "user@example.com", Index: 150, UUID: "uuid-150"},
+					{CertSubject: "user@example.com", Index: 160, UUID: "uuid-160"},
+				},
+			},
+		},
+	}
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	data := templateTestData()
+	body, err := data.Payload.ToNotificationBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := data.Payload.ToNotificationHeader()
+
+	githubBody, err := generateGitHubIssueBody(NotificationTemplates{}, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := header + "\n```\n" + string(body) + "\n```"; githubBody != want {
+		t.Errorf("default GitHub issue body = %q, want %q", githubBody, want)
+	}
+
+	emailBody, err := generateEmailBody(NotificationTemplates{}, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := strings.Join([]string{header, "<pre>" + string(body) + "</pre>"}, "\n"); emailBody != want {
+		t.Errorf("default email body = %q, want %q", emailBody, want)
+	}
+
+	subject, err := NotificationTemplates{}.renderSubject(data)
+	if err != nil || subject != "rekor-monitor workflow results" {
+		t.Errorf("default subject = %q, %v", subject, err)
+	}
+}
+
+func TestCustomTemplates(t *testing.T) {
+	templates := NotificationTemplates{
+		SubjectTemplate: "[{{.Context.MonitorType}}] {{len .Entries}} entries\n in {{.LogOrigin}}",
+		BodyTemplate:    "{{range .Entries}}{{.Index}} {{.CertSubject}}\n{{end}}searched {{.Checkpoint.StartIndex}}-{{.Checkpoint.EndIndex}}",
+	}
+	if err := templates.validate("test", false); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	subject, err := templates.renderSubject(templateTestData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "[rekor-monitor] 2 entries in rekor.sigstore.dev"; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+
+	body, err := templates.renderBody(templateTestData(), DefaultGitHubIssueBodyTemplate, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "150 user@example.com\n160 user@example.com\nsearched 100-200"; body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestHTMLTemplatesEscape(t *testing.T) {
+	templates := NotificationTemplates{BodyTemplate: "<p>{{range .Identities}}{{.Identity}}{{end}}</p>"}
+	data := templateTestData()
+	data.Payload = identity.MonitoredIdentityList{{Identity: "<script>alert(1)</script>"}}
+
+	body, err := generateEmailBody(templates, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("expected identity to be escaped, got %q", body)
+	}
+}
+
+func TestTemplateValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates NotificationTemplates
+		errMsg    string
+	}{
+		{
+			name:      "syntax error",
+			templates: NotificationTemplates{SubjectTemplate: "{{.Context.Subject"},
+			errMsg:    "invalid test subjectTemplate: error parsing subject template",
+		},
+		{
+			name:      "unknown field",
+			templates: NotificationTemplates{BodyTemplate: "{{.Unknown}}"},
+			errMsg:    "invalid test bodyTemplate: error executing body template",
+		},
+		{
+			name:      "field of other kinds of notifications",
+			templates: NotificationTemplates{BodyTemplate: "{{.ConsistencyFailure.Error}}"},
+			errMsg:    "invalid test bodyTemplate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.templates.validate("test", false)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+
+	guarded := NotificationTemplates{BodyTemplate: "{{with .ConsistencyFailure}}{{.Error}}{{end}}"}
+	if err := guarded.validate("test", false); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+}
+
+func TestTemplatesConfiguration(t *testing.T) {
+	configYAML := `
+emailNotificationSMTP:
+  recipientEmailAddress: security@example.com
+  subjectTemplate: "{{.Context.MonitorType}} found {{len .Entries}} entries"
+notifiers:
+  - name: issues
+    type: githubIssue
+    repositoryOwner: owner
+    repositoryName: repo
+    bodyTemplate: "{{.Body"
+`
+	var config IdentityMonitorConfiguration
+	if err := yaml.Unmarshal([]byte(configYAML), &config); err != nil {
+		t.Fatalf("error decoding configuration: %v", err)
+	}
+	if config.EmailNotificationSMTP.SubjectTemplate != "{{.Context.MonitorType}} found {{len .Entries}} entries" {
+		t.Errorf("expected subject template to be decoded, got %+v", config.EmailNotificationSMTP)
+	}
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "invalid notifier issues: invalid githubIssue bodyTemplate") {
+		t.Errorf("expected invalid body template error, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util"
+)
+
+const (
+	// WebhookSchemaVersion is the version of the JSON schema of webhook payloads
+	WebhookSchemaVersion = "1"
+	// WebhookSignatureHeader is the header holding the HMAC-SHA256 signature of
+	// the webhook request body, formatted as "sha256=<hex digest>"
+	WebhookSignatureHeader = "X-Rekor-Monitor-Signature-256"
+
+	defaultHTTPNotificationTimeout = 30 * time.Second
+)
+
+// WebhookNotificationInput extends the NotificationPlatform interface to support
+// found identity notification by POSTing a JSON payload to a configured URL.
+type WebhookNotificationInput struct {
+	URL string `yaml:"url"`
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string `yaml:"headers"`
+	// If set, requests are signed with HMAC-SHA256 using this secret,
+	// and the signature is sent in the X-Rekor-Monitor-Signature-256 header
+	Secret string `yaml:"secret"`
+	// Timeout of a request, defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+	// Path to a bundle of CA certificates in PEM format to verify the server certificate
+	CAFile string `yaml:"caFile"`
+}
+
+// WebhookLogEntry is a log entry matching a monitored identity in a webhook
+// payload, with the schema of log entries in the identities file
+type WebhookLogEntry struct {
+	Identity string `json:"identity"`
+	identity.LogEntryRecord
+}
+
+// WebhookPayload is the JSON body sent by the webhook notification platform.
+// Depending on the kind of the notification, either MatchedEntries,
+// FailedEntries, ConsistencyFailure or Body is set.
+type WebhookPayload struct {
+	SchemaVersion      string                    `json:"schemaVersion"`
+	Kind               string                    `json:"kind"`
+	Context            NotificationContext       `json:"context"`
+	Summary            string                    `json:"summary"`
+	SentAt             time.Time                 `json:"sentAt"`
+	MatchedEntries     []WebhookLogEntry         `json:"matchedEntries,omitempty"`
+	FailedEntries      []identity.FailedLogEntry `json:"failedEntries,omitempty"`
+	ConsistencyFailure *ConsistencyFailure       `json:"consistencyFailure,omitempty"`
+	Body               string                    `json:"body,omitempty"`
+}
+
+// Validate checks the webhook configuration
+func (webhookNotificationInput WebhookNotificationInput) Validate() error {
+	return validateHTTPURL("webhook", webhookNotificationInput.URL)
+}
+
+// NewWebhookPayload converts notification data to a webhook payload
+func NewWebhookPayload(data NotificationData) (WebhookPayload, error) {
+	payload := WebhookPayload{
+		SchemaVersion: WebhookSchemaVersion,
+		Context:       data.Context,
+		Summary:       data.Payload.ToNotificationHeader(),
+		SentAt:        time.Now().UTC(),
+	}
+	switch p := data.Payload.(type) {
+	case identity.MonitoredIdentityList:
+		payload.Kind = payloadKindMonitoredIdentities
+		payload.MatchedEntries = []WebhookLogEntry{}
+		for _, monitoredIdentity := range p {
+			for _, entry := range monitoredIdentity.FoundIdentityEntries {
+				webhookEntry := WebhookLogEntry{
+					Identity:       monitoredIdentity.Identity,
+					LogEntryRecord: entry.Record(),
+				}
+				payload.MatchedEntries = append(payload.MatchedEntries, webhookEntry)
+			}
+		}
+	case identity.FailedLogEntryList:
+		payload.Kind = payloadKindFailedLogEntries
+		payload.FailedEntries = p
+	case ConsistencyFailure:
+		payload.Kind = payloadKindConsistencyFailure
+		payload.ConsistencyFailure = &p
+	default:
+		body, err := data.Payload.ToNotificationBody()
+		if err != nil {
+			return WebhookPayload{}, err
+		}
+		payload.Kind = "generic"
+		payload.Body = string(body)
+	}
+	return payload, nil
+}
+
+// SignWebhookBody returns the value of the signature header for a request body
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send implements the NotificationPlatform interface
+func (webhookNotificationInput WebhookNotificationInput) Send(ctx context.Context, data NotificationData) error {
+	payload, err := NewWebhookPayload(data)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload: %w", err)
+	}
+
+	headers := map[string]string{}
+	for k, v := range webhookNotificationInput.Headers {
+		headers[k] = v
+	}
+	if webhookNotificationInput.Secret != "" {
+		headers[WebhookSignatureHeader] = SignWebhookBody(webhookNotificationInput.Secret, body)
+	}
+
+	client, err := newHTTPClient(webhookNotificationInput.Timeout, webhookNotificationInput.CAFile)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, client, webhookNotificationInput.URL, body, headers)
+}
+
+// httpStatusError is returned for unsuccessful HTTP responses of notification
+// platforms. It implements StatusCode() so that util.Retry doesn't retry
+// client errors.
+type httpStatusError struct {
+	statusCode int
+	body       string
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d: %s", e.statusCode, e.body)
+}
+
+func (e httpStatusError) StatusCode() int {
+	return e.statusCode
+}
+
+// validateHTTPURL checks that a notification platform URL is an absolute HTTP(S) URL
+func validateHTTPURL(platform, rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("%s URL must be set", platform)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid %s URL: %v", platform, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s URL %s: must be an absolute http or https URL", platform, rawURL)
+	}
+	return nil
+}
+
+// newHTTPClient returns an HTTP client with the given request timeout, verifying
+// server certificates against the CA certificates of caFile if set
+func newHTTPClient(timeout time.Duration, caFile string) (*http.Client, error) {
+	if timeout <= 0 {
+		timeout = defaultHTTPNotificationTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	if caFile != "" {
+		tlsConfig, err := util.TLSConfigForCA(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading CA file %s: %w", caFile, err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+	return client, nil
+}
+
+// postJSON POSTs a JSON body to a URL and returns an error for non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	_, err := postJSONWithResponse(ctx, client, url, body, headers)
+	return err
+}
+
+// postJSONWithResponse POSTs a JSON body to a URL and returns the response body,
+// or an error for non-2xx responses
+func postJSONWithResponse(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, util.WrapError(httpStatusError{statusCode: resp.StatusCode, body: string(respBody)})
+	}
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifications
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var received WebhookPayload
+	var signature, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %s", r.Header.Get("Content-Type"))
+		}
+		signature = r.Header.Get(WebhookSignatureHeader)
+		authorization = r.Header.Get("Authorization")
+		if signature != SignWebhookBody("test-secret", body) {
+			t.Errorf("signature %s does not match body", signature)
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Fatal(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := WebhookNotificationInput{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer test-token"},
+		Secret:  "test-secret",
+	}
+	data := NotificationData{
+		Context: CreateNotificationContext("test-monitor", "test-subject"),
+		Payload: identity.MonitoredIdentityList{
+			{
+				Identity: "test-identity",
+				FoundIdentityEntries: []identity.LogEntry{
+					{
+						MatchedIdentity:     "test-identity",
+						MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
+						Index:               42,
+						UUID:                "test-uuid",
+						OIDExtension:        asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 9},
+						ExtensionValue:      "test-value",
+					},
+				},
+			},
+		},
+	}
+	if err := webhook.Send(context.Background(), data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if authorization != "Bearer test-token" {
+		t.Errorf("expected custom header, got %q", authorization)
+	}
+	if received.SchemaVersion != WebhookSchemaVersion || received.Kind != "monitoredIdentities" {
+		t.Errorf("unexpected payload %+v", received)
+	}
+	if received.Context.MonitorType != "test-monitor" || received.Context.Subject != "test-subject" {
+		t.Errorf("unexpected context %+v", received.Context)
+	}
+	if len(received.MatchedEntries) != 1 {
+		t.Fatalf("expected 1 matched entry, got %d", len(received.MatchedEntries))
+	}
+	entry := received.MatchedEntries[0]
+	if entry.Identity != "test-identity" || entry.Index != 42 || entry.UUID != "test-uuid" ||
+		entry.OIDExtension != "1.3.6.1.4.1.57264.1.9" || entry.MatchedIdentityType != "extensionValue" {
+		t.Errorf("unexpected matched entry %+v", entry)
+	}
+}
+
+func TestWebhookSendFailedEntries(t *testing.T) {
+	var received WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(WebhookSignatureHeader) != "" {
+			t.Errorf("expected unsigned request without secret")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer server.Close()
+
+	data := NotificationData{
+		Context: CreateNotificationContext("test-monitor", "test-subject"),
+		Payload: identity.FailedLogEntryList{{Index: 3, UUID: "test-uuid", Error: "parse error"}},
+	}
+	if err := (WebhookNotificationInput{URL: server.URL}).Send(context.Background(), data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if received.Kind != "failedLogEntries" || len(received.FailedEntries) != 1 || received.FailedEntries[0].Error != "parse error" {
+		t.Errorf("unexpected payload %+v", received)
+	}
+}
+
+func TestWebhookSendErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := (WebhookNotificationInput{URL: server.URL}).Send(context.Background(), testNotificationData())
+	if err == nil {
+		t.Fatal("expected error for bad request")
+	}
+	retryErr, ok := err.(util.RetryError)
+	if !ok || retryErr.ShouldRetry() {
+		t.Errorf("expected non-retryable error for client error status, got %v", err)
+	}
+}
+
+func TestWebhookSendCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// the server certificate is not trusted without the CA file
+	if err := (WebhookNotificationInput{URL: server.URL}).Send(context.Background(), testNotificationData()); err == nil {
+		t.Fatal("expected error for untrusted server certificate")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	webhook := WebhookNotificationInput{URL: server.URL, CAFile: caFile}
+	if err := webhook.Send(context.Background(), testNotificationData()); err != nil {
+		t.Errorf("Send() error = %v", err)
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	for _, rawURL := range []string{"", "example.com/hook", "ftp://example.com/hook"} {
+		if err := (WebhookNotificationInput{URL: rawURL}).Validate(); err == nil {
+			t.Errorf("expected error for URL %q", rawURL)
+		}
+	}
+	if err := (WebhookNotificationInput{URL: "https://example.com/hook"}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
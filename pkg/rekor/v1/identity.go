@@ -20,18 +20,22 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
-	"os"
-	"regexp"
+	"log/slog"
+	"time"
 
 	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/swag/conv"
+	"github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/notifications"
 	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/rekor/pkg/generated/client"
 	"github.com/sigstore/rekor/pkg/generated/models"
 	"github.com/sigstore/rekor/pkg/pki"
 	"github.com/sigstore/rekor/pkg/types"
+	intoto_v002 "github.com/sigstore/rekor/pkg/types/intoto/v0.0.2"
 	"github.com/sigstore/rekor/pkg/util"
 
 	// required imports to call init methods
@@ -41,7 +45,6 @@ import (
 	_ "github.com/sigstore/rekor/pkg/types/hashedrekord/v0.0.1"
 	_ "github.com/sigstore/rekor/pkg/types/helm/v0.0.1"
 	_ "github.com/sigstore/rekor/pkg/types/intoto/v0.0.1"
-	_ "github.com/sigstore/rekor/pkg/types/intoto/v0.0.2"
 	_ "github.com/sigstore/rekor/pkg/types/jar/v0.0.1"
 	_ "github.com/sigstore/rekor/pkg/types/rekord/v0.0.1"
 	_ "github.com/sigstore/rekor/pkg/types/rfc3161/v0.0.1"
@@ -49,103 +52,146 @@ import (
 	_ "github.com/sigstore/rekor/pkg/types/tuf/v0.0.1"
 )
 
+// MatchLogEntryFingerprints returns the matches of the monitored fingerprints in a
+// log entry.
+//
+// Deprecated: MatchLogEntryFingerprints compiles the monitored values on every
+// call. Use MatchedIndices with a matcher compiled once by identity.NewMatcher.
 func MatchLogEntryFingerprints(logEntryAnon models.LogEntryAnon, uuid string, entryFingerprints []string, monitoredFingerprints []string) []identity.LogEntry {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredFp := range monitoredFingerprints {
-		for _, fp := range entryFingerprints {
-			if fp == monitoredFp {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     monitoredFp,
-					MatchedIdentityType: identity.MatchedIdentityTypeFingerprint,
-					Fingerprint:         fp,
-					Index:               *logEntryAnon.LogIndex,
-					UUID:                uuid,
-				})
-			}
-		}
+	// fingerprints are compared as they are, so compiling them can't fail
+	matcher, _ := identity.NewMatcher(identity.MonitoredValues{Fingerprints: monitoredFingerprints})
+	return matchLogEntryFingerprints(logEntryAnon, uuid, entryFingerprints, matcher)
+}
+
+// MatchLogEntryCertificateIdentities returns the matches of the monitored
+// certificate identities in a log entry.
+//
+// Deprecated: MatchLogEntryCertificateIdentities compiles the monitored values on
+// every call. Use MatchedIndices with a matcher compiled once by
+// identity.NewMatcher.
+func MatchLogEntryCertificateIdentities(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, monitoredCertIDs []identity.CertificateIdentity) ([]identity.LogEntry, error) {
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{CertificateIdentities: monitoredCertIDs})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	return matchLogEntryCertificateIdentities(logEntryAnon, uuid, entryCertificates, matcher)
+}
+
+// MatchLogEntrySubjects returns the matches of the monitored subjects in a log
+// entry.
+//
+// Deprecated: MatchLogEntrySubjects compiles the monitored values on every call.
+// Use MatchedIndices with a matcher compiled once by identity.NewMatcher.
+func MatchLogEntrySubjects(logEntryAnon models.LogEntryAnon, uuid string, entrySubjects []string, monitoredSubjects []string) ([]identity.LogEntry, error) {
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{Subjects: monitoredSubjects})
+	if err != nil {
+		return nil, fmt.Errorf("error compiling regex for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	return matchLogEntrySubjects(logEntryAnon, uuid, entrySubjects, matcher), nil
+}
+
+// MatchLogEntryOIDs returns the matches of the monitored OID extensions in a log
+// entry.
+//
+// Deprecated: MatchLogEntryOIDs compiles the monitored values on every call. Use
+// MatchedIndices with a matcher compiled once by identity.NewMatcher.
+func MatchLogEntryOIDs(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, monitoredOIDMatchers []extensions.OIDExtension) ([]identity.LogEntry, error) {
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{OIDMatchers: monitoredOIDMatchers})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	return matchLogEntryOIDs(logEntryAnon, uuid, entryCertificates, matcher)
+}
+
+func matchLogEntryFingerprints(logEntryAnon models.LogEntryAnon, uuid string, entryFingerprints []string, matcher *identity.Matcher) []identity.LogEntry {
+	matchedEntries := matcher.MatchFingerprints(entryFingerprints)
+	for i := range matchedEntries {
+		matchedEntries[i].Index = *logEntryAnon.LogIndex
+		matchedEntries[i].UUID = uuid
 	}
 	return matchedEntries
 }
 
-func MatchLogEntryCertificateIdentities(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, monitoredCertIDs []identity.CertificateIdentity) ([]identity.LogEntry, error) {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredCertID := range monitoredCertIDs {
-		for _, cert := range entryCertificates {
-			match, sub, iss, err := identity.CertMatchesPolicy(cert, monitoredCertID.CertSubject, monitoredCertID.Issuers)
-			if err != nil {
-				return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
-			} else if match {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     monitoredCertID.CertSubject,
-					MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
-					CertSubject:         sub,
-					Issuer:              iss,
-					Index:               *logEntryAnon.LogIndex,
-					UUID:                uuid,
-				})
-			}
-		}
+func matchLogEntryCertificateIdentities(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, matcher *identity.Matcher) ([]identity.LogEntry, error) {
+	matchedEntries, err := identity.MatchCertificateIdentities(matcher, entryCertificates)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = *logEntryAnon.LogIndex
+		matchedEntries[i].UUID = uuid
 	}
 	return matchedEntries, nil
 }
 
-func MatchLogEntrySubjects(logEntryAnon models.LogEntryAnon, uuid string, entrySubjects []string, monitoredSubjects []string) ([]identity.LogEntry, error) {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredSub := range monitoredSubjects {
-		regex, err := regexp.Compile(monitoredSub)
-		if err != nil {
-			return nil, fmt.Errorf("error compiling regex for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
-		}
-		for _, sub := range entrySubjects {
-			if regex.MatchString(sub) {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     monitoredSub,
-					MatchedIdentityType: identity.MatchedIdentityTypeSubject,
-					Subject:             sub,
-					Index:               *logEntryAnon.LogIndex,
-					UUID:                uuid,
-				})
-			}
-		}
+func matchLogEntrySubjects(logEntryAnon models.LogEntryAnon, uuid string, entrySubjects []string, matcher *identity.Matcher) []identity.LogEntry {
+	matchedEntries := matcher.MatchSubjects(entrySubjects)
+	for i := range matchedEntries {
+		matchedEntries[i].Index = *logEntryAnon.LogIndex
+		matchedEntries[i].UUID = uuid
+	}
+	return matchedEntries
+}
+
+func matchLogEntryOIDs(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, matcher *identity.Matcher) ([]identity.LogEntry, error) {
+	matchedEntries, err := identity.MatchOIDExtensions(matcher, entryCertificates)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = *logEntryAnon.LogIndex
+		matchedEntries[i].UUID = uuid
 	}
 	return matchedEntries, nil
 }
 
-func MatchLogEntryOIDs(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, monitoredOIDMatchers []extensions.OIDExtension) ([]identity.LogEntry, error) {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredOID := range monitoredOIDMatchers {
-		for _, cert := range entryCertificates {
-			match, oid, extValue, err := identity.OIDMatchesPolicy(cert, monitoredOID.ObjectIdentifier, monitoredOID.ExtensionValues)
-			if err != nil {
-				return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
-			}
-			if match {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     extValue,
-					MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
-					Index:               *logEntryAnon.LogIndex,
-					UUID:                uuid,
-					OIDExtension:        oid,
-					ExtensionValue:      extValue,
-				})
-			}
-		}
+// MatchLogEntryRules returns the matches of the compiled rules in a log entry,
+// evaluated on its certificates, subjects and key fingerprints
+func MatchLogEntryRules(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, entrySubjects []string, entryFingerprints []string, rules identity.CompiledRules) ([]identity.LogEntry, error) {
+	if len(rules) == 0 {
+		return []identity.LogEntry{}, nil
+	}
+	matchedEntries, err := rules.Match(identity.NewRuleEntry(entryCertificates, entrySubjects, entryFingerprints))
+	if err != nil {
+		return nil, fmt.Errorf("error with rule matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = *logEntryAnon.LogIndex
+		matchedEntries[i].UUID = uuid
 	}
 	return matchedEntries, nil
 }
 
-// MatchedIndices returns a list of log indices that contain the requested identities.
-func MatchedIndices(logEntries []models.LogEntry, mvs identity.MonitoredValues, caRoots string, caIntermediates string) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
-	if err := identity.VerifyMonitoredValues(mvs); err != nil {
-		return nil, nil, err
+// MatchLogEntryPolicies returns the matches of the compiled policies in a log
+// entry, evaluated on its certificates, subjects, key fingerprints and metadata
+func MatchLogEntryPolicies(logEntryAnon models.LogEntryAnon, uuid string, entryCertificates []*x509.Certificate, entrySubjects []string, entryFingerprints []string, metadata identity.EntryMetadata, policies identity.CompiledPolicies) ([]identity.LogEntry, error) {
+	if len(policies) == 0 {
+		return []identity.LogEntry{}, nil
 	}
+	policyEntry, err := identity.NewPolicyEntry(entryCertificates, entrySubjects, entryFingerprints, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	matchedEntries, err := policies.Match(policyEntry)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching for UUID %s at index %d: %w", uuid, logEntryAnon.LogIndex, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = *logEntryAnon.LogIndex
+		matchedEntries[i].UUID = uuid
+	}
+	return matchedEntries, nil
+}
 
+// MatchedIndices returns a list of log indices that contain the requested identities,
+// with a single finding per log entry listing every rule that matched the entry.
+func MatchedIndices(logEntries []models.LogEntry, matcher *identity.Matcher, caRoots string, caIntermediates string) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 	var matchedEntries []identity.LogEntry
 	var failedEntries []identity.FailedLogEntry
 
 	for _, entries := range logEntries {
 		for uuid, entry := range entries {
-			verifiers, err := extractVerifiers(&entry)
+			verifiers, metadata, err := extractVerifiers(&entry)
 			if err != nil {
 				failedEntries = append(failedEntries, identity.FailedLogEntry{
 					Index: *entry.LogIndex,
@@ -166,70 +212,118 @@ func MatchedIndices(logEntries []models.LogEntry, mvs identity.MonitoredValues,
 
 			// Validate that the certificate chain up to a trusted CA
 			if err := identity.ValidateCertificateChain(certs, caRoots, caIntermediates); err != nil {
-				fmt.Fprintf(os.Stderr, "Certificate chain for log entry (UUID: %s, Index: %d) could not be verified against trusted CAs, skipping the entry: %v\n", uuid, *entry.LogIndex, err)
+				slog.Warn("certificate chain for log entry could not be verified against trusted CAs, skipping the entry",
+					slog.String(logging.KeyUUID, uuid), slog.Int64(logging.KeyIndex, *entry.LogIndex), logging.Err(err))
 				continue
 			}
 
-			matchedFingerprintEntries := MatchLogEntryFingerprints(entry, uuid, fps, mvs.Fingerprints)
-			matchedEntries = append(matchedEntries, matchedFingerprintEntries...)
+			matchedFingerprintEntries := matchLogEntryFingerprints(entry, uuid, fps, matcher)
+			matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedFingerprintEntries, metadata)...)
+
+			matchedSubjectEntries := matchLogEntrySubjects(entry, uuid, subjects, matcher)
+			matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedSubjectEntries, metadata)...)
 
-			matchedSubjectEntries, err := MatchLogEntrySubjects(entry, uuid, subjects, mvs.Subjects)
+			matchedCertIDEntries, err := matchLogEntryCertificateIdentities(entry, uuid, certs, matcher)
 			if err != nil {
 				failedEntries = append(failedEntries, identity.FailedLogEntry{
 					Index: *entry.LogIndex,
 					UUID:  uuid,
-					Error: fmt.Sprintf("error matching subjects: %v", err),
+					Error: fmt.Sprintf("error matching certificate identities: %v", err),
 				})
 				continue
 			}
-			matchedEntries = append(matchedEntries, matchedSubjectEntries...)
+			matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedCertIDEntries, metadata)...)
 
-			matchedCertIDEntries, err := MatchLogEntryCertificateIdentities(entry, uuid, certs, mvs.CertificateIdentities)
+			matchedOIDEntries, err := matchLogEntryOIDs(entry, uuid, certs, matcher)
 			if err != nil {
 				failedEntries = append(failedEntries, identity.FailedLogEntry{
 					Index: *entry.LogIndex,
 					UUID:  uuid,
-					Error: fmt.Sprintf("error matching certificate identities: %v", err),
+					Error: fmt.Sprintf("error matching object identifier extensions and values: %v", err),
 				})
 				continue
 			}
-			matchedEntries = append(matchedEntries, matchedCertIDEntries...)
+			matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedOIDEntries, metadata)...)
 
-			matchedOIDEntries, err := MatchLogEntryOIDs(entry, uuid, certs, mvs.OIDMatchers)
+			matchedRuleEntries, err := MatchLogEntryRules(entry, uuid, certs, subjects, fps, matcher.Rules)
 			if err != nil {
 				failedEntries = append(failedEntries, identity.FailedLogEntry{
 					Index: *entry.LogIndex,
 					UUID:  uuid,
-					Error: fmt.Sprintf("error matching object identifier extensions and values: %v", err),
+					Error: fmt.Sprintf("error matching rules: %v", err),
 				})
 				continue
 			}
-			matchedEntries = append(matchedEntries, matchedOIDEntries...)
+			matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedRuleEntries, metadata)...)
+
+			matchedPolicyEntries, err := MatchLogEntryPolicies(entry, uuid, certs, subjects, fps, metadata, matcher.Policies)
+			if err != nil {
+				failedEntries = append(failedEntries, identity.FailedLogEntry{
+					Index: *entry.LogIndex,
+					UUID:  uuid,
+					Error: fmt.Sprintf("error matching policies: %v", err),
+				})
+				continue
+			}
+			matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedPolicyEntries, metadata)...)
 		}
 	}
 
-	return matchedEntries, failedEntries, nil
+	return identity.ConsolidateMatches(matchedEntries), failedEntries, nil
 }
 
-// extractVerifiers extracts a set of keys or certificates that can verify an
-// artifact signature from a Rekor entry
-func extractVerifiers(e *models.LogEntryAnon) ([]pki.PublicKey, error) {
+// extractEntry unmarshals the body of a Rekor entry, and returns the entry
+// with the metadata shared by all matches of the entry
+func extractEntry(e *models.LogEntryAnon) (types.EntryImpl, identity.EntryMetadata, error) {
+	metadata := identity.EntryMetadata{LogKind: identity.LogKindRekorV1}
 	b, err := base64.StdEncoding.DecodeString(e.Body.(string))
 	if err != nil {
-		return nil, err
+		return nil, metadata, err
 	}
 
 	pe, err := models.UnmarshalProposedEntry(bytes.NewReader(b), runtime.JSONConsumer())
 	if err != nil {
-		return nil, err
+		return nil, metadata, err
 	}
 
 	eimpl, err := types.UnmarshalEntry(pe)
 	if err != nil {
-		return nil, err
+		return nil, metadata, err
 	}
 
-	return eimpl.Verifiers()
+	metadata.EntryKind = pe.Kind()
+	if e.IntegratedTime != nil {
+		metadata.IntegratedTime = time.Unix(*e.IntegratedTime, 0).UTC()
+	}
+	// not every type of entry has an artifact digest
+	if artifactHash, err := eimpl.ArtifactHash(); err == nil {
+		metadata.ArtifactDigest = artifactHash
+	}
+	// the payload type of DSSE envelopes is only kept in intoto v0.0.2 entries
+	if intotoEntry, ok := eimpl.(*intoto_v002.V002Entry); ok && intotoEntry.IntotoObj.Content != nil && intotoEntry.IntotoObj.Content.Envelope != nil {
+		metadata.PayloadType = conv.Value(intotoEntry.IntotoObj.Content.Envelope.PayloadType)
+	}
+	// in-toto statements are only returned if Rekor stored the attestation
+	if e.Attestation != nil && len(e.Attestation.Data) > 0 {
+		if subjectDigests, err := identity.InTotoSubjectDigests(e.Attestation.Data); err == nil {
+			metadata.SubjectDigests = subjectDigests
+			if metadata.PayloadType == "" {
+				metadata.PayloadType = in_toto.PayloadType
+			}
+		}
+	}
+	return eimpl, metadata, nil
+}
+
+// extractVerifiers extracts a set of keys or certificates that can verify an
+// artifact signature from a Rekor entry
+func extractVerifiers(e *models.LogEntryAnon) ([]pki.PublicKey, identity.EntryMetadata, error) {
+	eimpl, metadata, err := extractEntry(e)
+	if err != nil {
+		return nil, metadata, err
+	}
+	verifiers, err := eimpl.Verifiers()
+	return verifiers, metadata, err
 }
 
 // extractAllIdentities gets all certificates, email addresses, and key fingerprints
@@ -269,22 +363,37 @@ func GetCheckpointIndex(logInfo *models.LogInfo, checkpoint *util.SignedCheckpoi
 	return index
 }
 
-func IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, rekorClient *client.Rekor, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+// SearchIdentities returns the entries of the log index range (StartIndex, EndIndex]
+// of the configuration matching the compiled monitored values, and the entries that
+// could not be parsed. Unlike IdentitySearch, it doesn't write the identity outputs.
+func SearchIdentities(ctx context.Context, config *notifications.IdentityMonitorConfiguration, rekorClient *client.Rekor, logOrigin string, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 	entries, err := GetEntriesByIndexRange(ctx, rekorClient, *config.StartIndex, *config.EndIndex)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error getting entries by index range: %v", err)
+		return nil, nil, fmt.Errorf("error getting entries by index range: %w", err)
 	}
-	matchedEntries, failedEntries, err := MatchedIndices(entries, monitoredValues, config.CARootsFile, config.CAIntermediatesFile)
+	matchedEntries, failedEntries, err := MatchedIndices(entries, matcher, config.CARootsFile, config.CAIntermediatesFile)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error matching indices: %v", err)
 	}
+	identity.SetDetected(matchedEntries, logOrigin, time.Now())
+	return matchedEntries, failedEntries, nil
+}
+
+// IdentitySearch searches the log index range (StartIndex, EndIndex] of the
+// configuration, writes the matched entries to the identity outputs and
+// returns them grouped by monitored identity
+func IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, rekorClient *client.Rekor, logOrigin string, matcher *identity.Matcher) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+	matchedEntries, failedEntries, err := SearchIdentities(ctx, config, rekorClient, logOrigin, matcher)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	err = file.WriteMatchedIdentityEntries(config.OutputIdentitiesFile, config.OutputIdentitiesFormat, matchedEntries, config.IdentityMetadataFile, *config.EndIndex)
+	err = file.WriteMatchedIdentityEntries(config.IdentityOutputs(), matchedEntries, config.IdentityMetadataFile, *config.EndIndex)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	identities := identity.CreateIdentitiesList(monitoredValues)
+	identities := identity.CreateIdentitiesList(matcher.MonitoredValues())
 	monitoredIdentities := identity.CreateMonitoredIdentities(matchedEntries, identities)
 	return monitoredIdentities, failedEntries, nil
 }
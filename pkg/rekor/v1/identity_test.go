@@ -28,21 +28,25 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-openapi/swag/conv"
+	"github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/test"
 	"github.com/sigstore/rekor/pkg/generated/models"
 	"github.com/sigstore/rekor/pkg/types"
 	hashedrekord_v001 "github.com/sigstore/rekor/pkg/types/hashedrekord/v0.0.1"
+	intoto_v002 "github.com/sigstore/rekor/pkg/types/intoto/v0.0.2"
 	rekord_v001 "github.com/sigstore/rekor/pkg/types/rekord/v0.0.1"
 	"github.com/sigstore/rekor/pkg/util"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
 )
 
 const (
@@ -50,6 +54,16 @@ const (
 	issuer  = "oidc-issuer@domain.com"
 )
 
+// testMatcher compiles the monitored values of a test
+func testMatcher(t *testing.T, mvs identity.MonitoredValues) *identity.Matcher {
+	t.Helper()
+	matcher, err := identity.NewMatcher(mvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matcher
+}
+
 func TestMatchedIndicesForCertificates(t *testing.T) {
 	rootCert, rootKey, _ := test.GenerateRootCA()
 	leafCert, leafKey, _ := test.GenerateLeafCert(subject, issuer, rootCert, rootKey)
@@ -96,12 +110,12 @@ func TestMatchedIndicesForCertificates(t *testing.T) {
 	logEntry := models.LogEntry{uuid: logEntryAnon}
 
 	//  match to subject with certificate in hashedrekord
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: subject,
 			},
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -123,15 +137,30 @@ func TestMatchedIndicesForCertificates(t *testing.T) {
 	if matches[0].UUID != uuid {
 		t.Fatalf("mismatched UUIDs: %s %s", matches[0].UUID, uuid)
 	}
+	if matches[0].LogKind != identity.LogKindRekorV1 || matches[0].EntryKind != "hashedrekord" {
+		t.Fatalf("unexpected log and entry kind: %s %s", matches[0].LogKind, matches[0].EntryKind)
+	}
+	if !matches[0].IntegratedTime.Equal(time.Unix(integratedTime.Unix(), 0)) {
+		t.Fatalf("mismatched integrated time: %s %s", matches[0].IntegratedTime, integratedTime)
+	}
+	if matches[0].ArtifactDigest != "sha256:"+hex.EncodeToString(hash[:]) {
+		t.Fatalf("mismatched artifact digest: %s", matches[0].ArtifactDigest)
+	}
+	if matches[0].CertificateSerial != leafCert.SerialNumber.Text(16) {
+		t.Fatalf("mismatched certificate serial: %s %s", matches[0].CertificateSerial, leafCert.SerialNumber.Text(16))
+	}
+	if matches[0].Certificate != string(pemCert) {
+		t.Fatalf("mismatched certificate: %s %s", matches[0].Certificate, pemCert)
+	}
 
 	// match to subject and issuer with certificate in hashedrekord
-	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: subject,
 				Issuers:     []string{issuer},
 			},
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -142,14 +171,34 @@ func TestMatchedIndicesForCertificates(t *testing.T) {
 		t.Fatalf("expected 0 failed entries, got %d", len(failedEntries))
 	}
 
+	// matches of several certificate identities are consolidated into a single finding
+	matches, _, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
+		CertificateIdentities: []identity.CertificateIdentity{
+			{CertSubject: ".*ubje.*"},
+			{CertSubject: subject, Issuers: []string{issuer}},
+		}}), "", "")
+	if err != nil {
+		t.Fatalf("expected error matching IDs, got %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	wantRules := []identity.MatchedRule{
+		{MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, MatchedIdentity: ".*ubje.*", Value: subject},
+		{MatchedIdentityType: identity.MatchedIdentityTypeCertSubject, MatchedIdentity: subject, Value: subject},
+	}
+	if !reflect.DeepEqual(matches[0].MatchedRules, wantRules) || matches[0].MatchedIdentity != ".*ubje.*" {
+		t.Fatalf("unexpected matched rules: %+v", matches[0].MatchedRules)
+	}
+
 	// match with regex subject and regex issuer with certificate in hashedrekord
-	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: ".*ubje.*",
 				Issuers:     []string{".+@domain.com"},
 			},
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -190,13 +239,13 @@ func TestMatchedIndicesForCertificates(t *testing.T) {
 		}
 		logEntry := models.LogEntry{uuid: logEntryAnon}
 
-		matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+		matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 			CertificateIdentities: []identity.CertificateIdentity{
 				{
 					CertSubject: ".*ubje.*",
 					Issuers:     []string{".+@domain.com"},
 				},
-			}}, "", "")
+			}}), "", "")
 		if err != nil {
 			t.Fatalf("expected error matching IDs, got %v", err)
 		}
@@ -226,7 +275,7 @@ func TestMatchedIndicesForCertificates(t *testing.T) {
 		},
 	}
 	for _, monitoredValues := range testedMonitoredValues {
-		matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, monitoredValues, "", "")
+		matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, monitoredValues), "", "")
 		if err != nil {
 			t.Fatalf("expected error matching IDs, got %v", err)
 		}
@@ -241,6 +290,70 @@ func TestMatchedIndicesForCertificates(t *testing.T) {
 
 // Test verifies that certificates containing only the deprecated
 // extensions can still be monitored
+func TestMatchedIndicesForInTotoAttestations(t *testing.T) {
+	rootCert, rootKey, _ := test.GenerateRootCA()
+	leafCert, leafKey, _ := test.GenerateLeafCert(subject, issuer, rootCert, rootKey)
+
+	signer, err := signature.LoadECDSASignerVerifier(leafKey, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemCert, _ := cryptoutils.MarshalCertificateToPEM(leafCert)
+
+	statement := []byte(`{"_type":"https://in-toto.io/Statement/v1","predicateType":"https://slsa.dev/provenance/v1",` +
+		`"subject":[{"name":"a","digest":{"sha512":"CD","sha256":"ab"}},{"name":"b","digest":{"sha256":"ef"}}],"predicate":{}}`)
+	envelope, err := dsse.WrapSigner(signer, in_toto.PayloadType).SignMessage(bytes.NewReader(statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe, err := (&intoto_v002.V002Entry{}).CreateFromArtifactProperties(context.Background(), types.ArtifactProperties{
+		ArtifactBytes:  envelope,
+		PublicKeyBytes: [][]byte{pemCert},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := types.UnmarshalEntry(pe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := entry.Canonicalize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logEntryAnon := models.LogEntryAnon{
+		Body:        base64.StdEncoding.EncodeToString(leaf),
+		LogIndex:    conv.Pointer(int64(1)),
+		Attestation: &models.LogEntryAnonAttestation{Data: statement},
+	}
+	monitoredValues := identity.MonitoredValues{CertificateIdentities: []identity.CertificateIdentity{{CertSubject: subject}}}
+
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{{"123-456-123": logEntryAnon}}, testMatcher(t, monitoredValues), "", "")
+	if err != nil {
+		t.Fatalf("expected error matching IDs, got %v", err)
+	}
+	if len(matches) != 1 || len(failedEntries) != 0 {
+		t.Fatalf("expected 1 match and 0 failed entries, got %d and %d", len(matches), len(failedEntries))
+	}
+	if matches[0].EntryKind != "intoto" || matches[0].PayloadType != in_toto.PayloadType {
+		t.Fatalf("unexpected entry kind and payload type: %s %s", matches[0].EntryKind, matches[0].PayloadType)
+	}
+	wantDigests := []string{"sha256:ab", "sha512:cd", "sha256:ef"}
+	if strings.Join(matches[0].SubjectDigests, ",") != strings.Join(wantDigests, ",") {
+		t.Fatalf("mismatched subject digests: %v %v", matches[0].SubjectDigests, wantDigests)
+	}
+
+	// subject digests are unknown if Rekor didn't store the attestation
+	logEntryAnon.Attestation = nil
+	matches, _, err = MatchedIndices([]models.LogEntry{{"123-456-123": logEntryAnon}}, testMatcher(t, monitoredValues), "", "")
+	if err != nil {
+		t.Fatalf("expected error matching IDs, got %v", err)
+	}
+	if len(matches) != 1 || matches[0].PayloadType != in_toto.PayloadType || len(matches[0].SubjectDigests) != 0 {
+		t.Fatalf("unexpected match %+v", matches)
+	}
+}
+
 func TestMatchedIndicesForDeprecatedCertificates(t *testing.T) {
 	rootCert, rootKey, _ := test.GenerateRootCA()
 	leafCert, leafKey, _ := test.GenerateDeprecatedLeafCert(subject, issuer, rootCert, rootKey)
@@ -287,13 +400,13 @@ func TestMatchedIndicesForDeprecatedCertificates(t *testing.T) {
 	logEntry := models.LogEntry{uuid: logEntryAnon}
 
 	//  match to subject with certificate in hashedrekord
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: subject,
 				Issuers:     []string{issuer},
 			},
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -368,10 +481,10 @@ func TestMatchedIndicesForFingerprints(t *testing.T) {
 	fp := hex.EncodeToString(digest[:])
 
 	//  match to key fingerprint in hashedrekord
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		Fingerprints: []string{
 			fp,
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -392,10 +505,10 @@ func TestMatchedIndicesForFingerprints(t *testing.T) {
 	}
 
 	// no match with different fingerprints
-	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		Fingerprints: []string{
 			"other-fp",
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching fingerprints, got %v", err)
 	}
@@ -453,10 +566,10 @@ func TestMatchedIndicesForSubjects(t *testing.T) {
 	logEntry := models.LogEntry{uuid: logEntryAnon}
 
 	//  match to subject with certificate in hashedrekord
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		Subjects: []string{
 			subject,
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -477,10 +590,10 @@ func TestMatchedIndicesForSubjects(t *testing.T) {
 	}
 
 	// no match with different subjects
-	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		Subjects: []string{
 			"other-sub",
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching subjects, got %v", err)
 	}
@@ -550,13 +663,13 @@ func TestMatchedIndicesForOIDMatchers(t *testing.T) {
 	logEntry := models.LogEntry{uuid: logEntryAnon}
 
 	// match to oid with matching extension value
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		OIDMatchers: []extensions.OIDExtension{
 			{
 				ObjectIdentifier: oid,
-				ExtensionValues:  []string{extValueString},
+				ExtensionValues:  extensions.ExactValues(extValueString),
 			},
-		}}, "", "")
+		}}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -578,7 +691,7 @@ func TestMatchedIndicesForOIDMatchers(t *testing.T) {
 			OIDMatchers: []extensions.OIDExtension{
 				{
 					ObjectIdentifier: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 9},
-					ExtensionValues:  []string{"wrong"},
+					ExtensionValues:  extensions.ExactValues("wrong"),
 				},
 			},
 		},
@@ -586,13 +699,13 @@ func TestMatchedIndicesForOIDMatchers(t *testing.T) {
 			OIDMatchers: []extensions.OIDExtension{
 				{
 					ObjectIdentifier: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 14},
-					ExtensionValues:  []string{"test cert value"},
+					ExtensionValues:  extensions.ExactValues("test cert value"),
 				},
 			},
 		},
 	}
 	for _, monitoredValues := range testedMonitoredValues {
-		matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, monitoredValues, "", "")
+		matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, monitoredValues), "", "")
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -665,16 +778,16 @@ func TestMatchedIndicesForFulcioOIDMatchers(t *testing.T) {
 	// match to oid with matching extension value
 	oidMatchers := extensions.OIDMatchers{
 		FulcioExtensions: extensions.FulcioExtensions{
-			BuildSignerURI: []string{extValueString},
+			BuildSignerURI: extensions.ExactValues(extValueString),
 		},
 	}
 	renderedOIDMatchers, err := oidMatchers.RenderOIDMatchers()
 	if err != nil {
 		t.Fatalf("received error rendering OID matchers: %v", err)
 	}
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		OIDMatchers: renderedOIDMatchers,
-	}, "", "")
+	}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -694,16 +807,16 @@ func TestMatchedIndicesForFulcioOIDMatchers(t *testing.T) {
 	// no match to oid with different oid extension field
 	oidMatchers = extensions.OIDMatchers{
 		FulcioExtensions: extensions.FulcioExtensions{
-			BuildSignerDigest: []string{extValueString},
+			BuildSignerDigest: extensions.ExactValues(extValueString),
 		},
 	}
 	renderedOIDMatchers, err = oidMatchers.RenderOIDMatchers()
 	if err != nil {
 		t.Fatalf("received error rendering OID matchers: %v", err)
 	}
-	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
+	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
 		OIDMatchers: renderedOIDMatchers,
-	}, "", "")
+	}), "", "")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -777,7 +890,7 @@ func TestMatchedIndicesForCustomOIDMatchers(t *testing.T) {
 		CustomExtensions: []extensions.CustomExtension{
 			{
 				ObjectIdentifier: "1.3.6.1.4.1.57264.1.9",
-				ExtensionValues:  []string{extValueString},
+				ExtensionValues:  extensions.ExactValues(extValueString),
 			},
 		},
 	}
@@ -785,8 +898,8 @@ func TestMatchedIndicesForCustomOIDMatchers(t *testing.T) {
 	if err != nil {
 		t.Fatalf("received error rendering OID matchers: %v", err)
 	}
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
-		OIDMatchers: renderedOIDMatchers}, "", "")
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
+		OIDMatchers: renderedOIDMatchers}), "", "")
 	if err != nil {
 		t.Fatalf("expected error matching IDs, got %v", err)
 	}
@@ -807,11 +920,11 @@ func TestMatchedIndicesForCustomOIDMatchers(t *testing.T) {
 		CustomExtensions: []extensions.CustomExtension{
 			{
 				ObjectIdentifier: "1.3.6.1.4.1.57264.1.9",
-				ExtensionValues:  []string{"wrong"},
+				ExtensionValues:  extensions.ExactValues("wrong"),
 			},
 			{
 				ObjectIdentifier: "1.3.6.1.4.1.57264.1.16",
-				ExtensionValues:  []string{extValueString},
+				ExtensionValues:  extensions.ExactValues(extValueString),
 			},
 		},
 	}
@@ -819,8 +932,8 @@ func TestMatchedIndicesForCustomOIDMatchers(t *testing.T) {
 	if err != nil {
 		t.Fatalf("received error rendering OID matchers: %v", err)
 	}
-	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, identity.MonitoredValues{
-		OIDMatchers: renderedOIDMatchers}, "", "")
+	matches, failedEntries, err = MatchedIndices([]models.LogEntry{logEntry}, testMatcher(t, identity.MonitoredValues{
+		OIDMatchers: renderedOIDMatchers}), "", "")
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -833,79 +946,21 @@ func TestMatchedIndicesForCustomOIDMatchers(t *testing.T) {
 	}
 }
 
-func TestMatchedIndicesFailures(t *testing.T) {
-	monitoredValuesTests := map[string]struct {
-		input       identity.MonitoredValues
-		errorString string
-	}{
-		"no monitored values": {
-			input:       identity.MonitoredValues{},
-			errorString: "no identities provided to monitor",
-		},
-		"cert subject empty": {
-			input: identity.MonitoredValues{
-				CertificateIdentities: []identity.CertificateIdentity{
-					{
-						CertSubject: "",
-					},
-				},
-			},
-			errorString: "certificate subject empty",
-		},
-		"empty issuer": {
-			input: identity.MonitoredValues{CertificateIdentities: []identity.CertificateIdentity{
-				{
-					CertSubject: "s",
-					Issuers:     []string{""},
-				},
-			}},
-			errorString: "issuer empty",
-		},
-		"empty subject": {
-			input:       identity.MonitoredValues{Subjects: []string{""}},
-			errorString: "subject empty",
-		},
-		"empty fingerprint": {
-			input:       identity.MonitoredValues{Fingerprints: []string{""}},
-			errorString: "fingerprint empty",
-		},
-		"empty oid extension": {
-			input: identity.MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
-				ObjectIdentifier: asn1.ObjectIdentifier{},
-				ExtensionValues:  []string{""},
-			}}},
-			errorString: "oid extension empty",
-		},
-		"empty oid matched values": {
-			input: identity.MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
-				ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
-				ExtensionValues:  []string{},
-			}}},
-			errorString: "oid matched values empty",
-		},
-		"empty oid matched value": {
-			input: identity.MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
-				ObjectIdentifier: asn1.ObjectIdentifier{2, 5, 29, 17},
-				ExtensionValues:  []string{""},
-			}}},
-			errorString: "oid matched value empty",
-		},
-		"empty oid field": {
-			input: identity.MonitoredValues{OIDMatchers: []extensions.OIDExtension{{
-				ObjectIdentifier: asn1.ObjectIdentifier{},
-				ExtensionValues:  []string{""},
-			}}},
-			errorString: "oid extension empty",
-		},
+func TestMatchLogEntryMonitoredValues(t *testing.T) {
+	logEntryAnon := models.LogEntryAnon{LogIndex: conv.Pointer(int64(1234))}
+	fingerprintMatches := MatchLogEntryFingerprints(logEntryAnon, "123-456-123", []string{"aa", "bb"}, []string{"bb"})
+	if len(fingerprintMatches) != 1 || fingerprintMatches[0].Fingerprint != "bb" || fingerprintMatches[0].Index != 1234 || fingerprintMatches[0].UUID != "123-456-123" {
+		t.Errorf("unexpected fingerprint matches %+v", fingerprintMatches)
 	}
-
-	for name, testCase := range monitoredValuesTests {
-		t.Run(name, func(t *testing.T) {
-			_, _, err := MatchedIndices(nil, testCase.input, "", "")
-			if err == nil || !strings.Contains(err.Error(), testCase.errorString) {
-				t.Fatalf("expected error %v, received %v", testCase.errorString, err)
-			}
-		})
+	subjectMatches, err := MatchLogEntrySubjects(logEntryAnon, "123-456-123", []string{"user@example.com"}, []string{`.*@example\.com`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subjectMatches) != 1 || subjectMatches[0].Subject != "user@example.com" || subjectMatches[0].Index != 1234 {
+		t.Errorf("unexpected subject matches %+v", subjectMatches)
+	}
+	if _, err := MatchLogEntrySubjects(logEntryAnon, "123-456-123", []string{"user@example.com"}, []string{"("}); err == nil {
+		t.Errorf("expected error for invalid subject regex")
 	}
 }
 
@@ -1000,14 +1055,14 @@ func TestMatchedIndicesWithTrustedCAs(t *testing.T) {
 	untrustedLogEntry := createLogEntry(untrustedLeafCert, untrustedLeafKey, "untrusted-uuid")
 
 	// Test with trusted CAs provided - should only match trusted certificate
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: ".*@example.com",
 				Issuers:     []string{issuer},
 			},
 		},
-	}, trustedCAFile.Name(), "")
+	}), trustedCAFile.Name(), "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1024,14 +1079,14 @@ func TestMatchedIndicesWithTrustedCAs(t *testing.T) {
 	}
 
 	// Test with no trusted CAs provided - should match both certificates
-	matches, failedEntries, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, identity.MonitoredValues{
+	matches, failedEntries, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: ".*@example.com",
 				Issuers:     []string{issuer},
 			},
 		},
-	}, "", "")
+	}), "", "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1165,14 +1220,14 @@ func TestMatchedIndicesWithCARootsAndIntermediates(t *testing.T) {
 
 	// Test the core functionality: proper root-intermediate-leaf chain validation
 	// Should only match the trusted certificate chain (Root CA -> Intermediate CA -> Leaf Cert)
-	matches, failedEntries, err := MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, identity.MonitoredValues{
+	matches, failedEntries, err := MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: ".*@example.com",
 				Issuers:     []string{issuer},
 			},
 		},
-	}, trustedRootFile, trustedIntermediateFile)
+	}), trustedRootFile, trustedIntermediateFile)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1189,14 +1244,14 @@ func TestMatchedIndicesWithCARootsAndIntermediates(t *testing.T) {
 	}
 
 	// Test that we should not match any certificates because the untrusted intermediate CA is not signed by the trusted root CA
-	matches, _, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, identity.MonitoredValues{
+	matches, _, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: ".*@example.com",
 				Issuers:     []string{issuer},
 			},
 		},
-	}, trustedRootFile, untrustedIntermediateFile)
+	}), trustedRootFile, untrustedIntermediateFile)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1206,14 +1261,14 @@ func TestMatchedIndicesWithCARootsAndIntermediates(t *testing.T) {
 
 	// Test that we should not match any certificates because the trusted intermediate CA is not signed by the untrusted root CA
 	untrustedRootFile := createTempCertFile(t, untrustedRootCert, "untrusted-root-ca")
-	matches, _, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, identity.MonitoredValues{
+	matches, _, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: ".*@example.com",
 				Issuers:     []string{issuer},
 			},
 		},
-	}, untrustedRootFile, trustedIntermediateFile)
+	}), untrustedRootFile, trustedIntermediateFile)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -1222,14 +1277,14 @@ func TestMatchedIndicesWithCARootsAndIntermediates(t *testing.T) {
 	}
 
 	// Make sure the untrusted chain is actually good to use with the right caRoots and caIntermediates
-	matches, _, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, identity.MonitoredValues{
+	matches, _, err = MatchedIndices([]models.LogEntry{trustedLogEntry, untrustedLogEntry}, testMatcher(t, identity.MonitoredValues{
 		CertificateIdentities: []identity.CertificateIdentity{
 			{
 				CertSubject: ".*@example.com",
 				Issuers:     []string{issuer},
 			},
 		},
-	}, untrustedRootFile, untrustedIntermediateFile)
+	}), untrustedRootFile, untrustedIntermediateFile)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -20,9 +20,11 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/rekor/pkg/generated/client"
 	"github.com/sigstore/rekor/pkg/generated/models"
 	"github.com/sigstore/rekor/pkg/util"
@@ -108,8 +110,11 @@ func verifyCheckpointConsistency(logInfoFile string, checkpoint *util.SignedChec
 	if err := verify.ProveConsistency(context.Background(), rekorClient, prevCheckpoint, checkpoint, treeID); err != nil {
 		return nil, fmt.Errorf("failed to verify log consistency: %v", err)
 	}
-	fmt.Fprintf(os.Stderr, "Root hash consistency verified - Current Size: %d Root Hash: %s - Previous Size: %d Root Hash %s\n",
-		checkpoint.Size, hex.EncodeToString(checkpoint.Hash), prevCheckpoint.Size, hex.EncodeToString(prevCheckpoint.Hash))
+	slog.Info("root hash consistency verified",
+		slog.Uint64(logging.KeyTreeSize, checkpoint.Size),
+		slog.String(logging.KeyRootHash, hex.EncodeToString(checkpoint.Hash)),
+		slog.Uint64(logging.KeyPreviousTreeSize, prevCheckpoint.Size),
+		slog.String(logging.KeyPreviousRootHash, hex.EncodeToString(prevCheckpoint.Hash)))
 	return prevCheckpoint, nil
 }
 
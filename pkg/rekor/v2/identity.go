@@ -18,14 +18,17 @@ import (
 	"bytes"
 	"context"
 	"crypto/x509"
+	"encoding/hex"
 	"fmt"
-	"os"
-	"regexp"
+	"log/slog"
+	"time"
 
+	v1 "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
 	"github.com/sigstore/rekor-monitor/pkg/fulcio/extensions"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor-monitor/pkg/notifications"
 	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/rekor-tiles/v2/pkg/generated/protobuf"
 	"github.com/sigstore/rekor-tiles/v2/pkg/verifier"
 	"github.com/sigstore/rekor-tiles/v2/pkg/verifier/certificate"
@@ -33,83 +36,127 @@ import (
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 )
 
+// MatchLogEntryFingerprints returns the matches of the monitored fingerprints in a
+// log entry.
+//
+// Deprecated: MatchLogEntryFingerprints compiles the monitored values on every
+// call. Use MatchedIndices with a matcher compiled once by identity.NewMatcher.
 func MatchLogEntryFingerprints(entry Entry, entryFingerprints []string, monitoredFingerprints []string) []identity.LogEntry {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredFp := range monitoredFingerprints {
-		for _, fp := range entryFingerprints {
-			if fp == monitoredFp {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     monitoredFp,
-					MatchedIdentityType: identity.MatchedIdentityTypeFingerprint,
-					Fingerprint:         fp,
-					Index:               int64(entry.Index),
-				})
-			}
-		}
+	// fingerprints are compared as they are, so compiling them can't fail
+	matcher, _ := identity.NewMatcher(identity.MonitoredValues{Fingerprints: monitoredFingerprints})
+	return matchLogEntryFingerprints(entry, entryFingerprints, matcher)
+}
+
+// MatchLogEntryCertificateIdentities returns the matches of the monitored
+// certificate identities in a log entry.
+//
+// Deprecated: MatchLogEntryCertificateIdentities compiles the monitored values on
+// every call. Use MatchedIndices with a matcher compiled once by
+// identity.NewMatcher.
+func MatchLogEntryCertificateIdentities(entry Entry, entryCertificates []*x509.Certificate, monitoredCertIDs []identity.CertificateIdentity) ([]identity.LogEntry, error) {
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{CertificateIdentities: monitoredCertIDs})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
+	}
+	return matchLogEntryCertificateIdentities(entry, entryCertificates, matcher)
+}
+
+// MatchLogEntrySubjects returns the matches of the monitored subjects in a log
+// entry.
+//
+// Deprecated: MatchLogEntrySubjects compiles the monitored values on every call.
+// Use MatchedIndices with a matcher compiled once by identity.NewMatcher.
+func MatchLogEntrySubjects(entry Entry, entrySubjects []string, monitoredSubjects []string) ([]identity.LogEntry, error) {
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{Subjects: monitoredSubjects})
+	if err != nil {
+		return nil, fmt.Errorf("error compiling regex at index %d: %w", entry.Index, err)
+	}
+	return matchLogEntrySubjects(entry, entrySubjects, matcher), nil
+}
+
+// MatchLogEntryOIDs returns the matches of the monitored OID extensions in a log
+// entry.
+//
+// Deprecated: MatchLogEntryOIDs compiles the monitored values on every call. Use
+// MatchedIndices with a matcher compiled once by identity.NewMatcher.
+func MatchLogEntryOIDs(entry Entry, entryCertificates []*x509.Certificate, monitoredOIDMatchers []extensions.OIDExtension) ([]identity.LogEntry, error) {
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{OIDMatchers: monitoredOIDMatchers})
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
+	}
+	return matchLogEntryOIDs(entry, entryCertificates, matcher)
+}
+
+func matchLogEntryFingerprints(entry Entry, entryFingerprints []string, matcher *identity.Matcher) []identity.LogEntry {
+	matchedEntries := matcher.MatchFingerprints(entryFingerprints)
+	for i := range matchedEntries {
+		matchedEntries[i].Index = int64(entry.Index)
 	}
 	return matchedEntries
 }
 
-func MatchLogEntryCertificateIdentities(entry Entry, entryCertificates []*x509.Certificate, monitoredCertIDs []identity.CertificateIdentity) ([]identity.LogEntry, error) {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredCertID := range monitoredCertIDs {
-		for _, cert := range entryCertificates {
-			match, sub, iss, err := identity.CertMatchesPolicy(cert, monitoredCertID.CertSubject, monitoredCertID.Issuers)
-			if err != nil {
-				return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
-			} else if match {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     monitoredCertID.CertSubject,
-					MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
-					CertSubject:         sub,
-					Issuer:              iss,
-					Index:               int64(entry.Index),
-				})
-			}
-		}
+func matchLogEntryCertificateIdentities(entry Entry, entryCertificates []*x509.Certificate, matcher *identity.Matcher) ([]identity.LogEntry, error) {
+	matchedEntries, err := identity.MatchCertificateIdentities(matcher, entryCertificates)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = int64(entry.Index)
 	}
 	return matchedEntries, nil
 }
 
-func MatchLogEntrySubjects(entry Entry, entrySubjects []string, monitoredSubjects []string) ([]identity.LogEntry, error) {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredSub := range monitoredSubjects {
-		regex, err := regexp.Compile(monitoredSub)
-		if err != nil {
-			return nil, fmt.Errorf("error compiling regex at index %d: %w", entry.Index, err)
-		}
-		for _, sub := range entrySubjects {
-			if regex.MatchString(sub) {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     monitoredSub,
-					MatchedIdentityType: identity.MatchedIdentityTypeSubject,
-					Subject:             sub,
-					Index:               int64(entry.Index),
-				})
-			}
-		}
+func matchLogEntrySubjects(entry Entry, entrySubjects []string, matcher *identity.Matcher) []identity.LogEntry {
+	matchedEntries := matcher.MatchSubjects(entrySubjects)
+	for i := range matchedEntries {
+		matchedEntries[i].Index = int64(entry.Index)
+	}
+	return matchedEntries
+}
+
+func matchLogEntryOIDs(entry Entry, entryCertificates []*x509.Certificate, matcher *identity.Matcher) ([]identity.LogEntry, error) {
+	matchedEntries, err := identity.MatchOIDExtensions(matcher, entryCertificates)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = int64(entry.Index)
 	}
 	return matchedEntries, nil
 }
 
-func MatchLogEntryOIDs(entry Entry, entryCertificates []*x509.Certificate, monitoredOIDMatchers []extensions.OIDExtension) ([]identity.LogEntry, error) {
-	matchedEntries := []identity.LogEntry{}
-	for _, monitoredOID := range monitoredOIDMatchers {
-		for _, cert := range entryCertificates {
-			match, oid, extValue, err := identity.OIDMatchesPolicy(cert, monitoredOID.ObjectIdentifier, monitoredOID.ExtensionValues)
-			if err != nil {
-				return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
-			}
-			if match {
-				matchedEntries = append(matchedEntries, identity.LogEntry{
-					MatchedIdentity:     extValue,
-					MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
-					Index:               int64(entry.Index),
-					OIDExtension:        oid,
-					ExtensionValue:      extValue,
-				})
-			}
-		}
+// MatchLogEntryRules returns the matches of the compiled rules in a log entry,
+// evaluated on its certificates, subjects and key fingerprints
+func MatchLogEntryRules(entry Entry, entryCertificates []*x509.Certificate, entrySubjects []string, entryFingerprints []string, rules identity.CompiledRules) ([]identity.LogEntry, error) {
+	if len(rules) == 0 {
+		return []identity.LogEntry{}, nil
+	}
+	matchedEntries, err := rules.Match(identity.NewRuleEntry(entryCertificates, entrySubjects, entryFingerprints))
+	if err != nil {
+		return nil, fmt.Errorf("error with rule matching at index %d: %w", entry.Index, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = int64(entry.Index)
+	}
+	return matchedEntries, nil
+}
+
+// MatchLogEntryPolicies returns the matches of the compiled policies in a log
+// entry, evaluated on its certificates, subjects, key fingerprints and metadata
+func MatchLogEntryPolicies(entry Entry, entryCertificates []*x509.Certificate, entrySubjects []string, entryFingerprints []string, metadata identity.EntryMetadata, policies identity.CompiledPolicies) ([]identity.LogEntry, error) {
+	if len(policies) == 0 {
+		return []identity.LogEntry{}, nil
+	}
+	policyEntry, err := identity.NewPolicyEntry(entryCertificates, entrySubjects, entryFingerprints, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
+	}
+	matchedEntries, err := policies.Match(policyEntry)
+	if err != nil {
+		return nil, fmt.Errorf("error with policy matching at index %d: %w", entry.Index, err)
+	}
+	for i := range matchedEntries {
+		matchedEntries[i].Index = int64(entry.Index)
 	}
 	return matchedEntries, nil
 }
@@ -157,6 +204,41 @@ func extractVerifiers(e *protobuf.Entry) ([]verifier.Verifier, error) {
 	return verifiers, nil
 }
 
+// entryMetadata returns the metadata of a Rekor entry shared by all matches of the entry.
+// Rekor v2 entries have no integrated time. Unlike Rekor v1 intoto entries, Rekor v2
+// dsse entries only store the hash of the DSSE payload and the signatures, not the
+// envelope, so the payload type and the subjects of the in-toto statement are unknown.
+func entryMetadata(e *protobuf.Entry) identity.EntryMetadata {
+	metadata := identity.EntryMetadata{
+		LogKind:   identity.LogKindRekorV2,
+		EntryKind: e.GetKind(),
+	}
+	switch e.GetKind() {
+	case "dsse":
+		metadata.ArtifactDigest = hashOutputDigest(e.GetSpec().GetDsseV002().GetPayloadHash())
+	case "hashedrekord":
+		metadata.ArtifactDigest = hashOutputDigest(e.GetSpec().GetHashedRekordV002().GetData())
+	}
+	return metadata
+}
+
+// hashOutputDigest returns a digest prefixed with the name of its hash algorithm,
+// e.g. sha256:abcdef, or an empty string if the digest or its algorithm is unknown
+func hashOutputDigest(digest *v1.HashOutput) string {
+	if len(digest.GetDigest()) == 0 {
+		return ""
+	}
+	switch digest.GetAlgorithm() {
+	case v1.HashAlgorithm_SHA2_256:
+		return "sha256:" + hex.EncodeToString(digest.GetDigest())
+	case v1.HashAlgorithm_SHA2_384:
+		return "sha384:" + hex.EncodeToString(digest.GetDigest())
+	case v1.HashAlgorithm_SHA2_512:
+		return "sha512:" + hex.EncodeToString(digest.GetDigest())
+	}
+	return ""
+}
+
 // extractAllIdentities gets all certificates, email addresses, and key fingerprints
 // from a list of verifiers
 func extractAllIdentities(verifiers []verifier.Verifier) ([]string, []*x509.Certificate, []string, error) {
@@ -180,12 +262,9 @@ func extractAllIdentities(verifiers []verifier.Verifier) ([]string, []*x509.Cert
 	return subjects, certificates, fps, nil
 }
 
-// MatchedIndices returns a list of log entries that contain the requested identities.
-func MatchedIndices(logEntries []Entry, mvs identity.MonitoredValues, caRoots string, caIntermediates string) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
-	if err := identity.VerifyMonitoredValues(mvs); err != nil {
-		return nil, nil, err
-	}
-
+// MatchedIndices returns a list of log entries that contain the requested identities,
+// with a single finding per log entry listing every rule that matched the entry.
+func MatchedIndices(logEntries []Entry, matcher *identity.Matcher, caRoots string, caIntermediates string) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 	var matchedEntries []identity.LogEntry
 	var failedEntries []identity.FailedLogEntry
 
@@ -209,48 +288,66 @@ func MatchedIndices(logEntries []Entry, mvs identity.MonitoredValues, caRoots st
 
 		// Validate that the certificate chain up to a trusted CA
 		if err := identity.ValidateCertificateChain(certs, caRoots, caIntermediates); err != nil {
-			fmt.Fprintf(os.Stderr, "Certificate chain for log entry (Index: %d) could not be verified against trusted CAs, skipping the entry: %v\n", entry.Index, err)
+			slog.Warn("certificate chain for log entry could not be verified against trusted CAs, skipping the entry",
+				slog.Int64(logging.KeyIndex, entry.Index), logging.Err(err))
 			continue
 		}
+		metadata := entryMetadata(entry.ProtoEntry)
 
-		matchedFingerprintEntries := MatchLogEntryFingerprints(entry, fps, mvs.Fingerprints)
-		matchedEntries = append(matchedEntries, matchedFingerprintEntries...)
+		matchedFingerprintEntries := matchLogEntryFingerprints(entry, fps, matcher)
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedFingerprintEntries, metadata)...)
 
-		matchedSubjectEntries, err := MatchLogEntrySubjects(entry, subjects, mvs.Subjects)
+		matchedSubjectEntries := matchLogEntrySubjects(entry, subjects, matcher)
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedSubjectEntries, metadata)...)
+
+		matchedCertIDEntries, err := matchLogEntryCertificateIdentities(entry, certs, matcher)
 		if err != nil {
 			failedEntries = append(failedEntries, identity.FailedLogEntry{
 				Index: int64(entry.Index),
-				Error: fmt.Sprintf("error matching subjects: %v", err),
+				Error: fmt.Sprintf("error matching certificate identities: %v", err),
 			})
 			continue
 		}
-		matchedEntries = append(matchedEntries, matchedSubjectEntries...)
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedCertIDEntries, metadata)...)
 
-		matchedCertIDEntries, err := MatchLogEntryCertificateIdentities(entry, certs, mvs.CertificateIdentities)
+		matchedOIDEntries, err := matchLogEntryOIDs(entry, certs, matcher)
 		if err != nil {
 			failedEntries = append(failedEntries, identity.FailedLogEntry{
 				Index: int64(entry.Index),
-				Error: fmt.Sprintf("error matching certificate identities: %v", err),
+				Error: fmt.Sprintf("error matching object identifier extensions and values: %v", err),
 			})
 			continue
 		}
-		matchedEntries = append(matchedEntries, matchedCertIDEntries...)
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedOIDEntries, metadata)...)
 
-		matchedOIDEntries, err := MatchLogEntryOIDs(entry, certs, mvs.OIDMatchers)
+		matchedRuleEntries, err := MatchLogEntryRules(entry, certs, subjects, fps, matcher.Rules)
 		if err != nil {
 			failedEntries = append(failedEntries, identity.FailedLogEntry{
 				Index: int64(entry.Index),
-				Error: fmt.Sprintf("error matching object identifier extensions and values: %v", err),
+				Error: fmt.Sprintf("error matching rules: %v", err),
+			})
+			continue
+		}
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedRuleEntries, metadata)...)
+
+		matchedPolicyEntries, err := MatchLogEntryPolicies(entry, certs, subjects, fps, metadata, matcher.Policies)
+		if err != nil {
+			failedEntries = append(failedEntries, identity.FailedLogEntry{
+				Index: int64(entry.Index),
+				Error: fmt.Sprintf("error matching policies: %v", err),
 			})
 			continue
 		}
-		matchedEntries = append(matchedEntries, matchedOIDEntries...)
+		matchedEntries = append(matchedEntries, identity.WithEntryMetadata(matchedPolicyEntries, metadata)...)
 	}
 
-	return matchedEntries, failedEntries, nil
+	return identity.ConsolidateMatches(matchedEntries), failedEntries, nil
 }
 
-func IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, rekorShards map[string]ShardInfo, latestShardOrigin string, monitoredValues identity.MonitoredValues) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+// SearchIdentities returns the entries of the log index range (StartIndex, EndIndex]
+// of the configuration matching the compiled monitored values, and the entries that
+// could not be parsed. Unlike IdentitySearch, it doesn't write the identity outputs.
+func SearchIdentities(ctx context.Context, config *notifications.IdentityMonitorConfiguration, rekorShards map[string]ShardInfo, latestShardOrigin string, matcher *identity.Matcher) ([]identity.LogEntry, []identity.FailedLogEntry, error) {
 	// TODO: handle sharding
 	activeShard := rekorShards[latestShardOrigin]
 	entries, err := GetEntriesByIndexRange(ctx, activeShard, *config.StartIndex, *config.EndIndex)
@@ -258,17 +355,29 @@ func IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorCo
 		return nil, nil, fmt.Errorf("error getting entries by index range: %v", err)
 	}
 
-	matchedEntries, failedEntries, err := MatchedIndices(entries, monitoredValues, config.CARootsFile, config.CAIntermediatesFile)
+	matchedEntries, failedEntries, err := MatchedIndices(entries, matcher, config.CARootsFile, config.CAIntermediatesFile)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error matching indices: %v", err)
 	}
+	identity.SetDetected(matchedEntries, latestShardOrigin, time.Now())
+	return matchedEntries, failedEntries, nil
+}
+
+// IdentitySearch searches the log index range (StartIndex, EndIndex] of the
+// configuration, writes the matched entries to the identity outputs and
+// returns them grouped by monitored identity
+func IdentitySearch(ctx context.Context, config *notifications.IdentityMonitorConfiguration, rekorShards map[string]ShardInfo, latestShardOrigin string, matcher *identity.Matcher) ([]identity.MonitoredIdentity, []identity.FailedLogEntry, error) {
+	matchedEntries, failedEntries, err := SearchIdentities(ctx, config, rekorShards, latestShardOrigin, matcher)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	err = file.WriteMatchedIdentityEntries(config.OutputIdentitiesFile, config.OutputIdentitiesFormat, matchedEntries, config.IdentityMetadataFile, *config.EndIndex)
+	err = file.WriteMatchedIdentityEntries(config.IdentityOutputs(), matchedEntries, config.IdentityMetadataFile, *config.EndIndex)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	identities := identity.CreateIdentitiesList(monitoredValues)
+	identities := identity.CreateIdentitiesList(matcher.MonitoredValues())
 	monitoredIdentities := identity.CreateMonitoredIdentities(matchedEntries, identities)
 	return monitoredIdentities, failedEntries, nil
 }
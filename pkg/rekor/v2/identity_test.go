@@ -0,0 +1,166 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	v1 "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-tiles/v2/pkg/generated/protobuf"
+)
+
+func testVerifier(t *testing.T) (*protobuf.Verifier, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := sha256.Sum256(der)
+	return &protobuf.Verifier{
+		Verifier:   &protobuf.Verifier_PublicKey{PublicKey: &protobuf.PublicKey{RawBytes: der}},
+		KeyDetails: v1.PublicKeyDetails_PKIX_ECDSA_P256_SHA_256,
+	}, hex.EncodeToString(fingerprint[:])
+}
+
+func testHashedRekordEntry(verifier *protobuf.Verifier, digest *v1.HashOutput) *protobuf.Entry {
+	return &protobuf.Entry{
+		Kind:       "hashedrekord",
+		ApiVersion: "0.0.2",
+		Spec: &protobuf.Spec{Spec: &protobuf.Spec_HashedRekordV002{HashedRekordV002: &protobuf.HashedRekordLogEntryV002{
+			Data:      digest,
+			Signature: &protobuf.Signature{Content: []byte("signature"), Verifier: verifier},
+		}}},
+	}
+}
+
+func testDSSEEntry(verifier *protobuf.Verifier, digest *v1.HashOutput) *protobuf.Entry {
+	return &protobuf.Entry{
+		Kind:       "dsse",
+		ApiVersion: "0.0.2",
+		Spec: &protobuf.Spec{Spec: &protobuf.Spec_DsseV002{DsseV002: &protobuf.DSSELogEntryV002{
+			PayloadHash: digest,
+			Signatures:  []*protobuf.Signature{{Content: []byte("signature"), Verifier: verifier}},
+		}}},
+	}
+}
+
+func TestEntryMetadata(t *testing.T) {
+	verifier, _ := testVerifier(t)
+	digest := []byte{0xab, 0xcd, 0xef}
+	tests := []struct {
+		name  string
+		entry *protobuf.Entry
+		want  identity.EntryMetadata
+	}{
+		{
+			name:  "hashedrekord sha256",
+			entry: testHashedRekordEntry(verifier, &v1.HashOutput{Algorithm: v1.HashAlgorithm_SHA2_256, Digest: digest}),
+			want:  identity.EntryMetadata{LogKind: identity.LogKindRekorV2, EntryKind: "hashedrekord", ArtifactDigest: "sha256:abcdef"},
+		},
+		{
+			name:  "hashedrekord sha512",
+			entry: testHashedRekordEntry(verifier, &v1.HashOutput{Algorithm: v1.HashAlgorithm_SHA2_512, Digest: digest}),
+			want:  identity.EntryMetadata{LogKind: identity.LogKindRekorV2, EntryKind: "hashedrekord", ArtifactDigest: "sha512:abcdef"},
+		},
+		{
+			name:  "hashedrekord without digest",
+			entry: testHashedRekordEntry(verifier, nil),
+			want:  identity.EntryMetadata{LogKind: identity.LogKindRekorV2, EntryKind: "hashedrekord"},
+		},
+		{
+			// Rekor v2 doesn't store the payload type or the in-toto statement
+			name:  "dsse sha384",
+			entry: testDSSEEntry(verifier, &v1.HashOutput{Algorithm: v1.HashAlgorithm_SHA2_384, Digest: digest}),
+			want:  identity.EntryMetadata{LogKind: identity.LogKindRekorV2, EntryKind: "dsse", ArtifactDigest: "sha384:abcdef"},
+		},
+		{
+			name:  "dsse with unknown hash algorithm",
+			entry: testDSSEEntry(verifier, &v1.HashOutput{Algorithm: v1.HashAlgorithm_HASH_ALGORITHM_UNSPECIFIED, Digest: digest}),
+			want:  identity.EntryMetadata{LogKind: identity.LogKindRekorV2, EntryKind: "dsse"},
+		},
+		{
+			name:  "unsupported kind",
+			entry: &protobuf.Entry{Kind: "rekord"},
+			want:  identity.EntryMetadata{LogKind: identity.LogKindRekorV2, EntryKind: "rekord"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryMetadata(tt.entry); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected metadata %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMatchedIndicesForFingerprints(t *testing.T) {
+	verifier, fingerprint := testVerifier(t)
+	otherVerifier, _ := testVerifier(t)
+	digest := &v1.HashOutput{Algorithm: v1.HashAlgorithm_SHA2_256, Digest: []byte{0x01, 0x02}}
+	matcher, err := identity.NewMatcher(identity.MonitoredValues{Fingerprints: []string{fingerprint}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		entry       *protobuf.Entry
+		wantMatch   bool
+		wantFailure bool
+	}{
+		{name: "hashedrekord", entry: testHashedRekordEntry(verifier, digest), wantMatch: true},
+		{name: "dsse", entry: testDSSEEntry(verifier, digest), wantMatch: true},
+		{name: "other key", entry: testDSSEEntry(otherVerifier, digest)},
+		{name: "unsupported kind", entry: &protobuf.Entry{Kind: "rekord", Spec: &protobuf.Spec{}}, wantFailure: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, failures, err := MatchedIndices([]Entry{{ProtoEntry: tt.entry, Index: 7}}, matcher, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantFailure != (len(failures) == 1) {
+				t.Fatalf("unexpected failed entries %+v", failures)
+			}
+			if !tt.wantMatch {
+				if len(matches) != 0 {
+					t.Errorf("expected no matches, got %+v", matches)
+				}
+				return
+			}
+			if len(matches) != 1 {
+				t.Fatalf("expected 1 match, got %+v", matches)
+			}
+			match := matches[0]
+			if match.Index != 7 || match.Fingerprint != fingerprint || match.MatchedIdentityType != identity.MatchedIdentityTypeFingerprint {
+				t.Errorf("unexpected match %+v", match)
+			}
+			if match.LogKind != identity.LogKindRekorV2 || match.EntryKind != tt.entry.GetKind() || match.ArtifactDigest != "sha256:0102" {
+				t.Errorf("unexpected metadata of match %+v", match)
+			}
+		})
+	}
+}
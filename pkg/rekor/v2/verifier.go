@@ -22,11 +22,13 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 
 	"github.com/sigstore/rekor-monitor/pkg/util/file"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/rekor-tiles/v2/pkg/client"
 	"github.com/sigstore/sigstore-go/pkg/root"
 	"github.com/sigstore/sigstore/pkg/signature"
@@ -170,8 +172,12 @@ func RunConsistencyCheck(ctx context.Context, rekorShards map[string]ShardInfo,
 			return nil, nil, fmt.Errorf("consistency check failed: %v", err)
 		}
 
-		fmt.Fprintf(os.Stderr, "Root hash consistency verified - Current Size: %d Root Hash: %s - Previous Size: %d Root Hash %s\n",
-			newCheckpoint.Size, hex.EncodeToString(newCheckpoint.Hash), prevCheckpoint.Size, hex.EncodeToString(prevCheckpoint.Hash))
+		slog.Info("root hash consistency verified",
+			slog.String(logging.KeyLogOrigin, newCheckpoint.Origin),
+			slog.Uint64(logging.KeyTreeSize, newCheckpoint.Size),
+			slog.String(logging.KeyRootHash, hex.EncodeToString(newCheckpoint.Hash)),
+			slog.Uint64(logging.KeyPreviousTreeSize, prevCheckpoint.Size),
+			slog.String(logging.KeyPreviousRootHash, hex.EncodeToString(prevCheckpoint.Hash)))
 	}
 
 	return prevCheckpoint, latestShardCheckpoint, nil
@@ -0,0 +1,184 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secrets resolves secret references in the monitor configuration,
+// such as env:NAME or file:/path, and redacts resolved secrets from output.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Redacted replaces secrets in redacted strings
+	Redacted = "[REDACTED]"
+	// LiteralPrefix escapes plaintext values that would otherwise be taken
+	// for a secret reference, e.g. literal:env:value is the value env:value
+	LiteralPrefix = "literal:"
+)
+
+// Provider resolves the name of a secret reference, the part after the
+// "scheme:" prefix, to the value of the secret
+type Provider interface {
+	Resolve(ctx context.Context, name string) (string, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface
+type ProviderFunc func(ctx context.Context, name string) (string, error)
+
+// Resolve calls f(ctx, name)
+func (f ProviderFunc) Resolve(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{
+		"env":  ProviderFunc(resolveEnv),
+		"file": ProviderFunc(resolveFile),
+	}
+	// resolved holds the resolved secrets, which are redacted by Redact
+	resolved = map[string]bool{}
+)
+
+// RegisterProvider registers a secret provider for references of the form
+// "scheme:name". It must be called before the configuration is loaded,
+// e.g. from an init function.
+func RegisterProvider(scheme string, provider Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[scheme] = provider
+}
+
+// resolveEnv resolves env:NAME references to the value of the environment variable
+func resolveEnv(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFile resolves file:/path references to the content of the file,
+// without trailing newlines. The file is read again on every resolution,
+// so that rotated secrets are picked up.
+func resolveFile(_ context.Context, name string) (string, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret file: %w", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// provider returns the provider of a secret reference, and the name of the secret
+func provider(value string) (Provider, string, bool) {
+	scheme, name, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, "", false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[scheme]
+	return p, name, ok
+}
+
+// IsReference returns whether the value is a reference to a secret of a
+// registered provider
+func IsReference(value string) bool {
+	_, _, ok := provider(value)
+	return ok
+}
+
+// Resolve returns the secret referenced by the value, or the value itself if
+// it is not a secret reference. Values prefixed with LiteralPrefix are returned
+// without the prefix. Only secrets resolved from a reference are redacted by
+// Redact. Errors mention the reference, never the secret.
+func Resolve(ctx context.Context, value string) (string, error) {
+	if literal, ok := strings.CutPrefix(value, LiteralPrefix); ok {
+		return literal, nil
+	}
+	p, name, ok := provider(value)
+	if !ok {
+		return value, nil
+	}
+	secret, err := p.Resolve(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("error resolving secret %s: %w", value, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("error resolving secret %s: secret is empty", value)
+	}
+	track(secret)
+	return secret, nil
+}
+
+// track records a secret to be redacted
+func track(secret string) {
+	if secret == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	resolved[secret] = true
+}
+
+// Redact replaces the secrets resolved so far in s with [REDACTED]
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(resolved) == 0 {
+		return s
+	}
+	// replace longer secrets first, in case a secret contains another one
+	secrets := make([]string, 0, len(resolved))
+	for secret := range resolved {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+// RedactError returns an error whose message has resolved secrets redacted.
+// The original error can still be inspected with errors.Is and errors.As.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := Redact(err.Error())
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{message: message, err: err}
+}
+
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("REKOR_MONITOR_TEST_SECRET", "env-secret")
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"env:REKOR_MONITOR_TEST_SECRET", "env-secret"},
+		{"file:" + secretFile, "file-secret"},
+		{"plaintext-secret", "plaintext-secret"},
+		{"https://hooks.example.com/services/T000", "https://hooks.example.com/services/T000"},
+		{"literal:env:plaintext-secret", "env:plaintext-secret"},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(context.Background(), tt.value)
+		if err != nil {
+			t.Errorf("Resolve(%q) returned error: %v", tt.value, err)
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	t.Setenv("REKOR_MONITOR_TEST_EMPTY_SECRET", "")
+	tests := []struct {
+		value  string
+		errMsg string
+	}{
+		{"env:REKOR_MONITOR_TEST_UNSET_SECRET", "error resolving secret env:REKOR_MONITOR_TEST_UNSET_SECRET: environment variable REKOR_MONITOR_TEST_UNSET_SECRET is not set"},
+		{"env:REKOR_MONITOR_TEST_EMPTY_SECRET", "secret is empty"},
+		{"file:/nonexistent/secret", "error resolving secret file:/nonexistent/secret: error reading secret file"},
+	}
+	for _, tt := range tests {
+		_, err := Resolve(context.Background(), tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("Resolve(%q) expected error containing %q, got %v", tt.value, tt.errMsg, err)
+		}
+	}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	RegisterProvider("vault", ProviderFunc(func(_ context.Context, name string) (string, error) {
+		return "vault-" + name, nil
+	}))
+	defer func() {
+		mu.Lock()
+		delete(providers, "vault")
+		mu.Unlock()
+	}()
+
+	if !IsReference("vault:kv/monitor") {
+		t.Errorf("expected vault:kv/monitor to be a secret reference")
+	}
+	got, err := Resolve(context.Background(), "vault:kv/monitor")
+	if err != nil || got != "vault-kv/monitor" {
+		t.Errorf("Resolve() = %q, %v", got, err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Setenv("REKOR_MONITOR_TEST_TOKEN", "ghp_redactme")
+	if _, err := Resolve(context.Background(), "env:REKOR_MONITOR_TEST_TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := Redact("token ghp_redactme rejected"), "token [REDACTED] rejected"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+
+	cause := errors.New("request with ghp_redactme failed")
+	err := RedactError(cause)
+	if err.Error() != "request with [REDACTED] failed" {
+		t.Errorf("unexpected redacted error %q", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("expected redacted error to wrap the original error")
+	}
+	if unchanged := errors.New("nothing to redact"); RedactError(unchanged) != unchanged {
+		t.Errorf("expected error without secrets to be returned as is")
+	}
+
+	// plaintext values are not secrets resolved from a reference
+	for _, value := range []string{"https://rekor.example.com", "literal:env:not-a-reference"} {
+		resolvedValue, err := Resolve(context.Background(), value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Redact("value " + resolvedValue); got != "value "+resolvedValue {
+			t.Errorf("expected plaintext value not to be redacted, got %q", got)
+		}
+	}
+}
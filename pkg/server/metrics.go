@@ -27,6 +27,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sigstore/rekor-monitor/pkg/events"
 )
 
 type metrics struct {
@@ -35,6 +36,11 @@ type metrics struct {
 	// custom metrics
 	logIndexVerificationTotal   prometheus.Counter
 	logIndexVerificationFailure prometheus.Counter
+	identitySearchFailure       prometheus.Counter
+	notificationFailure         prometheus.Counter
+	checkpointWriteFailure      prometheus.Counter
+	consecutiveRunFailures      prometheus.Gauge
+	monitorEvents               *prometheus.CounterVec
 
 	// system
 	signalChan chan os.Signal
@@ -60,6 +66,26 @@ var _initMetricsFunc = sync.OnceValue[*metrics](func() *metrics {
 		Name: "log_index_verification_failure",
 		Help: "Total number of failed log consistency check attempts.",
 	})
+	m.identitySearchFailure = f.NewCounter(prometheus.CounterOpts{
+		Name: "identity_search_failure",
+		Help: "Total number of failed identity searches.",
+	})
+	m.notificationFailure = f.NewCounter(prometheus.CounterOpts{
+		Name: "notification_failure",
+		Help: "Total number of failed notification attempts.",
+	})
+	m.checkpointWriteFailure = f.NewCounter(prometheus.CounterOpts{
+		Name: "checkpoint_write_failure",
+		Help: "Total number of failed checkpoint writes.",
+	})
+	m.consecutiveRunFailures = f.NewGauge(prometheus.GaugeOpts{
+		Name: "consecutive_run_failures",
+		Help: "Number of consecutive failed monitor runs, reset to 0 after a successful run.",
+	})
+	m.monitorEvents = f.NewCounterVec(prometheus.CounterOpts{
+		Name: "monitor_events_total",
+		Help: "Total number of events published by the monitor loop, by event type.",
+	}, []string{"type"})
 
 	// subscribe to termination signals
 	signal.Notify(m.signalChan, os.Interrupt, syscall.SIGTERM)
@@ -77,6 +103,34 @@ func IncLogIndexVerificationFailure() {
 	getMetrics().logIndexVerificationFailure.Inc()
 }
 
+// IncIdentitySearchFailure increments the identity search failure counter
+func IncIdentitySearchFailure() {
+	getMetrics().identitySearchFailure.Inc()
+}
+
+// IncNotificationFailure increments the notification failure counter
+func IncNotificationFailure() {
+	getMetrics().notificationFailure.Inc()
+}
+
+// IncCheckpointWriteFailure increments the checkpoint write failure counter
+func IncCheckpointWriteFailure() {
+	getMetrics().checkpointWriteFailure.Inc()
+}
+
+// SetConsecutiveRunFailures sets the number of consecutive failed monitor runs
+func SetConsecutiveRunFailures(n int) {
+	getMetrics().consecutiveRunFailures.Set(float64(n))
+}
+
+// EventsSubscriber returns a subscriber counting the events published by the
+// monitor loop by event type
+func EventsSubscriber() events.Subscriber {
+	return events.SubscriberFunc(func(_ context.Context, event events.Event) {
+		getMetrics().monitorEvents.WithLabelValues(string(event.EventType())).Inc()
+	})
+}
+
 // GetSignalChan returns the signal channel for handling SIGINT/SIGTERM.
 func GetSignalChan() chan os.Signal {
 	return getMetrics().signalChan
@@ -92,6 +146,31 @@ func GetLogIndexVerificationFailure() prometheus.Counter {
 	return getMetrics().logIndexVerificationFailure
 }
 
+// GetIdentitySearchFailure returns the identity search failure counter.
+func GetIdentitySearchFailure() prometheus.Counter {
+	return getMetrics().identitySearchFailure
+}
+
+// GetNotificationFailure returns the notification failure counter.
+func GetNotificationFailure() prometheus.Counter {
+	return getMetrics().notificationFailure
+}
+
+// GetCheckpointWriteFailure returns the checkpoint write failure counter.
+func GetCheckpointWriteFailure() prometheus.Counter {
+	return getMetrics().checkpointWriteFailure
+}
+
+// GetConsecutiveRunFailures returns the consecutive run failures gauge.
+func GetConsecutiveRunFailures() prometheus.Gauge {
+	return getMetrics().consecutiveRunFailures
+}
+
+// GetMonitorEvents returns the monitor events counter.
+func GetMonitorEvents() *prometheus.CounterVec {
+	return getMetrics().monitorEvents
+}
+
 // StartMetricsServer starts the metrics server
 func StartMetricsServer(ctx context.Context, port int) error {
 	m := getMetrics()
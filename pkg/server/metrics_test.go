@@ -22,6 +22,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sigstore/rekor-monitor/pkg/events"
 )
 
 // TestStartMetricsServer verifies that the metrics server starts and serves the /metrics endpoint.
@@ -114,3 +117,42 @@ func TestIncLogIndexVerificationFailure(t *testing.T) {
 		t.Errorf("expected failure counter incremented, got:\n%s", b)
 	}
 }
+
+// TestFailureMetrics verifies that the monitor run failure metrics are updated correctly.
+func TestFailureMetrics(t *testing.T) {
+	searchBefore := testutil.ToFloat64(GetIdentitySearchFailure())
+	notificationBefore := testutil.ToFloat64(GetNotificationFailure())
+	checkpointBefore := testutil.ToFloat64(GetCheckpointWriteFailure())
+
+	IncIdentitySearchFailure()
+	IncNotificationFailure()
+	IncNotificationFailure()
+	IncCheckpointWriteFailure()
+	SetConsecutiveRunFailures(3)
+
+	if got := testutil.ToFloat64(GetIdentitySearchFailure()) - searchBefore; got != 1 {
+		t.Errorf("expected identity search failures to increase by 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(GetNotificationFailure()) - notificationBefore; got != 2 {
+		t.Errorf("expected notification failures to increase by 2, got %v", got)
+	}
+	if got := testutil.ToFloat64(GetCheckpointWriteFailure()) - checkpointBefore; got != 1 {
+		t.Errorf("expected checkpoint write failures to increase by 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(GetConsecutiveRunFailures()); got != 3 {
+		t.Errorf("expected 3 consecutive run failures, got %v", got)
+	}
+}
+
+// TestEventsSubscriber verifies that published events are counted by type.
+func TestEventsSubscriber(t *testing.T) {
+	before := testutil.ToFloat64(GetMonitorEvents().WithLabelValues(string(events.TypeShardsUpdated)))
+
+	bus := events.NewBus(EventsSubscriber())
+	bus.Publish(context.Background(), events.ShardsUpdated{})
+	bus.Publish(context.Background(), events.ShardsUpdated{})
+
+	if got := testutil.ToFloat64(GetMonitorEvents().WithLabelValues(string(events.TypeShardsUpdated))) - before; got != 2 {
+		t.Errorf("expected ShardsUpdated events to increase by 2, got %v", got)
+	}
+}
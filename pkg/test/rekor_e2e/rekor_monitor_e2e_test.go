@@ -178,7 +178,7 @@ func TestIdentitySearch(t *testing.T) {
 			OIDExtensions: []extensions.OIDExtension{
 				{
 					ObjectIdentifier: oid,
-					ExtensionValues:  []string{extValueString},
+					ExtensionValues:  extensions.ExactValues(extValueString),
 				},
 			},
 			FulcioExtensions: extensions.FulcioExtensions{},
@@ -276,7 +276,11 @@ func TestIdentitySearch(t *testing.T) {
 		OutputIdentitiesFile:   tempOutputIdentitiesFileName,
 		OutputIdentitiesFormat: "text",
 	}
-	_, _, err = rekor_v1.IdentitySearch(context.Background(), config, rekorClient, monitoredVals)
+	matcher, err := identity.NewMatcher(monitoredVals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = rekor_v1.IdentitySearch(context.Background(), config, rekorClient, rekorURL, matcher)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
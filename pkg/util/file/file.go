@@ -18,11 +18,13 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/sigstore/rekor-monitor/pkg/identity"
+	"github.com/sigstore/rekor-monitor/pkg/util/logging"
 	"github.com/sigstore/rekor/pkg/util"
 	"github.com/transparency-dev/formats/log"
 )
@@ -108,7 +110,7 @@ func ReadLatestCTSignedTreeHead(logInfoFile string) (*ct.SignedTreeHead, error)
 func WriteCTSignedTreeHead(sth *ct.SignedTreeHead, prev *ct.SignedTreeHead, logInfoFile string, force bool) error {
 	// Skip writing if the current tree size is 0
 	if sth.TreeSize == 0 {
-		fmt.Fprintf(os.Stderr, "skipping write of tree head: tree size is 0\n")
+		slog.Info("skipping write of tree head: tree size is 0")
 		return nil
 	}
 
@@ -138,7 +140,7 @@ func writeCheckpointBytes(checkpoint []byte, logInfoFile string) error {
 func WriteCheckpointRekorV1(checkpoint *util.SignedCheckpoint, prev *util.SignedCheckpoint, logInfoFile string, force bool) error {
 	// Skip writing if the current checkpoint size is 0
 	if checkpoint.Size == 0 {
-		fmt.Fprintf(os.Stderr, "skipping write of checkpoint: size is 0\n")
+		slog.Info("skipping write of checkpoint: size is 0")
 		return nil
 	}
 
@@ -159,7 +161,7 @@ func WriteCheckpointRekorV1(checkpoint *util.SignedCheckpoint, prev *util.Signed
 func WriteCheckpointRekorV2(checkpoint *log.Checkpoint, prev *log.Checkpoint, logInfoFile string, force bool) error {
 	// Skip writing if the current checkpoint size is 0
 	if checkpoint.Size == 0 {
-		fmt.Fprintf(os.Stderr, "skipping write of checkpoint: size is 0\n")
+		slog.Info("skipping write of checkpoint: size is 0", slog.String(logging.KeyLogOrigin, checkpoint.Origin))
 		return nil
 	}
 
@@ -173,29 +175,7 @@ func WriteCheckpointRekorV2(checkpoint *log.Checkpoint, prev *log.Checkpoint, lo
 
 // WriteIdentity writes an identity found in the log to a file
 func WriteIdentity(idFile string, idFileFormat string, idEntry identity.LogEntry) error {
-	file, err := os.OpenFile(idFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open identities file: %w", err)
-	}
-	defer file.Close()
-
-	switch idFileFormat {
-	case "text":
-		if _, err := fmt.Fprintf(file, "%s\n", idEntry.String()); err != nil {
-			return fmt.Errorf("failed to write to file: %w", err)
-		}
-	case "json":
-		marshalled, err := json.Marshal(idEntry)
-		if err != nil {
-			return fmt.Errorf("failed to marshal identity: %w", err)
-		}
-		marshalled = append(marshalled, '\n')
-		if _, err := file.Write(marshalled); err != nil {
-			return fmt.Errorf("failed to write to file: %w", err)
-		}
-	}
-
-	return nil
+	return WriteIdentityOutputs([]IdentityOutput{{Path: idFile, Format: idFileFormat}}, []identity.LogEntry{idEntry})
 }
 
 // WriteIdentityMetadata writes information about what log indices have been scanned to a file
@@ -227,15 +207,18 @@ func ReadIdentityMetadata(metadataFile string) (*IdentityMetadata, error) {
 	return idMetadata, nil
 }
 
-// WriteMatchedIdentityEntries writes a list of matched identities to a file
-func WriteMatchedIdentityEntries(identitiesFile string, identitiesFileFormat string, matchedEntries []identity.LogEntry, idMetadataFile *string, endIndex int64) error {
+// WriteMatchedIdentityEntries writes a list of matched identities to the output files
+func WriteMatchedIdentityEntries(outputs []IdentityOutput, matchedEntries []identity.LogEntry, idMetadataFile *string, endIndex int64) error {
 	if len(matchedEntries) > 0 {
 		for _, idEntry := range matchedEntries {
-			fmt.Fprintf(os.Stderr, "Found %s\n", idEntry.String())
-
-			if err := WriteIdentity(identitiesFile, identitiesFileFormat, idEntry); err != nil {
-				return fmt.Errorf("failed to write entry: %v", err)
-			}
+			slog.Info("found matching log entry",
+				slog.Int64(logging.KeyIndex, idEntry.Index),
+				slog.String(logging.KeyUUID, idEntry.UUID),
+				slog.String(logging.KeyMatchedIdentity, idEntry.MatchedIdentity),
+				slog.String("entry", idEntry.String()))
+		}
+		if err := WriteIdentityOutputs(outputs, matchedEntries); err != nil {
+			return fmt.Errorf("failed to write entry: %v", err)
 		}
 	}
 
@@ -16,7 +16,9 @@ package file
 
 import (
 	"bytes"
+	"encoding/asn1"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -25,6 +27,7 @@ import (
 	"testing"
 
 	ct "github.com/google/certificate-transparency-go"
+	"github.com/sigstore/rekor-monitor/pkg/identity"
 	"github.com/sigstore/rekor/pkg/util"
 	"golang.org/x/mod/sumdb/note"
 )
@@ -154,3 +157,48 @@ func TestReadWriteIdentityMetadata(t *testing.T) {
 		t.Errorf("expected latest index of 1, received incorrect or nil")
 	}
 }
+
+func TestWriteIdentity(t *testing.T) {
+	entry := identity.LogEntry{
+		MatchedIdentity:     "test cert value",
+		MatchedIdentityType: identity.MatchedIdentityTypeExtensionValue,
+		Index:               1,
+		OIDExtension:        asn1.ObjectIdentifier{2, 5, 29, 17},
+		ExtensionValue:      "test cert value",
+		LogKind:             identity.LogKindCT,
+	}
+
+	jsonFile := filepath.Join(t.TempDir(), "identities.jsonl")
+	for range 2 {
+		if err := WriteIdentity(jsonFile, "json", entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", content)
+	}
+	var record identity.LogEntryRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.SchemaVersion != identity.LogEntrySchemaVersion || record.OIDExtension != "2.5.29.17" || record.LogKind != identity.LogKindCT {
+		t.Errorf("unexpected identities file record %+v", record)
+	}
+
+	textFile := filepath.Join(t.TempDir(), "identities.txt")
+	if err := WriteIdentity(textFile, "text", entry); err != nil {
+		t.Fatal(err)
+	}
+	content, err = os.ReadFile(textFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns := strings.Split(strings.TrimSuffix(string(content), "\n"), "\t"); len(columns) != len(identity.LogEntryColumns) {
+		t.Errorf("expected %d columns, got %q", len(identity.LogEntryColumns), content)
+	}
+}
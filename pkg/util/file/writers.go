@@ -0,0 +1,458 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+const (
+	sarifSchema       = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion      = "2.1.0"
+	sarifToolName     = "rekor-monitor"
+	sarifToolInfoURI  = "https://github.com/sigstore/rekor-monitor"
+	otlpServiceName   = "rekor-monitor"
+	otlpScopeName     = "github.com/sigstore/rekor-monitor"
+	otlpAttributeRoot = "rekor_monitor."
+	// otlpSeverityWarn is the OpenTelemetry severity number of WARN
+	otlpSeverityWarn = 13
+	// sarifMaxResults is the number of results GitHub code scanning accepts
+	// in a run. Older results are dropped from the SARIF log beyond it.
+	sarifMaxResults = 25000
+)
+
+// IdentityOutput is an output file of log entries matching monitored identities
+type IdentityOutput struct {
+	Path string `yaml:"path"`
+	// Format is one of IdentityWriterFormats, defaults to text
+	Format string `yaml:"format"`
+	// ArtifactURI is the file of the repository that SARIF results are reported
+	// at, e.g. the monitor configuration, as GitHub code scanning requires results
+	// to have a file location. It must be set for the sarif format.
+	ArtifactURI string `yaml:"artifactURI"`
+}
+
+// Validate checks the output configuration
+func (o IdentityOutput) Validate() error {
+	if o.Path == "" {
+		return errors.New("output path must be set")
+	}
+	if _, err := identityWriter(o.Format); err != nil {
+		return err
+	}
+	if o.Format == "sarif" && o.ArtifactURI == "" {
+		return errors.New("artifactURI must be set for the sarif format")
+	}
+	return nil
+}
+
+// IdentityWriter appends log entries matching monitored identities to an output file
+type IdentityWriter interface {
+	Write(output IdentityOutput, entries []identity.LogEntry) error
+}
+
+// IdentityWriterFunc adapts a function to the IdentityWriter interface
+type IdentityWriterFunc func(output IdentityOutput, entries []identity.LogEntry) error
+
+// Write calls f(output, entries)
+func (f IdentityWriterFunc) Write(output IdentityOutput, entries []identity.LogEntry) error {
+	return f(output, entries)
+}
+
+// identityWriters maps output formats to their writers
+var identityWriters = map[string]IdentityWriter{
+	"text":   IdentityWriterFunc(writeText),
+	"json":   IdentityWriterFunc(writeNDJSON),
+	"ndjson": IdentityWriterFunc(writeNDJSON),
+	"csv":    IdentityWriterFunc(writeCSV),
+	"sarif":  IdentityWriterFunc(writeSARIF),
+	"otlp":   IdentityWriterFunc(writeOTLP),
+}
+
+// RegisterIdentityWriter registers a writer for an output format. It must be
+// called before the configuration is loaded, e.g. from an init function.
+func RegisterIdentityWriter(format string, writer IdentityWriter) {
+	identityWriters[format] = writer
+}
+
+// IdentityWriterFormats returns the registered output formats
+func IdentityWriterFormats() []string {
+	formats := make([]string, 0, len(identityWriters))
+	for format := range identityWriters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func identityWriter(format string) (IdentityWriter, error) {
+	if format == "" {
+		format = "text"
+	}
+	writer, ok := identityWriters[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid output format %s: must be one of %s", format, strings.Join(IdentityWriterFormats(), ", "))
+	}
+	return writer, nil
+}
+
+// WriteIdentityOutputs writes the log entries to every output, in the format of the output
+func WriteIdentityOutputs(outputs []IdentityOutput, entries []identity.LogEntry) error {
+	for _, output := range outputs {
+		writer, err := identityWriter(output.Format)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(output, entries); err != nil {
+			return fmt.Errorf("failed to write to %s: %w", output.Path, err)
+		}
+	}
+	return nil
+}
+
+// WriteFileAtomic writes a file through a temporary file, so that a crash
+// never leaves a partially written file behind
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// appendLines appends lines to a file, creating it if needed. header is
+// called with whether the file is empty, and returns lines written first.
+func appendLines(path string, header func(empty bool) ([]byte, error), lines []byte) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open identities file: %w", err)
+	}
+	defer file.Close()
+	if header != nil {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to open identities file: %w", err)
+		}
+		h, err := header(info.Size() == 0)
+		if err != nil {
+			return err
+		}
+		lines = append(h, lines...)
+	}
+	if _, err := file.Write(lines); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	return nil
+}
+
+// writeText writes the LogEntryColumns of each entry as a line of tab-separated values
+func writeText(output IdentityOutput, entries []identity.LogEntry) error {
+	var lines []byte
+	for _, entry := range entries {
+		lines = append(lines, entry.String()+"\n"...)
+	}
+	return appendLines(output.Path, nil, lines)
+}
+
+// writeNDJSON writes each entry as a line of JSON
+func writeNDJSON(output IdentityOutput, entries []identity.LogEntry) error {
+	var lines []byte
+	for _, entry := range entries {
+		marshalled, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal identity: %w", err)
+		}
+		lines = append(append(lines, marshalled...), '\n')
+	}
+	return appendLines(output.Path, nil, lines)
+}
+
+// writeCSV writes each entry as a CSV row of its LogEntryColumns, with a
+// header row if the file is new
+func writeCSV(output IdentityOutput, entries []identity.LogEntry) error {
+	var rows strings.Builder
+	w := csv.NewWriter(&rows)
+	for _, entry := range entries {
+		if err := w.Write(entry.Columns()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	header := func(empty bool) ([]byte, error) {
+		if !empty {
+			return nil, nil
+		}
+		var header strings.Builder
+		w := csv.NewWriter(&header)
+		if err := w.Write(identity.LogEntryColumns); err != nil {
+			return nil, err
+		}
+		w.Flush()
+		return []byte(header.String()), w.Error()
+	}
+	return appendLines(output.Path, header, []byte(rows.String()))
+}
+
+// sarifLog is a SARIF 2.1.0 log, with the properties used by rekor-monitor
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string                   `json:"ruleId"`
+	Level               string                   `json:"level"`
+	Message             sarifMessage             `json:"message"`
+	Locations           []sarifLocation          `json:"locations"`
+	PartialFingerprints map[string]string        `json:"partialFingerprints"`
+	Properties          *identity.LogEntryRecord `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation struct {
+		URI string `json:"uri"`
+	} `json:"artifactLocation"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifRules are the SARIF rules of the kinds of matched identities
+var sarifRules = []sarifRule{
+	{ID: string(identity.MatchedIdentityTypeCertSubject), Name: "MonitoredCertificateSubject", ShortDescription: sarifMessage{"A log entry was signed with a certificate for a monitored subject"}},
+	{ID: string(identity.MatchedIdentityTypeExtensionValue), Name: "MonitoredCertificateExtension", ShortDescription: sarifMessage{"A log entry was signed with a certificate with a monitored extension value"}},
+	{ID: string(identity.MatchedIdentityTypeFingerprint), Name: "MonitoredFingerprint", ShortDescription: sarifMessage{"A log entry was signed with a monitored key or certificate"}},
+	{ID: string(identity.MatchedIdentityTypeSubject), Name: "MonitoredSubject", ShortDescription: sarifMessage{"A log entry was signed with a key of a monitored subject"}},
+	{ID: string(identity.MatchedIdentityTypeRule), Name: "MonitoredRule", ShortDescription: sarifMessage{"A log entry matched a monitored rule"}},
+	{ID: string(identity.MatchedIdentityTypePolicy), Name: "MonitoredPolicy", ShortDescription: sarifMessage{"A log entry matched a monitored policy expression"}},
+}
+
+// writeSARIF adds the entries as results to the SARIF log of the output,
+// so that they can be uploaded to GitHub code scanning. SARIF files can't be
+// appended to, so the SARIF log is rewritten through a temporary file. Results
+// are deduplicated by their partial fingerprints, and the log keeps the latest
+// sarifMaxResults results, so that rewriting it doesn't grow with every run.
+func writeSARIF(output IdentityOutput, entries []identity.LogEntry) error {
+	log := sarifLog{Schema: sarifSchema, Version: sarifVersion}
+	content, err := os.ReadFile(output.Path)
+	switch {
+	case err == nil && len(content) > 0:
+		if err := json.Unmarshal(content, &log); err != nil {
+			return fmt.Errorf("failed to parse SARIF file: %w", err)
+		}
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read SARIF file: %w", err)
+	}
+	if len(log.Runs) == 0 {
+		log.Runs = []sarifRun{{Results: []sarifResult{}}}
+	}
+	run := &log.Runs[0]
+	run.Tool.Driver.Name = sarifToolName
+	run.Tool.Driver.InformationURI = sarifToolInfoURI
+	run.Tool.Driver.Rules = sarifRules
+
+	results := make([]sarifResult, 0, len(entries))
+	for _, entry := range entries {
+		record := entry.Record()
+		logicalName := fmt.Sprintf("%s/%d", record.LogOrigin, record.Index)
+		location := sarifLocation{LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: logicalName, Kind: "logEntry"}}}
+		location.PhysicalLocation = &sarifPhysicalLocation{}
+		location.PhysicalLocation.ArtifactLocation.URI = output.ArtifactURI
+		results = append(results, sarifResult{
+			RuleID:    string(record.MatchedIdentityType),
+			Level:     "warning",
+			Message:   sarifMessage{Text: fmt.Sprintf("Log entry %d of %s matches the monitored %s %s", record.Index, record.LogOrigin, record.MatchedIdentityType, record.MatchedIdentity)},
+			Locations: []sarifLocation{location},
+			PartialFingerprints: map[string]string{
+				"logEntry/v1": fmt.Sprintf("%s:%s:%s", logicalName, record.MatchedIdentityType, record.MatchedIdentity),
+			},
+			Properties: &record,
+		})
+	}
+	run.Results = appendSARIFResults(run.Results, results, sarifMaxResults)
+
+	marshalled, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SARIF log: %w", err)
+	}
+	if err := WriteFileAtomic(output.Path, append(marshalled, '\n')); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	return nil
+}
+
+// appendSARIFResults appends the results that don't have the partial
+// fingerprints of an earlier result, and drops the oldest results beyond
+// maxResults
+func appendSARIFResults(results, added []sarifResult, maxResults int) []sarifResult {
+	seen := make(map[string]bool, len(results)+len(added))
+	key := func(result sarifResult) string {
+		marshalled, _ := json.Marshal(result.PartialFingerprints)
+		return string(marshalled)
+	}
+	for _, result := range results {
+		seen[key(result)] = true
+	}
+	for _, result := range added {
+		if k := key(result); !seen[k] {
+			seen[k] = true
+			results = append(results, result)
+		}
+	}
+	if len(results) > maxResults {
+		results = slices.Delete(results, 0, len(results)-maxResults)
+	}
+	return results
+}
+
+// otlpLogsRequest is an OTLP ExportLogsServiceRequest in the OTLP/JSON encoding
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string          `json:"timeUnixNano"`
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 otlpValue       `json:"body"`
+	Attributes           []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is an OTLP AnyValue. 64-bit integers are encoded as strings in OTLP/JSON.
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func otlpString(value string) otlpValue {
+	return otlpValue{StringValue: &value}
+}
+
+// writeOTLP writes the entries as a line of an OTLP/JSON logs request, the
+// format of the OpenTelemetry collector file receiver, with a log record per entry
+func writeOTLP(output IdentityOutput, entries []identity.LogEntry) error {
+	if len(entries) == 0 {
+		return appendLines(output.Path, nil, nil)
+	}
+	now := time.Now()
+	scopeLogs := otlpScopeLogs{}
+	scopeLogs.Scope.Name = otlpScopeName
+	for _, entry := range entries {
+		record := entry.Record()
+		detectedAt := record.DetectedAt
+		if detectedAt.IsZero() {
+			detectedAt = now
+		}
+		logRecord := otlpLogRecord{
+			TimeUnixNano:         strconv.FormatInt(detectedAt.UnixNano(), 10),
+			ObservedTimeUnixNano: strconv.FormatInt(now.UnixNano(), 10),
+			SeverityNumber:       otlpSeverityWarn,
+			SeverityText:         "WARN",
+			Body:                 otlpString("found matching log entry"),
+		}
+		index := strconv.FormatInt(record.Index, 10)
+		logRecord.Attributes = append(logRecord.Attributes, otlpAttribute{Key: otlpAttributeRoot + "index", Value: otlpValue{IntValue: &index}})
+		columns := entry.Columns()
+		for i, column := range identity.LogEntryColumns {
+			if column == "index" {
+				continue
+			}
+			if value := columns[i]; value != "" {
+				logRecord.Attributes = append(logRecord.Attributes, otlpAttribute{Key: otlpAttributeRoot + column, Value: otlpString(value)})
+			}
+		}
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, logRecord)
+	}
+	resourceLogs := otlpResourceLogs{ScopeLogs: []otlpScopeLogs{scopeLogs}}
+	resourceLogs.Resource.Attributes = []otlpAttribute{{Key: "service.name", Value: otlpString(otlpServiceName)}}
+
+	marshalled, err := json.Marshal(otlpLogsRequest{ResourceLogs: []otlpResourceLogs{resourceLogs}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTLP logs: %w", err)
+	}
+	return appendLines(output.Path, nil, append(marshalled, '\n'))
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sigstore/rekor-monitor/pkg/identity"
+)
+
+func writersTestEntry(index int64) identity.LogEntry {
+	return identity.LogEntry{
+		CertSubject:         "user@example.com",
+		Issuer:              "https://accounts.example.com",
+		MatchedIdentity:     "user@example.com",
+		MatchedIdentityType: identity.MatchedIdentityTypeCertSubject,
+		Index:               index,
+		LogOrigin:           "rekor.sigstore.dev",
+		LogKind:             identity.LogKindRekorV1,
+		DetectedAt:          time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWriteIdentityOutputsCSV(t *testing.T) {
+	output := IdentityOutput{Path: filepath.Join(t.TempDir(), "identities.csv"), Format: "csv"}
+	for i := range 2 {
+		if err := WriteIdentityOutputs([]IdentityOutput{output}, []identity.LogEntry{writersTestEntry(int64(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err := os.ReadFile(output.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %q", content)
+	}
+	if strings.Join(rows[0], ",") != strings.Join(identity.LogEntryColumns, ",") {
+		t.Errorf("unexpected header %v", rows[0])
+	}
+	if rows[1][4] != "0" || rows[2][4] != "1" {
+		t.Errorf("unexpected rows %v", rows[1:])
+	}
+}
+
+func TestWriteIdentityOutputsNDJSON(t *testing.T) {
+	output := IdentityOutput{Path: filepath.Join(t.TempDir(), "identities.jsonl"), Format: "ndjson"}
+	if err := WriteIdentityOutputs([]IdentityOutput{output}, []identity.LogEntry{writersTestEntry(1), writersTestEntry(2)}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(output.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", content)
+	}
+	var entry identity.LogEntry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Index != 2 || entry.LogOrigin != "rekor.sigstore.dev" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestWriteIdentityOutputsSARIF(t *testing.T) {
+	output := IdentityOutput{Path: filepath.Join(t.TempDir(), "identities.sarif"), Format: "sarif", ArtifactURI: "config.yaml"}
+	for i := range 2 {
+		if err := WriteIdentityOutputs([]IdentityOutput{output}, []identity.LogEntry{writersTestEntry(int64(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// entries already in the SARIF log aren't added again
+	if err := WriteIdentityOutputs([]IdentityOutput{output}, []identity.LogEntry{writersTestEntry(1)}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(output.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(content, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log %s", content)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != sarifToolName || len(run.Tool.Driver.Rules) != len(sarifRules) {
+		t.Errorf("unexpected SARIF tool %+v", run.Tool)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(run.Results))
+	}
+	result := run.Results[1]
+	if result.RuleID != string(identity.MatchedIdentityTypeCertSubject) || result.Level != "warning" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if got := result.PartialFingerprints["logEntry/v1"]; got != "rekor.sigstore.dev/1:certSubject:user@example.com" {
+		t.Errorf("unexpected fingerprint %s", got)
+	}
+	if len(result.Locations) != 1 || result.Locations[0].PhysicalLocation == nil || result.Locations[0].PhysicalLocation.ArtifactLocation.URI != "config.yaml" {
+		t.Errorf("unexpected locations %+v", result.Locations)
+	}
+	if result.Properties == nil || result.Properties.Index != 1 {
+		t.Errorf("unexpected properties %+v", result.Properties)
+	}
+}
+
+func TestAppendSARIFResults(t *testing.T) {
+	result := func(fingerprint string) sarifResult {
+		return sarifResult{PartialFingerprints: map[string]string{"logEntry/v1": fingerprint}}
+	}
+	results := appendSARIFResults(nil, []sarifResult{result("a"), result("b"), result("a")}, 3)
+	results = appendSARIFResults(results, []sarifResult{result("b"), result("c"), result("d")}, 3)
+	var fingerprints []string
+	for _, result := range results {
+		fingerprints = append(fingerprints, result.PartialFingerprints["logEntry/v1"])
+	}
+	if want := []string{"b", "c", "d"}; !slices.Equal(fingerprints, want) {
+		t.Errorf("expected results %v, got %v", want, fingerprints)
+	}
+}
+
+func TestWriteIdentityOutputsOTLP(t *testing.T) {
+	output := IdentityOutput{Path: filepath.Join(t.TempDir(), "identities.otlp.jsonl"), Format: "otlp"}
+	if err := WriteIdentityOutputs([]IdentityOutput{output}, []identity.LogEntry{writersTestEntry(7)}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(output.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request otlpLogsRequest
+	if err := json.Unmarshal(content, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.ResourceLogs) != 1 || len(request.ResourceLogs[0].ScopeLogs) != 1 {
+		t.Fatalf("unexpected OTLP request %s", content)
+	}
+	records := request.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 1 {
+		t.Fatalf("expected 1 log record, got %d", len(records))
+	}
+	record := records[0]
+	if record.SeverityNumber != otlpSeverityWarn || record.TimeUnixNano != "1735725600000000000" {
+		t.Errorf("unexpected log record %+v", record)
+	}
+	attributes := map[string]otlpValue{}
+	for _, attribute := range record.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	if index := attributes["rekor_monitor.index"]; index.IntValue == nil || *index.IntValue != "7" {
+		t.Errorf("unexpected index attribute %+v", index)
+	}
+	if origin := attributes["rekor_monitor.logOrigin"]; origin.StringValue == nil || *origin.StringValue != "rekor.sigstore.dev" {
+		t.Errorf("unexpected logOrigin attribute %+v", origin)
+	}
+	if _, ok := attributes["rekor_monitor.uuid"]; ok {
+		t.Errorf("expected empty values to be omitted, got %+v", attributes)
+	}
+}
+
+func TestIdentityOutputValidate(t *testing.T) {
+	if err := (IdentityOutput{Path: "identities.txt"}).Validate(); err != nil {
+		t.Errorf("expected default format to be valid, got %v", err)
+	}
+	if err := (IdentityOutput{Format: "csv"}).Validate(); err == nil {
+		t.Errorf("expected error for missing path")
+	}
+	if err := (IdentityOutput{Path: "identities.sarif", Format: "sarif"}).Validate(); err == nil {
+		t.Errorf("expected error for missing artifactURI")
+	}
+	err := (IdentityOutput{Path: "identities.xml", Format: "xml"}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "invalid output format xml: must be one of csv, json, ndjson, otlp, sarif, text") {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging configures the structured logger used by the monitors and
+// defines the attribute keys shared by all log records, so that log pipelines
+// can index and alert on monitor events.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sigstore/rekor-monitor/pkg/secrets"
+)
+
+// Attribute keys used consistently across all packages
+const (
+	KeyLogOrigin        = "logOrigin"
+	KeyStartIndex       = "startIndex"
+	KeyEndIndex         = "endIndex"
+	KeyIndex            = "index"
+	KeyUUID             = "uuid"
+	KeyTreeSize         = "treeSize"
+	KeyRootHash         = "rootHash"
+	KeyPreviousTreeSize = "previousTreeSize"
+	KeyPreviousRootHash = "previousRootHash"
+	KeyMatchedIdentity  = "matchedIdentity"
+	KeyError            = "err"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel parses a log level name (debug, info, warn or error)
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return l, fmt.Errorf("invalid log level %q: must be one of debug, info, warn or error", level)
+	}
+	return l, nil
+}
+
+// NewLogger creates a logger writing records to w in the given format ("text" or "json")
+// and discarding records below the given level. Secrets resolved from the configuration
+// are redacted from messages and attributes.
+func NewLogger(w io.Writer, format string, level string) (*slog.Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch format {
+	case FormatText, "":
+		return slog.New(redactingHandler{slog.NewTextHandler(w, opts)}), nil
+	case FormatJSON:
+		return slog.New(redactingHandler{slog.NewJSONHandler(w, opts)}), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: must be 'text' or 'json'", format)
+	}
+}
+
+// Err returns an attribute for an error, using the shared error key
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// IndexRange returns the attributes for a range of log indices
+func IndexRange(start, end int64) []any {
+	return []any{slog.Int64(KeyStartIndex, start), slog.Int64(KeyEndIndex, end)}
+}
+
+// Origin holds the origin of the monitored log, which changes when the
+// monitor moves to a new shard
+type Origin struct {
+	origin atomic.Value
+}
+
+// NewOrigin creates an Origin set to the given log origin
+func NewOrigin(origin string) *Origin {
+	o := &Origin{}
+	o.Set(origin)
+	return o
+}
+
+// Set updates the log origin added to the records of loggers using o
+func (o *Origin) Set(origin string) {
+	o.origin.Store(origin)
+}
+
+// String returns the current log origin
+func (o *Origin) String() string {
+	origin, _ := o.origin.Load().(string)
+	return origin
+}
+
+// WithOrigin returns a logger that adds the current value of origin to each
+// record, unless the record or the logger already sets a log origin
+func WithOrigin(logger *slog.Logger, origin *Origin) *slog.Logger {
+	return slog.New(originHandler{Handler: logger.Handler(), origin: origin})
+}
+
+// originHandler adds the current log origin to the records of a handler
+type originHandler struct {
+	slog.Handler
+	origin *Origin
+}
+
+func (h originHandler) Handle(ctx context.Context, record slog.Record) error {
+	hasOrigin := false
+	record.Attrs(func(attr slog.Attr) bool {
+		hasOrigin = attr.Key == KeyLogOrigin
+		return !hasOrigin
+	})
+	if !hasOrigin {
+		record = record.Clone()
+		record.AddAttrs(slog.String(KeyLogOrigin, h.origin.String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h originHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	for _, attr := range attrs {
+		if attr.Key == KeyLogOrigin {
+			return h.Handler.WithAttrs(attrs)
+		}
+	}
+	return originHandler{Handler: h.Handler.WithAttrs(attrs), origin: h.origin}
+}
+
+func (h originHandler) WithGroup(name string) slog.Handler {
+	return originHandler{Handler: h.Handler.WithGroup(name), origin: h.origin}
+}
+
+// redactingHandler redacts resolved secrets from the records of a handler
+type redactingHandler struct {
+	slog.Handler
+}
+
+func (h redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, secrets.Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		redacted = append(redacted, redactAttr(attr))
+	}
+	return redactingHandler{h.Handler.WithAttrs(redacted)}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr redacts resolved secrets from string and error attribute values
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, secrets.Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, 0, len(group))
+		for _, groupAttr := range group {
+			redacted = append(redacted, redactAttr(groupAttr))
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, secrets.Redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/rekor-monitor/pkg/secrets"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	}
+	for name, want := range tests {
+		got, err := ParseLevel(name)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) returned error: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("expected error for invalid level")
+	}
+}
+
+func TestNewLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, FormatJSON, "info")
+	if err != nil {
+		t.Fatalf("NewLogger returned error: %v", err)
+	}
+	logger.Debug("dropped")
+	logger.Info("kept", slog.Int64(KeyIndex, 42), Err(errors.New("boom")))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 record, got %d: %s", len(lines), buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("record is not valid JSON: %v", err)
+	}
+	if record["msg"] != "kept" || record[KeyIndex] != float64(42) || record[KeyError] != "boom" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestNewLoggerInvalidFormat(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Errorf("expected error for invalid format")
+	}
+}
+
+func TestNewLoggerRedactsSecrets(t *testing.T) {
+	t.Setenv("REKOR_MONITOR_TEST_LOG_SECRET", "s3cr3t-value")
+	if _, err := secrets.Resolve(context.Background(), "env:REKOR_MONITOR_TEST_LOG_SECRET"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, FormatText, "info")
+	if err != nil {
+		t.Fatalf("NewLogger returned error: %v", err)
+	}
+	logger.With("token", "s3cr3t-value").Info("sending with s3cr3t-value",
+		Err(errors.New("401 for s3cr3t-value")), slog.Group("request", slog.String("auth", "Bearer s3cr3t-value")))
+
+	if strings.Contains(buf.String(), "s3cr3t-value") {
+		t.Errorf("expected secret to be redacted, got %s", buf.String())
+	}
+	if strings.Count(buf.String(), secrets.Redacted) != 4 {
+		t.Errorf("expected 4 redacted values, got %s", buf.String())
+	}
+}
+
+func TestWithOrigin(t *testing.T) {
+	var buf bytes.Buffer
+	base, err := NewLogger(&buf, FormatJSON, "info")
+	if err != nil {
+		t.Fatalf("NewLogger returned error: %v", err)
+	}
+	origin := NewOrigin("rekor.sigstore.dev - 1")
+	logger := WithOrigin(base, origin)
+
+	logger.Info("first")
+	origin.Set("log2025-1.rekor.sigstore.dev")
+	logger.Info("second")
+	logger.Info("explicit", slog.String(KeyLogOrigin, "checkpoint origin"))
+	logger.With(KeyIndex, 1).Info("with attrs")
+
+	want := []string{"rekor.sigstore.dev - 1", "log2025-1.rekor.sigstore.dev", "checkpoint origin", "log2025-1.rekor.sigstore.dev"}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d records, got %d: %s", len(want), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if n := strings.Count(line, `"`+KeyLogOrigin+`"`); n != 1 {
+			t.Errorf("record %d has %d log origins: %s", i, n, line)
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("record is not valid JSON: %v", err)
+		}
+		if record[KeyLogOrigin] != want[i] {
+			t.Errorf("record %d: expected log origin %q, got %v", i, want[i], record[KeyLogOrigin])
+		}
+	}
+}
@@ -49,6 +49,10 @@ func (r RetryError) Error() string {
 	return r.Err.Error()
 }
 
+func (r RetryError) Unwrap() error {
+	return r.Err
+}
+
 func (r RetryError) ShouldRetry() bool {
 	if r.Err == nil {
 		return false